@@ -8,8 +8,10 @@ import (
 	"log/slog"
 	"net/http"
 	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
 	"github.com/gin-gonic/gin"
@@ -17,12 +19,25 @@ import (
 
 type authService interface {
 	Register(ctx context.Context, username string, email string, password string, name string, surname string) error
-	LoginByUsername(ctx context.Context, username string, password string) (*user.User, error)
-	LoginByEmail(ctx context.Context, email string, password string) (*user.User, error)
-	GenerateJWT(userID string) (string, error)
-	GenerateRefreshToken(ctx context.Context, userID string) (*refresh_token.RefreshToken, error)
-	ValidateRefreshToken(ctx context.Context, token string) (string, error)
-	RotateRefreshToken(ctx context.Context, userID string) (*refresh_token.RefreshToken, error)
+	LoginByUsername(ctx context.Context, username string, password string) (*user.User, string, error)
+	LoginByEmail(ctx context.Context, email string, password string) (*user.User, string, error)
+	CompleteMFALogin(ctx context.Context, ticket string, code string) (*user.User, error)
+	GenerateJWT(userID string, sessionID string, emailVerified bool) (string, error)
+	CheckEmailVerified(ctx context.Context, userID string) (bool, error)
+	GenerateRefreshToken(ctx context.Context, userID string, userAgent string, ip string) (*refresh_token.RefreshToken, error)
+	ValidateRefreshToken(ctx context.Context, token string) (*refresh_token.Session, error)
+	RotateRefreshToken(ctx context.Context, session *refresh_token.Session, userAgent string, ip string) (*refresh_token.RefreshToken, error)
+	GetSessions(ctx context.Context, userID string) ([]*refresh_token.Session, error)
+	RevokeSession(ctx context.Context, userID string, sessionID string) error
+	Logout(ctx context.Context, userID string, sessionID string, jti string, expiresAt time.Time) error
+	ForgotPassword(ctx context.Context, email string) (time.Duration, error)
+	ResetPassword(ctx context.Context, token string, password string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) (time.Duration, error)
+	GetProfile(ctx context.Context, userID string) (*user.User, error)
+	UpdateProfile(ctx context.Context, userID string, update user.ProfileUpdate, currentPassword string) (*user.User, error)
+	ChangePassword(ctx context.Context, userID string, sessionID string, currentPassword string, newPassword string) error
+	DeleteAccount(ctx context.Context, userID string, password string, jti string, expiresAt time.Time) error
 }
 
 type Controller struct {
@@ -51,16 +66,81 @@ type loginByEmailRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+type loginMFARequest struct {
+	Ticket string `json:"ticket" binding:"required,max=128"`
+	Code   string `json:"code" binding:"required,max=32"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required,max=128"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type verifyEmailRequest struct {
+	Token string `form:"token" binding:"required,max=128"`
+}
+
+type resendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// updateProfileRequest only changes the fields that are present
+type updateProfileRequest struct {
+	Username *string `json:"username" binding:"omitempty,min=1,max=50"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+	Name     *string `json:"name" binding:"omitempty,min=1,max=50"`
+	Surname  *string `json:"surname" binding:"omitempty,min=1,max=50"`
+	// CurrentPassword is required only to change the email
+	CurrentPassword string `json:"current_password"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
 type loginUserResponse struct {
 	Message string   `json:"message"`
 	User    userInfo `json:"user"`
 }
 
+// mfaRequiredResponse is returned instead of the cookies
+// when the user has to present the second factor
+type mfaRequiredResponse struct {
+	Message     string `json:"message"`
+	MFARequired bool   `json:"mfa_required"`
+	MFATicket   string `json:"mfa_ticket"`
+}
+
 type userInfo struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Name     string `json:"name"`
-	Surname  string `json:"surname"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	Surname       string `json:"surname"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+type sessionURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is true for the session that issued the access token of the request
+	Current    bool      `json:"current"`
 }
 
 func (uc *Controller) Register(c *gin.Context) {
@@ -131,11 +211,16 @@ func (uc *Controller) LoginByUsername(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	user, err := uc.service.LoginByUsername(ctx, request.Username, request.Password)
+	user, mfaTicket, err := uc.service.LoginByUsername(ctx, request.Username, request.Password)
+	recordLogin("username", mfaTicket, err)
 	if err != nil {
 		uc.handleLoginError(c, err)
 		return
 	}
+	if mfaTicket != "" {
+		uc.handleMFARequired(c, mfaTicket)
+		return
+	}
 
 	uc.handleSuccessfulLogin(c, user)
 }
@@ -157,8 +242,44 @@ func (uc *Controller) LoginByEmail(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
-	user, err := uc.service.LoginByEmail(ctx, request.Email, request.Password)
+	user, mfaTicket, err := uc.service.LoginByEmail(ctx, request.Email, request.Password)
+	recordLogin("email", mfaTicket, err)
+	if err != nil {
+		uc.handleLoginError(c, err)
+		return
+	}
+	if mfaTicket != "" {
+		uc.handleMFARequired(c, mfaTicket)
+		return
+	}
+
+	uc.handleSuccessfulLogin(c, user)
+}
+
+// LoginMFA completes the login of the users with the second factor enabled
+func (uc *Controller) LoginMFA(c *gin.Context) {
+	var request loginMFARequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid mfa login request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := uc.service.CompleteMFALogin(ctx, request.Ticket, request.Code)
+	recordLogin("mfa", "", err)
 	if err != nil {
+		if errors.Is(err, ErrInvalidMFATicket) {
+			slog.Warn("invalid mfa login ticket", "error", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired ticket"})
+			return
+		}
+		if errors.Is(err, ErrInvalidMFACode) {
+			slog.Warn("invalid mfa login code", "error", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+			return
+		}
 		uc.handleLoginError(c, err)
 		return
 	}
@@ -166,12 +287,63 @@ func (uc *Controller) LoginByEmail(c *gin.Context) {
 	uc.handleSuccessfulLogin(c, user)
 }
 
+// recordLogin counts the login by its outcome, the credentials are
+// checked once a user with the second factor enabled gets the ticket
+func recordLogin(method string, mfaTicket string, err error) {
+	outcome := "error"
+	var lockedErr *AccountLockedError
+	switch {
+	case err == nil && mfaTicket != "":
+		outcome = "mfa_required"
+	case err == nil:
+		outcome = "success"
+	case errors.Is(err, ErrUserNotExists):
+		outcome = "user_not_exists"
+	case errors.Is(err, ErrInvalidPassword):
+		outcome = "invalid_password"
+	case errors.As(err, &lockedErr):
+		outcome = "account_locked"
+	case errors.Is(err, ErrEmailNotVerified):
+		outcome = "email_not_verified"
+	case errors.Is(err, ErrInvalidMFATicket):
+		outcome = "invalid_mfa_ticket"
+	case errors.Is(err, ErrInvalidMFACode):
+		outcome = "invalid_mfa_code"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		outcome = "canceled"
+	}
+	metrics.LoginAttempts.WithLabelValues(method, outcome).Inc()
+}
+
+func (uc *Controller) handleMFARequired(c *gin.Context, mfaTicket string) {
+	slog.Info("two-factor authentication required")
+	c.JSON(http.StatusOK, mfaRequiredResponse{
+		Message:     "two-factor authentication required",
+		MFARequired: true,
+		MFATicket:   mfaTicket,
+	})
+}
+
 func (uc *Controller) handleLoginError(c *gin.Context, err error) {
 	if errors.Is(err, ErrUserNotExists) || errors.Is(err, ErrInvalidPassword) {
 		slog.Warn("invalid login credentials", "error", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
+	var lockedErr *AccountLockedError
+	if errors.As(err, &lockedErr) {
+		slog.Warn("login to a locked account", "error", err)
+		// the header is in seconds, rounded up so that the client never retries too early
+		seconds := int((lockedErr.RetryAfter + time.Second - 1) / time.Second)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed attempts, retry later"})
+		return
+	}
+	if errors.Is(err, ErrEmailNotVerified) {
+		slog.Warn("login with an unverified email", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": "email not verified"})
+		return
+	}
 	if errors.Is(err, context.Canceled) {
 		return
 	}
@@ -188,7 +360,24 @@ func (uc *Controller) handleLoginError(c *gin.Context, err error) {
 func (uc *Controller) handleSuccessfulLogin(c *gin.Context, user *user.User) {
 	ctx := c.Request.Context()
 
-	accessToken, err := uc.service.GenerateJWT(user.ID)
+	// the refresh token is generated first since the
+	// access token carries the id of its session
+	refreshToken, err := uc.service.GenerateRefreshToken(ctx, user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("request timeout", "error", err)
+			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			return
+		}
+		slog.Error("failed to generate refresh token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	accessToken, err := uc.service.GenerateJWT(user.ID, refreshToken.SessionID, user.IsEmailVerified())
 	if err != nil {
 		slog.Error("failed to generate JWT", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -205,21 +394,6 @@ func (uc *Controller) handleSuccessfulLogin(c *gin.Context, user *user.User) {
 		true,					// httpOnly
 	)
 
-	refreshToken, err := uc.service.GenerateRefreshToken(ctx, user.ID)
-	if err != nil {
-		if errors.Is(err, context.Canceled) {
-			return
-		}
-		if errors.Is(err, context.DeadlineExceeded) {
-			slog.Warn("request timeout", "error", err)
-			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
-			return
-		}
-		slog.Error("failed to generate refresh token", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
-	}
-
 	c.SetCookie(
 		"__Host-refresh_token",												// name
 		refreshToken.RefreshToken,  									// value
@@ -232,12 +406,7 @@ func (uc *Controller) handleSuccessfulLogin(c *gin.Context, user *user.User) {
 
 	loginUserResponse := &loginUserResponse{
 		Message: "login successful",
-		User: userInfo{
-			Username: user.Username,
-			Email:    user.Email,
-			Name:     user.Name,
-			Surname:  user.Surname,
-		},
+		User:    toUserInfo(user),
 	}
 
 	slog.Info("user logged in successfully", "userID", user.ID)
@@ -257,8 +426,17 @@ func (uc *Controller) RefreshToken(c *gin.Context) {
 	}
 
 	// validate the refresh token
-	userID, err := uc.service.ValidateRefreshToken(ctx, rt)
+	session, err := uc.service.ValidateRefreshToken(ctx, rt)
 	if err != nil {
+		if errors.Is(err, ErrTokenReused) {
+			// every session of the user has been revoked,
+			// so both the cookies of this client are cleared
+			c.SetCookie(refreshCookie, "", -1, "/", "", true, true)
+			c.SetCookie("jwt", "", -1, "/", "", true, true)
+			slog.Warn("refresh token reused", "error", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "refresh token reused, all sessions have been revoked"})
+			return
+		}
 		if errors.Is(err, ErrInvalidToken) {
 			// this two deletes the refresh token cookie in a secure way
 			// with this we ensure that the browser makes another request 
@@ -281,9 +459,42 @@ func (uc *Controller) RefreshToken(c *gin.Context) {
 		return
 	}
 
+	// the email could have been verified since the last access token was issued
+	emailVerified, err := uc.service.CheckEmailVerified(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, ErrEmailNotVerified) {
+			slog.Warn("refresh with an unverified email", "error", err)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "email not verified"})
+			return
+		}
+		if errors.Is(err, ErrUserNotExists) {
+			c.SetCookie(refreshCookie, "", -1, "/", "", true, true)
+			slog.Warn("refresh token of a deleted user", "error", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("request timeout", "error", err)
+			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			return
+		}
+		slog.Error("failed to check the email verification", "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
 	// generate a new refresh token by rotate
-	newRefreshToken, err := uc.service.RotateRefreshToken(ctx, userID)
+	newRefreshToken, err := uc.service.RotateRefreshToken(ctx, session, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			c.SetCookie(refreshCookie, "", -1, "/", "", true, true)
+			slog.Warn("refresh token rotated concurrently", "error", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+			return
+		}
 		if errors.Is(err, context.Canceled) {
 			return
 		}
@@ -308,7 +519,7 @@ func (uc *Controller) RefreshToken(c *gin.Context) {
 	)
 
 	// generate a new JWT
-	tokenString, err := uc.service.GenerateJWT(userID)
+	tokenString, err := uc.service.GenerateJWT(session.UserID, session.ID, emailVerified)
 	if err != nil {
 		slog.Error("failed to generate JWT", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -325,12 +536,410 @@ func (uc *Controller) RefreshToken(c *gin.Context) {
 		true,					// httpOnly
 	)
 
-	slog.Info("token refreshed successfully", "userID", userID)
+	slog.Info("token refreshed successfully", "userID", session.UserID, "sessionID", session.ID)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "new jwt and refresh token generated",
 	})
 }
 
+func (uc *Controller) Logout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// these values are set by the auth middleware
+	userID := c.GetString("userID")
+	sessionID := c.GetString("sessionID")
+	jti := c.GetString("jti")
+	expiresAt := c.GetTime("tokenExpiresAt")
+
+	err := uc.service.Logout(ctx, userID, sessionID, jti, expiresAt)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("request timeout", "error", err)
+			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			return
+		}
+		slog.Error("failed to logout user", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	// a negative max age tells the browser to delete the cookies
+	c.SetCookie("jwt", "", -1, "/", "", true, true)
+	c.SetCookie("__Host-refresh_token", "", -1, "/", "", true, true)
+
+	slog.Info("user logged out successfully", "userID", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "logout successful"})
+}
+
+func (uc *Controller) ForgotPassword(c *gin.Context) {
+	var request forgotPasswordRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid forgot password request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	retryAfter, err := uc.service.ForgotPassword(ctx, request.Email)
+	if err != nil {
+		if errors.Is(err, ErrResendThrottled) {
+			// the header is in seconds, rounded up so that the client never retries too early
+			seconds := int((retryAfter + time.Second - 1) / time.Second)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			slog.Warn("password reset email throttled", "error", err)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, retry later"})
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("request timeout", "error", err)
+			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			return
+		}
+		slog.Error("failed to start the password reset", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	// the response is the same whether the email is registered or not
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+func (uc *Controller) ResetPassword(c *gin.Context) {
+	var request resetPasswordRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid reset password request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = uc.validatePassword(request.Password)
+	if err != nil {
+		slog.Warn("invalid password format", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = uc.service.ResetPassword(ctx, request.Token, request.Password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidResetToken) {
+			slog.Warn("invalid password reset token", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired reset token"})
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("request timeout", "error", err)
+			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			return
+		}
+		slog.Error("failed to reset password", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	slog.Info("password reset successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+}
+
+func (uc *Controller) VerifyEmail(c *gin.Context) {
+	var request verifyEmailRequest
+	err := c.ShouldBindQuery(&request)
+	if err != nil {
+		slog.Warn("invalid verify email request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err = uc.service.VerifyEmail(ctx, request.Token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			slog.Warn("invalid email verification token", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired verification token"})
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("request timeout", "error", err)
+			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			return
+		}
+		slog.Error("failed to verify email", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	slog.Info("email verified successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+func (uc *Controller) ResendVerification(c *gin.Context) {
+	var request resendVerificationRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid resend verification request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	retryAfter, err := uc.service.ResendVerification(ctx, request.Email)
+	if err != nil {
+		if errors.Is(err, ErrResendThrottled) {
+			// the header is in seconds, rounded up so that the client never retries too early
+			seconds := int((retryAfter + time.Second - 1) / time.Second)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			slog.Warn("verification email resend throttled", "error", err)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, retry later"})
+			return
+		}
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("request timeout", "error", err)
+			c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+			return
+		}
+		slog.Error("failed to resend the verification email", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	// the response is the same whether the email is registered or not
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered and not verified, a verification link has been sent"})
+}
+
+func (uc *Controller) GetSessions(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+
+	sessions, err := uc.service.GetSessions(ctx, userID)
+	if err != nil {
+		uc.handleSessionError(c, err, "failed to get sessions")
+		return
+	}
+
+	currentSessionID := c.GetString("sessionID")
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, sessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    currentSessionID != "" && session.ID == currentSessionID,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (uc *Controller) DeleteSession(c *gin.Context) {
+	var uri sessionURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid session id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = uc.service.RevokeSession(ctx, userID, uri.ID)
+	if err != nil {
+		uc.handleSessionError(c, err, "failed to revoke session")
+		return
+	}
+
+	slog.Info("session revoked successfully", "userID", userID, "sessionID", uri.ID)
+	c.Status(http.StatusNoContent)
+}
+
+func (uc *Controller) handleSessionError(c *gin.Context, err error, message string) {
+	if errors.Is(err, ErrSessionNotFound) {
+		slog.Warn("session not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
+
+func (uc *Controller) GetProfile(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+
+	profile, err := uc.service.GetProfile(ctx, userID)
+	if err != nil {
+		uc.handleAccountError(c, err, "failed to get profile")
+		return
+	}
+	c.JSON(http.StatusOK, toUserInfo(profile))
+}
+
+func (uc *Controller) UpdateProfile(c *gin.Context) {
+	var request updateProfileRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid update profile request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Username == nil && request.Email == nil && request.Name == nil && request.Surname == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	profile, err := uc.service.UpdateProfile(ctx, userID, user.ProfileUpdate{
+		Username: request.Username,
+		Email:    request.Email,
+		Name:     request.Name,
+		Surname:  request.Surname,
+	}, request.CurrentPassword)
+	if err != nil {
+		uc.handleAccountError(c, err, "failed to update profile")
+		return
+	}
+
+	slog.Info("profile updated successfully", "userID", userID)
+	c.JSON(http.StatusOK, toUserInfo(profile))
+}
+
+func (uc *Controller) ChangePassword(c *gin.Context) {
+	var request changePasswordRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid change password request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = uc.validatePassword(request.NewPassword)
+	if err != nil {
+		slog.Warn("invalid password format", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	sessionID := c.GetString("sessionID")
+	err = uc.service.ChangePassword(ctx, userID, sessionID, request.CurrentPassword, request.NewPassword)
+	if err != nil {
+		uc.handleAccountError(c, err, "failed to change password")
+		return
+	}
+
+	slog.Info("password changed successfully", "userID", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
+}
+
+func (uc *Controller) DeleteAccount(c *gin.Context) {
+	var request deleteAccountRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid delete account request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	jti := c.GetString("jti")
+	expiresAt := c.GetTime("tokenExpiresAt")
+	err = uc.service.DeleteAccount(ctx, userID, request.Password, jti, expiresAt)
+	if err != nil {
+		uc.handleAccountError(c, err, "failed to delete account")
+		return
+	}
+
+	// the cookies belong to an account that does not exist anymore
+	c.SetCookie("jwt", "", -1, "/", "", true, true)
+	c.SetCookie("__Host-refresh_token", "", -1, "/", "", true, true)
+
+	slog.Info("account deleted successfully", "userID", userID)
+	c.Status(http.StatusNoContent)
+}
+
+func (uc *Controller) handleAccountError(c *gin.Context, err error, message string) {
+	if errors.Is(err, ErrUserNotExists) {
+		slog.Warn("user not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if errors.Is(err, ErrUserAlreadyExists) {
+		slog.Warn("user already exists", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrPasswordRequired) {
+		slog.Warn("email change without the current password", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "current_password is required to change the email"})
+		return
+	}
+	if errors.Is(err, ErrInvalidPassword) {
+		// not 401, the client must not think that the session has expired
+		slog.Warn("invalid current password", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid password"})
+		return
+	}
+	var lockedErr *AccountLockedError
+	if errors.As(err, &lockedErr) {
+		slog.Warn("password check on a locked account", "error", err)
+		seconds := int((lockedErr.RetryAfter + time.Second - 1) / time.Second)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed attempts, retry later"})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
+
+func toUserInfo(user *user.User) userInfo {
+	return userInfo{
+		Username:      user.Username,
+		Email:         user.Email,
+		Name:          user.Name,
+		Surname:       user.Surname,
+		EmailVerified: user.IsEmailVerified(),
+	}
+}
+
 func (uc *Controller) validatePassword(password string) error {
 	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
 	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
@@ -10,9 +10,11 @@ import (
 	"time"
 
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/auth/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/mock/gomock"
 )
 
@@ -235,14 +237,15 @@ func TestController_LoginByUsername(t *testing.T) {
 					Name:     "Mario",
 					Surname:  "Rossi",
 				}
-				m.EXPECT().LoginByUsername(gomock.Any(), "mario", "Testtest123").Return(dummyUser, nil)
-				m.EXPECT().GenerateJWT("1").Return("dummy_jwt_token", nil)
+				m.EXPECT().LoginByUsername(gomock.Any(), "mario", "Testtest123").Return(dummyUser, "", nil)
+				m.EXPECT().GenerateJWT("1", "session-1", false).Return("dummy_jwt_token", nil)
 				dummyRefreshToken := &refresh_token.RefreshToken{
 					RefreshToken: "dummy_refresh_token",
 					UserID:       "1",
+					SessionID:    "session-1",
 					TTL:          time.Now().Add(24 * time.Hour),
 				}
-				m.EXPECT().GenerateRefreshToken(gomock.Any(), "1").Return(dummyRefreshToken, nil)
+				m.EXPECT().GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).Return(dummyRefreshToken, nil)
 			},
 		},
 		{
@@ -269,6 +272,17 @@ func TestController_LoginByUsername(t *testing.T) {
 			expectedCode: http.StatusBadRequest,
 			setupMock: func(m *mocks.MockauthService) {},
 		},
+		{
+			name: "mfa_required",
+			body: `{"username":"mario","password":"Testtest123"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				// no cookies are issued until the second factor is presented
+				m.EXPECT().
+				LoginByUsername(gomock.Any(), "mario", "Testtest123").
+				Return(&user.User{ID: "1"}, "mt1.ticket", nil)
+			},
+		},
 		{
 			name: "login_unsuccessful",
 			body: `{"username":"mario","password":"Testtest123"}`,
@@ -276,7 +290,7 @@ func TestController_LoginByUsername(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 				LoginByUsername(gomock.Any(), "mario", "Testtest123").
-				Return(nil, ErrUserNotExists)
+				Return(nil, "", ErrUserNotExists)
 			},
 		},
 	}
@@ -328,16 +342,17 @@ func TestController_LoginByEmail(t *testing.T) {
 				dummyRefreshToken := &refresh_token.RefreshToken{
 					RefreshToken: "dummy_refresh_token",
 					UserID:       "1",
+					SessionID:    "session-1",
 					TTL:          time.Now().Add(24 * time.Hour),
 				}
 				m.EXPECT().
 					LoginByEmail(gomock.Any(), "mario@example.com", "Testtest123").
-					Return(dummyUser, nil)
+					Return(dummyUser, "", nil)
 				m.EXPECT().
-					GenerateJWT("1").
+					GenerateJWT("1", "session-1", false).
 					Return("dummy_jwt_token", nil)
 				m.EXPECT().
-					GenerateRefreshToken(gomock.Any(), "1").
+					GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).
 					Return(dummyRefreshToken, nil)
 			},
 		},
@@ -372,7 +387,7 @@ func TestController_LoginByEmail(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 				LoginByEmail(gomock.Any(), "mario@example.com", "Testtest123").
-				Return(nil, ErrUserNotExists)
+				Return(nil, "", ErrUserNotExists)
 			},
 		},
 	}
@@ -401,6 +416,96 @@ func TestController_LoginByEmail(t *testing.T) {
 	}
 }
 
+func TestController_LoginMFA(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		expectedCode    int
+		expectedCookies bool
+		setupMock       func(*mocks.MockauthService)
+	}{
+		{
+			name:            "success",
+			body:            `{"ticket":"mt1.ticket","code":"123456"}`,
+			expectedCode:    http.StatusOK,
+			expectedCookies: true,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					CompleteMFALogin(gomock.Any(), "mt1.ticket", "123456").
+					Return(&user.User{ID: "1", Username: "mario"}, nil)
+				m.EXPECT().
+					GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).
+					Return(&refresh_token.RefreshToken{
+						RefreshToken: "dummy_refresh_token",
+						UserID:       "1",
+						SessionID:    "session-1",
+						TTL:          time.Now().Add(24 * time.Hour),
+					}, nil)
+				m.EXPECT().GenerateJWT("1", "session-1", false).Return("dummy_jwt_token", nil)
+			},
+		},
+		{
+			name:         "missing_code",
+			body:         `{"ticket":"mt1.ticket"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "invalid_ticket",
+			body:         `{"ticket":"mt1.ticket","code":"123456"}`,
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					CompleteMFALogin(gomock.Any(), "mt1.ticket", "123456").
+					Return(nil, ErrInvalidMFATicket)
+			},
+		},
+		{
+			name:         "invalid_code",
+			body:         `{"ticket":"mt1.ticket","code":"123456"}`,
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					CompleteMFALogin(gomock.Any(), "mt1.ticket", "123456").
+					Return(nil, ErrInvalidMFACode)
+			},
+		},
+		{
+			name:         "internal_error",
+			body:         `{"ticket":"mt1.ticket","code":"123456"}`,
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					CompleteMFALogin(gomock.Any(), "mt1.ticket", "123456").
+					Return(nil, fmt.Errorf("redis error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodPost, "/login/mfa", []byte(tt.body))
+
+			uc.LoginMFA(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if hasCookies := len(w.Result().Cookies()) > 0; hasCookies != tt.expectedCookies {
+				t.Errorf("expected cookies %v, got %v", tt.expectedCookies, w.Result().Cookies())
+			}
+		})
+	}
+}
+
 func TestController_RefreshToken(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -416,19 +521,24 @@ func TestController_RefreshToken(t *testing.T) {
 				dummyNewRefreshToken := &refresh_token.RefreshToken{
 					RefreshToken: "new_dummy_refresh_token",
 					UserID:       "1",
+					SessionID:    "session-1",
 					TTL:          time.Now().Add(24 * time.Hour),
 				}
 
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("1", nil)
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(true, nil)
 
 				m.EXPECT().
-					RotateRefreshToken(gomock.Any(), "1").
+					RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(dummyNewRefreshToken, nil)
 
 				m.EXPECT().
-					GenerateJWT("1").
+					GenerateJWT("1", "session-1", true).
 					Return("dummy_jwt_token", nil)
 			},
 		},
@@ -445,7 +555,17 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "invalid_refresh_token").
-					Return("", ErrInvalidToken)
+					Return(nil, ErrInvalidToken)
+			},
+		},
+		{
+			name: 			 "refresh_token_reused",
+			cookies:      []*http.Cookie{{Name: "__Host-refresh_token", Value: "stolen_refresh_token"}},
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					ValidateRefreshToken(gomock.Any(), "stolen_refresh_token").
+					Return(nil, fmt.Errorf("%w: session session-1", ErrTokenReused))
 			},
 		},
 		{
@@ -455,7 +575,7 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("", context.Canceled)
+					Return(nil, context.Canceled)
 			},
 		},
 		{
@@ -465,7 +585,7 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("", context.DeadlineExceeded)
+					Return(nil, context.DeadlineExceeded)
 			},
 		},
 		{
@@ -475,7 +595,7 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("", fmt.Errorf("some internal error"))
+					Return(nil, fmt.Errorf("some internal error"))
 			},
 		},
 		{
@@ -485,10 +605,14 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("1", nil)
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(true, nil)
 
 				m.EXPECT().
-					RotateRefreshToken(gomock.Any(), "1").
+					RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil, fmt.Errorf("some error"))
 			},
 		},
@@ -499,10 +623,14 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("1", nil)
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(true, nil)
 
 				m.EXPECT().
-					RotateRefreshToken(gomock.Any(), "1").
+					RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil, context.Canceled)
 			},
 		},
@@ -513,13 +641,35 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("1", nil)
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(true, nil)
 
 				m.EXPECT().
-					RotateRefreshToken(gomock.Any(), "1").
+					RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil, context.DeadlineExceeded)
 			},
 		},
+		{
+			name: 			 "rotate_refresh_token_already_rotated",
+			cookies:      []*http.Cookie{{Name: "__Host-refresh_token", Value: "dummy_refresh_token"}},
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(true, nil)
+
+				m.EXPECT().
+					RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, ErrInvalidToken)
+			},
+		},
 		{
 			name: 			 "generate_jwt_error",
 			cookies:      []*http.Cookie{{Name: "__Host-refresh_token", Value: "dummy_refresh_token"}},
@@ -527,23 +677,79 @@ func TestController_RefreshToken(t *testing.T) {
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
 					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
-					Return("1", nil)
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(true, nil)
 
 				dummyNewRefreshToken := &refresh_token.RefreshToken{
 					RefreshToken: "new_dummy_refresh_token",
 					UserID:       "1",
+					SessionID:    "session-1",
 					TTL:          time.Now().Add(24 * time.Hour),
 				}
 
 				m.EXPECT().
-					RotateRefreshToken(gomock.Any(), "1").
+					RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(dummyNewRefreshToken, nil)
 
 				m.EXPECT().
-					GenerateJWT("1").
+					GenerateJWT("1", "session-1", true).
 					Return("", fmt.Errorf("some error"))	
 			},
 		},
+		{
+			name:         "email_not_verified_read_only",
+			cookies:      []*http.Cookie{{Name: "__Host-refresh_token", Value: "dummy_refresh_token"}},
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(false, nil)
+
+				m.EXPECT().
+					RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&refresh_token.RefreshToken{RefreshToken: "new_dummy_refresh_token", TTL: time.Now().Add(time.Hour)}, nil)
+
+				// the new access token is read only again
+				m.EXPECT().
+					GenerateJWT("1", "session-1", false).
+					Return("dummy_jwt_token", nil)
+			},
+		},
+		{
+			name:         "email_not_verified_deny",
+			cookies:      []*http.Cookie{{Name: "__Host-refresh_token", Value: "dummy_refresh_token"}},
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(false, ErrEmailNotVerified)
+			},
+		},
+		{
+			name:         "user_deleted",
+			cookies:      []*http.Cookie{{Name: "__Host-refresh_token", Value: "dummy_refresh_token"}},
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					ValidateRefreshToken(gomock.Any(), "dummy_refresh_token").
+					Return(&refresh_token.Session{ID: "session-1", UserID: "1"}, nil)
+
+				m.EXPECT().
+					CheckEmailVerified(gomock.Any(), "1").
+					Return(false, ErrUserNotExists)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -591,6 +797,16 @@ func TestController_HandleLoginError(t *testing.T) {
 			err:          ErrInvalidPassword,
 			expectedCode: http.StatusUnauthorized,
 		},
+		{
+			name:         "email_not_verified",
+			err:          ErrEmailNotVerified,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "account_locked",
+			err:          &AccountLockedError{RetryAfter: 1500 * time.Millisecond},
+			expectedCode: http.StatusTooManyRequests,
+		},
 		{
 			name:         "context_cancelled",
 			err:          context.Canceled,
@@ -624,6 +840,41 @@ func TestController_HandleLoginError(t *testing.T) {
 			if w.Code != tt.expectedCode {
 				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
 			}
+			if tt.expectedCode == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "2" {
+				t.Errorf("expected Retry-After 2, got %q", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestRecordLogin(t *testing.T) {
+	tests := []struct {
+		name            string
+		mfaTicket       string
+		err             error
+		expectedOutcome string
+	}{
+		{name: "success", expectedOutcome: "success"},
+		{name: "mfa_required", mfaTicket: "ticket", expectedOutcome: "mfa_required"},
+		{name: "user_not_exists", err: ErrUserNotExists, expectedOutcome: "user_not_exists"},
+		{name: "invalid_password", err: ErrInvalidPassword, expectedOutcome: "invalid_password"},
+		{name: "account_locked", err: &AccountLockedError{RetryAfter: time.Second}, expectedOutcome: "account_locked"},
+		{name: "email_not_verified", err: ErrEmailNotVerified, expectedOutcome: "email_not_verified"},
+		{name: "invalid_mfa_code", err: ErrInvalidMFACode, expectedOutcome: "invalid_mfa_code"},
+		{name: "deadline_exceeded", err: context.DeadlineExceeded, expectedOutcome: "canceled"},
+		{name: "internal_error", err: fmt.Errorf("connection refused"), expectedOutcome: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.LoginAttempts.WithLabelValues("username", tt.expectedOutcome)
+			before := testutil.ToFloat64(counter)
+
+			recordLogin("username", tt.mfaTicket, tt.err)
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("expected the %s outcome to be counted once, got %v", tt.expectedOutcome, got)
+			}
 		})
 	}
 }
@@ -650,13 +901,14 @@ func TestController_HandleSuccessfulLogin(t *testing.T) {
 				dummyRefreshToken := &refresh_token.RefreshToken{
 					RefreshToken: "dummy_refresh_token",
 					UserID:       "1",
+					SessionID:    "session-1",
 					TTL:          time.Now().Add(24 * time.Hour),
 				}
 				m.EXPECT().
-					GenerateJWT("1").
+					GenerateJWT("1", "session-1", false).
 					Return("dummy_jwt_token", nil)
 				m.EXPECT().
-					GenerateRefreshToken(gomock.Any(), "1").
+					GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).
 					Return(dummyRefreshToken, nil)
 			},
 		},
@@ -672,8 +924,17 @@ func TestController_HandleSuccessfulLogin(t *testing.T) {
 				Surname:  "Rossi",
 			},
 			setupMock: func(m *mocks.MockauthService) {
+				dummyRefreshToken := &refresh_token.RefreshToken{
+					RefreshToken: "dummy_refresh_token",
+					UserID:       "1",
+					SessionID:    "session-1",
+					TTL:          time.Now().Add(24 * time.Hour),
+				}
+				m.EXPECT().
+					GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).
+					Return(dummyRefreshToken, nil)
 				m.EXPECT().
-					GenerateJWT("1").
+					GenerateJWT("1", "session-1", false).
 					Return("", fmt.Errorf("some error"))
 			},
 		},
@@ -690,10 +951,7 @@ func TestController_HandleSuccessfulLogin(t *testing.T) {
 			},
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
-					GenerateJWT("1").
-					Return("dummy_jwt_token", nil)
-				m.EXPECT().
-					GenerateRefreshToken(gomock.Any(), "1").
+					GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).
 					Return(nil, fmt.Errorf("some error"))
 			},
 		},
@@ -710,10 +968,7 @@ func TestController_HandleSuccessfulLogin(t *testing.T) {
 			},
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
-					GenerateJWT("1").
-					Return("dummy_jwt_token", nil)
-				m.EXPECT().
-					GenerateRefreshToken(gomock.Any(), "1").
+					GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).
 					Return(nil, context.Canceled)
 			},
 		},
@@ -730,10 +985,7 @@ func TestController_HandleSuccessfulLogin(t *testing.T) {
 			},
 			setupMock: func(m *mocks.MockauthService) {
 				m.EXPECT().
-					GenerateJWT("1").
-					Return("dummy_jwt_token", nil)
-				m.EXPECT().
-					GenerateRefreshToken(gomock.Any(), "1").
+					GenerateRefreshToken(gomock.Any(), "1", gomock.Any(), gomock.Any()).
 					Return(nil, context.DeadlineExceeded)
 			},
 		},
@@ -760,4 +1012,764 @@ func TestController_HandleSuccessfulLogin(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestController_Logout(t *testing.T) {
+	expiresAt := time.Now().Add(30 * time.Minute)
+
+	tests := []struct {
+		name           string
+		expectedCode   int
+		expectsCleared bool
+		setupMock      func(*mocks.MockauthService)
+	}{
+		{
+			name:           "success",
+			expectedCode:   http.StatusOK,
+			expectsCleared: true,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().Logout(gomock.Any(), "1", "session-1", "jti-1", expiresAt).Return(nil)
+			},
+		},
+		{
+			name:         "db_error",
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().Logout(gomock.Any(), "1", "session-1", "jti-1", expiresAt).Return(fmt.Errorf("some error"))
+			},
+		},
+		{
+			name:         "deadline_exceeded",
+			expectedCode: http.StatusRequestTimeout,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().Logout(gomock.Any(), "1", "session-1", "jti-1", expiresAt).Return(context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodPost, "/logout", nil)
+			c.Set("userID", "1")
+			c.Set("sessionID", "session-1")
+			c.Set("jti", "jti-1")
+			c.Set("tokenExpiresAt", expiresAt)
+
+			uc.Logout(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+
+			cleared := map[string]bool{}
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.MaxAge < 0 && cookie.Value == "" {
+					cleared[cookie.Name] = true
+				}
+			}
+			if tt.expectsCleared && (!cleared["jwt"] || !cleared["__Host-refresh_token"]) {
+				t.Errorf("expected both cookies to be cleared, got %v", w.Result().Cookies())
+			}
+			if !tt.expectsCleared && len(cleared) > 0 {
+				t.Errorf("expected no cookie to be cleared, got %v", cleared)
+			}
+		})
+	}
+}
+
+func TestController_GetSessions(t *testing.T) {
+	tests := []struct {
+		name         string
+		expectedCode int
+		expectedBody string
+		setupMock    func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			expectedCode: http.StatusOK,
+			expectedBody: `"current":true`,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					GetSessions(gomock.Any(), "1").
+					Return([]*refresh_token.Session{
+						{ID: "session-1", UserID: "1", UserAgent: "phone"},
+						{ID: "session-2", UserID: "1", UserAgent: "laptop"},
+					}, nil)
+			},
+		},
+		{
+			name:         "no_sessions",
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().GetSessions(gomock.Any(), "1").Return([]*refresh_token.Session{}, nil)
+			},
+		},
+		{
+			name:         "db_error",
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().GetSessions(gomock.Any(), "1").Return(nil, fmt.Errorf("some error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodGet, "/sessions", nil)
+			c.Set("userID", "1")
+			c.Set("sessionID", "session-1")
+
+			uc.GetSessions(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if !bytes.Contains(w.Body.Bytes(), []byte(tt.expectedBody)) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_DeleteSession(t *testing.T) {
+	const testSessionID = "3f2b8c1e-5d4a-4b6f-9e7d-1a2b3c4d5e6f"
+
+	tests := []struct {
+		name         string
+		sessionID    string
+		expectedCode int
+		setupMock    func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			sessionID:    testSessionID,
+			expectedCode: http.StatusNoContent,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().RevokeSession(gomock.Any(), "1", testSessionID).Return(nil)
+			},
+		},
+		{
+			name:         "invalid_session_id",
+			sessionID:    "42",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "not_found",
+			sessionID:    testSessionID,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().RevokeSession(gomock.Any(), "1", testSessionID).Return(ErrSessionNotFound)
+			},
+		},
+		{
+			name:         "deadline_exceeded",
+			sessionID:    testSessionID,
+			expectedCode: http.StatusRequestTimeout,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().RevokeSession(gomock.Any(), "1", testSessionID).Return(context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodDelete, "/sessions/"+tt.sessionID, nil)
+			c.Params = gin.Params{{Key: "id", Value: tt.sessionID}}
+			c.Set("userID", "1")
+
+			uc.DeleteSession(c)
+
+			// the status of an empty response is only written when the handler completes
+			c.Writer.WriteHeaderNow()
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_ForgotPassword(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		expectedCode       int
+		expectedRetryAfter string
+		setupMock          func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			body:         `{"email":"mario@example.com"}`,
+			expectedCode: http.StatusAccepted,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ForgotPassword(gomock.Any(), "mario@example.com").Return(time.Duration(0), nil)
+			},
+		},
+		{
+			name:               "throttled",
+			body:               `{"email":"mario@example.com"}`,
+			expectedCode:       http.StatusTooManyRequests,
+			expectedRetryAfter: "42",
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					ForgotPassword(gomock.Any(), "mario@example.com").
+					Return(41*time.Second+500*time.Millisecond, fmt.Errorf("%w: retry later", ErrResendThrottled))
+			},
+		},
+		{
+			name:         "invalid_email",
+			body:         `{"email":"mario"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "internal_error",
+			body:         `{"email":"mario@example.com"}`,
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ForgotPassword(gomock.Any(), "mario@example.com").Return(time.Duration(0), fmt.Errorf("some error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+			c, w := newTestContext(http.MethodPost, "/password/forgot", []byte(tt.body))
+
+			uc.ForgotPassword(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != tt.expectedRetryAfter {
+				t.Errorf("expected Retry-After %q, got %q", tt.expectedRetryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+func TestController_ResetPassword(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			body:         `{"token":"pr1.token","password":"NewPassword123"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ResetPassword(gomock.Any(), "pr1.token", "NewPassword123").Return(nil)
+			},
+		},
+		{
+			name:         "missing_token",
+			body:         `{"password":"NewPassword123"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "weak_password",
+			body:         `{"token":"pr1.token","password":"newpassword"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "invalid_token",
+			body:         `{"token":"pr1.token","password":"NewPassword123"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ResetPassword(gomock.Any(), "pr1.token", "NewPassword123").Return(ErrInvalidResetToken)
+			},
+		},
+		{
+			name:         "deadline_exceeded",
+			body:         `{"token":"pr1.token","password":"NewPassword123"}`,
+			expectedCode: http.StatusRequestTimeout,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ResetPassword(gomock.Any(), "pr1.token", "NewPassword123").Return(context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+			c, w := newTestContext(http.MethodPost, "/password/reset", []byte(tt.body))
+
+			uc.ResetPassword(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_VerifyEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+		setupMock    func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			path:         "/verify-email?token=ev1.verifytoken",
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().VerifyEmail(gomock.Any(), "ev1.verifytoken").Return(nil)
+			},
+		},
+		{
+			name:         "missing_token",
+			path:         "/verify-email",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "invalid_token",
+			path:         "/verify-email?token=ev1.invalid",
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().VerifyEmail(gomock.Any(), "ev1.invalid").Return(ErrInvalidVerificationToken)
+			},
+		},
+		{
+			name:         "internal_error",
+			path:         "/verify-email?token=ev1.verifytoken",
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().VerifyEmail(gomock.Any(), "ev1.verifytoken").Return(fmt.Errorf("some error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+			c, w := newTestContext(http.MethodGet, tt.path, nil)
+
+			uc.VerifyEmail(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_ResendVerification(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		expectedCode       int
+		expectedRetryAfter string
+		setupMock          func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			body:         `{"email":"mario@example.com"}`,
+			expectedCode: http.StatusAccepted,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ResendVerification(gomock.Any(), "mario@example.com").Return(time.Duration(0), nil)
+			},
+		},
+		{
+			name:         "invalid_email",
+			body:         `{"email":"mario"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:               "throttled",
+			body:               `{"email":"mario@example.com"}`,
+			expectedCode:       http.StatusTooManyRequests,
+			expectedRetryAfter: "42",
+			setupMock: func(m *mocks.MockauthService) {
+				// the seconds are rounded up
+				m.EXPECT().
+					ResendVerification(gomock.Any(), "mario@example.com").
+					Return(41*time.Second+500*time.Millisecond, fmt.Errorf("%w: retry later", ErrResendThrottled))
+			},
+		},
+		{
+			name:         "internal_error",
+			body:         `{"email":"mario@example.com"}`,
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ResendVerification(gomock.Any(), "mario@example.com").Return(time.Duration(0), fmt.Errorf("some error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+			c, w := newTestContext(http.MethodPost, "/verify-email/resend", []byte(tt.body))
+
+			uc.ResendVerification(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != tt.expectedRetryAfter {
+				t.Errorf("expected Retry-After %q, got %q", tt.expectedRetryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+func TestController_GetProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		expectedCode int
+		expectedBody string
+		setupMock    func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"username":"luigi","email":"luigi@example.com","name":"luigi","surname":"verdi","email_verified":false}`,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().GetProfile(gomock.Any(), "1").Return(&user.User{ID: "1", Username: "luigi", Email: "luigi@example.com", Password: "hash", Name: "luigi", Surname: "verdi"}, nil)
+			},
+		},
+		{
+			name:         "user_deleted",
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().GetProfile(gomock.Any(), "1").Return(nil, ErrUserNotExists)
+			},
+		},
+		{
+			name:         "db_error",
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().GetProfile(gomock.Any(), "1").Return(nil, fmt.Errorf("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodGet, "/me", nil)
+			c.Set("userID", "1")
+
+			uc.GetProfile(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			// the password hash is never returned
+			if tt.expectedBody != "" && w.Body.String() != tt.expectedBody {
+				t.Errorf("got body %s want %s", w.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestController_UpdateProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			body:         `{"name":"mario"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					UpdateProfile(gomock.Any(), "1", gomock.Any(), "").
+					DoAndReturn(func(ctx context.Context, userID string, update user.ProfileUpdate, currentPassword string) (*user.User, error) {
+						// only the fields in the body are changed
+						if update.Name == nil || *update.Name != "mario" || update.Username != nil || update.Email != nil || update.Surname != nil {
+							t.Errorf("unexpected update %+v", update)
+						}
+						return &user.User{ID: "1", Username: "luigi", Name: "mario"}, nil
+					})
+			},
+		},
+		{
+			name:         "email_with_password",
+			body:         `{"email":"mario@example.com","current_password":"Testtest123"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().
+					UpdateProfile(gomock.Any(), "1", gomock.Any(), "Testtest123").
+					Return(&user.User{ID: "1", Username: "luigi", Email: "mario@example.com"}, nil)
+			},
+		},
+		{
+			name:         "email_without_password",
+			body:         `{"email":"mario@example.com"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().UpdateProfile(gomock.Any(), "1", gomock.Any(), "").Return(nil, ErrPasswordRequired)
+			},
+		},
+		{
+			name:         "email_with_wrong_password",
+			body:         `{"email":"mario@example.com","current_password":"Wrongpass123"}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().UpdateProfile(gomock.Any(), "1", gomock.Any(), "Wrongpass123").Return(nil, ErrInvalidPassword)
+			},
+		},
+		{
+			name:         "empty_body",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "invalid_email",
+			body:         `{"email":"not-an-email"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "empty_username",
+			body:         `{"username":""}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "username_taken",
+			body:         `{"username":"mario"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().UpdateProfile(gomock.Any(), "1", gomock.Any(), "").Return(nil, fmt.Errorf("%w: username already registered", ErrUserAlreadyExists))
+			},
+		},
+		{
+			name:         "context_canceled",
+			body:         `{"name":"mario"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().UpdateProfile(gomock.Any(), "1", gomock.Any(), "").Return(nil, context.Canceled)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodPatch, "/me", []byte(tt.body))
+			c.Set("userID", "1")
+
+			uc.UpdateProfile(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockauthService)
+	}{
+		{
+			name:         "success",
+			body:         `{"current_password":"Testtest123","new_password":"NewPassword123"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ChangePassword(gomock.Any(), "1", "SessionID", "Testtest123", "NewPassword123").Return(nil)
+			},
+		},
+		{
+			name:         "weak_new_password",
+			body:         `{"current_password":"Testtest123","new_password":"newpassword"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "missing_current_password",
+			body:         `{"new_password":"NewPassword123"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "wrong_current_password",
+			body:         `{"current_password":"Wrongpass123","new_password":"NewPassword123"}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ChangePassword(gomock.Any(), "1", "SessionID", "Wrongpass123", "NewPassword123").Return(ErrInvalidPassword)
+			},
+		},
+		{
+			name:         "account_locked",
+			body:         `{"current_password":"Testtest123","new_password":"NewPassword123"}`,
+			expectedCode: http.StatusTooManyRequests,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().ChangePassword(gomock.Any(), "1", "SessionID", gomock.Any(), gomock.Any()).Return(&AccountLockedError{RetryAfter: time.Minute})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodPost, "/me/password", []byte(tt.body))
+			c.Set("userID", "1")
+			c.Set("sessionID", "SessionID")
+
+			uc.ChangePassword(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_DeleteAccount(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		body          string
+		expectedCode  int
+		expectCleared bool
+		setupMock     func(*mocks.MockauthService)
+	}{
+		{
+			name:          "success",
+			body:          `{"password":"Testtest123"}`,
+			expectedCode:  http.StatusNoContent,
+			expectCleared: true,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().DeleteAccount(gomock.Any(), "1", "Testtest123", "JTI", expiresAt).Return(nil)
+			},
+		},
+		{
+			name:         "missing_password",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockauthService) {},
+		},
+		{
+			name:         "wrong_password",
+			body:         `{"password":"Wrongpass123"}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().DeleteAccount(gomock.Any(), "1", "Wrongpass123", "JTI", expiresAt).Return(ErrInvalidPassword)
+			},
+		},
+		{
+			name:         "db_error",
+			body:         `{"password":"Testtest123"}`,
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockauthService) {
+				m.EXPECT().DeleteAccount(gomock.Any(), "1", "Testtest123", "JTI", expiresAt).Return(fmt.Errorf("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuthService := mocks.NewMockauthService(ctrl)
+			tt.setupMock(mockAuthService)
+
+			uc := NewAuthController(mockAuthService)
+
+			c, w := newTestContext(http.MethodDelete, "/me", []byte(tt.body))
+			c.Set("userID", "1")
+			c.Set("jti", "JTI")
+			c.Set("tokenExpiresAt", expiresAt)
+
+			uc.DeleteAccount(c)
+
+			c.Writer.WriteHeaderNow()
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if tt.expectCleared && len(w.Result().Cookies()) != 2 {
+				t.Errorf("expected the cookies to be cleared, got %v", w.Result().Cookies())
+			}
+		})
+	}
+}
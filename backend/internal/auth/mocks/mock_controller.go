@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=controller.go -destination=mocks/mock_auth_controller.go -package=mocks
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
 //
 
 // Package mocks is a generated GoMock package.
@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	refresh_token "github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
 	user "github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
@@ -42,45 +43,149 @@ func (m *MockauthService) EXPECT() *MockauthServiceMockRecorder {
 	return m.recorder
 }
 
+// ChangePassword mocks base method.
+func (m *MockauthService) ChangePassword(ctx context.Context, userID, sessionID, currentPassword, newPassword string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, userID, sessionID, currentPassword, newPassword)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockauthServiceMockRecorder) ChangePassword(ctx, userID, sessionID, currentPassword, newPassword any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockauthService)(nil).ChangePassword), ctx, userID, sessionID, currentPassword, newPassword)
+}
+
+// CheckEmailVerified mocks base method.
+func (m *MockauthService) CheckEmailVerified(ctx context.Context, userID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckEmailVerified", ctx, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckEmailVerified indicates an expected call of CheckEmailVerified.
+func (mr *MockauthServiceMockRecorder) CheckEmailVerified(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckEmailVerified", reflect.TypeOf((*MockauthService)(nil).CheckEmailVerified), ctx, userID)
+}
+
+// CompleteMFALogin mocks base method.
+func (m *MockauthService) CompleteMFALogin(ctx context.Context, ticket, code string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteMFALogin", ctx, ticket, code)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteMFALogin indicates an expected call of CompleteMFALogin.
+func (mr *MockauthServiceMockRecorder) CompleteMFALogin(ctx, ticket, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteMFALogin", reflect.TypeOf((*MockauthService)(nil).CompleteMFALogin), ctx, ticket, code)
+}
+
+// DeleteAccount mocks base method.
+func (m *MockauthService) DeleteAccount(ctx context.Context, userID, password, jti string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccount", ctx, userID, password, jti, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccount indicates an expected call of DeleteAccount.
+func (mr *MockauthServiceMockRecorder) DeleteAccount(ctx, userID, password, jti, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockauthService)(nil).DeleteAccount), ctx, userID, password, jti, expiresAt)
+}
+
+// ForgotPassword mocks base method.
+func (m *MockauthService) ForgotPassword(ctx context.Context, email string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForgotPassword", ctx, email)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ForgotPassword indicates an expected call of ForgotPassword.
+func (mr *MockauthServiceMockRecorder) ForgotPassword(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgotPassword", reflect.TypeOf((*MockauthService)(nil).ForgotPassword), ctx, email)
+}
+
 // GenerateJWT mocks base method.
-func (m *MockauthService) GenerateJWT(userID string) (string, error) {
+func (m *MockauthService) GenerateJWT(userID, sessionID string, emailVerified bool) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateJWT", userID)
+	ret := m.ctrl.Call(m, "GenerateJWT", userID, sessionID, emailVerified)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateJWT indicates an expected call of GenerateJWT.
-func (mr *MockauthServiceMockRecorder) GenerateJWT(userID any) *gomock.Call {
+func (mr *MockauthServiceMockRecorder) GenerateJWT(userID, sessionID, emailVerified any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateJWT", reflect.TypeOf((*MockauthService)(nil).GenerateJWT), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateJWT", reflect.TypeOf((*MockauthService)(nil).GenerateJWT), userID, sessionID, emailVerified)
 }
 
 // GenerateRefreshToken mocks base method.
-func (m *MockauthService) GenerateRefreshToken(ctx context.Context, userID string) (*refresh_token.RefreshToken, error) {
+func (m *MockauthService) GenerateRefreshToken(ctx context.Context, userID, userAgent, ip string) (*refresh_token.RefreshToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateRefreshToken", ctx, userID)
+	ret := m.ctrl.Call(m, "GenerateRefreshToken", ctx, userID, userAgent, ip)
 	ret0, _ := ret[0].(*refresh_token.RefreshToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateRefreshToken indicates an expected call of GenerateRefreshToken.
-func (mr *MockauthServiceMockRecorder) GenerateRefreshToken(ctx, userID any) *gomock.Call {
+func (mr *MockauthServiceMockRecorder) GenerateRefreshToken(ctx, userID, userAgent, ip any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockauthService)(nil).GenerateRefreshToken), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateRefreshToken", reflect.TypeOf((*MockauthService)(nil).GenerateRefreshToken), ctx, userID, userAgent, ip)
 }
 
-// LoginByEmail mocks base method.
-func (m *MockauthService) LoginByEmail(ctx context.Context, email, password string) (*user.User, error) {
+// GetProfile mocks base method.
+func (m *MockauthService) GetProfile(ctx context.Context, userID string) (*user.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoginByEmail", ctx, email, password)
+	ret := m.ctrl.Call(m, "GetProfile", ctx, userID)
 	ret0, _ := ret[0].(*user.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GetProfile indicates an expected call of GetProfile.
+func (mr *MockauthServiceMockRecorder) GetProfile(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfile", reflect.TypeOf((*MockauthService)(nil).GetProfile), ctx, userID)
+}
+
+// GetSessions mocks base method.
+func (m *MockauthService) GetSessions(ctx context.Context, userID string) ([]*refresh_token.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userID)
+	ret0, _ := ret[0].([]*refresh_token.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockauthServiceMockRecorder) GetSessions(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockauthService)(nil).GetSessions), ctx, userID)
+}
+
+// LoginByEmail mocks base method.
+func (m *MockauthService) LoginByEmail(ctx context.Context, email, password string) (*user.User, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginByEmail", ctx, email, password)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // LoginByEmail indicates an expected call of LoginByEmail.
 func (mr *MockauthServiceMockRecorder) LoginByEmail(ctx, email, password any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
@@ -88,12 +193,13 @@ func (mr *MockauthServiceMockRecorder) LoginByEmail(ctx, email, password any) *g
 }
 
 // LoginByUsername mocks base method.
-func (m *MockauthService) LoginByUsername(ctx context.Context, username, password string) (*user.User, error) {
+func (m *MockauthService) LoginByUsername(ctx context.Context, username, password string) (*user.User, string, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "LoginByUsername", ctx, username, password)
 	ret0, _ := ret[0].(*user.User)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // LoginByUsername indicates an expected call of LoginByUsername.
@@ -102,6 +208,20 @@ func (mr *MockauthServiceMockRecorder) LoginByUsername(ctx, username, password a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginByUsername", reflect.TypeOf((*MockauthService)(nil).LoginByUsername), ctx, username, password)
 }
 
+// Logout mocks base method.
+func (m *MockauthService) Logout(ctx context.Context, userID, sessionID, jti string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, userID, sessionID, jti, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockauthServiceMockRecorder) Logout(ctx, userID, sessionID, jti, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockauthService)(nil).Logout), ctx, userID, sessionID, jti, expiresAt)
+}
+
 // Register mocks base method.
 func (m *MockauthService) Register(ctx context.Context, username, email, password, name, surname string) error {
 	m.ctrl.T.Helper()
@@ -116,26 +236,84 @@ func (mr *MockauthServiceMockRecorder) Register(ctx, username, email, password,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockauthService)(nil).Register), ctx, username, email, password, name, surname)
 }
 
+// ResendVerification mocks base method.
+func (m *MockauthService) ResendVerification(ctx context.Context, email string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResendVerification", ctx, email)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResendVerification indicates an expected call of ResendVerification.
+func (mr *MockauthServiceMockRecorder) ResendVerification(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResendVerification", reflect.TypeOf((*MockauthService)(nil).ResendVerification), ctx, email)
+}
+
+// ResetPassword mocks base method.
+func (m *MockauthService) ResetPassword(ctx context.Context, token, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, token, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockauthServiceMockRecorder) ResetPassword(ctx, token, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockauthService)(nil).ResetPassword), ctx, token, password)
+}
+
+// RevokeSession mocks base method.
+func (m *MockauthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockauthServiceMockRecorder) RevokeSession(ctx, userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockauthService)(nil).RevokeSession), ctx, userID, sessionID)
+}
+
 // RotateRefreshToken mocks base method.
-func (m *MockauthService) RotateRefreshToken(ctx context.Context, userID string) (*refresh_token.RefreshToken, error) {
+func (m *MockauthService) RotateRefreshToken(ctx context.Context, session *refresh_token.Session, userAgent, ip string) (*refresh_token.RefreshToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, userID)
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, session, userAgent, ip)
 	ret0, _ := ret[0].(*refresh_token.RefreshToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // RotateRefreshToken indicates an expected call of RotateRefreshToken.
-func (mr *MockauthServiceMockRecorder) RotateRefreshToken(ctx, userID any) *gomock.Call {
+func (mr *MockauthServiceMockRecorder) RotateRefreshToken(ctx, session, userAgent, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockauthService)(nil).RotateRefreshToken), ctx, session, userAgent, ip)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockauthService) UpdateProfile(ctx context.Context, userID string, update user.ProfileUpdate, currentPassword string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, userID, update, currentPassword)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockauthServiceMockRecorder) UpdateProfile(ctx, userID, update, currentPassword any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockauthService)(nil).RotateRefreshToken), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockauthService)(nil).UpdateProfile), ctx, userID, update, currentPassword)
 }
 
 // ValidateRefreshToken mocks base method.
-func (m *MockauthService) ValidateRefreshToken(ctx context.Context, token string) (string, error) {
+func (m *MockauthService) ValidateRefreshToken(ctx context.Context, token string) (*refresh_token.Session, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ValidateRefreshToken", ctx, token)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(*refresh_token.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -145,3 +323,17 @@ func (mr *MockauthServiceMockRecorder) ValidateRefreshToken(ctx, token any) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateRefreshToken", reflect.TypeOf((*MockauthService)(nil).ValidateRefreshToken), ctx, token)
 }
+
+// VerifyEmail mocks base method.
+func (m *MockauthService) VerifyEmail(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockauthServiceMockRecorder) VerifyEmail(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockauthService)(nil).VerifyEmail), ctx, token)
+}
@@ -12,9 +12,15 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	email_verification_token "github.com/AliceOrlandini/Auto-Light-Pi/internal/email_verification_token"
+	mailer "github.com/AliceOrlandini/Auto-Light-Pi/internal/mailer"
+	mfa_ticket "github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa_ticket"
+	password_reset_token "github.com/AliceOrlandini/Auto-Light-Pi/internal/password_reset_token"
 	refresh_token "github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
 	user "github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	jwt "github.com/golang-jwt/jwt/v5"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -56,6 +62,20 @@ func (mr *MockuserRepositoryMockRecorder) CreateOne(ctx, arg1 any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockuserRepository)(nil).CreateOne), ctx, arg1)
 }
 
+// DeleteOneByID mocks base method.
+func (m *MockuserRepository) DeleteOneByID(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOneByID", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOneByID indicates an expected call of DeleteOneByID.
+func (mr *MockuserRepositoryMockRecorder) DeleteOneByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneByID", reflect.TypeOf((*MockuserRepository)(nil).DeleteOneByID), ctx, id)
+}
+
 // GetOneByEmail mocks base method.
 func (m *MockuserRepository) GetOneByEmail(ctx context.Context, email string) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -71,6 +91,21 @@ func (mr *MockuserRepositoryMockRecorder) GetOneByEmail(ctx, email any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByEmail", reflect.TypeOf((*MockuserRepository)(nil).GetOneByEmail), ctx, email)
 }
 
+// GetOneByID mocks base method.
+func (m *MockuserRepository) GetOneByID(ctx context.Context, id string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByID", ctx, id)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByID indicates an expected call of GetOneByID.
+func (mr *MockuserRepositoryMockRecorder) GetOneByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByID", reflect.TypeOf((*MockuserRepository)(nil).GetOneByID), ctx, id)
+}
+
 // GetOneByUsername mocks base method.
 func (m *MockuserRepository) GetOneByUsername(ctx context.Context, username string) (*user.User, error) {
 	m.ctrl.T.Helper()
@@ -86,6 +121,48 @@ func (mr *MockuserRepositoryMockRecorder) GetOneByUsername(ctx, username any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByUsername", reflect.TypeOf((*MockuserRepository)(nil).GetOneByUsername), ctx, username)
 }
 
+// MarkEmailVerified mocks base method.
+func (m *MockuserRepository) MarkEmailVerified(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerified", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerified indicates an expected call of MarkEmailVerified.
+func (mr *MockuserRepositoryMockRecorder) MarkEmailVerified(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerified", reflect.TypeOf((*MockuserRepository)(nil).MarkEmailVerified), ctx, id)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockuserRepository) UpdatePassword(ctx context.Context, id, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockuserRepositoryMockRecorder) UpdatePassword(ctx, id, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockuserRepository)(nil).UpdatePassword), ctx, id, password)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockuserRepository) UpdateProfile(ctx context.Context, arg1 *user.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockuserRepositoryMockRecorder) UpdateProfile(ctx, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockuserRepository)(nil).UpdateProfile), ctx, arg1)
+}
+
 // MockrefreshTokenRepository is a mock of refreshTokenRepository interface.
 type MockrefreshTokenRepository struct {
 	ctrl     *gomock.Controller
@@ -111,21 +188,310 @@ func (m *MockrefreshTokenRepository) EXPECT() *MockrefreshTokenRepositoryMockRec
 }
 
 // CreateOne mocks base method.
-func (m *MockrefreshTokenRepository) CreateOne(ctx context.Context, refreshToken *refresh_token.RefreshToken) error {
+func (m *MockrefreshTokenRepository) CreateOne(ctx context.Context, refreshToken *refresh_token.RefreshToken, session *refresh_token.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, refreshToken, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockrefreshTokenRepositoryMockRecorder) CreateOne(ctx, refreshToken, session any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockrefreshTokenRepository)(nil).CreateOne), ctx, refreshToken, session)
+}
+
+// DeleteAllByUserID mocks base method.
+func (m *MockrefreshTokenRepository) DeleteAllByUserID(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAllByUserID", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAllByUserID indicates an expected call of DeleteAllByUserID.
+func (mr *MockrefreshTokenRepositoryMockRecorder) DeleteAllByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllByUserID", reflect.TypeOf((*MockrefreshTokenRepository)(nil).DeleteAllByUserID), ctx, userID)
+}
+
+// DeleteOneBySessionID mocks base method.
+func (m *MockrefreshTokenRepository) DeleteOneBySessionID(ctx context.Context, userID, sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOneBySessionID", ctx, userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOneBySessionID indicates an expected call of DeleteOneBySessionID.
+func (mr *MockrefreshTokenRepositoryMockRecorder) DeleteOneBySessionID(ctx, userID, sessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneBySessionID", reflect.TypeOf((*MockrefreshTokenRepository)(nil).DeleteOneBySessionID), ctx, userID, sessionID)
+}
+
+// DeleteOthersByUserID mocks base method.
+func (m *MockrefreshTokenRepository) DeleteOthersByUserID(ctx context.Context, userID, keepSessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOthersByUserID", ctx, userID, keepSessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOthersByUserID indicates an expected call of DeleteOthersByUserID.
+func (mr *MockrefreshTokenRepositoryMockRecorder) DeleteOthersByUserID(ctx, userID, keepSessionID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOthersByUserID", reflect.TypeOf((*MockrefreshTokenRepository)(nil).DeleteOthersByUserID), ctx, userID, keepSessionID)
+}
+
+// GetAllByUserID mocks base method.
+func (m *MockrefreshTokenRepository) GetAllByUserID(ctx context.Context, userID string) ([]*refresh_token.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllByUserID", ctx, userID)
+	ret0, _ := ret[0].([]*refresh_token.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllByUserID indicates an expected call of GetAllByUserID.
+func (mr *MockrefreshTokenRepositoryMockRecorder) GetAllByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByUserID", reflect.TypeOf((*MockrefreshTokenRepository)(nil).GetAllByUserID), ctx, userID)
+}
+
+// GetOneByRotatedTokenHash mocks base method.
+func (m *MockrefreshTokenRepository) GetOneByRotatedTokenHash(ctx context.Context, tokenHash string) (*refresh_token.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByRotatedTokenHash", ctx, tokenHash)
+	ret0, _ := ret[0].(*refresh_token.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByRotatedTokenHash indicates an expected call of GetOneByRotatedTokenHash.
+func (mr *MockrefreshTokenRepositoryMockRecorder) GetOneByRotatedTokenHash(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByRotatedTokenHash", reflect.TypeOf((*MockrefreshTokenRepository)(nil).GetOneByRotatedTokenHash), ctx, tokenHash)
+}
+
+// GetOneByTokenHash mocks base method.
+func (m *MockrefreshTokenRepository) GetOneByTokenHash(ctx context.Context, tokenHash string) (*refresh_token.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByTokenHash", ctx, tokenHash)
+	ret0, _ := ret[0].(*refresh_token.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByTokenHash indicates an expected call of GetOneByTokenHash.
+func (mr *MockrefreshTokenRepositoryMockRecorder) GetOneByTokenHash(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByTokenHash", reflect.TypeOf((*MockrefreshTokenRepository)(nil).GetOneByTokenHash), ctx, tokenHash)
+}
+
+// RotateOne mocks base method.
+func (m *MockrefreshTokenRepository) RotateOne(ctx context.Context, oldTokenHash string, refreshToken *refresh_token.RefreshToken, session *refresh_token.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateOne", ctx, oldTokenHash, refreshToken, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateOne indicates an expected call of RotateOne.
+func (mr *MockrefreshTokenRepositoryMockRecorder) RotateOne(ctx, oldTokenHash, refreshToken, session any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateOne", reflect.TypeOf((*MockrefreshTokenRepository)(nil).RotateOne), ctx, oldTokenHash, refreshToken, session)
+}
+
+// MocktokenDenylistRepository is a mock of tokenDenylistRepository interface.
+type MocktokenDenylistRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocktokenDenylistRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MocktokenDenylistRepositoryMockRecorder is the mock recorder for MocktokenDenylistRepository.
+type MocktokenDenylistRepositoryMockRecorder struct {
+	mock *MocktokenDenylistRepository
+}
+
+// NewMocktokenDenylistRepository creates a new mock instance.
+func NewMocktokenDenylistRepository(ctrl *gomock.Controller) *MocktokenDenylistRepository {
+	mock := &MocktokenDenylistRepository{ctrl: ctrl}
+	mock.recorder = &MocktokenDenylistRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktokenDenylistRepository) EXPECT() *MocktokenDenylistRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateOne mocks base method.
+func (m *MocktokenDenylistRepository) CreateOne(ctx context.Context, jti string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, jti, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MocktokenDenylistRepositoryMockRecorder) CreateOne(ctx, jti, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MocktokenDenylistRepository)(nil).CreateOne), ctx, jti, expiresAt)
+}
+
+// MockpasswordResetTokenRepository is a mock of passwordResetTokenRepository interface.
+type MockpasswordResetTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockpasswordResetTokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockpasswordResetTokenRepositoryMockRecorder is the mock recorder for MockpasswordResetTokenRepository.
+type MockpasswordResetTokenRepositoryMockRecorder struct {
+	mock *MockpasswordResetTokenRepository
+}
+
+// NewMockpasswordResetTokenRepository creates a new mock instance.
+func NewMockpasswordResetTokenRepository(ctrl *gomock.Controller) *MockpasswordResetTokenRepository {
+	mock := &MockpasswordResetTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockpasswordResetTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockpasswordResetTokenRepository) EXPECT() *MockpasswordResetTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AcquireResendSlot mocks base method.
+func (m *MockpasswordResetTokenRepository) AcquireResendSlot(ctx context.Context, emailHash string, cooldown time.Duration) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireResendSlot", ctx, emailHash, cooldown)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireResendSlot indicates an expected call of AcquireResendSlot.
+func (mr *MockpasswordResetTokenRepositoryMockRecorder) AcquireResendSlot(ctx, emailHash, cooldown any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireResendSlot", reflect.TypeOf((*MockpasswordResetTokenRepository)(nil).AcquireResendSlot), ctx, emailHash, cooldown)
+}
+
+// ConsumeOneByTokenHash mocks base method.
+func (m *MockpasswordResetTokenRepository) ConsumeOneByTokenHash(ctx context.Context, tokenHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOneByTokenHash", ctx, tokenHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOneByTokenHash indicates an expected call of ConsumeOneByTokenHash.
+func (mr *MockpasswordResetTokenRepositoryMockRecorder) ConsumeOneByTokenHash(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOneByTokenHash", reflect.TypeOf((*MockpasswordResetTokenRepository)(nil).ConsumeOneByTokenHash), ctx, tokenHash)
+}
+
+// CreateOne mocks base method.
+func (m *MockpasswordResetTokenRepository) CreateOne(ctx context.Context, token *password_reset_token.PasswordResetToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockpasswordResetTokenRepositoryMockRecorder) CreateOne(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockpasswordResetTokenRepository)(nil).CreateOne), ctx, token)
+}
+
+// DeleteOneByUserID mocks base method.
+func (m *MockpasswordResetTokenRepository) DeleteOneByUserID(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOneByUserID", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOneByUserID indicates an expected call of DeleteOneByUserID.
+func (mr *MockpasswordResetTokenRepositoryMockRecorder) DeleteOneByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneByUserID", reflect.TypeOf((*MockpasswordResetTokenRepository)(nil).DeleteOneByUserID), ctx, userID)
+}
+
+// MockemailVerificationTokenRepository is a mock of emailVerificationTokenRepository interface.
+type MockemailVerificationTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockemailVerificationTokenRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockemailVerificationTokenRepositoryMockRecorder is the mock recorder for MockemailVerificationTokenRepository.
+type MockemailVerificationTokenRepositoryMockRecorder struct {
+	mock *MockemailVerificationTokenRepository
+}
+
+// NewMockemailVerificationTokenRepository creates a new mock instance.
+func NewMockemailVerificationTokenRepository(ctrl *gomock.Controller) *MockemailVerificationTokenRepository {
+	mock := &MockemailVerificationTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockemailVerificationTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockemailVerificationTokenRepository) EXPECT() *MockemailVerificationTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AcquireResendSlot mocks base method.
+func (m *MockemailVerificationTokenRepository) AcquireResendSlot(ctx context.Context, emailHash string, cooldown time.Duration) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireResendSlot", ctx, emailHash, cooldown)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireResendSlot indicates an expected call of AcquireResendSlot.
+func (mr *MockemailVerificationTokenRepositoryMockRecorder) AcquireResendSlot(ctx, emailHash, cooldown any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireResendSlot", reflect.TypeOf((*MockemailVerificationTokenRepository)(nil).AcquireResendSlot), ctx, emailHash, cooldown)
+}
+
+// ConsumeOneByTokenHash mocks base method.
+func (m *MockemailVerificationTokenRepository) ConsumeOneByTokenHash(ctx context.Context, tokenHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOneByTokenHash", ctx, tokenHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOneByTokenHash indicates an expected call of ConsumeOneByTokenHash.
+func (mr *MockemailVerificationTokenRepositoryMockRecorder) ConsumeOneByTokenHash(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOneByTokenHash", reflect.TypeOf((*MockemailVerificationTokenRepository)(nil).ConsumeOneByTokenHash), ctx, tokenHash)
+}
+
+// CreateOne mocks base method.
+func (m *MockemailVerificationTokenRepository) CreateOne(ctx context.Context, token *email_verification_token.EmailVerificationToken) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOne", ctx, refreshToken)
+	ret := m.ctrl.Call(m, "CreateOne", ctx, token)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateOne indicates an expected call of CreateOne.
-func (mr *MockrefreshTokenRepositoryMockRecorder) CreateOne(ctx, refreshToken any) *gomock.Call {
+func (mr *MockemailVerificationTokenRepositoryMockRecorder) CreateOne(ctx, token any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockrefreshTokenRepository)(nil).CreateOne), ctx, refreshToken)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockemailVerificationTokenRepository)(nil).CreateOne), ctx, token)
 }
 
 // DeleteOneByUserID mocks base method.
-func (m *MockrefreshTokenRepository) DeleteOneByUserID(ctx context.Context, userID string) error {
+func (m *MockemailVerificationTokenRepository) DeleteOneByUserID(ctx context.Context, userID string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteOneByUserID", ctx, userID)
 	ret0, _ := ret[0].(error)
@@ -133,37 +499,272 @@ func (m *MockrefreshTokenRepository) DeleteOneByUserID(ctx context.Context, user
 }
 
 // DeleteOneByUserID indicates an expected call of DeleteOneByUserID.
-func (mr *MockrefreshTokenRepositoryMockRecorder) DeleteOneByUserID(ctx, userID any) *gomock.Call {
+func (mr *MockemailVerificationTokenRepositoryMockRecorder) DeleteOneByUserID(ctx, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneByUserID", reflect.TypeOf((*MockrefreshTokenRepository)(nil).DeleteOneByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneByUserID", reflect.TypeOf((*MockemailVerificationTokenRepository)(nil).DeleteOneByUserID), ctx, userID)
+}
+
+// MockmfaTicketRepository is a mock of mfaTicketRepository interface.
+type MockmfaTicketRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockmfaTicketRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockmfaTicketRepositoryMockRecorder is the mock recorder for MockmfaTicketRepository.
+type MockmfaTicketRepositoryMockRecorder struct {
+	mock *MockmfaTicketRepository
+}
+
+// NewMockmfaTicketRepository creates a new mock instance.
+func NewMockmfaTicketRepository(ctrl *gomock.Controller) *MockmfaTicketRepository {
+	mock := &MockmfaTicketRepository{ctrl: ctrl}
+	mock.recorder = &MockmfaTicketRepositoryMockRecorder{mock}
+	return mock
 }
 
-// GetOneTokenHashByUserID mocks base method.
-func (m *MockrefreshTokenRepository) GetOneTokenHashByUserID(ctx context.Context, userID string) (string, error) {
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmfaTicketRepository) EXPECT() *MockmfaTicketRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AttemptOne mocks base method.
+func (m *MockmfaTicketRepository) AttemptOne(ctx context.Context, ticketHash string, maxAttempts int) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOneTokenHashByUserID", ctx, userID)
+	ret := m.ctrl.Call(m, "AttemptOne", ctx, ticketHash, maxAttempts)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOneTokenHashByUserID indicates an expected call of GetOneTokenHashByUserID.
-func (mr *MockrefreshTokenRepositoryMockRecorder) GetOneTokenHashByUserID(ctx, userID any) *gomock.Call {
+// AttemptOne indicates an expected call of AttemptOne.
+func (mr *MockmfaTicketRepositoryMockRecorder) AttemptOne(ctx, ticketHash, maxAttempts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttemptOne", reflect.TypeOf((*MockmfaTicketRepository)(nil).AttemptOne), ctx, ticketHash, maxAttempts)
+}
+
+// CreateOne mocks base method.
+func (m *MockmfaTicketRepository) CreateOne(ctx context.Context, ticket *mfa_ticket.MFATicket) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, ticket)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockmfaTicketRepositoryMockRecorder) CreateOne(ctx, ticket any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneTokenHashByUserID", reflect.TypeOf((*MockrefreshTokenRepository)(nil).GetOneTokenHashByUserID), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockmfaTicketRepository)(nil).CreateOne), ctx, ticket)
 }
 
-// GetOneUserIDByTokenHash mocks base method.
-func (m *MockrefreshTokenRepository) GetOneUserIDByTokenHash(ctx context.Context, tokenHash string) (string, error) {
+// DeleteOne mocks base method.
+func (m *MockmfaTicketRepository) DeleteOne(ctx context.Context, ticketHash string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOneUserIDByTokenHash", ctx, tokenHash)
+	ret := m.ctrl.Call(m, "DeleteOne", ctx, ticketHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOne indicates an expected call of DeleteOne.
+func (mr *MockmfaTicketRepositoryMockRecorder) DeleteOne(ctx, ticketHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOne", reflect.TypeOf((*MockmfaTicketRepository)(nil).DeleteOne), ctx, ticketHash)
+}
+
+// MockloginAttemptRepository is a mock of loginAttemptRepository interface.
+type MockloginAttemptRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockloginAttemptRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockloginAttemptRepositoryMockRecorder is the mock recorder for MockloginAttemptRepository.
+type MockloginAttemptRepositoryMockRecorder struct {
+	mock *MockloginAttemptRepository
+}
+
+// NewMockloginAttemptRepository creates a new mock instance.
+func NewMockloginAttemptRepository(ctrl *gomock.Controller) *MockloginAttemptRepository {
+	mock := &MockloginAttemptRepository{ctrl: ctrl}
+	mock.recorder = &MockloginAttemptRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockloginAttemptRepository) EXPECT() *MockloginAttemptRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetLockout mocks base method.
+func (m *MockloginAttemptRepository) GetLockout(ctx context.Context, key string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLockout", ctx, key)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLockout indicates an expected call of GetLockout.
+func (mr *MockloginAttemptRepositoryMockRecorder) GetLockout(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLockout", reflect.TypeOf((*MockloginAttemptRepository)(nil).GetLockout), ctx, key)
+}
+
+// RecordFailure mocks base method.
+func (m *MockloginAttemptRepository) RecordFailure(ctx context.Context, key string, threshold int, baseLockout, maxLockout, memory time.Duration) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordFailure", ctx, key, threshold, baseLockout, maxLockout, memory)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordFailure indicates an expected call of RecordFailure.
+func (mr *MockloginAttemptRepositoryMockRecorder) RecordFailure(ctx, key, threshold, baseLockout, maxLockout, memory any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordFailure", reflect.TypeOf((*MockloginAttemptRepository)(nil).RecordFailure), ctx, key, threshold, baseLockout, maxLockout, memory)
+}
+
+// Reset mocks base method.
+func (m *MockloginAttemptRepository) Reset(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockloginAttemptRepositoryMockRecorder) Reset(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockloginAttemptRepository)(nil).Reset), ctx, key)
+}
+
+// MocktokenSigner is a mock of tokenSigner interface.
+type MocktokenSigner struct {
+	ctrl     *gomock.Controller
+	recorder *MocktokenSignerMockRecorder
+	isgomock struct{}
+}
+
+// MocktokenSignerMockRecorder is the mock recorder for MocktokenSigner.
+type MocktokenSignerMockRecorder struct {
+	mock *MocktokenSigner
+}
+
+// NewMocktokenSigner creates a new mock instance.
+func NewMocktokenSigner(ctrl *gomock.Controller) *MocktokenSigner {
+	mock := &MocktokenSigner{ctrl: ctrl}
+	mock.recorder = &MocktokenSignerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktokenSigner) EXPECT() *MocktokenSignerMockRecorder {
+	return m.recorder
+}
+
+// Sign mocks base method.
+func (m *MocktokenSigner) Sign(claims jwt.Claims) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sign", claims)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetOneUserIDByTokenHash indicates an expected call of GetOneUserIDByTokenHash.
-func (mr *MockrefreshTokenRepositoryMockRecorder) GetOneUserIDByTokenHash(ctx, tokenHash any) *gomock.Call {
+// Sign indicates an expected call of Sign.
+func (mr *MocktokenSignerMockRecorder) Sign(claims any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sign", reflect.TypeOf((*MocktokenSigner)(nil).Sign), claims)
+}
+
+// MockmfaVerifier is a mock of mfaVerifier interface.
+type MockmfaVerifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockmfaVerifierMockRecorder
+	isgomock struct{}
+}
+
+// MockmfaVerifierMockRecorder is the mock recorder for MockmfaVerifier.
+type MockmfaVerifierMockRecorder struct {
+	mock *MockmfaVerifier
+}
+
+// NewMockmfaVerifier creates a new mock instance.
+func NewMockmfaVerifier(ctrl *gomock.Controller) *MockmfaVerifier {
+	mock := &MockmfaVerifier{ctrl: ctrl}
+	mock.recorder = &MockmfaVerifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmfaVerifier) EXPECT() *MockmfaVerifierMockRecorder {
+	return m.recorder
+}
+
+// IsEnabled mocks base method.
+func (m *MockmfaVerifier) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsEnabled", ctx, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsEnabled indicates an expected call of IsEnabled.
+func (mr *MockmfaVerifierMockRecorder) IsEnabled(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsEnabled", reflect.TypeOf((*MockmfaVerifier)(nil).IsEnabled), ctx, userID)
+}
+
+// Verify mocks base method.
+func (m *MockmfaVerifier) Verify(ctx context.Context, userID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockmfaVerifierMockRecorder) Verify(ctx, userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockmfaVerifier)(nil).Verify), ctx, userID, code)
+}
+
+// MockmailSender is a mock of mailSender interface.
+type MockmailSender struct {
+	ctrl     *gomock.Controller
+	recorder *MockmailSenderMockRecorder
+	isgomock struct{}
+}
+
+// MockmailSenderMockRecorder is the mock recorder for MockmailSender.
+type MockmailSenderMockRecorder struct {
+	mock *MockmailSender
+}
+
+// NewMockmailSender creates a new mock instance.
+func NewMockmailSender(ctrl *gomock.Controller) *MockmailSender {
+	mock := &MockmailSender{ctrl: ctrl}
+	mock.recorder = &MockmailSenderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmailSender) EXPECT() *MockmailSenderMockRecorder {
+	return m.recorder
+}
+
+// Send mocks base method.
+func (m *MockmailSender) Send(ctx context.Context, message *mailer.Message) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, message)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockmailSenderMockRecorder) Send(ctx, message any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneUserIDByTokenHash", reflect.TypeOf((*MockrefreshTokenRepository)(nil).GetOneUserIDByTokenHash), ctx, tokenHash)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockmailSender)(nil).Send), ctx, message)
 }
@@ -10,48 +10,206 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/email_verification_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mailer"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa_ticket"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/password_reset_token"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const refreshTokenVersion = "rt1"
 
+const (
+	passwordResetTokenVersion = "pr1"
+	// the reset link must be used shortly after it has been requested
+	passwordResetTTL = 30 * time.Minute
+	// a new reset link can be asked once per minute for each address
+	passwordResetResendCooldown = time.Minute
+	// the emails are sent in background, so they cannot use the request timeout
+	mailTimeout = 30 * time.Second
+)
+
+const (
+	emailVerificationTokenVersion = "ev1"
+	// the verification link is usually opened right after the registration,
+	// a day leaves enough time to whoever does not check the inbox often
+	emailVerificationTTL = 24 * time.Hour
+	// a new verification email can be asked once per minute for each address
+	emailVerificationResendCooldown = time.Minute
+)
+
+const (
+	mfaTicketVersion = "mt1"
+	// the ticket only needs to last the time to open the authenticator app
+	mfaTicketTTL = 5 * time.Minute
+	// a few typos are tolerated, then the password must be entered again
+	mfaTicketMaxAttempts = 5
+)
+
+const (
+	// from the fifth wrong password in a row the account is locked for a minute,
+	// every further failure doubles the lockout up to an hour
+	lockoutThreshold = 5
+	lockoutBase      = time.Minute
+	lockoutMax       = time.Hour
+	// the failures are forgotten after a day without new ones
+	lockoutMemory = 24 * time.Hour
+)
+
+// the policy applied to the users that have not verified their email yet,
+// it is chosen with Config.UnverifiedEmailPolicy
+const (
+	// the user can do everything, as before the verification was introduced
+	unverifiedEmailAllow = "allow"
+	// the user can login but the access token can only be used to read
+	unverifiedEmailReadOnly = "read_only"
+	// the user cannot login until the email is verified
+	unverifiedEmailDeny = "deny"
+)
+
+// readOnlyScope is the scope of the access tokens that cannot be used to write
+const readOnlyScope = "read_only"
+
 var	(
 	ErrUserAlreadyExists = errors.New("user already exists")
 	ErrUserNotExists 		 = errors.New("user not exists")
-	ErrInvalidPassword   = errors.New("password not valid")
+	ErrInvalidPassword   = user.ErrInvalidPassword
 	ErrInvalidToken      = errors.New("invalid refresh token")
 	ErrExpired           = errors.New("expired")
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrTokenReused       = errors.New("refresh token reused")
+	ErrInvalidResetToken = errors.New("invalid password reset token")
+	ErrEmailNotVerified  = errors.New("email not verified")
+	ErrInvalidVerificationToken = errors.New("invalid email verification token")
+	ErrResendThrottled   = errors.New("email resend throttled")
+	ErrInvalidMFATicket  = errors.New("invalid two-factor login ticket")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrAccountLocked     = user.ErrAccountLocked
+	ErrPasswordRequired  = user.ErrPasswordRequired
 )
 
+// AccountLockedError is returned instead of ErrAccountLocked
+// to tell the client how long to wait
+type AccountLockedError = user.AccountLockedError
+
 type userRepository interface {
 	CreateOne(ctx context.Context, user *user.User) error
 	GetOneByEmail(ctx context.Context, email string) (*user.User, error)
 	GetOneByUsername(ctx context.Context, username string) (*user.User, error)
+	GetOneByID(ctx context.Context, id string) (*user.User, error)
+	UpdatePassword(ctx context.Context, id string, password string) error
+	MarkEmailVerified(ctx context.Context, id string) error
+	UpdateProfile(ctx context.Context, user *user.User) error
+	DeleteOneByID(ctx context.Context, id string) error
 }
 
 type refreshTokenRepository interface {
-	CreateOne(ctx context.Context, refreshToken *refresh_token.RefreshToken) error
-	GetOneUserIDByTokenHash(ctx context.Context, tokenHash string) (string, error)
-	GetOneTokenHashByUserID(ctx context.Context, userID string) (string, error)
+	CreateOne(ctx context.Context, refreshToken *refresh_token.RefreshToken, session *refresh_token.Session) error
+	RotateOne(ctx context.Context, oldTokenHash string, refreshToken *refresh_token.RefreshToken, session *refresh_token.Session) error
+	GetOneByTokenHash(ctx context.Context, tokenHash string) (*refresh_token.Session, error)
+	GetOneByRotatedTokenHash(ctx context.Context, tokenHash string) (*refresh_token.Session, error)
+	GetAllByUserID(ctx context.Context, userID string) ([]*refresh_token.Session, error)
+	DeleteOneBySessionID(ctx context.Context, userID string, sessionID string) error
+	DeleteAllByUserID(ctx context.Context, userID string) error
+	DeleteOthersByUserID(ctx context.Context, userID string, keepSessionID string) error
+}
+
+type tokenDenylistRepository interface {
+	CreateOne(ctx context.Context, jti string, expiresAt time.Time) error
+}
+
+type passwordResetTokenRepository interface {
+	CreateOne(ctx context.Context, token *password_reset_token.PasswordResetToken) error
+	ConsumeOneByTokenHash(ctx context.Context, tokenHash string) (string, error)
+	DeleteOneByUserID(ctx context.Context, userID string) error
+	AcquireResendSlot(ctx context.Context, emailHash string, cooldown time.Duration) (time.Duration, error)
+}
+
+type emailVerificationTokenRepository interface {
+	CreateOne(ctx context.Context, token *email_verification_token.EmailVerificationToken) error
+	ConsumeOneByTokenHash(ctx context.Context, tokenHash string) (string, error)
 	DeleteOneByUserID(ctx context.Context, userID string) error
+	AcquireResendSlot(ctx context.Context, emailHash string, cooldown time.Duration) (time.Duration, error)
+}
+
+type mfaTicketRepository interface {
+	CreateOne(ctx context.Context, ticket *mfa_ticket.MFATicket) error
+	AttemptOne(ctx context.Context, ticketHash string, maxAttempts int) (string, error)
+	DeleteOne(ctx context.Context, ticketHash string) error
+}
+
+type loginAttemptRepository interface {
+	GetLockout(ctx context.Context, key string) (time.Duration, error)
+	RecordFailure(ctx context.Context, key string, threshold int, baseLockout time.Duration, maxLockout time.Duration, memory time.Duration) (time.Duration, error)
+	Reset(ctx context.Context, key string) error
+}
+
+// tokenSigner signs the access tokens with the current signing key
+type tokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+type mfaVerifier interface {
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+	Verify(ctx context.Context, userID string, code string) error
+}
+
+type mailSender interface {
+	Send(ctx context.Context, message *mailer.Message) error
+}
+
+// Config is the part of the configuration used by the service
+type Config struct {
+	// Issuer is the iss claim of the access tokens
+	Issuer string
+	// PasswordResetURL is the page of the app where the user chooses the new password,
+	// when it is empty only the token is sent and it must be pasted in the app
+	PasswordResetURL string
+	// EmailVerificationURL is the page that confirms the email, it can be either the
+	// app or GET /api/verify-email, when it is empty only the token is sent
+	EmailVerificationURL string
+	// UnverifiedEmailPolicy is one of allow, read_only and deny, empty means allow
+	UnverifiedEmailPolicy string
 }
 
 type service struct {
-	userRepo         userRepository
-	refreshTokenRepo refreshTokenRepository
+	userRepo                   userRepository
+	refreshTokenRepo           refreshTokenRepository
+	tokenDenylistRepo          tokenDenylistRepository
+	passwordResetTokenRepo     passwordResetTokenRepository
+	emailVerificationTokenRepo emailVerificationTokenRepository
+	mfaTicketRepo              mfaTicketRepository
+	mfa                        mfaVerifier
+	loginAttemptRepo           loginAttemptRepository
+	signer                     tokenSigner
+	mailer                     mailSender
+	config                     Config
 }
 
-func NewAuthService(userRepo userRepository, refreshTokenRepo refreshTokenRepository) *service {
+func NewAuthService(userRepo userRepository, refreshTokenRepo refreshTokenRepository, tokenDenylistRepo tokenDenylistRepository, passwordResetTokenRepo passwordResetTokenRepository, emailVerificationTokenRepo emailVerificationTokenRepository, mfaTicketRepo mfaTicketRepository, mfa mfaVerifier, loginAttemptRepo loginAttemptRepository, signer tokenSigner, mailer mailSender, config Config) *service {
 	return &service{
 		userRepo: userRepo,
 		refreshTokenRepo: refreshTokenRepo,
+		tokenDenylistRepo: tokenDenylistRepo,
+		passwordResetTokenRepo: passwordResetTokenRepo,
+		emailVerificationTokenRepo: emailVerificationTokenRepo,
+		mfaTicketRepo: mfaTicketRepo,
+		mfa: mfa,
+		loginAttemptRepo: loginAttemptRepo,
+		signer: signer,
+		mailer: mailer,
+		config: config,
 	}
 }
 
@@ -75,13 +233,15 @@ func (s *service) Register(ctx context.Context, username string, email string, p
 		return fmt.Errorf("%w: username already registered", ErrUserAlreadyExists)
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	passwordHash, err := hashPassword(password)
 	if err != nil {
 		// this is also an internal server error
 		return err
 	}
 
 	user := &user.User{
+		// the id is chosen here since it is needed by the verification token
+		ID: uuid.NewString(),
 		Username: username,
 		Email: email,
 		Password: string(passwordHash),
@@ -89,98 +249,258 @@ func (s *service) Register(ctx context.Context, username string, email string, p
 		Surname: surname,
 	}
 
-	return s.userRepo.CreateOne(ctx, user)
+	err = s.userRepo.CreateOne(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	// the account has already been created, if the email cannot be sent
+	// the user can still ask for a new one
+	err = s.sendVerificationEmail(ctx, user)
+	if err != nil {
+		slog.Error("failed to create the email verification token", "userID", user.ID, "error", err)
+	}
+	return nil
 }
 
-func (s *service) LoginByUsername(ctx context.Context, username string, password string) (*user.User, error) {
+// LoginByUsername checks the credentials of the user, when the second factor is
+// enabled it also returns the ticket to complete the login with CompleteMFALogin
+func (s *service) LoginByUsername(ctx context.Context, username string, password string) (*user.User, string, error) {
 	user, err := s.userRepo.GetOneByUsername(ctx, username)
 	// if there is an error than it is an internal server error
 	// since is db releted 
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	// if the user field is empty than the username does not exists in the db
+	return s.login(ctx, user, "username:"+username, password)
+}
+
+func (s *service) LoginByEmail(ctx context.Context, email string, password string) (*user.User, string, error) {
+	user, err := s.userRepo.GetOneByEmail(ctx, email)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.login(ctx, user, "email:"+email, password)
+}
+
+// login checks the password of the user found by the identifier, the failures
+// are counted per account so that from lockoutThreshold on the account is
+// locked and the password is not even compared
+func (s *service) login(ctx context.Context, user *user.User, identifier string, password string) (*user.User, string, error) {
+	// if the user field is empty than the account does not exists in the db
 	if user == nil {
-		return nil, ErrUserNotExists
+		// the unknown accounts are locked as well, otherwise
+		// the lockout would tell which accounts exist
+		lockoutKey := "login:" + hashToken(strings.ToLower(identifier))
+		err := s.checkLockout(ctx, lockoutKey)
+		if err != nil {
+			return nil, "", err
+		}
+		s.recordLoginFailure(ctx, lockoutKey)
+		return nil, "", ErrUserNotExists
+	}
+
+	lockoutKey := userLockoutKey(user.ID)
+	err := s.checkLockout(ctx, lockoutKey)
+	if err != nil {
+		return nil, "", err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = comparePassword(user.Password, password)
 	// if there is an error comparing the passwords, check if the error is
 	// a mismatched one or is an internal server error
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return nil, ErrInvalidPassword
+			s.recordLoginFailure(ctx, lockoutKey)
+			return nil, "", ErrInvalidPassword
 		}
-		return nil, err
+		return nil, "", err
 	}
 
-	return user, nil
+	if !user.IsEmailVerified() && s.unverifiedEmailPolicy() == unverifiedEmailDeny {
+		return nil, "", ErrEmailNotVerified
+	}
+
+	ticket, err := s.newMFATicket(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+	// with the second factor the failures are forgotten only once the code is valid
+	if ticket == "" {
+		s.resetLockout(ctx, lockoutKey)
+	}
+
+	return user, ticket, nil
 }
 
-func (s *service) LoginByEmail(ctx context.Context, email string, password string) (*user.User, error) {
-	user, err := s.userRepo.GetOneByEmail(ctx, email)
+func userLockoutKey(userID string) string {
+	return "user:" + userID
+}
+
+func (s *service) checkLockout(ctx context.Context, lockoutKey string) error {
+	remaining, err := s.loginAttemptRepo.GetLockout(ctx, lockoutKey)
 	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return &AccountLockedError{RetryAfter: remaining}
+	}
+	return nil
+}
+
+// recordLoginFailure only logs the errors, the caller
+// has already a more relevant error to return
+func (s *service) recordLoginFailure(ctx context.Context, lockoutKey string) {
+	lockout, err := s.loginAttemptRepo.RecordFailure(ctx, lockoutKey, lockoutThreshold, lockoutBase, lockoutMax, lockoutMemory)
+	if err != nil {
+		slog.Error("failed to record login failure", "error", err)
+		return
+	}
+	if lockout > 0 {
+		slog.Warn("account locked after repeated login failures", "lockout", lockout)
+	}
+}
+
+func (s *service) resetLockout(ctx context.Context, lockoutKey string) {
+	err := s.loginAttemptRepo.Reset(ctx, lockoutKey)
+	if err != nil {
+		slog.Error("failed to reset login failures", "error", err)
+	}
+}
+
+// newMFATicket returns an empty ticket when the user has no second factor
+func (s *service) newMFATicket(ctx context.Context, user *user.User) (string, error) {
+	enabled, err := s.mfa.IsEnabled(ctx, user.ID)
+	if err != nil || !enabled {
+		return "", err
+	}
+
+	ticket, err := newOpaqueToken(mfaTicketVersion)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.mfaTicketRepo.CreateOne(ctx, &mfa_ticket.MFATicket{
+		Ticket:     ticket,
+		TicketHash: hashToken(ticket),
+		UserID:     user.ID,
+		TTL:        time.Now().Add(mfaTicketTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// CompleteMFALogin exchanges the ticket of the login and a code of the second
+// factor, either of the authenticator app or a recovery code, for the user
+func (s *service) CompleteMFALogin(ctx context.Context, ticket string, code string) (*user.User, error) {
+	if !strings.HasPrefix(ticket, mfaTicketVersion+".") {
+		return nil, ErrInvalidMFATicket
+	}
+
+	ticketHash := hashToken(ticket)
+	userID, err := s.mfaTicketRepo.AttemptOne(ctx, ticketHash, mfaTicketMaxAttempts)
+	if err != nil {
+		if errors.Is(err, mfa_ticket.ErrTicketHashNotFound) {
+			return nil, ErrInvalidMFATicket
+		}
 		return nil, err
 	}
-	if user == nil {
-		return nil, ErrUserNotExists
+
+	// the wrong codes count as wrong passwords, otherwise logging in again
+	// would give a fresh set of attempts to who knows the password
+	lockoutKey := userLockoutKey(userID)
+	err = s.checkLockout(ctx, lockoutKey)
+	if err != nil {
+		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = s.mfa.Verify(ctx, userID, code)
 	if err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return nil, ErrInvalidPassword
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			s.recordLoginFailure(ctx, lockoutKey)
+			return nil, fmt.Errorf("%w: %w", ErrInvalidMFACode, err)
+		}
+		// the second factor has been disabled after the ticket was issued,
+		// the login is started again to apply the current settings
+		if errors.Is(err, mfa.ErrNotEnabled) {
+			return nil, ErrInvalidMFATicket
 		}
 		return nil, err
 	}
 
+	// the ticket can be used only once
+	err = s.mfaTicketRepo.DeleteOne(ctx, ticketHash)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetOneByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidMFATicket
+	}
+
+	s.resetLockout(ctx, lockoutKey)
 	return user, nil
 }
 
-func (s *service) GenerateJWT(userID string) (string, error) {
-	var secret = []byte(os.Getenv("JWT_SECRET"))
-	var appName = os.Getenv("APPLICATION_NAME")
-
-  token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+func (s *service) GenerateJWT(userID string, sessionID string, emailVerified bool) (string, error) {
+	claims := jwt.MapClaims{
 		"sub": userID,
-		"iss": appName,
+		"iss": s.config.Issuer,
+		// the jti identifies the token, so that it can be revoked on logout
+		"jti": uuid.NewString(),
+		// the sid is the session of the refresh token that issued the token
+		"sid": sessionID,
 		"exp": time.Now().Add(time.Hour).Unix(),
 		"iat": time.Now().Unix(),
-	})
+	}
+	// the middleware refuses every write made with a read only token
+	if !emailVerified && s.unverifiedEmailPolicy() == unverifiedEmailReadOnly {
+		claims["scope"] = readOnlyScope
+	}
 
-	return token.SignedString(secret)
+	return s.signer.Sign(claims)
 }
 
-func (s *service) GenerateRefreshToken(ctx context.Context, userID string) (*refresh_token.RefreshToken, error) {
-	// generate 32 random bytes that will be used as the refresh token
-	bytes := make([]byte, 32)
-	_, err := rand.Read(bytes)
+// CheckEmailVerified tells if the user has verified its email, it is used when
+// the access token is refreshed since the user could have verified it meanwhile
+func (s *service) CheckEmailVerified(ctx context.Context, userID string) (bool, error) {
+	user, err := s.userRepo.GetOneByID(ctx, userID)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	if user == nil {
+		return false, ErrUserNotExists
 	}
 
-	// the TTL of the token is 7 days
-	TTL := time.Now().Add(7 * 24 * time.Hour)
+	if !user.IsEmailVerified() && s.unverifiedEmailPolicy() == unverifiedEmailDeny {
+		return false, ErrEmailNotVerified
+	}
+	return user.IsEmailVerified(), nil
+}
 
-	// the opaque is the base64 encoded version of the random bytes
-	opaque := base64.RawURLEncoding.EncodeToString(bytes)
-	
-	// the version is used in case in the future we need to change the format of the token
-	// in this way we can easily identify the version of the token
-	token := fmt.Sprintf("%s.%s", refreshTokenVersion, opaque)
-	
-	// in the db we will not save the opaque value, but only the hash
-	sum := sha256.Sum256([]byte(token))
-	tokenHash := hex.EncodeToString(sum[:])
+func (s *service) GenerateRefreshToken(ctx context.Context, userID string, userAgent string, ip string) (*refresh_token.RefreshToken, error) {
+	// every login starts a new session, so that the user
+	// can be logged in from more than one device at a time
+	refreshToken, err := newRefreshToken(userID, uuid.NewString())
+	if err != nil {
+		return nil, err
+	}
 
-	refreshToken := &refresh_token.RefreshToken{
-		RefreshToken:     token,
-		RefreshTokenHash: tokenHash,
-		UserID:           userID,
-		TTL:              TTL,
+	now := time.Now()
+	session := &refresh_token.Session{
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
 	}
 
-	err = s.refreshTokenRepo.CreateOne(ctx, refreshToken)
+	err = s.refreshTokenRepo.CreateOne(ctx, refreshToken, session)
 	if err != nil {
 		return nil, err
 	}
@@ -188,42 +508,587 @@ func (s *service) GenerateRefreshToken(ctx context.Context, userID string) (*ref
 	return refreshToken, nil
 }
 
-func (s *service) ValidateRefreshToken(ctx context.Context, token string) (string, error){
+func (s *service) ValidateRefreshToken(ctx context.Context, token string) (*refresh_token.Session, error) {
 
 	if !strings.HasPrefix(token, refreshTokenVersion+".") {
-		return "", ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
-	sum := sha256.Sum256([]byte(token))
-	tokenHash := hex.EncodeToString(sum[:])
-
-	userID, err := s.refreshTokenRepo.GetOneUserIDByTokenHash(ctx, tokenHash)
+	tokenHash := hashToken(token)
+	session, err := s.refreshTokenRepo.GetOneByTokenHash(ctx, tokenHash)
 	if err != nil {
 		if errors.Is(err, refresh_token.ErrTokenHashNotFound) {
-			return "", ErrInvalidToken
+			return nil, s.detectTokenReuse(ctx, tokenHash)
 		}
-		return "", err
+		return nil, err
 	}
-	if userID == "" {
-		return "", ErrInvalidToken
+	if session.UserID == "" {
+		return nil, ErrInvalidToken
 	}
 
-	return userID, nil
+	return session, nil
 }
 
-func (s *service) RotateRefreshToken(ctx context.Context, userID string) (*refresh_token.RefreshToken, error) {
+// detectTokenReuse is called for a token that is not valid anymore, if the token
+// has already been rotated either the user or an attacker still holds a copy of it,
+// since there is no way to tell who is who, every session of the user is revoked
+func (s *service) detectTokenReuse(ctx context.Context, tokenHash string) error {
+	session, err := s.refreshTokenRepo.GetOneByRotatedTokenHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, refresh_token.ErrTokenHashNotFound) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	slog.Warn("security event: refresh token reuse detected, revoking all sessions",
+		"event", "refresh_token_reuse",
+		"userID", session.UserID,
+		"sessionID", session.ID,
+	)
+
+	err = s.refreshTokenRepo.DeleteAllByUserID(ctx, session.UserID)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: session %s", ErrTokenReused, session.ID)
+}
 
-	// delete the old refresh token
-	err := s.refreshTokenRepo.DeleteOneByUserID(ctx, userID)
+func (s *service) RotateRefreshToken(ctx context.Context, session *refresh_token.Session, userAgent string, ip string) (*refresh_token.RefreshToken, error) {
+	// the new token belongs to the same session of the old one
+	newRefreshToken, err := newRefreshToken(session.UserID, session.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// generate a new one
-	newRefreshToken, err := s.GenerateRefreshToken(ctx, userID)
+	err = s.refreshTokenRepo.RotateOne(ctx, session.TokenHash, newRefreshToken, &refresh_token.Session{
+		UserAgent:  userAgent,
+		IP:         ip,
+		LastUsedAt: time.Now(),
+	})
 	if err != nil {
+		// the session has been revoked or the token has been
+		// rotated by another request in the meantime
+		if errors.Is(err, refresh_token.ErrTokenHashNotFound) {
+			return nil, ErrInvalidToken
+		}
 		return nil, err
 	}
 
 	return newRefreshToken, nil
-}
\ No newline at end of file
+}
+
+func (s *service) GetSessions(ctx context.Context, userID string) ([]*refresh_token.Session, error) {
+	return s.refreshTokenRepo.GetAllByUserID(ctx, userID)
+}
+
+func (s *service) RevokeSession(ctx context.Context, userID string, sessionID string) error {
+	// the access tokens already issued to the session are not revoked,
+	// they are still valid until they expire
+	err := s.refreshTokenRepo.DeleteOneBySessionID(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, refresh_token.ErrSessionNotFound) {
+			return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *service) Logout(ctx context.Context, userID string, sessionID string, jti string, expiresAt time.Time) error {
+	// the refresh token is deleted first, so that even if the
+	// denylist fails the session cannot be extended anymore
+	var err error
+	if sessionID != "" {
+		err = s.refreshTokenRepo.DeleteOneBySessionID(ctx, userID, sessionID)
+		// the session could already be expired or revoked
+		if errors.Is(err, refresh_token.ErrSessionNotFound) {
+			err = nil
+		}
+	} else {
+		// the tokens issued before the sessions were introduced do not
+		// tell which session they belong to, so all of them are closed
+		err = s.refreshTokenRepo.DeleteAllByUserID(ctx, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the tokens issued before the jti was introduced cannot be revoked,
+	// they are still valid until they expire
+	if jti == "" {
+		return nil
+	}
+
+	return s.tokenDenylistRepo.CreateOne(ctx, jti, expiresAt)
+}
+
+func (s *service) GetProfile(ctx context.Context, userID string) (*user.User, error) {
+	user, err := s.userRepo.GetOneByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// the access token can outlive the account it was issued to
+	if user == nil {
+		return nil, ErrUserNotExists
+	}
+	return user, nil
+}
+
+func (s *service) UpdateProfile(ctx context.Context, userID string, update user.ProfileUpdate, currentPassword string) (*user.User, error) {
+	current, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// with the email an access token alone could take over the account
+	// through the password reset, so the password is asked as for its change
+	if update.Email != nil && *update.Email != current.Email {
+		if currentPassword == "" {
+			return nil, ErrPasswordRequired
+		}
+		err = s.checkPassword(ctx, current, currentPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		// a link sent to the previous address must neither verify the new one nor
+		// reset the password, the links are dropped before the change so that a
+		// failure leaves the email unchanged
+		err = s.emailVerificationTokenRepo.DeleteOneByUserID(ctx, current.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = s.passwordResetTokenRepo.DeleteOneByUserID(ctx, current.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	updated := *current
+	if update.Username != nil {
+		updated.Username = *update.Username
+	}
+	if update.Email != nil {
+		updated.Email = *update.Email
+	}
+	if update.Name != nil {
+		updated.Name = *update.Name
+	}
+	if update.Surname != nil {
+		updated.Surname = *update.Surname
+	}
+
+	// the uniqueness is checked by the database, so
+	// that two concurrent updates cannot take the same value
+	err = s.userRepo.UpdateProfile(ctx, &updated)
+	if err != nil {
+		if errors.Is(err, user.ErrUsernameTaken) {
+			return nil, fmt.Errorf("%w: username already registered", ErrUserAlreadyExists)
+		}
+		if errors.Is(err, user.ErrEmailTaken) {
+			return nil, fmt.Errorf("%w: email already registered", ErrUserAlreadyExists)
+		}
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, ErrUserNotExists
+		}
+		return nil, err
+	}
+
+	if updated.Email == current.Email {
+		return &updated, nil
+	}
+
+	// the new address has to be verified again, the access tokens already
+	// issued keep their scope until the next refresh
+	updated.EmailVerifiedAt = nil
+	err = s.sendVerificationEmail(ctx, &updated)
+	if err != nil {
+		slog.Error("failed to create the email verification token", "userID", updated.ID, "error", err)
+	}
+	return &updated, nil
+}
+
+// ChangePassword replaces the password of the user and revokes every other session,
+// the session of the request is kept so that the user is not logged out
+func (s *service) ChangePassword(ctx context.Context, userID string, sessionID string, currentPassword string, newPassword string) error {
+	account, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkPassword(ctx, account, currentPassword)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.UpdatePassword(ctx, userID, string(passwordHash))
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return ErrUserNotExists
+		}
+		return err
+	}
+
+	// the access tokens already issued to the other
+	// sessions are still valid until they expire
+	if sessionID == "" {
+		// the tokens issued before the sessions were introduced do not
+		// tell which session they belong to, so all of them are closed
+		return s.refreshTokenRepo.DeleteAllByUserID(ctx, userID)
+	}
+	return s.refreshTokenRepo.DeleteOthersByUserID(ctx, userID, sessionID)
+}
+
+// DeleteAccount deletes the user together with its devices, readings and sessions,
+// the password is asked again since the account cannot be recovered
+func (s *service) DeleteAccount(ctx context.Context, userID string, password string, jti string, expiresAt time.Time) error {
+	account, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkPassword(ctx, account, password)
+	if err != nil {
+		return err
+	}
+
+	// the sessions are deleted first, otherwise if the redis call failed
+	// a refresh token could still issue access tokens to a deleted user
+	err = s.refreshTokenRepo.DeleteAllByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// the devices, their readings and targets and the second factor
+	// are deleted by the database together with the user
+	err = s.userRepo.DeleteOneByID(ctx, userID)
+	if err != nil {
+		// a concurrent request has already deleted the account
+		if errors.Is(err, user.ErrUserNotFound) {
+			return ErrUserNotExists
+		}
+		return err
+	}
+
+	if jti == "" {
+		return nil
+	}
+	return s.tokenDenylistRepo.CreateOne(ctx, jti, expiresAt)
+}
+
+// CheckPassword verifies the password of a logged in user for the changes
+// made outside of this package, such as the enrollment of the second factor
+func (s *service) CheckPassword(ctx context.Context, userID string, password string) error {
+	if password == "" {
+		return ErrPasswordRequired
+	}
+
+	account, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.checkPassword(ctx, account, password)
+}
+
+// checkPassword verifies the password of a logged in user, the failures are counted
+// with the ones of the login so that a stolen access token cannot guess it
+func (s *service) checkPassword(ctx context.Context, user *user.User, password string) error {
+	lockoutKey := userLockoutKey(user.ID)
+	err := s.checkLockout(ctx, lockoutKey)
+	if err != nil {
+		return err
+	}
+
+	err = comparePassword(user.Password, password)
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			s.recordLoginFailure(ctx, lockoutKey)
+			return ErrInvalidPassword
+		}
+		return err
+	}
+	return nil
+}
+
+// ForgotPassword sends a reset link, when the address has asked
+// for one too recently it returns how long the caller has to wait
+func (s *service) ForgotPassword(ctx context.Context, email string) (time.Duration, error) {
+	// the throttling is done before looking for the user,
+	// so that it does not tell if the email is registered
+	retryAfter, err := s.passwordResetTokenRepo.AcquireResendSlot(ctx, hashToken(strings.ToLower(email)), passwordResetResendCooldown)
+	if err != nil {
+		return 0, err
+	}
+	if retryAfter > 0 {
+		return retryAfter, fmt.Errorf("%w: retry after %s", ErrResendThrottled, retryAfter)
+	}
+
+	user, err := s.userRepo.GetOneByEmail(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+	// the caller must not be able to tell if the email is registered,
+	// so an unknown email is not an error
+	if user == nil {
+		return 0, nil
+	}
+
+	token, err := newOpaqueToken(passwordResetTokenVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.passwordResetTokenRepo.CreateOne(ctx, &password_reset_token.PasswordResetToken{
+		Token:     token,
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		TTL:       time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	message := &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Auto Light password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"we received a request to reset the password of your account. "+
+				"Use the link below within %d minutes to choose a new one:\n\n%s\n\n"+
+				"If you did not ask to reset your password you can ignore this email.\n",
+			user.Name, int(passwordResetTTL.Minutes()), s.passwordResetLink(token),
+		),
+	}
+
+	// the email is sent in background, otherwise the time of the
+	// response would tell if the email is registered or not
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+		defer cancel()
+
+		err := s.mailer.Send(ctx, message)
+		if err != nil {
+			slog.Error("failed to send the password reset email", "userID", user.ID, "error", err)
+		}
+	}()
+
+	return 0, nil
+}
+
+func (s *service) ResetPassword(ctx context.Context, token string, password string) error {
+	if !strings.HasPrefix(token, passwordResetTokenVersion+".") {
+		return ErrInvalidResetToken
+	}
+
+	// the token is deleted as soon as it is read, so that it can be used only once
+	userID, err := s.passwordResetTokenRepo.ConsumeOneByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, password_reset_token.ErrTokenHashNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepo.UpdatePassword(ctx, userID, string(passwordHash))
+	if err != nil {
+		// the user has been deleted after the token was sent
+		if errors.Is(err, user.ErrUserNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	// whoever knew the old password could still have a session open,
+	// the access tokens already issued are still valid until they expire
+	return s.refreshTokenRepo.DeleteAllByUserID(ctx, userID)
+}
+
+func (s *service) VerifyEmail(ctx context.Context, token string) error {
+	if !strings.HasPrefix(token, emailVerificationTokenVersion+".") {
+		return ErrInvalidVerificationToken
+	}
+
+	userID, err := s.emailVerificationTokenRepo.ConsumeOneByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, email_verification_token.ErrTokenHashNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+
+	err = s.userRepo.MarkEmailVerified(ctx, userID)
+	if err != nil {
+		// the user has been deleted after the token was sent
+		if errors.Is(err, user.ErrUserNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+	return nil
+}
+
+// ResendVerification sends a new verification link, when the address has asked
+// for one too recently it returns how long the caller has to wait
+func (s *service) ResendVerification(ctx context.Context, email string) (time.Duration, error) {
+	// the throttling is done before looking for the user,
+	// so that it does not tell if the email is registered
+	retryAfter, err := s.emailVerificationTokenRepo.AcquireResendSlot(ctx, hashToken(strings.ToLower(email)), emailVerificationResendCooldown)
+	if err != nil {
+		return 0, err
+	}
+	if retryAfter > 0 {
+		return retryAfter, fmt.Errorf("%w: retry after %s", ErrResendThrottled, retryAfter)
+	}
+
+	user, err := s.userRepo.GetOneByEmail(ctx, email)
+	if err != nil {
+		return 0, err
+	}
+	// as for the password reset an unknown email is not an error,
+	// and neither is an email that has already been verified
+	if user == nil || user.IsEmailVerified() {
+		return 0, nil
+	}
+
+	return 0, s.sendVerificationEmail(ctx, user)
+}
+
+func (s *service) sendVerificationEmail(ctx context.Context, user *user.User) error {
+	token, err := newOpaqueToken(emailVerificationTokenVersion)
+	if err != nil {
+		return err
+	}
+
+	err = s.emailVerificationTokenRepo.CreateOne(ctx, &email_verification_token.EmailVerificationToken{
+		Token:     token,
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		TTL:       time.Now().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	message := &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Auto Light email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"thanks for signing up to Auto Light. "+
+				"Use the link below within %d hours to verify your email:\n\n%s\n\n"+
+				"If you did not create an account you can ignore this email.\n",
+			user.Name, int(emailVerificationTTL.Hours()), s.emailVerificationLink(token),
+		),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+		defer cancel()
+
+		err := s.mailer.Send(ctx, message)
+		if err != nil {
+			slog.Error("failed to send the email verification email", "userID", user.ID, "error", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *service) passwordResetLink(token string) string {
+	return tokenLink(s.config.PasswordResetURL, token)
+}
+
+func (s *service) emailVerificationLink(token string) string {
+	return tokenLink(s.config.EmailVerificationURL, token)
+}
+
+func tokenLink(baseURL string, token string) string {
+	if baseURL == "" {
+		return token
+	}
+	return baseURL + "?token=" + url.QueryEscape(token)
+}
+
+// unverifiedEmailPolicy returns the configured policy, an unknown policy is
+// reported and the users are allowed to do everything as by default
+func (s *service) unverifiedEmailPolicy() string {
+	policy := s.config.UnverifiedEmailPolicy
+	switch policy {
+	case unverifiedEmailAllow, unverifiedEmailReadOnly, unverifiedEmailDeny:
+		return policy
+	case "":
+		return unverifiedEmailAllow
+	default:
+		slog.Warn("unknown unverified email policy, falling back to allow", "policy", policy)
+		return unverifiedEmailAllow
+	}
+}
+
+func newRefreshToken(userID string, sessionID string) (*refresh_token.RefreshToken, error) {
+	token, err := newOpaqueToken(refreshTokenVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// the TTL of the token is 7 days
+	TTL := time.Now().Add(7 * 24 * time.Hour)
+
+	return &refresh_token.RefreshToken{
+		RefreshToken:     token,
+		RefreshTokenHash: hashToken(token),
+		UserID:           userID,
+		SessionID:        sessionID,
+		TTL:              TTL,
+	}, nil
+}
+
+func newOpaqueToken(version string) (string, error) {
+	// generate 32 random bytes that will be used as the token
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+
+	// the opaque is the base64 encoded version of the random bytes
+	opaque := base64.RawURLEncoding.EncodeToString(bytes)
+	
+	// the version is used in case in the future we need to change the format of the token
+	// in this way we can easily identify the version of the token
+	return fmt.Sprintf("%s.%s", version, opaque), nil
+}
+
+// hashPassword and comparePassword time bcrypt, with cost 14 it
+// is most of the time of a login and of a registration
+func hashPassword(password string) ([]byte, error) {
+	start := time.Now()
+	defer observeBcrypt("hash", start)
+	return bcrypt.GenerateFromPassword([]byte(password), 14)
+}
+
+func comparePassword(passwordHash string, password string) error {
+	start := time.Now()
+	defer observeBcrypt("compare", start)
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+}
+
+func observeBcrypt(operation string, start time.Time) {
+	metrics.BcryptDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// in the db we will not save the opaque value, but only the hash
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -3,12 +3,20 @@ package auth
 import (
 	"context"
 	"errors"
-	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/auth/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/email_verification_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mailer"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa_ticket"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/password_reset_token"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/mock/gomock"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -23,6 +31,10 @@ func TestService_Register(t *testing.T) {
 		surname       string
 		setupMock     func(*mocks.MockuserRepository)
 		expectedError error
+
+		// the verification token is created only once the user exists
+		expectVerification bool
+		verificationErr    error
 	}{
 		{
 			name:     "success",
@@ -36,7 +48,25 @@ func TestService_Register(t *testing.T) {
 				m.EXPECT().GetOneByUsername(gomock.Any(), "mario").Return(nil, nil)
 				m.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(nil)
 			},
-			expectedError: nil,
+			expectVerification: true,
+			expectedError:      nil,
+		},
+		{
+			name:     "verification_token_error",
+			username: "mario",
+			email:    "mariorossi@gmail.com",
+			password: "Testtest123",
+			userName: "mario",
+			surname:  "rossi",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByEmail(gomock.Any(), "mariorossi@gmail.com").Return(nil, nil)
+				m.EXPECT().GetOneByUsername(gomock.Any(), "mario").Return(nil, nil)
+				m.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			// the user has been registered anyway, it can ask for another email
+			expectVerification: true,
+			verificationErr:    errors.New("redis error"),
+			expectedError:      nil,
 		},
 		{
 			name:     "email_already_exists",
@@ -111,11 +141,45 @@ func TestService_Register(t *testing.T) {
 
 			mockUserRepo := mocks.NewMockuserRepository(ctrl)
 			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			mockVerificationRepo := mocks.NewMockemailVerificationTokenRepository(ctrl)
+			mockMailer := mocks.NewMockmailSender(ctrl)
 			tt.setupMock(mockUserRepo)
 
-			s := NewAuthService(mockUserRepo, mockTokenRepo)
+			sent := make(chan *mailer.Message, 1)
+			if tt.expectVerification {
+				mockVerificationRepo.EXPECT().
+					CreateOne(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, token *email_verification_token.EmailVerificationToken) error {
+						if token.UserID == "" || token.TokenHash == "" || token.TokenHash == token.Token {
+							t.Errorf("unexpected verification token %+v", token)
+						}
+						return tt.verificationErr
+					})
+				if tt.verificationErr == nil {
+					mockMailer.EXPECT().
+						Send(gomock.Any(), gomock.Any()).
+						DoAndReturn(func(ctx context.Context, message *mailer.Message) error {
+							sent <- message
+							return nil
+						})
+				}
+			}
+
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mockVerificationRepo, mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mockMailer, Config{})
 			err := s.Register(context.Background(), tt.username, tt.email, tt.password, tt.userName, tt.surname)
 
+			if tt.expectVerification && tt.verificationErr == nil {
+				// the email is sent in background
+				select {
+				case message := <-sent:
+					if message.To != tt.email || !strings.Contains(message.Body, "ev1.") {
+						t.Errorf("unexpected email %+v", message)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("the verification email was not sent")
+				}
+			}
+
 			if tt.expectedError != nil {
 				if err == nil {
 					t.Errorf("expected error %v, got nil", tt.expectedError)
@@ -133,6 +197,16 @@ func TestService_Register(t *testing.T) {
 	}
 }
 
+// newUnlockedLoginAttemptRepo returns a repository where no account
+// is ever locked, the lockout is covered by TestService_LoginLockout
+func newUnlockedLoginAttemptRepo(ctrl *gomock.Controller) *mocks.MockloginAttemptRepository {
+	m := mocks.NewMockloginAttemptRepository(ctrl)
+	m.EXPECT().GetLockout(gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).AnyTimes()
+	m.EXPECT().RecordFailure(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), nil).AnyTimes()
+	m.EXPECT().Reset(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return m
+}
+
 func TestService_LoginByUsername(t *testing.T) {
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), 14)
 
@@ -140,6 +214,7 @@ func TestService_LoginByUsername(t *testing.T) {
 		name          string
 		username      string
 		password      string
+		policy        string
 		setupMock     func(*mocks.MockuserRepository)
 		expectedUser  *user.User
 		expectedError error
@@ -193,6 +268,37 @@ func TestService_LoginByUsername(t *testing.T) {
 			expectedUser:  nil,
 			expectedError: ErrInvalidPassword,
 		},
+		{
+			name:     "email_not_verified_deny",
+			username: "mario",
+			password: "Testtest123",
+			policy:   "deny",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByUsername(gomock.Any(), "mario").Return(&user.User{
+					Username: "mario",
+					Password: string(hashedPassword),
+				}, nil)
+			},
+			expectedUser:  nil,
+			expectedError: ErrEmailNotVerified,
+		},
+		{
+			name:     "email_not_verified_read_only",
+			username: "mario",
+			password: "Testtest123",
+			policy:   "read_only",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByUsername(gomock.Any(), "mario").Return(&user.User{
+					Username: "mario",
+					Password: string(hashedPassword),
+				}, nil)
+			},
+			expectedUser: &user.User{
+				Username: "mario",
+				Password: string(hashedPassword),
+			},
+			expectedError: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -203,9 +309,12 @@ func TestService_LoginByUsername(t *testing.T) {
 			mockUserRepo := mocks.NewMockuserRepository(ctrl)
 			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
 			tt.setupMock(mockUserRepo)
+			// the second factor is covered by TestService_LoginMFATicket
+			mockMFA := mocks.NewMockmfaVerifier(ctrl)
+			mockMFA.EXPECT().IsEnabled(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
 
-			s := NewAuthService(mockUserRepo, mockTokenRepo)
-			user, err := s.LoginByUsername(context.Background(), tt.username, tt.password)
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mockMFA, newUnlockedLoginAttemptRepo(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{UnverifiedEmailPolicy: tt.policy})
+			user, mfaTicket, err := s.LoginByUsername(context.Background(), tt.username, tt.password)
 
 			if tt.expectedError != nil {
 				if err == nil {
@@ -222,6 +331,9 @@ func TestService_LoginByUsername(t *testing.T) {
 				if user.Username != tt.expectedUser.Username {
 					t.Errorf("expected user %v, got %v", tt.expectedUser, user)
 				}
+				if mfaTicket != "" {
+					t.Errorf("expected no mfa ticket, got %v", mfaTicket)
+				}
 			}
 		})
 	}
@@ -234,6 +346,7 @@ func TestService_LoginByEmail(t *testing.T) {
 		name          string
 		email         string
 		password      string
+		policy        string
 		setupMock     func(*mocks.MockuserRepository)
 		expectedUser  *user.User
 		expectedError error
@@ -287,6 +400,37 @@ func TestService_LoginByEmail(t *testing.T) {
 			expectedUser:  nil,
 			expectedError: ErrInvalidPassword,
 		},
+		{
+			name:     "email_not_verified_deny",
+			email:    "mariorossi@gmail.com",
+			password: "Testtest123",
+			policy:   "deny",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByEmail(gomock.Any(), "mariorossi@gmail.com").Return(&user.User{
+					Email:    "mariorossi@gmail.com",
+					Password: string(hashedPassword),
+				}, nil)
+			},
+			expectedUser:  nil,
+			expectedError: ErrEmailNotVerified,
+		},
+		{
+			name:     "email_not_verified_read_only",
+			email:    "mariorossi@gmail.com",
+			password: "Testtest123",
+			policy:   "read_only",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByEmail(gomock.Any(), "mariorossi@gmail.com").Return(&user.User{
+					Email:    "mariorossi@gmail.com",
+					Password: string(hashedPassword),
+				}, nil)
+			},
+			expectedUser: &user.User{
+				Email:    "mariorossi@gmail.com",
+				Password: string(hashedPassword),
+			},
+			expectedError: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -297,9 +441,12 @@ func TestService_LoginByEmail(t *testing.T) {
 			mockUserRepo := mocks.NewMockuserRepository(ctrl)
 			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
 			tt.setupMock(mockUserRepo)
+			// the second factor is covered by TestService_LoginMFATicket
+			mockMFA := mocks.NewMockmfaVerifier(ctrl)
+			mockMFA.EXPECT().IsEnabled(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
 
-			s := NewAuthService(mockUserRepo, mockTokenRepo)
-			user, err := s.LoginByEmail(context.Background(), tt.email, tt.password)
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mockMFA, newUnlockedLoginAttemptRepo(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{UnverifiedEmailPolicy: tt.policy})
+			user, mfaTicket, err := s.LoginByEmail(context.Background(), tt.email, tt.password)
 
 			if tt.expectedError != nil {
 				if err == nil {
@@ -316,54 +463,69 @@ func TestService_LoginByEmail(t *testing.T) {
 				if user.Email != tt.expectedUser.Email {
 					t.Errorf("expected user %v, got %v", tt.expectedUser, user)
 				}
+				if mfaTicket != "" {
+					t.Errorf("expected no mfa ticket, got %v", mfaTicket)
+				}
 			}
 		})
 	}
 }
 
-func TestService_GenerateJWT(t *testing.T) {
-	os.Setenv("JWT_SECRET", "secret")
-	os.Setenv("APPLICATION_NAME", "app")
-
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	mockUserRepo := mocks.NewMockuserRepository(ctrl)
-	mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
-
-	s := NewAuthService(mockUserRepo, mockTokenRepo)
-	token, err := s.GenerateJWT("UserID")
-
-	if err != nil {
-		t.Errorf("expected no error, got %v", err)
-	}
-	if token == "" {
-		t.Errorf("expected token, got empty string")
-	}
-}
+func TestService_LoginMFATicket(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), 14)
 
-func TestService_GenerateRefreshToken(t *testing.T) {
 	tests := []struct {
-		name          string
-		userID        string
-		setupMock     func(*mocks.MockrefreshTokenRepository)
-		expectedError error
+		name           string
+		setupMock      func(*mocks.MockmfaVerifier, *mocks.MockmfaTicketRepository)
+		expectedTicket bool
+		expectedError  error
 	}{
 		{
-			name:   "success",
-			userID: "UserID",
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(nil)
+			name: "mfa_disabled",
+			setupMock: func(mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mv.EXPECT().IsEnabled(gomock.Any(), "user-id").Return(false, nil)
 			},
-			expectedError: nil,
+			expectedTicket: false,
+			expectedError:  nil,
 		},
 		{
-			name:   "db_error",
-			userID: "UserID",
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			name: "mfa_enabled",
+			setupMock: func(mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mv.EXPECT().IsEnabled(gomock.Any(), "user-id").Return(true, nil)
+				mt.EXPECT().CreateOne(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, ticket *mfa_ticket.MFATicket) error {
+						if ticket.UserID != "user-id" {
+							t.Errorf("expected user id user-id, got %v", ticket.UserID)
+						}
+						if ticket.TicketHash != hashToken(ticket.Ticket) {
+							t.Errorf("expected the hash of the ticket to be stored")
+						}
+						if time.Until(ticket.TTL) > mfaTicketTTL {
+							t.Errorf("expected ticket ttl within %v, got %v", mfaTicketTTL, ticket.TTL)
+						}
+						return nil
+					},
+				)
 			},
-			expectedError: errors.New("db error"),
+			expectedTicket: true,
+			expectedError:  nil,
+		},
+		{
+			name: "mfa_lookup_error",
+			setupMock: func(mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mv.EXPECT().IsEnabled(gomock.Any(), "user-id").Return(false, errors.New("db error"))
+			},
+			expectedTicket: false,
+			expectedError:  errors.New("db error"),
+		},
+		{
+			name: "ticket_store_error",
+			setupMock: func(mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mv.EXPECT().IsEnabled(gomock.Any(), "user-id").Return(true, nil)
+				mt.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(errors.New("redis error"))
+			},
+			expectedTicket: false,
+			expectedError:  errors.New("redis error"),
 		},
 	}
 
@@ -373,85 +535,111 @@ func TestService_GenerateRefreshToken(t *testing.T) {
 			defer ctrl.Finish()
 
 			mockUserRepo := mocks.NewMockuserRepository(ctrl)
-			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
-			tt.setupMock(mockTokenRepo)
+			mockMFA := mocks.NewMockmfaVerifier(ctrl)
+			mockTicketRepo := mocks.NewMockmfaTicketRepository(ctrl)
+			mockUserRepo.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(&user.User{
+				ID:       "user-id",
+				Email:    "mario@example.com",
+				Password: string(hashedPassword),
+			}, nil)
+			tt.setupMock(mockMFA, mockTicketRepo)
 
-			s := NewAuthService(mockUserRepo, mockTokenRepo)
-			token, err := s.GenerateRefreshToken(context.Background(), tt.userID)
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mockTicketRepo, mockMFA, newUnlockedLoginAttemptRepo(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			user, mfaTicket, err := s.LoginByEmail(context.Background(), "mario@example.com", "Testtest123")
 
 			if tt.expectedError != nil {
-				if err == nil {
-					t.Errorf("expected error %v, got nil", tt.expectedError)
-				} else {
-					if err.Error() != tt.expectedError.Error() {
-						t.Errorf("expected error %v, got %v", tt.expectedError, err)
-					}
-				}
-			} else {
-				if err != nil {
-					t.Errorf("expected no error, got %v", err)
+				if err == nil || err.Error() != tt.expectedError.Error() {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
 				}
-				if token == nil {
-					t.Errorf("expected token, got nil")
+				if user != nil || mfaTicket != "" {
+					t.Errorf("expected no user and no ticket on error")
 				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.expectedTicket && !strings.HasPrefix(mfaTicket, mfaTicketVersion+".") {
+				t.Errorf("expected mfa ticket, got %q", mfaTicket)
+			}
+			if !tt.expectedTicket && mfaTicket != "" {
+				t.Errorf("expected no mfa ticket, got %q", mfaTicket)
 			}
 		})
 	}
 }
 
-func TestService_ValidateRefreshToken(t *testing.T) {
-	validToken := "rt1.validtokenopaque"
+func TestService_CompleteMFALogin(t *testing.T) {
+	ticket := mfaTicketVersion + ".ticket"
+	ticketHash := hashToken(ticket)
 
 	tests := []struct {
-		name           string
-		token          string
-		setupMock      func(*mocks.MockrefreshTokenRepository)
-		expectedUserID string
-		expectedError  error
+		name          string
+		ticket        string
+		setupMock     func(*mocks.MockuserRepository, *mocks.MockmfaVerifier, *mocks.MockmfaTicketRepository)
+		expectedError error
 	}{
 		{
-			name:  "success",
-			token: validToken,
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().GetOneUserIDByTokenHash(gomock.Any(), gomock.Any()).Return("UserID", nil)
+			name:   "success",
+			ticket: ticket,
+			setupMock: func(mu *mocks.MockuserRepository, mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mt.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("user-id", nil)
+				mv.EXPECT().Verify(gomock.Any(), "user-id", "123456").Return(nil)
+				mt.EXPECT().DeleteOne(gomock.Any(), ticketHash).Return(nil)
+				mu.EXPECT().GetOneByID(gomock.Any(), "user-id").Return(&user.User{ID: "user-id"}, nil)
 			},
-			expectedUserID: "UserID",
-			expectedError:  nil,
+			expectedError: nil,
 		},
 		{
-			name:  "invalid_prefix",
-			token: "invalid.token",
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+			name:          "malformed_ticket",
+			ticket:        "ticket",
+			setupMock:     func(mu *mocks.MockuserRepository, mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {},
+			expectedError: ErrInvalidMFATicket,
+		},
+		{
+			name:   "unknown_or_exhausted_ticket",
+			ticket: ticket,
+			setupMock: func(mu *mocks.MockuserRepository, mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mt.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("", mfa_ticket.ErrTicketHashNotFound)
 			},
-			expectedUserID: "",
-			expectedError:  ErrInvalidToken,
+			expectedError: ErrInvalidMFATicket,
 		},
 		{
-			name:  "token_not_found",
-			token: validToken,
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().GetOneUserIDByTokenHash(gomock.Any(), gomock.Any()).Return("", refresh_token.ErrTokenHashNotFound)
+			name:   "invalid_code_keeps_ticket",
+			ticket: ticket,
+			setupMock: func(mu *mocks.MockuserRepository, mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mt.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("user-id", nil)
+				mv.EXPECT().Verify(gomock.Any(), "user-id", "123456").Return(mfa.ErrInvalidCode)
 			},
-			expectedUserID: "",
-			expectedError:  ErrInvalidToken,
+			expectedError: ErrInvalidMFACode,
 		},
 		{
-			name:  "db_error",
-			token: validToken,
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().GetOneUserIDByTokenHash(gomock.Any(), gomock.Any()).Return("", errors.New("db error"))
+			name:   "mfa_disabled_meanwhile",
+			ticket: ticket,
+			setupMock: func(mu *mocks.MockuserRepository, mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mt.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("user-id", nil)
+				mv.EXPECT().Verify(gomock.Any(), "user-id", "123456").Return(mfa.ErrNotEnabled)
 			},
-			expectedUserID: "",
-			expectedError:  errors.New("db error"),
+			expectedError: ErrInvalidMFATicket,
 		},
 		{
-			name:  "empty_user_id",
-			token: validToken,
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().GetOneUserIDByTokenHash(gomock.Any(), gomock.Any()).Return("", nil)
+			name:   "user_deleted",
+			ticket: ticket,
+			setupMock: func(mu *mocks.MockuserRepository, mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mt.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("user-id", nil)
+				mv.EXPECT().Verify(gomock.Any(), "user-id", "123456").Return(nil)
+				mt.EXPECT().DeleteOne(gomock.Any(), ticketHash).Return(nil)
+				mu.EXPECT().GetOneByID(gomock.Any(), "user-id").Return(nil, nil)
 			},
-			expectedUserID: "",
-			expectedError:  ErrInvalidToken,
+			expectedError: ErrInvalidMFATicket,
+		},
+		{
+			name:   "redis_error",
+			ticket: ticket,
+			setupMock: func(mu *mocks.MockuserRepository, mv *mocks.MockmfaVerifier, mt *mocks.MockmfaTicketRepository) {
+				mt.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("", errors.New("redis error"))
+			},
+			expectedError: errors.New("redis error"),
 		},
 	}
 
@@ -461,64 +649,105 @@ func TestService_ValidateRefreshToken(t *testing.T) {
 			defer ctrl.Finish()
 
 			mockUserRepo := mocks.NewMockuserRepository(ctrl)
-			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
-			tt.setupMock(mockTokenRepo)
+			mockMFA := mocks.NewMockmfaVerifier(ctrl)
+			mockTicketRepo := mocks.NewMockmfaTicketRepository(ctrl)
+			tt.setupMock(mockUserRepo, mockMFA, mockTicketRepo)
 
-			s := NewAuthService(mockUserRepo, mockTokenRepo)
-			userID, err := s.ValidateRefreshToken(context.Background(), tt.token)
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mockTicketRepo, mockMFA, newUnlockedLoginAttemptRepo(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			user, err := s.CompleteMFALogin(context.Background(), tt.ticket, "123456")
 
 			if tt.expectedError != nil {
-				if err == nil {
-					t.Errorf("expected error %v, got nil", tt.expectedError)
-				} else {
-					if !errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error() {
-						t.Errorf("expected error %v, got %v", tt.expectedError, err)
-					}
-				}
-			} else {
-				if err != nil {
-					t.Errorf("expected no error, got %v", err)
-				}
-				if userID != tt.expectedUserID {
-					t.Errorf("expected userID %v, got %v", tt.expectedUserID, userID)
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
 				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if user == nil || user.ID != "user-id" {
+				t.Errorf("expected user user-id, got %v", user)
 			}
 		})
 	}
 }
 
-func TestService_RotateRefreshToken(t *testing.T) {
+func TestService_LoginLockout(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), 14)
+	unknownKey := "login:" + hashToken("email:nobody@example.com")
+
 	tests := []struct {
 		name          string
-		userID        string
-		setupMock     func(*mocks.MockrefreshTokenRepository)
+		email         string
+		password      string
+		setupMock     func(*mocks.MockuserRepository, *mocks.MockloginAttemptRepository)
 		expectedError error
+		retryAfter    time.Duration
 	}{
 		{
-			name:   "success",
-			userID: "UserID",
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
-				m.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(nil)
+			name:     "locked_account_skips_password_check",
+			email:    "mario@example.com",
+			password: "Testtest123",
+			setupMock: func(mu *mocks.MockuserRepository, ml *mocks.MockloginAttemptRepository) {
+				mu.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(&user.User{ID: "user-id", Password: string(hashedPassword)}, nil)
+				ml.EXPECT().GetLockout(gomock.Any(), "user:user-id").Return(2*time.Minute, nil)
+			},
+			expectedError: ErrAccountLocked,
+			retryAfter:    2 * time.Minute,
+		},
+		{
+			name:     "wrong_password_records_failure",
+			email:    "mario@example.com",
+			password: "WrongPassword",
+			setupMock: func(mu *mocks.MockuserRepository, ml *mocks.MockloginAttemptRepository) {
+				mu.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(&user.User{ID: "user-id", Password: string(hashedPassword)}, nil)
+				ml.EXPECT().GetLockout(gomock.Any(), "user:user-id").Return(time.Duration(0), nil)
+				ml.EXPECT().RecordFailure(gomock.Any(), "user:user-id", lockoutThreshold, lockoutBase, lockoutMax, lockoutMemory).Return(time.Minute, nil)
+			},
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:     "success_resets_failures",
+			email:    "mario@example.com",
+			password: "Testtest123",
+			setupMock: func(mu *mocks.MockuserRepository, ml *mocks.MockloginAttemptRepository) {
+				mu.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(&user.User{ID: "user-id", Password: string(hashedPassword)}, nil)
+				ml.EXPECT().GetLockout(gomock.Any(), "user:user-id").Return(time.Duration(0), nil)
+				ml.EXPECT().Reset(gomock.Any(), "user:user-id").Return(nil)
 			},
 			expectedError: nil,
 		},
 		{
-			name:   "delete_error",
-			userID: "UserID",
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(errors.New("db error"))
+			name:     "unknown_account_records_failure",
+			email:    "Nobody@example.com",
+			password: "Testtest123",
+			setupMock: func(mu *mocks.MockuserRepository, ml *mocks.MockloginAttemptRepository) {
+				mu.EXPECT().GetOneByEmail(gomock.Any(), "Nobody@example.com").Return(nil, nil)
+				ml.EXPECT().GetLockout(gomock.Any(), unknownKey).Return(time.Duration(0), nil)
+				ml.EXPECT().RecordFailure(gomock.Any(), unknownKey, lockoutThreshold, lockoutBase, lockoutMax, lockoutMemory).Return(time.Duration(0), nil)
 			},
-			expectedError: errors.New("db error"),
+			expectedError: ErrUserNotExists,
 		},
 		{
-			name:   "create_error",
-			userID: "UserID",
-			setupMock: func(m *mocks.MockrefreshTokenRepository) {
-				m.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
-				m.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			name:     "unknown_account_locked",
+			email:    "nobody@example.com",
+			password: "Testtest123",
+			setupMock: func(mu *mocks.MockuserRepository, ml *mocks.MockloginAttemptRepository) {
+				mu.EXPECT().GetOneByEmail(gomock.Any(), "nobody@example.com").Return(nil, nil)
+				ml.EXPECT().GetLockout(gomock.Any(), unknownKey).Return(time.Minute, nil)
 			},
-			expectedError: errors.New("db error"),
+			expectedError: ErrAccountLocked,
+			retryAfter:    time.Minute,
+		},
+		{
+			name:     "lockout_check_error",
+			email:    "mario@example.com",
+			password: "Testtest123",
+			setupMock: func(mu *mocks.MockuserRepository, ml *mocks.MockloginAttemptRepository) {
+				mu.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(&user.User{ID: "user-id", Password: string(hashedPassword)}, nil)
+				ml.EXPECT().GetLockout(gomock.Any(), "user:user-id").Return(time.Duration(0), errors.New("redis error"))
+			},
+			expectedError: errors.New("redis error"),
 		},
 	}
 
@@ -528,28 +757,1586 @@ func TestService_RotateRefreshToken(t *testing.T) {
 			defer ctrl.Finish()
 
 			mockUserRepo := mocks.NewMockuserRepository(ctrl)
-			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
-			tt.setupMock(mockTokenRepo)
+			mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+			mockMFA := mocks.NewMockmfaVerifier(ctrl)
+			mockMFA.EXPECT().IsEnabled(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+			tt.setupMock(mockUserRepo, mockLoginAttemptRepo)
 
-			s := NewAuthService(mockUserRepo, mockTokenRepo)
-			token, err := s.RotateRefreshToken(context.Background(), tt.userID)
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mockMFA, mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			_, _, err := s.LoginByEmail(context.Background(), tt.email, tt.password)
 
-			if tt.expectedError != nil {
-				if err == nil {
-					t.Errorf("expected error %v, got nil", tt.expectedError)
-				} else {
-					if err.Error() != tt.expectedError.Error() {
-						t.Errorf("expected error %v, got %v", tt.expectedError, err)
-					}
-				}
-			} else {
+			if tt.expectedError == nil {
 				if err != nil {
 					t.Errorf("expected no error, got %v", err)
 				}
-				if token == nil {
-					t.Errorf("expected token, got nil")
-				}
+				return
+			}
+			if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			var lockedErr *AccountLockedError
+			if tt.retryAfter > 0 && (!errors.As(err, &lockedErr) || lockedErr.RetryAfter != tt.retryAfter) {
+				t.Errorf("expected retry after %v, got %v", tt.retryAfter, err)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestService_CompleteMFALogin_Lockout(t *testing.T) {
+	ticket := mfaTicketVersion + ".ticket"
+	ticketHash := hashToken(ticket)
+
+	t.Run("locked_account_skips_code_check", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockTicketRepo := mocks.NewMockmfaTicketRepository(ctrl)
+		mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+		mockTicketRepo.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("user-id", nil)
+		mockLoginAttemptRepo.EXPECT().GetLockout(gomock.Any(), "user:user-id").Return(time.Minute, nil)
+
+		s := NewAuthService(mocks.NewMockuserRepository(ctrl), mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mockTicketRepo, mocks.NewMockmfaVerifier(ctrl), mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+		_, err := s.CompleteMFALogin(context.Background(), ticket, "123456")
+		if !errors.Is(err, ErrAccountLocked) {
+			t.Errorf("expected error %v, got %v", ErrAccountLocked, err)
+		}
+	})
+
+	t.Run("wrong_code_records_failure", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockTicketRepo := mocks.NewMockmfaTicketRepository(ctrl)
+		mockMFA := mocks.NewMockmfaVerifier(ctrl)
+		mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+		mockTicketRepo.EXPECT().AttemptOne(gomock.Any(), ticketHash, mfaTicketMaxAttempts).Return("user-id", nil)
+		mockLoginAttemptRepo.EXPECT().GetLockout(gomock.Any(), "user:user-id").Return(time.Duration(0), nil)
+		mockMFA.EXPECT().Verify(gomock.Any(), "user-id", "123456").Return(mfa.ErrInvalidCode)
+		mockLoginAttemptRepo.EXPECT().RecordFailure(gomock.Any(), "user:user-id", lockoutThreshold, lockoutBase, lockoutMax, lockoutMemory).Return(time.Duration(0), nil)
+
+		s := NewAuthService(mocks.NewMockuserRepository(ctrl), mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mockTicketRepo, mockMFA, mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+		_, err := s.CompleteMFALogin(context.Background(), ticket, "123456")
+		if !errors.Is(err, ErrInvalidMFACode) {
+			t.Errorf("expected error %v, got %v", ErrInvalidMFACode, err)
+		}
+	})
+}
+
+func TestService_GenerateJWT(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUserRepo := mocks.NewMockuserRepository(ctrl)
+	mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+	signingKeys := signing_key.NewSigningKeyService(signing_key.NewMemorySigningKeyRepository(), signing_key.DefaultConfig())
+	if err := signingKeys.Refresh(context.Background()); err != nil {
+		t.Fatalf("failed to create the signing key: %v", err)
+	}
+
+	s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), signingKeys, mocks.NewMockmailSender(ctrl), Config{Issuer: "app"})
+	token, err := s.GenerateJWT("UserID", "SessionID", true)
+
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if token == "" {
+		t.Errorf("expected token, got empty string")
+	}
+
+	// the token is signed by the current key and names it in the header
+	parsed, err := jwt.Parse(token, signingKeys.Keyfunc)
+	if err != nil || !parsed.Valid {
+		t.Errorf("expected a token verified by the signing keys, got %v", err)
+	}
+	if parsed != nil && parsed.Header["kid"] == nil {
+		t.Errorf("expected a kid in the header")
+	}
+
+	// every token has its own jti, used to revoke it on logout
+	other, _ := s.GenerateJWT("UserID", "SessionID", true)
+	claimOf := func(tokenString string, name string) string {
+		claims := jwt.MapClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+		if err != nil {
+			t.Fatalf("failed to parse token: %v", err)
+		}
+		value, _ := claims[name].(string)
+		return value
+	}
+	if claimOf(token, "jti") == "" || claimOf(token, "jti") == claimOf(other, "jti") {
+		t.Errorf("expected a unique jti, got %q and %q", claimOf(token, "jti"), claimOf(other, "jti"))
+	}
+
+	// the session is needed to logout only from the current device
+	if claimOf(token, "sid") != "SessionID" {
+		t.Errorf("expected sid SessionID, got %q", claimOf(token, "sid"))
+	}
+
+	// the issuer is the name of the application in the configuration
+	if claimOf(token, "iss") != "app" {
+		t.Errorf("expected iss app, got %q", claimOf(token, "iss"))
+	}
+
+	// an unverified user gets a read only token only when the policy says so
+	scopes := []struct {
+		policy        string
+		emailVerified bool
+		expectedScope string
+	}{
+		{policy: "", emailVerified: false, expectedScope: ""},
+		{policy: "allow", emailVerified: false, expectedScope: ""},
+		{policy: "read_only", emailVerified: false, expectedScope: "read_only"},
+		{policy: "read_only", emailVerified: true, expectedScope: ""},
+	}
+	for _, tt := range scopes {
+		s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), signingKeys, mocks.NewMockmailSender(ctrl), Config{UnverifiedEmailPolicy: tt.policy})
+		token, err := s.GenerateJWT("UserID", "SessionID", tt.emailVerified)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if scope := claimOf(token, "scope"); scope != tt.expectedScope {
+			t.Errorf("policy %q, verified %v: expected scope %q, got %q", tt.policy, tt.emailVerified, tt.expectedScope, scope)
+		}
+	}
+}
+
+func TestService_CheckEmailVerified(t *testing.T) {
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name          string
+		policy        string
+		setupMock     func(*mocks.MockuserRepository)
+		expected      bool
+		expectedError error
+	}{
+		{
+			name:   "verified",
+			policy: "deny",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(&user.User{ID: "UserID", EmailVerifiedAt: &verifiedAt}, nil)
+			},
+			expected: true,
+		},
+		{
+			name:   "not_verified_read_only",
+			policy: "read_only",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(&user.User{ID: "UserID"}, nil)
+			},
+			expected: false,
+		},
+		{
+			name:   "not_verified_deny",
+			policy: "deny",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(&user.User{ID: "UserID"}, nil)
+			},
+			expectedError: ErrEmailNotVerified,
+		},
+		{
+			name:   "user_deleted",
+			policy: "allow",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(nil, nil)
+			},
+			expectedError: ErrUserNotExists,
+		},
+		{
+			name:   "db_error",
+			policy: "allow",
+			setupMock: func(m *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			tt.setupMock(mockUserRepo)
+
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{UnverifiedEmailPolicy: tt.policy})
+			verified, err := s.CheckEmailVerified(context.Background(), "UserID")
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if verified != tt.expected {
+				t.Errorf("expected verified %v, got %v", tt.expected, verified)
+			}
+		})
+	}
+}
+
+func TestService_GenerateRefreshToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		userID        string
+		setupMock     func(*mocks.MockrefreshTokenRepository)
+		expectedError error
+	}{
+		{
+			name:   "success",
+			userID: "UserID",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().
+					CreateOne(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, rt *refresh_token.RefreshToken, session *refresh_token.Session) error {
+						if rt.SessionID == "" || session.UserAgent != "agent" || session.IP != "192.0.2.1" {
+							t.Errorf("unexpected session %q %+v", rt.SessionID, session)
+						}
+						return nil
+					})
+			},
+			expectedError: nil,
+		},
+		{
+			name:   "db_error",
+			userID: "UserID",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().CreateOne(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			tt.setupMock(mockTokenRepo)
+
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			token, err := s.GenerateRefreshToken(context.Background(), tt.userID, "agent", "192.0.2.1")
+
+			if tt.expectedError != nil {
+				if err == nil {
+					t.Errorf("expected error %v, got nil", tt.expectedError)
+				} else {
+					if err.Error() != tt.expectedError.Error() {
+						t.Errorf("expected error %v, got %v", tt.expectedError, err)
+					}
+				}
+			} else {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				if token == nil {
+					t.Errorf("expected token, got nil")
+				}
+			}
+		})
+	}
+}
+
+func TestService_ValidateRefreshToken(t *testing.T) {
+	validToken := "rt1.validtokenopaque"
+
+	tests := []struct {
+		name           string
+		token          string
+		setupMock      func(*mocks.MockrefreshTokenRepository)
+		expectedUserID string
+		expectedError  error
+	}{
+		{
+			name:  "success",
+			token: validToken,
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().GetOneByTokenHash(gomock.Any(), gomock.Any()).Return(&refresh_token.Session{ID: "SessionID", UserID: "UserID"}, nil)
+			},
+			expectedUserID: "UserID",
+			expectedError:  nil,
+		},
+		{
+			name:  "invalid_prefix",
+			token: "invalid.token",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+			},
+			expectedUserID: "",
+			expectedError:  ErrInvalidToken,
+		},
+		{
+			name:  "token_not_found",
+			token: validToken,
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().GetOneByTokenHash(gomock.Any(), gomock.Any()).Return(nil, refresh_token.ErrTokenHashNotFound)
+				m.EXPECT().GetOneByRotatedTokenHash(gomock.Any(), gomock.Any()).Return(nil, refresh_token.ErrTokenHashNotFound)
+			},
+			expectedUserID: "",
+			expectedError:  ErrInvalidToken,
+		},
+		{
+			name:  "rotated_token_reused",
+			token: validToken,
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().GetOneByTokenHash(gomock.Any(), gomock.Any()).Return(nil, refresh_token.ErrTokenHashNotFound)
+				m.EXPECT().
+					GetOneByRotatedTokenHash(gomock.Any(), gomock.Any()).
+					Return(&refresh_token.Session{ID: "SessionID", UserID: "UserID"}, nil)
+				// the whole family is revoked, together with every other session of the user
+				m.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil)
+			},
+			expectedUserID: "",
+			expectedError:  ErrTokenReused,
+		},
+		{
+			name:  "rotated_token_reused_revocation_error",
+			token: validToken,
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().GetOneByTokenHash(gomock.Any(), gomock.Any()).Return(nil, refresh_token.ErrTokenHashNotFound)
+				m.EXPECT().
+					GetOneByRotatedTokenHash(gomock.Any(), gomock.Any()).
+					Return(&refresh_token.Session{ID: "SessionID", UserID: "UserID"}, nil)
+				m.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(errors.New("db error"))
+			},
+			expectedUserID: "",
+			expectedError:  errors.New("db error"),
+		},
+		{
+			name:  "rotated_token_lookup_error",
+			token: validToken,
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().GetOneByTokenHash(gomock.Any(), gomock.Any()).Return(nil, refresh_token.ErrTokenHashNotFound)
+				m.EXPECT().GetOneByRotatedTokenHash(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedUserID: "",
+			expectedError:  errors.New("db error"),
+		},
+		{
+			name:  "db_error",
+			token: validToken,
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().GetOneByTokenHash(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			expectedUserID: "",
+			expectedError:  errors.New("db error"),
+		},
+		{
+			name:  "empty_user_id",
+			token: validToken,
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().GetOneByTokenHash(gomock.Any(), gomock.Any()).Return(&refresh_token.Session{}, nil)
+			},
+			expectedUserID: "",
+			expectedError:  ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			tt.setupMock(mockTokenRepo)
+
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			session, err := s.ValidateRefreshToken(context.Background(), tt.token)
+
+			if tt.expectedError != nil {
+				if err == nil {
+					t.Errorf("expected error %v, got nil", tt.expectedError)
+				} else {
+					if !errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error() {
+						t.Errorf("expected error %v, got %v", tt.expectedError, err)
+					}
+				}
+			} else {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				if session.UserID != tt.expectedUserID {
+					t.Errorf("expected userID %v, got %v", tt.expectedUserID, session.UserID)
+				}
+			}
+		})
+	}
+}
+
+func TestService_RotateRefreshToken(t *testing.T) {
+	session := &refresh_token.Session{ID: "SessionID", UserID: "UserID", TokenHash: "old_hash"}
+
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockrefreshTokenRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().
+					RotateOne(gomock.Any(), "old_hash", gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, oldTokenHash string, rt *refresh_token.RefreshToken, s *refresh_token.Session) error {
+						// the new token stays in the same session
+						if rt.SessionID != "SessionID" || rt.UserID != "UserID" || rt.RefreshTokenHash == "old_hash" {
+							t.Errorf("unexpected refresh token %+v", rt)
+						}
+						return nil
+					})
+			},
+			expectedError: nil,
+		},
+		{
+			name: "already_rotated",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().RotateOne(gomock.Any(), "old_hash", gomock.Any(), gomock.Any()).Return(refresh_token.ErrTokenHashNotFound)
+			},
+			expectedError: ErrInvalidToken,
+		},
+		{
+			name: "db_error",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().RotateOne(gomock.Any(), "old_hash", gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			tt.setupMock(mockTokenRepo)
+
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			token, err := s.RotateRefreshToken(context.Background(), session, "agent", "192.0.2.1")
+
+			if tt.expectedError != nil {
+				if err == nil {
+					t.Errorf("expected error %v, got nil", tt.expectedError)
+				} else {
+					if err.Error() != tt.expectedError.Error() {
+						t.Errorf("expected error %v, got %v", tt.expectedError, err)
+					}
+				}
+			} else {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				if token == nil {
+					t.Errorf("expected token, got nil")
+				}
+			}
+		})
+	}
+}
+
+func TestService_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockrefreshTokenRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().DeleteOneBySessionID(gomock.Any(), "UserID", "SessionID").Return(nil)
+			},
+		},
+		{
+			name: "not_found",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().DeleteOneBySessionID(gomock.Any(), "UserID", "SessionID").Return(refresh_token.ErrSessionNotFound)
+			},
+			expectedError: ErrSessionNotFound,
+		},
+		{
+			name: "db_error",
+			setupMock: func(m *mocks.MockrefreshTokenRepository) {
+				m.EXPECT().DeleteOneBySessionID(gomock.Any(), "UserID", "SessionID").Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			tt.setupMock(mockTokenRepo)
+
+			s := NewAuthService(mocks.NewMockuserRepository(ctrl), mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			err := s.RevokeSession(context.Background(), "UserID", "SessionID")
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_Logout(t *testing.T) {
+	expiresAt := time.Now().Add(30 * time.Minute)
+
+	tests := []struct {
+		name          string
+		sessionID     string
+		jti           string
+		setupMock     func(*mocks.MockrefreshTokenRepository, *mocks.MocktokenDenylistRepository)
+		expectedError error
+	}{
+		{
+			name:      "success",
+			sessionID: "SessionID",
+			jti:       "jti-1",
+			setupMock: func(r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				r.EXPECT().DeleteOneBySessionID(gomock.Any(), "UserID", "SessionID").Return(nil)
+				d.EXPECT().CreateOne(gomock.Any(), "jti-1", expiresAt).Return(nil)
+			},
+		},
+		{
+			name:      "session_already_closed",
+			sessionID: "SessionID",
+			jti:       "jti-1",
+			setupMock: func(r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				r.EXPECT().DeleteOneBySessionID(gomock.Any(), "UserID", "SessionID").Return(refresh_token.ErrSessionNotFound)
+				d.EXPECT().CreateOne(gomock.Any(), "jti-1", expiresAt).Return(nil)
+			},
+		},
+		{
+			name: "token_without_session",
+			jti:  "jti-1",
+			setupMock: func(r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil)
+				d.EXPECT().CreateOne(gomock.Any(), "jti-1", expiresAt).Return(nil)
+			},
+		},
+		{
+			name: "token_without_jti",
+			setupMock: func(r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil)
+			},
+		},
+		{
+			name:      "delete_error",
+			sessionID: "SessionID",
+			jti:       "jti-1",
+			setupMock: func(r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				r.EXPECT().DeleteOneBySessionID(gomock.Any(), "UserID", "SessionID").Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name:      "denylist_error",
+			sessionID: "SessionID",
+			jti:       "jti-1",
+			setupMock: func(r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				r.EXPECT().DeleteOneBySessionID(gomock.Any(), "UserID", "SessionID").Return(nil)
+				d.EXPECT().CreateOne(gomock.Any(), "jti-1", expiresAt).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			mockDenylistRepo := mocks.NewMocktokenDenylistRepository(ctrl)
+			tt.setupMock(mockTokenRepo, mockDenylistRepo)
+
+			s := NewAuthService(mocks.NewMockuserRepository(ctrl), mockTokenRepo, mockDenylistRepo, mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			err := s.Logout(context.Background(), "UserID", tt.sessionID, tt.jti, expiresAt)
+
+			if tt.expectedError != nil {
+				if err == nil || err.Error() != tt.expectedError.Error() {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_ForgotPassword(t *testing.T) {
+	config := Config{PasswordResetURL: "https://autolight.example.com/reset-password"}
+
+	registered := &user.User{ID: "UserID", Email: "mario@example.com", Name: "Mario"}
+
+	tests := []struct {
+		name               string
+		email              string
+		setupMock          func(*mocks.MockuserRepository, *mocks.MockpasswordResetTokenRepository, *mocks.MockmailSender, chan<- *mailer.Message)
+		expectMail         bool
+		expectedRetryAfter time.Duration
+		expectedError      error
+	}{
+		{
+			name:  "success",
+			email: "mario@example.com",
+			setupMock: func(u *mocks.MockuserRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				p.EXPECT().AcquireResendSlot(gomock.Any(), hashToken("mario@example.com"), passwordResetResendCooldown).Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(registered, nil)
+				p.EXPECT().
+					CreateOne(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, token *password_reset_token.PasswordResetToken) error {
+						// only the hash of the token is stored
+						if token.UserID != "UserID" || token.TokenHash == "" || token.TokenHash == token.Token {
+							t.Errorf("unexpected reset token %+v", token)
+						}
+						return nil
+					})
+				m.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, message *mailer.Message) error {
+						sent <- message
+						return nil
+					})
+			},
+			expectMail: true,
+		},
+		{
+			name:  "throttled",
+			email: "Mario@Example.com",
+			setupMock: func(u *mocks.MockuserRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				// the address is throttled regardless of its case
+				p.EXPECT().AcquireResendSlot(gomock.Any(), hashToken("mario@example.com"), passwordResetResendCooldown).Return(42*time.Second, nil)
+			},
+			expectedRetryAfter: 42 * time.Second,
+			expectedError:      ErrResendThrottled,
+		},
+		{
+			name:  "unknown_email",
+			email: "ghost@example.com",
+			setupMock: func(u *mocks.MockuserRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				p.EXPECT().AcquireResendSlot(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "ghost@example.com").Return(nil, nil)
+			},
+		},
+		{
+			name:  "db_error",
+			email: "mario@example.com",
+			setupMock: func(u *mocks.MockuserRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				p.EXPECT().AcquireResendSlot(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(registered, nil)
+				p.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name:  "redis_error",
+			email: "mario@example.com",
+			setupMock: func(u *mocks.MockuserRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				p.EXPECT().AcquireResendSlot(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), errors.New("redis error"))
+			},
+			expectedError: errors.New("redis error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockResetRepo := mocks.NewMockpasswordResetTokenRepository(ctrl)
+			mockMailer := mocks.NewMockmailSender(ctrl)
+			sent := make(chan *mailer.Message, 1)
+			tt.setupMock(mockUserRepo, mockResetRepo, mockMailer, sent)
+
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mockResetRepo, mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mockMailer, config)
+			retryAfter, err := s.ForgotPassword(context.Background(), tt.email)
+
+			if retryAfter != tt.expectedRetryAfter {
+				t.Errorf("expected retry after %v, got %v", tt.expectedRetryAfter, retryAfter)
+			}
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !tt.expectMail {
+				return
+			}
+			// the email is sent in background
+			select {
+			case message := <-sent:
+				if message.To != "mario@example.com" || !strings.Contains(message.Body, "https://autolight.example.com/reset-password?token=pr1.") {
+					t.Errorf("unexpected email %+v", message)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("the email was not sent")
+			}
+		})
+	}
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	validToken := "pr1.validtokenopaque"
+
+	tests := []struct {
+		name          string
+		token         string
+		setupMock     func(*mocks.MockuserRepository, *mocks.MockrefreshTokenRepository, *mocks.MockpasswordResetTokenRepository)
+		expectedError error
+	}{
+		{
+			name:  "success",
+			token: validToken,
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, p *mocks.MockpasswordResetTokenRepository) {
+				p.EXPECT().ConsumeOneByTokenHash(gomock.Any(), hashToken(validToken)).Return("UserID", nil)
+				u.EXPECT().
+					UpdatePassword(gomock.Any(), "UserID", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id string, password string) error {
+						if bcrypt.CompareHashAndPassword([]byte(password), []byte("NewPassword123")) != nil {
+							t.Errorf("expected the bcrypt hash of the new password, got %q", password)
+						}
+						return nil
+					})
+				// every session of the user is closed
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil)
+			},
+		},
+		{
+			name:          "invalid_prefix",
+			token:         "rt1.refreshtoken",
+			setupMock:     func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, p *mocks.MockpasswordResetTokenRepository) {},
+			expectedError: ErrInvalidResetToken,
+		},
+		{
+			name:  "token_not_found",
+			token: validToken,
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, p *mocks.MockpasswordResetTokenRepository) {
+				p.EXPECT().ConsumeOneByTokenHash(gomock.Any(), gomock.Any()).Return("", password_reset_token.ErrTokenHashNotFound)
+			},
+			expectedError: ErrInvalidResetToken,
+		},
+		{
+			name:  "user_deleted",
+			token: validToken,
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, p *mocks.MockpasswordResetTokenRepository) {
+				p.EXPECT().ConsumeOneByTokenHash(gomock.Any(), gomock.Any()).Return("UserID", nil)
+				u.EXPECT().UpdatePassword(gomock.Any(), "UserID", gomock.Any()).Return(user.ErrUserNotFound)
+			},
+			expectedError: ErrInvalidResetToken,
+		},
+		{
+			name:  "revocation_error",
+			token: validToken,
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, p *mocks.MockpasswordResetTokenRepository) {
+				p.EXPECT().ConsumeOneByTokenHash(gomock.Any(), gomock.Any()).Return("UserID", nil)
+				u.EXPECT().UpdatePassword(gomock.Any(), "UserID", gomock.Any()).Return(nil)
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			mockResetRepo := mocks.NewMockpasswordResetTokenRepository(ctrl)
+			tt.setupMock(mockUserRepo, mockTokenRepo, mockResetRepo)
+
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mockResetRepo, mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			err := s.ResetPassword(context.Background(), tt.token, "NewPassword123")
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_VerifyEmail(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		setupMock     func(*mocks.MockuserRepository, *mocks.MockemailVerificationTokenRepository)
+		expectedError error
+	}{
+		{
+			name:  "success",
+			token: "ev1.verifytoken",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository) {
+				e.EXPECT().ConsumeOneByTokenHash(gomock.Any(), hashToken("ev1.verifytoken")).Return("UserID", nil)
+				u.EXPECT().MarkEmailVerified(gomock.Any(), "UserID").Return(nil)
+			},
+		},
+		{
+			name:          "invalid_prefix",
+			token:         "pr1.resettoken",
+			setupMock:     func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository) {},
+			expectedError: ErrInvalidVerificationToken,
+		},
+		{
+			name:  "token_not_found",
+			token: "ev1.verifytoken",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository) {
+				e.EXPECT().ConsumeOneByTokenHash(gomock.Any(), gomock.Any()).Return("", email_verification_token.ErrTokenHashNotFound)
+			},
+			expectedError: ErrInvalidVerificationToken,
+		},
+		{
+			name:  "user_deleted",
+			token: "ev1.verifytoken",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository) {
+				e.EXPECT().ConsumeOneByTokenHash(gomock.Any(), gomock.Any()).Return("UserID", nil)
+				u.EXPECT().MarkEmailVerified(gomock.Any(), "UserID").Return(user.ErrUserNotFound)
+			},
+			expectedError: ErrInvalidVerificationToken,
+		},
+		{
+			name:  "redis_error",
+			token: "ev1.verifytoken",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository) {
+				e.EXPECT().ConsumeOneByTokenHash(gomock.Any(), gomock.Any()).Return("", errors.New("redis error"))
+			},
+			expectedError: errors.New("redis error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockVerificationRepo := mocks.NewMockemailVerificationTokenRepository(ctrl)
+			tt.setupMock(mockUserRepo, mockVerificationRepo)
+
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mockVerificationRepo, mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			err := s.VerifyEmail(context.Background(), tt.token)
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_ResendVerification(t *testing.T) {
+	config := Config{EmailVerificationURL: "https://autolight.example.com/api/verify-email"}
+
+	verifiedAt := time.Now()
+	unverified := &user.User{ID: "UserID", Email: "mario@example.com", Name: "Mario"}
+
+	tests := []struct {
+		name               string
+		email              string
+		setupMock          func(*mocks.MockuserRepository, *mocks.MockemailVerificationTokenRepository, *mocks.MockmailSender, chan<- *mailer.Message)
+		expectMail         bool
+		expectedRetryAfter time.Duration
+		expectedError      error
+	}{
+		{
+			name:  "success",
+			email: "mario@example.com",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				e.EXPECT().AcquireResendSlot(gomock.Any(), hashToken("mario@example.com"), emailVerificationResendCooldown).Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(unverified, nil)
+				e.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(nil)
+				m.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, message *mailer.Message) error {
+						sent <- message
+						return nil
+					})
+			},
+			expectMail: true,
+		},
+		{
+			name:  "throttled",
+			email: "Mario@Example.com",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				// the address is throttled regardless of its case
+				e.EXPECT().AcquireResendSlot(gomock.Any(), hashToken("mario@example.com"), emailVerificationResendCooldown).Return(42*time.Second, nil)
+			},
+			expectedRetryAfter: 42 * time.Second,
+			expectedError:      ErrResendThrottled,
+		},
+		{
+			name:  "unknown_email",
+			email: "ghost@example.com",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				e.EXPECT().AcquireResendSlot(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "ghost@example.com").Return(nil, nil)
+			},
+		},
+		{
+			name:  "already_verified",
+			email: "mario@example.com",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				e.EXPECT().AcquireResendSlot(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "mario@example.com").Return(&user.User{ID: "UserID", EmailVerifiedAt: &verifiedAt}, nil)
+			},
+		},
+		{
+			name:  "redis_error",
+			email: "mario@example.com",
+			setupMock: func(u *mocks.MockuserRepository, e *mocks.MockemailVerificationTokenRepository, m *mocks.MockmailSender, sent chan<- *mailer.Message) {
+				e.EXPECT().AcquireResendSlot(gomock.Any(), gomock.Any(), gomock.Any()).Return(time.Duration(0), errors.New("redis error"))
+			},
+			expectedError: errors.New("redis error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockVerificationRepo := mocks.NewMockemailVerificationTokenRepository(ctrl)
+			mockMailer := mocks.NewMockmailSender(ctrl)
+			sent := make(chan *mailer.Message, 1)
+			tt.setupMock(mockUserRepo, mockVerificationRepo, mockMailer, sent)
+
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mockVerificationRepo, mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mocks.NewMockloginAttemptRepository(ctrl), mocks.NewMocktokenSigner(ctrl), mockMailer, config)
+			retryAfter, err := s.ResendVerification(context.Background(), tt.email)
+
+			if retryAfter != tt.expectedRetryAfter {
+				t.Errorf("expected retry after %v, got %v", tt.expectedRetryAfter, retryAfter)
+			}
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if !tt.expectMail {
+				return
+			}
+			select {
+			case message := <-sent:
+				if message.To != "mario@example.com" || !strings.Contains(message.Body, "https://autolight.example.com/api/verify-email?token=ev1.") {
+					t.Errorf("unexpected email %+v", message)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("the email was not sent")
+			}
+		})
+	}
+}
+
+func TestService_UpdateProfile(t *testing.T) {
+	newEmail := "new@example.com"
+	sameEmail := "luigi@example.com"
+	errRedis := errors.New("redis error")
+	newName := "mario"
+	taken := "taken"
+	// the minimum cost keeps the test fast, the hash is only compared
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), bcrypt.MinCost)
+
+	current := func() *user.User {
+		verifiedAt := time.Now()
+		return &user.User{ID: "UserID", Username: "luigi", Email: "luigi@example.com", Name: "luigi", Surname: "verdi", Password: string(hashedPassword), EmailVerifiedAt: &verifiedAt}
+	}
+
+	tests := []struct {
+		name            string
+		update          user.ProfileUpdate
+		currentPassword string
+		setupMock       func(*mocks.MockuserRepository, *mocks.MockemailVerificationTokenRepository, *mocks.MockpasswordResetTokenRepository, *mocks.MockmailSender, *mocks.MockloginAttemptRepository, chan *mailer.Message)
+		expectedError  error
+		expectedName   string
+		expectedEmail  string
+		expectVerified bool
+	}{
+		{
+			name:   "update_name",
+			update: user.ProfileUpdate{Name: &newName},
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				u.EXPECT().
+					UpdateProfile(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, updated *user.User) error {
+						// the fields that are not in the update are kept
+						if updated.Name != newName || updated.Username != "luigi" || updated.Surname != "verdi" {
+							t.Errorf("unexpected profile %+v", updated)
+						}
+						return nil
+					})
+			},
+			expectedName:   newName,
+			expectedEmail:  "luigi@example.com",
+			expectVerified: true,
+		},
+		{
+			name:            "new_email_is_verified_again",
+			update:          user.ProfileUpdate{Email: &newEmail},
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				// the links sent to the previous address are dropped
+				v.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
+				p.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
+				u.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil)
+				v.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(nil)
+				m.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, message *mailer.Message) error {
+						sent <- message
+						return nil
+					})
+			},
+			expectedName:   "luigi",
+			expectedEmail:  newEmail,
+			expectVerified: false,
+		},
+		{
+			name:   "username_taken",
+			update: user.ProfileUpdate{Username: &taken},
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				u.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(user.ErrUsernameTaken)
+			},
+			expectedError: ErrUserAlreadyExists,
+		},
+		{
+			name:            "email_taken",
+			update:          user.ProfileUpdate{Email: &taken},
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				v.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
+				p.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
+				u.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(user.ErrEmailTaken)
+			},
+			expectedError: ErrUserAlreadyExists,
+		},
+		{
+			name:            "verification_links_not_dropped",
+			update:          user.ProfileUpdate{Email: &newEmail},
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				// the email is not changed while the old link is still valid
+				v.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(errRedis)
+			},
+			expectedError: errRedis,
+		},
+		{
+			name:            "reset_links_not_dropped",
+			update:          user.ProfileUpdate{Email: &newEmail},
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				v.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
+				// the email is not changed while the old reset link is still valid
+				p.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(errRedis)
+			},
+			expectedError: errRedis,
+		},
+		{
+			name:   "new_email_without_password",
+			update: user.ProfileUpdate{Email: &newEmail},
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+			},
+			expectedError: ErrPasswordRequired,
+		},
+		{
+			name:            "new_email_with_wrong_password",
+			update:          user.ProfileUpdate{Email: &newEmail},
+			currentPassword: "Wrongpass123",
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				// the failure counts towards the lockout of the login
+				l.EXPECT().RecordFailure(gomock.Any(), "user:UserID", lockoutThreshold, lockoutBase, lockoutMax, lockoutMemory).Return(time.Duration(0), nil)
+			},
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:   "same_email_without_password",
+			update: user.ProfileUpdate{Email: &sameEmail, Name: &newName},
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(current(), nil)
+				u.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedName:   newName,
+			expectedEmail:  "luigi@example.com",
+			expectVerified: true,
+		},
+		{
+			name:   "user_deleted",
+			update: user.ProfileUpdate{Name: &newName},
+			setupMock: func(u *mocks.MockuserRepository, v *mocks.MockemailVerificationTokenRepository, p *mocks.MockpasswordResetTokenRepository, m *mocks.MockmailSender, l *mocks.MockloginAttemptRepository, sent chan *mailer.Message) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(nil, nil)
+			},
+			expectedError: ErrUserNotExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockVerificationRepo := mocks.NewMockemailVerificationTokenRepository(ctrl)
+			mockPasswordResetRepo := mocks.NewMockpasswordResetTokenRepository(ctrl)
+			mockMailer := mocks.NewMockmailSender(ctrl)
+			mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+			sent := make(chan *mailer.Message, 1)
+			tt.setupMock(mockUserRepo, mockVerificationRepo, mockPasswordResetRepo, mockMailer, mockLoginAttemptRepo, sent)
+
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mockPasswordResetRepo, mockVerificationRepo, mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mockMailer, Config{})
+			profile, err := s.UpdateProfile(context.Background(), "UserID", tt.update, tt.currentPassword)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if profile.Name != tt.expectedName || profile.Email != tt.expectedEmail || profile.IsEmailVerified() != tt.expectVerified {
+				t.Errorf("unexpected profile %+v", profile)
+			}
+
+			if !tt.expectVerified {
+				// the email is sent in background
+				select {
+				case message := <-sent:
+					if message.To != newEmail {
+						t.Errorf("expected the verification email to be sent to %s, got %s", newEmail, message.To)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("the verification email was not sent")
+				}
+			}
+		})
+	}
+}
+
+func TestService_CheckPassword(t *testing.T) {
+	// the minimum cost keeps the test fast, the hash is only compared
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), bcrypt.MinCost)
+	account := &user.User{ID: "UserID", Password: string(hashedPassword)}
+
+	tests := []struct {
+		name          string
+		password      string
+		setupMock     func(*mocks.MockuserRepository, *mocks.MockloginAttemptRepository)
+		expectedError error
+	}{
+		{
+			name:     "success",
+			password: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, l *mocks.MockloginAttemptRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+			},
+		},
+		{
+			name:          "missing_password",
+			setupMock:     func(u *mocks.MockuserRepository, l *mocks.MockloginAttemptRepository) {},
+			expectedError: ErrPasswordRequired,
+		},
+		{
+			name:     "wrong_password",
+			password: "Wrongpass123",
+			setupMock: func(u *mocks.MockuserRepository, l *mocks.MockloginAttemptRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				// the failure counts towards the lockout of the login
+				l.EXPECT().RecordFailure(gomock.Any(), "user:UserID", lockoutThreshold, lockoutBase, lockoutMax, lockoutMemory).Return(time.Duration(0), nil)
+			},
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:     "account_locked",
+			password: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, l *mocks.MockloginAttemptRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Minute, nil)
+			},
+			expectedError: ErrAccountLocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+			tt.setupMock(mockUserRepo, mockLoginAttemptRepo)
+
+			s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			err := s.CheckPassword(context.Background(), "UserID", tt.password)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_UpdateProfile_OldVerificationLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the minimum cost keeps the test fast, the hash is only compared
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), bcrypt.MinCost)
+	account := &user.User{ID: "UserID", Email: "luigi@example.com", Name: "luigi", Password: string(hashedPassword)}
+	newEmail := "new@example.com"
+
+	// the verification links of the user, the one sent at the registration is still valid
+	oldLink := "ev1.linksenttotheoldaddress"
+	links := map[string]string{hashToken(oldLink): "UserID"}
+
+	mockUserRepo := mocks.NewMockuserRepository(ctrl)
+	mockUserRepo.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+	mockUserRepo.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil)
+	mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+	mockLoginAttemptRepo.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+	mockVerificationRepo := mocks.NewMockemailVerificationTokenRepository(ctrl)
+	mockVerificationRepo.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").DoAndReturn(
+		func(ctx context.Context, userID string) error {
+			for tokenHash, owner := range links {
+				if owner == userID {
+					delete(links, tokenHash)
+				}
+			}
+			return nil
+		},
+	)
+	mockPasswordResetRepo := mocks.NewMockpasswordResetTokenRepository(ctrl)
+	mockPasswordResetRepo.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
+	// the link for the new address cannot be created, so it does not replace the old one
+	mockVerificationRepo.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(errors.New("redis error"))
+	mockVerificationRepo.EXPECT().ConsumeOneByTokenHash(gomock.Any(), hashToken(oldLink)).DoAndReturn(
+		func(ctx context.Context, tokenHash string) (string, error) {
+			userID, ok := links[tokenHash]
+			if !ok {
+				return "", email_verification_token.ErrTokenHashNotFound
+			}
+			delete(links, tokenHash)
+			return userID, nil
+		},
+	)
+
+	s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mockPasswordResetRepo, mockVerificationRepo, mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+	_, err := s.UpdateProfile(context.Background(), "UserID", user.ProfileUpdate{Email: &newEmail}, "Testtest123")
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+
+	// the link mailed to the previous address cannot mark the new one as verified
+	err = s.VerifyEmail(context.Background(), oldLink)
+	if !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected error %v, got %v", ErrInvalidVerificationToken, err)
+	}
+}
+
+func TestService_UpdateProfile_OldResetLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the minimum cost keeps the test fast, the hash is only compared
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), bcrypt.MinCost)
+	account := &user.User{ID: "UserID", Email: "luigi@example.com", Name: "luigi", Password: string(hashedPassword)}
+	newEmail := "new@example.com"
+
+	// a reset link was sent to the previous address before the change
+	oldLink := "pr1.linksenttotheoldaddress"
+	links := map[string]string{hashToken(oldLink): "UserID"}
+
+	mockUserRepo := mocks.NewMockuserRepository(ctrl)
+	mockUserRepo.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+	mockUserRepo.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Return(nil)
+	mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+	mockLoginAttemptRepo.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+	mockVerificationRepo := mocks.NewMockemailVerificationTokenRepository(ctrl)
+	mockVerificationRepo.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").Return(nil)
+	mockVerificationRepo.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(errors.New("redis error"))
+	mockPasswordResetRepo := mocks.NewMockpasswordResetTokenRepository(ctrl)
+	mockPasswordResetRepo.EXPECT().DeleteOneByUserID(gomock.Any(), "UserID").DoAndReturn(
+		func(ctx context.Context, userID string) error {
+			for tokenHash, owner := range links {
+				if owner == userID {
+					delete(links, tokenHash)
+				}
+			}
+			return nil
+		},
+	)
+	mockPasswordResetRepo.EXPECT().ConsumeOneByTokenHash(gomock.Any(), hashToken(oldLink)).DoAndReturn(
+		func(ctx context.Context, tokenHash string) (string, error) {
+			userID, ok := links[tokenHash]
+			if !ok {
+				return "", password_reset_token.ErrTokenHashNotFound
+			}
+			delete(links, tokenHash)
+			return userID, nil
+		},
+	)
+
+	s := NewAuthService(mockUserRepo, mocks.NewMockrefreshTokenRepository(ctrl), mocks.NewMocktokenDenylistRepository(ctrl), mockPasswordResetRepo, mockVerificationRepo, mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+	_, err := s.UpdateProfile(context.Background(), "UserID", user.ProfileUpdate{Email: &newEmail}, "Testtest123")
+	if err != nil {
+		t.Fatalf("UpdateProfile() error = %v", err)
+	}
+
+	// whoever holds the previous mailbox cannot take over the account
+	err = s.ResetPassword(context.Background(), oldLink, "Newpassword123")
+	if !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("expected error %v, got %v", ErrInvalidResetToken, err)
+	}
+}
+
+func TestService_ChangePassword(t *testing.T) {
+	// the minimum cost keeps the test fast, the hash is only compared
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), bcrypt.MinCost)
+	account := &user.User{ID: "UserID", Password: string(hashedPassword)}
+
+	tests := []struct {
+		name            string
+		sessionID       string
+		currentPassword string
+		setupMock       func(*mocks.MockuserRepository, *mocks.MockrefreshTokenRepository, *mocks.MockloginAttemptRepository)
+		expectedError   error
+	}{
+		{
+			name:            "success_keeps_current_session",
+			sessionID:       "SessionID",
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, l *mocks.MockloginAttemptRepository) {
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				u.EXPECT().
+					UpdatePassword(gomock.Any(), "UserID", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id string, password string) error {
+						if bcrypt.CompareHashAndPassword([]byte(password), []byte("NewPassword123")) != nil {
+							t.Errorf("expected the bcrypt hash of the new password, got %q", password)
+						}
+						return nil
+					})
+				r.EXPECT().DeleteOthersByUserID(gomock.Any(), "UserID", "SessionID").Return(nil)
+			},
+		},
+		{
+			name:            "legacy_token_closes_every_session",
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, l *mocks.MockloginAttemptRepository) {
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				u.EXPECT().UpdatePassword(gomock.Any(), "UserID", gomock.Any()).Return(nil)
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil)
+			},
+		},
+		{
+			name:            "wrong_current_password",
+			sessionID:       "SessionID",
+			currentPassword: "Wrongpass123",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, l *mocks.MockloginAttemptRepository) {
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Duration(0), nil)
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				// the failure counts towards the lockout of the login
+				l.EXPECT().RecordFailure(gomock.Any(), "user:UserID", lockoutThreshold, lockoutBase, lockoutMax, lockoutMemory).Return(time.Duration(0), nil)
+			},
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:            "account_locked",
+			sessionID:       "SessionID",
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, l *mocks.MockloginAttemptRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				l.EXPECT().GetLockout(gomock.Any(), "user:UserID").Return(time.Minute, nil)
+			},
+			expectedError: ErrAccountLocked,
+		},
+		{
+			name:            "user_deleted",
+			sessionID:       "SessionID",
+			currentPassword: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, l *mocks.MockloginAttemptRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(nil, nil)
+			},
+			expectedError: ErrUserNotExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			mockLoginAttemptRepo := mocks.NewMockloginAttemptRepository(ctrl)
+			tt.setupMock(mockUserRepo, mockTokenRepo, mockLoginAttemptRepo)
+
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mocks.NewMocktokenDenylistRepository(ctrl), mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), mockLoginAttemptRepo, mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			err := s.ChangePassword(context.Background(), "UserID", tt.sessionID, tt.currentPassword, "NewPassword123")
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_DeleteAccount(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Testtest123"), bcrypt.MinCost)
+	account := &user.User{ID: "UserID", Password: string(hashedPassword)}
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		password      string
+		jti           string
+		setupMock     func(*mocks.MockuserRepository, *mocks.MockrefreshTokenRepository, *mocks.MocktokenDenylistRepository)
+		expectedError error
+	}{
+		{
+			name:     "success",
+			password: "Testtest123",
+			jti:      "JTI",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				// the sessions are closed before the user is deleted
+				gomock.InOrder(
+					r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil),
+					u.EXPECT().DeleteOneByID(gomock.Any(), "UserID").Return(nil),
+				)
+				d.EXPECT().CreateOne(gomock.Any(), "JTI", expiresAt).Return(nil)
+			},
+		},
+		{
+			name:     "legacy_token_without_jti",
+			password: "Testtest123",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil)
+				u.EXPECT().DeleteOneByID(gomock.Any(), "UserID").Return(nil)
+			},
+		},
+		{
+			name:     "wrong_password",
+			password: "Wrongpass123",
+			jti:      "JTI",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+			},
+			expectedError: ErrInvalidPassword,
+		},
+		{
+			name:     "sessions_error_keeps_the_account",
+			password: "Testtest123",
+			jti:      "JTI",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(errors.New("redis error"))
+			},
+			expectedError: errors.New("redis error"),
+		},
+		{
+			name:     "already_deleted",
+			password: "Testtest123",
+			jti:      "JTI",
+			setupMock: func(u *mocks.MockuserRepository, r *mocks.MockrefreshTokenRepository, d *mocks.MocktokenDenylistRepository) {
+				u.EXPECT().GetOneByID(gomock.Any(), "UserID").Return(account, nil)
+				r.EXPECT().DeleteAllByUserID(gomock.Any(), "UserID").Return(nil)
+				u.EXPECT().DeleteOneByID(gomock.Any(), "UserID").Return(user.ErrUserNotFound)
+			},
+			expectedError: ErrUserNotExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			mockTokenRepo := mocks.NewMockrefreshTokenRepository(ctrl)
+			mockDenylistRepo := mocks.NewMocktokenDenylistRepository(ctrl)
+			tt.setupMock(mockUserRepo, mockTokenRepo, mockDenylistRepo)
+
+			s := NewAuthService(mockUserRepo, mockTokenRepo, mockDenylistRepo, mocks.NewMockpasswordResetTokenRepository(ctrl), mocks.NewMockemailVerificationTokenRepository(ctrl), mocks.NewMockmfaTicketRepository(ctrl), mocks.NewMockmfaVerifier(ctrl), newUnlockedLoginAttemptRepo(ctrl), mocks.NewMocktokenSigner(ctrl), mocks.NewMockmailSender(ctrl), Config{})
+			err := s.DeleteAccount(context.Background(), "UserID", tt.password, tt.jti, expiresAt)
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
-	"os"
+	"time"
 
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 )
@@ -14,17 +15,28 @@ import (
 var PostgresDB *sql.DB
 var RedisDB *redis.Client
 
-func InitRedisDB(ctx context.Context) error {
-	// load the environment to create the redis dsn
-	redisDBHost := os.Getenv("REDIS_HOST")
-	redisDBPort := os.Getenv("REDIS_PORT")
-	dbDsn := "redis://" + redisDBHost + ":" + redisDBPort
+// RedisBlockingDB serves only the long polls of the commands, a blocking read
+// holds its connection for up to a minute and must not starve the other requests
+var RedisBlockingDB *redis.Client
+
+// redisTimeout bounds every Redis command, it is about the timeout of the health checks
+const redisTimeout = 2 * time.Second
+
+func InitRedisDB(ctx context.Context, cfg config.RedisConfig) error {
+	dbDsn := fmt.Sprintf("redis://%s:%d", cfg.Host, cfg.Port)
 	opt, err := redis.ParseURL(dbDsn)
 	if err != nil {
 		slog.Error("failed to parse RedisDB URL", "error", err)
 		return err
 	}
 
+	// the defaults wait seconds and retry three times, a short timeout makes
+	// the requests fail fast when Redis is down instead of hanging
+	opt.DialTimeout = redisTimeout
+	opt.ReadTimeout = redisTimeout
+	opt.WriteTimeout = redisTimeout
+	opt.MaxRetries = 1
+
 	// create the redis client
 	rdb := redis.NewClient(opt)
 
@@ -35,21 +47,21 @@ func InitRedisDB(ctx context.Context) error {
 		return err
 	}
 
-	// set the redis client to the global variable
+	// a pool with a connection for each board that can wait for a command,
+	// the repository of the commands never waits for more than this
+	blockingOpt := *opt
+	blockingOpt.PoolSize = cfg.MaxLongPolls
+
+	// set the redis clients to the global variables
 	RedisDB = rdb
+	RedisBlockingDB = redis.NewClient(&blockingOpt)
 
 	return nil
 }
 
-func InitPosgresDB(ctx context.Context) error {
-	// load the environment to create the postgres dsn
-	postgresDBHost := os.Getenv("POSTGRES_HOST")
-	postgresDBUser := os.Getenv("POSTGRES_USER")
-	postgresDBPass := os.Getenv("POSTGRES_PASSWORD")
-	postgresDBName := os.Getenv("POSTGRES_DB")
-	postgresDBPort := os.Getenv("POSTGRES_PORT")
-	dbDsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-    postgresDBHost, postgresDBPort, postgresDBUser, postgresDBPass, postgresDBName)
+func InitPosgresDB(ctx context.Context, cfg config.PostgresConfig) error {
+	dbDsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+    cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DB)
 
   psqlDB, err := sql.Open("postgres", dbDsn)
   if err != nil {
@@ -0,0 +1,113 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+)
+
+// shutdownStepTimeout is how long each step of the shutdown has, it does not
+// depend on the drain of the requests so the databases are always closed
+const shutdownStepTimeout = 5 * time.Second
+
+// Server is the HTTP server of the backend together with everything
+// that must be stopped after it, such as the workers and the databases
+type Server struct {
+	httpServer *http.Server
+	stops      []stop
+}
+
+type stop struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func NewServer(handler http.Handler, cfg config.ServerConfig) *Server {
+	return &Server{
+		httpServer: &http.Server{
+			Addr:         fmt.Sprintf("0.0.0.0:%d", cfg.Port),
+			Handler:      handler,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			IdleTimeout:  cfg.IdleTimeout,
+		},
+	}
+}
+
+// OnShutdownStart adds a function called as soon as the shutdown starts, before
+// the requests are drained, it ends the waits that would otherwise hold the drain
+// such as the long polls and the live streams, the other requests are left alone
+func (s *Server) OnShutdownStart(fn func()) {
+	s.httpServer.RegisterOnShutdown(fn)
+}
+
+// OnShutdown adds a step to the shutdown, the steps run in the order they are
+// added once the HTTP server has stopped, so they can release what the requests use
+func (s *Server) OnShutdown(name string, fn func(ctx context.Context) error) {
+	s.stops = append(s.stops, stop{name: name, fn: fn})
+}
+
+// ListenAndServe serves the requests until Shutdown is called, then it returns nil
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve is ListenAndServe on a listener that is already open
+func (s *Server) Serve(listener net.Listener) error {
+	slog.Info("listening", "address", listener.Addr().String())
+	err := s.httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting new requests and waits for the in-flight ones, the
+// requests still running when ctx is done are dropped. Then it runs every step
+// with its own deadline, even when one of them fails, and returns all the errors
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		slog.Warn("in-flight requests not completed in time, closing them", "error", err)
+		err = errors.Join(err, s.httpServer.Close())
+	}
+
+	for _, step := range s.stops {
+		stepCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownStepTimeout)
+		stepErr := step.fn(stepCtx)
+		cancel()
+		if stepErr != nil {
+			slog.Error("failed to stop", "step", step.name, "error", stepErr)
+			err = errors.Join(err, fmt.Errorf("%s: %w", step.name, stepErr))
+			continue
+		}
+		slog.Info("stopped", "step", step.name)
+	}
+	return err
+}
+
+// waitGroup waits for the goroutines of wg until ctx is done
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
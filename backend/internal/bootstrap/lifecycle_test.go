@@ -0,0 +1,318 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/bootstrap"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+// newTestServer serves handler on a random port and returns its address
+func newTestServer(t *testing.T, handler http.Handler) (*bootstrap.Server, string, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := bootstrap.NewServer(handler, config.Default().Server)
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	return server, "http://" + listener.Addr().String(), served
+}
+
+// steps records the order in which the shutdown steps run
+type steps struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (s *steps) add(server *bootstrap.Server, name string, err error) {
+	server.OnShutdown(name, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.names = append(s.names, name)
+		return err
+	})
+}
+
+func (s *steps) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.names)
+}
+
+func TestServer_Shutdown_CompletesInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})
+	server, url, served := newTestServer(t, handler)
+	var steps steps
+	steps.add(server, "workers", nil)
+	steps.add(server, "postgres", nil)
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{code: resp.StatusCode, body: string(body), err: err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	// the server waits for the request and the steps wait for the server
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown() returned before the in-flight request completed: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	if got := steps.get(); len(got) != 0 {
+		t.Errorf("expected no step before the request completed, got %v", got)
+	}
+	// no new connection is accepted meanwhile
+	if conn, err := net.DialTimeout("tcp", url[len("http://"):], time.Second); err == nil {
+		conn.Close()
+		t.Errorf("expected the listener to be closed")
+	}
+
+	close(release)
+	got := <-response
+	if got.err != nil || got.code != http.StatusOK || got.body != "done" {
+		t.Errorf("expected the in-flight request to complete, got %+v", got)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+	if got := steps.get(); !slices.Equal(got, []string{"workers", "postgres"}) {
+		t.Errorf("expected the steps in order, got %v", got)
+	}
+}
+
+func TestServer_Shutdown_KeepsTheContextOfInFlightRequests(t *testing.T) {
+	// the handler stops its work when its context is cancelled, as the database calls do
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+			_, _ = io.WriteString(w, "saved")
+		case <-r.Context().Done():
+			http.Error(w, r.Context().Err().Error(), http.StatusInternalServerError)
+		}
+	})
+	server, url, served := newTestServer(t, handler)
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Post(url, "application/json", nil)
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{code: resp.StatusCode, body: string(body), err: err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	// the request is still running with its context alive when it is released
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	got := <-response
+	if got.err != nil || got.code != http.StatusOK || got.body != "saved" {
+		t.Errorf("expected the in-flight request to finish its work, got %+v", got)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
+
+func TestServer_Shutdown_Deadline(t *testing.T) {
+	// a request that does not stop when its context is cancelled
+	started := make(chan struct{})
+	stuck := make(chan struct{})
+	t.Cleanup(func() { close(stuck) })
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-stuck
+	})
+	server, url, _ := newTestServer(t, handler)
+	var steps steps
+	errRedis := errors.New("redis already closed")
+	steps.add(server, "redis", errRedis)
+	steps.add(server, "postgres", nil)
+
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		requestErr <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := server.Shutdown(ctx)
+
+	// the stuck request is dropped and every step runs anyway
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errRedis) {
+		t.Errorf("expected both the deadline and the step error, got %v", err)
+	}
+	if got := steps.get(); !slices.Equal(got, []string{"redis", "postgres"}) {
+		t.Errorf("expected every step to run, got %v", got)
+	}
+	select {
+	case err := <-requestErr:
+		if err == nil {
+			t.Errorf("expected the dropped request to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not dropped")
+	}
+}
+
+func TestServer_Shutdown_EndsLongPolls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the service waits for a command until the request is cancelled
+	waiting := make(chan struct{})
+	mockCommandService := mocks.NewMockcommandService(ctrl)
+	mockCommandService.EXPECT().GetPending(gomock.Any(), "device-1", command.MaxWait).DoAndReturn(
+		func(ctx context.Context, deviceID string, wait time.Duration) ([]*command.Command, error) {
+			close(waiting)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	)
+	commandController := command.NewCommandController(mockCommandService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/devices/me/commands", func(c *gin.Context) {
+		c.Set("deviceID", "device-1")
+	}, commandController.GetCommands)
+	server, url, served := newTestServer(t, router)
+	server.OnShutdownStart(commandController.EndWaits)
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url + "/api/devices/me/commands?wait=60s")
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{code: resp.StatusCode, body: string(body), err: err}
+	}()
+	<-waiting
+
+	// the shutdown does not wait for the minute of the long poll
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the long poll to end at once, the shutdown took %v", elapsed)
+	}
+
+	// the board gets an empty list, as if the wait had expired
+	got := <-response
+	if got.err != nil || got.code != http.StatusOK || got.body != `{"commands":[]}` {
+		t.Errorf("expected an empty list of commands, got %+v", got)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() error = %v", err)
+	}
+}
+
+func TestServer_Shutdown_StepDeadline(t *testing.T) {
+	server, _, _ := newTestServer(t, http.NotFoundHandler())
+	var deadlines []time.Duration
+	for _, name := range []string{"workers", "redis"} {
+		server.OnShutdown(name, func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Errorf("expected a deadline for %s", name)
+			}
+			deadlines = append(deadlines, time.Until(deadline))
+			return ctx.Err()
+		})
+	}
+
+	// the drain used the whole context, the steps still get their own time
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() error = %v", err)
+	}
+	for _, remaining := range deadlines {
+		if remaining < time.Second {
+			t.Errorf("expected each step to have its own deadline, got %v left", remaining)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package bootstrap
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mailer"
+)
+
+// loadMailer returns the SMTP mailer when the SMTP host is set, otherwise the emails
+// are written in the mail directory, or dropped when it is not set either
+func loadMailer(cfg config.MailConfig) mailer.Mailer {
+	if cfg.SMTPHost == "" && cfg.Dir == "" {
+		slog.Warn("SMTP host and mail directory not set, emails are dropped")
+		return mailer.NewFileMailer("", cfg.From)
+	}
+	if cfg.SMTPHost == "" {
+		slog.Info("SMTP host not set, emails are not sent", "dir", cfg.Dir)
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	}
+
+	return mailer.NewSMTPMailer(mailer.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     strconv.Itoa(cfg.SMTPPort),
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.From,
+	})
+}
@@ -0,0 +1,80 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/migrations"
+)
+
+var ErrUnknownMigrateCommand = errors.New("unknown migrate command, expected up, down or status")
+
+// MigratePostgresDB applies the pending migrations to PostgresDB
+func MigratePostgresDB(ctx context.Context) error {
+	migrator, err := migrations.NewMigrator(PostgresDB)
+	if err != nil {
+		slog.Error("failed to load the migrations", "error", err)
+		return err
+	}
+	_, err = migrator.Up(ctx)
+	if err != nil {
+		slog.Error("failed to migrate PostgresDB", "error", err)
+		return err
+	}
+	return nil
+}
+
+// RunMigrate runs the migrate subcommand, up applies the pending migrations,
+// down reverts the last applied one and status lists them all
+func RunMigrate(ctx context.Context, cfg *config.Config, command string, out io.Writer) error {
+	if command != "up" && command != "down" && command != "status" {
+		return ErrUnknownMigrateCommand
+	}
+	if err := InitPosgresDB(ctx, cfg.Postgres); err != nil {
+		return err
+	}
+	defer PostgresDB.Close()
+
+	migrator, err := migrations.NewMigrator(PostgresDB)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = "applied at " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package bootstrap
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mqtt"
+)
+
+var MQTTBridge *mqtt.Bridge
+
+// loadMQTTConfig returns the broker settings, MQTT is enabled only when
+// the broker URL is set, otherwise the boards use the HTTP endpoints only
+func loadMQTTConfig(cfg config.MQTTConfig) (mqtt.Config, bool) {
+	if cfg.BrokerURL == "" {
+		return mqtt.Config{}, false
+	}
+
+	return mqtt.Config{
+		BrokerURL: cfg.BrokerURL,
+		ClientID:  cfg.ClientID,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+	}, true
+}
+
+func InitMQTTBridge(ctx context.Context, bridge *mqtt.Bridge) error {
+	err := bridge.Connect(ctx)
+	if err != nil {
+		slog.Error("failed to connect to the MQTT broker", "error", err)
+		return err
+	}
+
+	// set the bridge to the global variable
+	MQTTBridge = bridge
+
+	return nil
+}
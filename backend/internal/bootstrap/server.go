@@ -2,34 +2,169 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/auth"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/control"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/email_verification_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/health"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/live"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa_ticket"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/migrations"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mqtt"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/password_reset_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/rate_limit"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/routes"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/token_denylist"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
-	"github.com/gin-gonic/gin"
 )
 
-func InitializeServer(ctx context.Context) (*gin.Engine, error) {
+// InitializeServer connects the databases, starts the workers and returns the server,
+// the shutdown of the server stops them all in the reverse order
+func InitializeServer(ctx context.Context, cfg *config.Config) (*Server, error) {
     // Initialize DB connections
-    if err := InitPosgresDB(ctx); err != nil {
+    if err := InitPosgresDB(ctx, cfg.Postgres); err != nil {
         return nil, err
     }
-    if err := InitRedisDB(ctx); err != nil {
+    // the schema is updated before anything uses it, the lock of the
+    // migrations makes it safe to start more instances at the same time
+    if err := MigratePostgresDB(ctx); err != nil {
+        return nil, err
+    }
+    if err := InitRedisDB(ctx, cfg.Redis); err != nil {
         return nil, err
     }
 
     // Repositories
     userRepo := user.NewUserRepository(PostgresDB)
     refreshTokenRepo := refresh_token.NewRefreshTokenRepository(RedisDB)
+    tokenDenylistRepo := token_denylist.NewTokenDenylistRepository(RedisDB)
+    passwordResetTokenRepo := password_reset_token.NewPasswordResetTokenRepository(RedisDB)
+    emailVerificationTokenRepo := email_verification_token.NewEmailVerificationTokenRepository(RedisDB)
+    mfaRepo := mfa.NewMFARepository(PostgresDB)
+    mfaTicketRepo := mfa_ticket.NewMFATicketRepository(RedisDB)
+    rateLimitRepo := rate_limit.NewRateLimitRepository(RedisDB)
+    signingKeyRepo := signing_key.NewSigningKeyRepository(PostgresDB)
+    deviceRepo := device.NewDeviceRepository(PostgresDB)
+    householdRepo := household.NewHouseholdRepository(PostgresDB)
+    pairingCodeRepo := pairing_code.NewPairingCodeRepository(RedisDB)
+    targetRepo := target.NewTargetRepository(PostgresDB)
+    telemetryRepo := telemetry.NewTelemetryRepository(PostgresDB)
+    commandRepo := command.NewCommandRepository(RedisDB, RedisBlockingDB)
+    liveRepo := live.NewLiveRepository(RedisDB)
+
+    // Metrics, a device is online when it has uploaded a reading in the last minutes
+    metrics.RegisterPostgres(PostgresDB)
+    RedisDB.AddHook(metrics.NewRedisHook())
+    RedisBlockingDB.AddHook(metrics.NewRedisHook())
+    metrics.RegisterOnlineDevices(telemetryRepo, 5*time.Minute)
 
     // Services
-    authService := auth.NewAuthService(userRepo, refreshTokenRepo)
+    signingKeyService := signing_key.NewSigningKeyService(signingKeyRepo, loadSigningKeyConfig(cfg.Auth))
+    // the first key is created here, before any token is signed
+    if err := signingKeyService.Refresh(ctx); err != nil {
+        slog.Error("failed to load the signing keys", "error", err)
+        return nil, err
+    }
+    mfaService := mfa.NewMFAService(mfaRepo, userRepo, cfg.App.Name)
+    authService := auth.NewAuthService(userRepo, refreshTokenRepo, tokenDenylistRepo, passwordResetTokenRepo, emailVerificationTokenRepo, mfaTicketRepo, mfaService, rateLimitRepo, signingKeyService, loadMailer(cfg.Mail), auth.Config{
+        Issuer:                cfg.App.Name,
+        PasswordResetURL:      cfg.Auth.PasswordResetURL,
+        EmailVerificationURL:  cfg.Auth.EmailVerificationURL,
+        UnverifiedEmailPolicy: cfg.Auth.UnverifiedEmailPolicy,
+    })
+    deviceService := device.NewDeviceService(deviceRepo, pairingCodeRepo, householdRepo, signingKeyService, cfg.App.Name)
+    householdService := household.NewHouseholdService(householdRepo, userRepo)
+    liveService := live.NewLiveService(liveRepo, deviceService, tokenDenylistRepo)
+    targetService := target.NewTargetService(targetRepo, deviceService, liveService)
+    controlService := control.NewControlService(targetRepo, control.DefaultConfig())
+    telemetryService := telemetry.NewTelemetryService(telemetryRepo, deviceService, controlService, liveService)
+
+    migrator, err := migrations.NewMigrator(PostgresDB)
+    if err != nil {
+        slog.Error("failed to load the migrations", "error", err)
+        return nil, err
+    }
+    healthService := health.NewHealthService(PostgresDB, RedisDB, migrator, health.DefaultConfig())
+
+    // MQTT is optional, it is an alternative to the HTTP endpoints of the boards,
+    // the publisher stays a nil interface when it is disabled
+    var commandPublisher interface{ PublishCommand(*command.Command) }
+    if mqttConfig, ok := loadMQTTConfig(cfg.MQTT); ok {
+        if err := InitMQTTBridge(ctx, mqtt.NewBridge(mqttConfig, telemetryService)); err != nil {
+            return nil, err
+        }
+        commandPublisher = MQTTBridge
+    } else {
+        slog.Info("MQTT broker URL not set, MQTT disabled")
+    }
+    commandService := command.NewCommandService(commandRepo, deviceService, commandPublisher)
+
+    // Workers, they get their own context so that they keep running
+    // while the in-flight requests are drained during the shutdown
+    workersCtx, stopWorkers := context.WithCancel(context.WithoutCancel(ctx))
+    var workers sync.WaitGroup
+    workers.Go(func() { signingKeyService.Run(workersCtx) })
+    workers.Go(func() { controlService.Run(workersCtx) })
+    workers.Go(func() { healthService.Run(workersCtx) })
 
     // Controllers
     authController := auth.NewAuthController(authService)
+    mfaController := mfa.NewMFAController(mfaService, authService)
+    signingKeyController := signing_key.NewSigningKeyController(signingKeyService)
+    deviceController := device.NewDeviceController(deviceService)
+    householdController := household.NewHouseholdController(householdService)
+    targetController := target.NewTargetController(targetService)
+    telemetryController := telemetry.NewTelemetryController(telemetryService)
+    commandController := command.NewCommandController(commandService)
+    liveController := live.NewLiveController(liveService)
+    healthController := health.NewHealthController(healthService)
 
     // Routes
-    engine := routes.SetupRoutes(authController)
-    return engine, nil
+    engine := routes.SetupRoutes(authController, mfaController, signingKeyController, deviceController, householdController, targetController, telemetryController, commandController, liveController, healthController, signingKeyService, tokenDenylistRepo, rateLimitRepo, healthService, cfg.Server.MetricsToken)
+    // only the X-Forwarded-For of these proxies gives the IP of the clients
+    if err := engine.SetTrustedProxies(config.SplitList(cfg.Server.TrustedProxies)); err != nil {
+        slog.Error("failed to set the trusted proxies", "error", err)
+        stopWorkers()
+        return nil, err
+    }
+
+    // Shutdown, once the HTTP server has stopped the producers are stopped
+    // first and the databases last, since everything else uses them
+    server := NewServer(engine, cfg.Server)
+    // the long polls and the live streams would hold the drain until they expire
+    server.OnShutdownStart(commandController.EndWaits)
+    server.OnShutdownStart(liveController.Close)
+    server.OnShutdown("live streams", liveController.Shutdown)
+    if MQTTBridge != nil {
+        server.OnShutdown("mqtt bridge", func(ctx context.Context) error {
+            MQTTBridge.Close()
+            return nil
+        })
+    }
+    server.OnShutdown("workers", func(ctx context.Context) error {
+        stopWorkers()
+        return waitGroup(ctx, &workers)
+    })
+    server.OnShutdown("redis", func(ctx context.Context) error {
+        return errors.Join(RedisBlockingDB.Close(), RedisDB.Close())
+    })
+    server.OnShutdown("postgres", func(ctx context.Context) error {
+        return PostgresDB.Close()
+    })
+    return server, nil
 }
@@ -0,0 +1,17 @@
+package bootstrap
+
+import (
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+)
+
+// loadSigningKeyConfig returns how often the signing key is replaced,
+// the period has already been checked against the activation delay by the config
+func loadSigningKeyConfig(cfg config.AuthConfig) signing_key.Config {
+	keyConfig := signing_key.DefaultConfig()
+	// a replaced key must verify both the user and the device tokens until they expire
+	keyConfig.TokenTTL = max(keyConfig.TokenTTL, device.TokenTTL)
+	keyConfig.RotationPeriod = cfg.KeyRotationPeriod
+	return keyConfig
+}
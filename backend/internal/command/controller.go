@@ -0,0 +1,226 @@
+package command
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/gin-gonic/gin"
+)
+
+type commandService interface {
+	SendCommand(ctx context.Context, userID string, deviceID string, commandType string, payload json.RawMessage) (*Command, error)
+	GetPending(ctx context.Context, deviceID string, wait time.Duration) ([]*Command, error)
+	Ack(ctx context.Context, deviceID string, ids []string) (int, error)
+}
+
+// errWaitsEnded is the cause of the long polls cancelled by EndWaits
+var errWaitsEnded = errors.New("long polls ended by the shutdown")
+
+type Controller struct {
+	service commandService
+	// closing is closed by EndWaits to end the long polls
+	closing  chan struct{}
+	endWaits sync.Once
+}
+
+func NewCommandController(service commandService) *Controller {
+	return &Controller{
+		service: service,
+		closing: make(chan struct{}),
+	}
+}
+
+// EndWaits ends the long polls in progress and the ones that start afterwards,
+// the boards get an empty list and poll again once the server is back
+func (cc *Controller) EndWaits() {
+	cc.endWaits.Do(func() { close(cc.closing) })
+}
+
+type deviceURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type sendCommandRequest struct {
+	Type    string          `json:"type" binding:"required,oneof=set_duty set_target reboot reconfigure"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type commandsQuery struct {
+	// Wait is a Go duration (e.g. "30s"), empty means return at once
+	Wait string `form:"wait"`
+}
+
+type ackCommandsRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100,dive,required"`
+}
+
+type commandResponse struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type commandsResponse struct {
+	Commands []commandResponse `json:"commands"`
+}
+
+type ackCommandsResponse struct {
+	Acked int `json:"acked"`
+}
+
+func (cc *Controller) SendCommand(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request sendCommandRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid send command request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	command, err := cc.service.SendCommand(ctx, userID, uri.ID, request.Type, request.Payload)
+	if err != nil {
+		cc.handleCommandError(c, err, "failed to send command")
+		return
+	}
+
+	slog.Info("command queued successfully", "userID", userID, "deviceID", uri.ID, "commandID", command.ID, "type", command.Type)
+	// the command is executed only when the device picks it up
+	c.JSON(http.StatusAccepted, toResponse(command))
+}
+
+func (cc *Controller) GetCommands(c *gin.Context) {
+	var query commandsQuery
+	err := c.ShouldBindQuery(&query)
+	if err != nil {
+		slog.Warn("invalid commands query", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var wait time.Duration
+	if query.Wait != "" {
+		wait, err = time.ParseDuration(query.Wait)
+		if err != nil || wait < 0 {
+			slog.Warn("invalid commands wait", "wait", query.Wait)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wait must be a positive duration"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if wait > 0 {
+		// only the wait is cut short by the shutdown, not the rest of the request
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		go func() {
+			select {
+			case <-cc.closing:
+				cancel(errWaitsEnded)
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	deviceID := c.GetString("deviceID")
+	commands, err := cc.service.GetPending(ctx, deviceID, wait)
+	// the server is shutting down, the wait ends as if it had expired
+	// and the board polls again once the server is back
+	if err != nil && errors.Is(context.Cause(ctx), errWaitsEnded) {
+		commands, err = []*Command{}, nil
+	}
+	if err != nil {
+		cc.handleCommandError(c, err, "failed to get commands")
+		return
+	}
+
+	response := commandsResponse{Commands: make([]commandResponse, 0, len(commands))}
+	for _, command := range commands {
+		response.Commands = append(response.Commands, toResponse(command))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (cc *Controller) AckCommands(c *gin.Context) {
+	var request ackCommandsRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid ack commands request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	deviceID := c.GetString("deviceID")
+	acked, err := cc.service.Ack(ctx, deviceID, request.IDs)
+	if err != nil {
+		cc.handleCommandError(c, err, "failed to ack commands")
+		return
+	}
+
+	c.JSON(http.StatusOK, ackCommandsResponse{Acked: acked})
+}
+
+func (cc *Controller) handleCommandError(c *gin.Context, err error, message string) {
+	// for a board this means that the device has been deleted while its token was
+	// still valid, for a user that the device does not exist or is not its own
+	if errors.Is(err, device.ErrDeviceNotFound) || errors.Is(err, device.ErrNotDeviceOwner) {
+		if c.GetString("deviceID") != "" {
+			slog.Warn("commands requested by unknown device", "error", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown device"})
+			return
+		}
+		slog.Warn("device not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+		return
+	}
+	if errors.Is(err, device.ErrPermissionDenied) {
+		slog.Warn("device action forbidden", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrInvalidCommand) || errors.Is(err, ErrInvalidCommandID) {
+		slog.Warn("invalid command", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
+
+func toResponse(command *Command) commandResponse {
+	return commandResponse{
+		ID:        command.ID,
+		Type:      command.Type,
+		Payload:   command.Payload,
+		CreatedAt: command.CreatedAt,
+	}
+}
@@ -0,0 +1,242 @@
+package command_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+const testDeviceID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+/**
+ * newTestContext creates a gin.Context for a request to path,
+ * authenticated as the user or as the device depending on the key.
+ */
+func newTestContext(method string, path string, body []byte, key string, value string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Set(key, value)
+	return c, w
+}
+
+func TestController_SendCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		deviceID     string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockcommandService)
+	}{
+		{
+			name:         "success",
+			deviceID:     testDeviceID,
+			body:         `{"type":"set_duty","payload":{"duty_cycle":30}}`,
+			expectedCode: http.StatusAccepted,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().
+					SendCommand(gomock.Any(), "user-1", testDeviceID, command.TypeSetDuty, gomock.Any()).
+					Return(&command.Command{ID: "1-0", Type: command.TypeSetDuty, Payload: []byte(`{"duty_cycle":30}`)}, nil)
+			},
+		},
+		{
+			name:         "unknown_type",
+			deviceID:     testDeviceID,
+			body:         `{"type":"self_destruct"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockcommandService) {},
+		},
+		{
+			name:         "invalid_payload",
+			deviceID:     testDeviceID,
+			body:         `{"type":"set_duty","payload":{"duty_cycle":300}}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().SendCommand(gomock.Any(), "user-1", testDeviceID, command.TypeSetDuty, gomock.Any()).Return(nil, command.ErrInvalidCommand)
+			},
+		},
+		{
+			name:         "invalid_device_id",
+			deviceID:     "42",
+			body:         `{"type":"reboot"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockcommandService) {},
+		},
+		{
+			name:         "not_owner",
+			deviceID:     testDeviceID,
+			body:         `{"type":"reboot"}`,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().SendCommand(gomock.Any(), "user-1", testDeviceID, command.TypeReboot, gomock.Any()).Return(nil, device.ErrNotDeviceOwner)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCommandService := mocks.NewMockcommandService(ctrl)
+			tt.setupMock(mockCommandService)
+
+			cc := command.NewCommandController(mockCommandService)
+			c, w := newTestContext(http.MethodPost, "/devices/"+tt.deviceID+"/commands", []byte(tt.body), "userID", "user-1")
+			c.Params = gin.Params{{Key: "id", Value: tt.deviceID}}
+
+			cc.SendCommand(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_GetCommands(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		setupMock    func(*mocks.MockcommandService)
+	}{
+		{
+			name:         "long_poll",
+			query:        "?wait=30s",
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().GetPending(gomock.Any(), testDeviceID, 30*time.Second).Return([]*command.Command{{ID: "1-0", Type: command.TypeReboot}}, nil)
+			},
+		},
+		{
+			name:         "no_wait",
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().GetPending(gomock.Any(), testDeviceID, time.Duration(0)).Return([]*command.Command{}, nil)
+			},
+		},
+		{
+			name:         "invalid_wait",
+			query:        "?wait=soon",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockcommandService) {},
+		},
+		{
+			name:         "deleted_device",
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().GetPending(gomock.Any(), testDeviceID, time.Duration(0)).Return(nil, device.ErrDeviceNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCommandService := mocks.NewMockcommandService(ctrl)
+			tt.setupMock(mockCommandService)
+
+			cc := command.NewCommandController(mockCommandService)
+			c, w := newTestContext(http.MethodGet, "/devices/me/commands"+tt.query, nil, "deviceID", testDeviceID)
+
+			cc.GetCommands(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_GetCommands_EndWaits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the service waits for a command until the wait is cancelled
+	mockCommandService := mocks.NewMockcommandService(ctrl)
+	mockCommandService.EXPECT().GetPending(gomock.Any(), testDeviceID, 30*time.Second).DoAndReturn(
+		func(ctx context.Context, deviceID string, wait time.Duration) ([]*command.Command, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	)
+
+	cc := command.NewCommandController(mockCommandService)
+	cc.EndWaits()
+	c, w := newTestContext(http.MethodGet, "/devices/me/commands?wait=30s", nil, "deviceID", testDeviceID)
+
+	cc.GetCommands(c)
+
+	// the board gets an empty list, as if the wait had expired
+	if w.Code != http.StatusOK || w.Body.String() != `{"commands":[]}` {
+		t.Fatalf("expected an empty list of commands, got %d; body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestController_AckCommands(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockcommandService)
+	}{
+		{
+			name:         "success",
+			body:         `{"ids":["1-0","2-0"]}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().Ack(gomock.Any(), testDeviceID, []string{"1-0", "2-0"}).Return(2, nil)
+			},
+		},
+		{
+			name:         "empty_ids",
+			body:         `{"ids":[]}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockcommandService) {},
+		},
+		{
+			name:         "invalid_id",
+			body:         `{"ids":["nope"]}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockcommandService) {
+				m.EXPECT().Ack(gomock.Any(), testDeviceID, []string{"nope"}).Return(0, command.ErrInvalidCommandID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCommandService := mocks.NewMockcommandService(ctrl)
+			tt.setupMock(mockCommandService)
+
+			cc := command.NewCommandController(mockCommandService)
+			c, w := newTestContext(http.MethodPost, "/devices/me/commands/ack", []byte(tt.body), "deviceID", testDeviceID)
+
+			cc.AckCommands(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
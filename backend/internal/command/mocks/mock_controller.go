@@ -0,0 +1,89 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	json "encoding/json"
+	reflect "reflect"
+	time "time"
+
+	command "github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockcommandService is a mock of commandService interface.
+type MockcommandService struct {
+	ctrl     *gomock.Controller
+	recorder *MockcommandServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockcommandServiceMockRecorder is the mock recorder for MockcommandService.
+type MockcommandServiceMockRecorder struct {
+	mock *MockcommandService
+}
+
+// NewMockcommandService creates a new mock instance.
+func NewMockcommandService(ctrl *gomock.Controller) *MockcommandService {
+	mock := &MockcommandService{ctrl: ctrl}
+	mock.recorder = &MockcommandServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcommandService) EXPECT() *MockcommandServiceMockRecorder {
+	return m.recorder
+}
+
+// Ack mocks base method.
+func (m *MockcommandService) Ack(ctx context.Context, deviceID string, ids []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ack", ctx, deviceID, ids)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ack indicates an expected call of Ack.
+func (mr *MockcommandServiceMockRecorder) Ack(ctx, deviceID, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ack", reflect.TypeOf((*MockcommandService)(nil).Ack), ctx, deviceID, ids)
+}
+
+// GetPending mocks base method.
+func (m *MockcommandService) GetPending(ctx context.Context, deviceID string, wait time.Duration) ([]*command.Command, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPending", ctx, deviceID, wait)
+	ret0, _ := ret[0].([]*command.Command)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPending indicates an expected call of GetPending.
+func (mr *MockcommandServiceMockRecorder) GetPending(ctx, deviceID, wait any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPending", reflect.TypeOf((*MockcommandService)(nil).GetPending), ctx, deviceID, wait)
+}
+
+// SendCommand mocks base method.
+func (m *MockcommandService) SendCommand(ctx context.Context, userID, deviceID, commandType string, payload json.RawMessage) (*command.Command, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendCommand", ctx, userID, deviceID, commandType, payload)
+	ret0, _ := ret[0].(*command.Command)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendCommand indicates an expected call of SendCommand.
+func (mr *MockcommandServiceMockRecorder) SendCommand(ctx, userID, deviceID, commandType, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendCommand", reflect.TypeOf((*MockcommandService)(nil).SendCommand), ctx, userID, deviceID, commandType, payload)
+}
@@ -0,0 +1,178 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	command "github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	device "github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockcommandRepository is a mock of commandRepository interface.
+type MockcommandRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockcommandRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockcommandRepositoryMockRecorder is the mock recorder for MockcommandRepository.
+type MockcommandRepositoryMockRecorder struct {
+	mock *MockcommandRepository
+}
+
+// NewMockcommandRepository creates a new mock instance.
+func NewMockcommandRepository(ctrl *gomock.Controller) *MockcommandRepository {
+	mock := &MockcommandRepository{ctrl: ctrl}
+	mock.recorder = &MockcommandRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcommandRepository) EXPECT() *MockcommandRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateOne mocks base method.
+func (m *MockcommandRepository) CreateOne(ctx context.Context, arg1 *command.Command) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockcommandRepositoryMockRecorder) CreateOne(ctx, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockcommandRepository)(nil).CreateOne), ctx, arg1)
+}
+
+// DeleteMany mocks base method.
+func (m *MockcommandRepository) DeleteMany(ctx context.Context, deviceID string, ids []string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMany", ctx, deviceID, ids)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteMany indicates an expected call of DeleteMany.
+func (mr *MockcommandRepositoryMockRecorder) DeleteMany(ctx, deviceID, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMany", reflect.TypeOf((*MockcommandRepository)(nil).DeleteMany), ctx, deviceID, ids)
+}
+
+// GetAllByDeviceID mocks base method.
+func (m *MockcommandRepository) GetAllByDeviceID(ctx context.Context, deviceID string, wait time.Duration) ([]*command.Command, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllByDeviceID", ctx, deviceID, wait)
+	ret0, _ := ret[0].([]*command.Command)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllByDeviceID indicates an expected call of GetAllByDeviceID.
+func (mr *MockcommandRepositoryMockRecorder) GetAllByDeviceID(ctx, deviceID, wait any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByDeviceID", reflect.TypeOf((*MockcommandRepository)(nil).GetAllByDeviceID), ctx, deviceID, wait)
+}
+
+// MockdeviceService is a mock of deviceService interface.
+type MockdeviceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeviceServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockdeviceServiceMockRecorder is the mock recorder for MockdeviceService.
+type MockdeviceServiceMockRecorder struct {
+	mock *MockdeviceService
+}
+
+// NewMockdeviceService creates a new mock instance.
+func NewMockdeviceService(ctrl *gomock.Controller) *MockdeviceService {
+	mock := &MockdeviceService{ctrl: ctrl}
+	mock.recorder = &MockdeviceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeviceService) EXPECT() *MockdeviceServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthorizeDevice mocks base method.
+func (m *MockdeviceService) AuthorizeDevice(ctx context.Context, userID, deviceID string, permission device.Permission) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeDevice", ctx, userID, deviceID, permission)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeDevice indicates an expected call of AuthorizeDevice.
+func (mr *MockdeviceServiceMockRecorder) AuthorizeDevice(ctx, userID, deviceID, permission any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeDevice", reflect.TypeOf((*MockdeviceService)(nil).AuthorizeDevice), ctx, userID, deviceID, permission)
+}
+
+// GetDeviceByID mocks base method.
+func (m *MockdeviceService) GetDeviceByID(ctx context.Context, deviceID string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceByID", ctx, deviceID)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceByID indicates an expected call of GetDeviceByID.
+func (mr *MockdeviceServiceMockRecorder) GetDeviceByID(ctx, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceByID", reflect.TypeOf((*MockdeviceService)(nil).GetDeviceByID), ctx, deviceID)
+}
+
+// MockcommandPublisher is a mock of commandPublisher interface.
+type MockcommandPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *MockcommandPublisherMockRecorder
+	isgomock struct{}
+}
+
+// MockcommandPublisherMockRecorder is the mock recorder for MockcommandPublisher.
+type MockcommandPublisherMockRecorder struct {
+	mock *MockcommandPublisher
+}
+
+// NewMockcommandPublisher creates a new mock instance.
+func NewMockcommandPublisher(ctrl *gomock.Controller) *MockcommandPublisher {
+	mock := &MockcommandPublisher{ctrl: ctrl}
+	mock.recorder = &MockcommandPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcommandPublisher) EXPECT() *MockcommandPublisherMockRecorder {
+	return m.recorder
+}
+
+// PublishCommand mocks base method.
+func (m *MockcommandPublisher) PublishCommand(arg0 *command.Command) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PublishCommand", arg0)
+}
+
+// PublishCommand indicates an expected call of PublishCommand.
+func (mr *MockcommandPublisherMockRecorder) PublishCommand(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishCommand", reflect.TypeOf((*MockcommandPublisher)(nil).PublishCommand), arg0)
+}
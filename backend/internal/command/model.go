@@ -0,0 +1,24 @@
+package command
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	TypeSetDuty     = "set_duty"
+	TypeSetTarget   = "set_target"
+	TypeReboot      = "reboot"
+	TypeReconfigure = "reconfigure"
+)
+
+// Command is an instruction for a device, the payload depends on the type:
+// set_duty {"duty_cycle": 0-100}, set_target {"brightness": 0-100},
+// reconfigure {any settings of the firmware} and reboot has none
+type Command struct {
+	ID        string
+	DeviceID  string
+	Type      string
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
@@ -0,0 +1,158 @@
+package command
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxQueueLength bounds the queue of a device that stays offline for a long time,
+// the oldest commands are dropped first
+const maxQueueLength = 100
+
+type repository struct {
+	db *redis.Client
+	// blockingDB serves the blocking reads, waits has a slot for each connection
+	// of its pool so that a long poll never waits for a free connection
+	blockingDB *redis.Client
+	waits      chan struct{}
+}
+
+func NewCommandRepository(db *redis.Client, blockingDB *redis.Client) *repository {
+	return &repository{
+		db:         db,
+		blockingDB: blockingDB,
+		waits:      make(chan struct{}, blockingDB.Options().PoolSize),
+	}
+}
+
+// the commands of a device are stored in a stream, cmd:{deviceID}, the id of
+// each entry is the id of the command and an entry is deleted only on ack
+
+func (r *repository) CreateOne(ctx context.Context, command *Command) error {
+	if command.CreatedAt.IsZero() {
+		command.CreatedAt = time.Now()
+	}
+
+	id, err := r.db.XAdd(ctx, &redis.XAddArgs{
+		Stream: "cmd:" + command.DeviceID,
+		MaxLen: maxQueueLength,
+		Approx: true,
+		Values: map[string]any{
+			"type":       command.Type,
+			"payload":    string(command.Payload),
+			"created_at": command.CreatedAt.UnixMilli(),
+		},
+	}).Result()
+	if err != nil {
+		return err
+	}
+	command.ID = id
+	return nil
+}
+
+// GetAllByDeviceID returns the pending commands of a device, oldest first,
+// when there are none it waits up to wait for a new one
+func (r *repository) GetAllByDeviceID(ctx context.Context, deviceID string, wait time.Duration) ([]*Command, error) {
+	if wait > 0 {
+		// when every slot is taken the board waits here without a connection,
+		// then it gets what is pending at the end of the wait
+		deadline := time.Now().Add(wait)
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case r.waits <- struct{}{}:
+			// the block is counted in milliseconds and 0 would wait forever
+			if remaining := time.Until(deadline).Round(time.Millisecond); remaining > 0 {
+				return r.readBlocking(ctx, deviceID, remaining)
+			}
+			<-r.waits
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return r.read(ctx, r.db, deviceID, -1)
+}
+
+// readBlocking waits for a command holding a slot of waits. go-redis does not
+// interrupt a read when ctx is cancelled, so the read is left to end in
+// background, it releases the slot and keeps the connection until then
+func (r *repository) readBlocking(ctx context.Context, deviceID string, block time.Duration) ([]*Command, error) {
+	type result struct {
+		commands []*Command
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() { <-r.waits }()
+		commands, err := r.read(context.WithoutCancel(ctx), r.blockingDB, deviceID, block)
+		done <- result{commands: commands, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.commands, result.err
+	case <-ctx.Done():
+		// what the read returns stays in the stream until it is acknowledged
+		return nil, ctx.Err()
+	}
+}
+
+// read returns the commands of the stream of the device, a negative block does not wait
+func (r *repository) read(ctx context.Context, client *redis.Client, deviceID string, block time.Duration) ([]*Command, error) {
+	// reading from id 0 returns at once what is already in the stream and
+	// blocks only if it is empty, so a command added between two polls is never lost
+	streams, err := client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{"cmd:" + deviceID, "0"},
+		Count:   maxQueueLength,
+		Block:   block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return []*Command{}, nil
+		}
+		return nil, err
+	}
+
+	commands := make([]*Command, 0)
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			commands = append(commands, toModel(deviceID, message))
+		}
+	}
+	return commands, nil
+}
+
+// DeleteMany removes the acknowledged commands and returns how many were still pending
+func (r *repository) DeleteMany(ctx context.Context, deviceID string, ids []string) (int, error) {
+	deleted, err := r.db.XDel(ctx, "cmd:"+deviceID, ids...).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+func toModel(deviceID string, message redis.XMessage) *Command {
+	command := &Command{
+		ID:       message.ID,
+		DeviceID: deviceID,
+	}
+	if value, ok := message.Values["type"].(string); ok {
+		command.Type = value
+	}
+	if value, ok := message.Values["payload"].(string); ok && value != "" {
+		command.Payload = []byte(value)
+	}
+	if value, ok := message.Values["created_at"].(string); ok {
+		if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+			command.CreatedAt = time.UnixMilli(millis)
+		}
+	}
+	return command
+}
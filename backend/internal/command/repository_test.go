@@ -0,0 +1,157 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/redis/go-redis/v9"
+)
+
+var testRedisDB *redis.Client
+
+func TestMain(m *testing.M) {
+	// the unit tests of this package do not need a container,
+	// so with -short we avoid starting it at all
+	flag.Parse()
+	if !testing.Short() {
+		redisConnectionStr := testutils.SetupRedis()
+		opt, _ := redis.ParseURL(redisConnectionStr)
+		testRedisDB = redis.NewClient(opt)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_CreateOneAndAck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewCommandRepository(testRedisDB, testRedisDB)
+
+	first := &Command{DeviceID: "device-queue", Type: TypeSetDuty, Payload: []byte(`{"duty_cycle":30}`)}
+	second := &Command{DeviceID: "device-queue", Type: TypeReboot}
+	for _, command := range []*Command{first, second} {
+		if err := repo.CreateOne(ctx, command); err != nil {
+			t.Fatalf("CreateOne() error = %v", err)
+		}
+	}
+
+	commands, err := repo.GetAllByDeviceID(ctx, "device-queue", 0)
+	if err != nil {
+		t.Fatalf("GetAllByDeviceID() error = %v", err)
+	}
+	if len(commands) != 2 || commands[0].ID != first.ID || commands[1].ID != second.ID {
+		t.Fatalf("unexpected commands %+v", commands)
+	}
+	if commands[0].Type != TypeSetDuty || string(commands[0].Payload) != `{"duty_cycle":30}` || commands[1].Payload != nil {
+		t.Errorf("unexpected values %+v %+v", commands[0], commands[1])
+	}
+
+	// until acknowledged, a command is delivered again
+	deleted, err := repo.DeleteMany(ctx, "device-queue", []string{first.ID, first.ID, "0-1"})
+	if err != nil {
+		t.Fatalf("DeleteMany() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted, got %d", deleted)
+	}
+	commands, err = repo.GetAllByDeviceID(ctx, "device-queue", 0)
+	if err != nil {
+		t.Fatalf("GetAllByDeviceID() error = %v", err)
+	}
+	if len(commands) != 1 || commands[0].ID != second.ID {
+		t.Errorf("expected only the second command, got %+v", commands)
+	}
+}
+
+func TestRepository_GetAllByDeviceID_LongPoll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewCommandRepository(testRedisDB, testRedisDB)
+
+	// an empty queue blocks for the whole wait
+	start := time.Now()
+	commands, err := repo.GetAllByDeviceID(ctx, "device-empty", 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("GetAllByDeviceID() error = %v", err)
+	}
+	if len(commands) != 0 || time.Since(start) < 200*time.Millisecond {
+		t.Errorf("expected an empty result after the wait, got %d commands in %v", len(commands), time.Since(start))
+	}
+
+	// a command added while waiting is returned at once
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = repo.CreateOne(ctx, &Command{DeviceID: "device-poll", Type: TypeReboot})
+	}()
+	start = time.Now()
+	commands, err = repo.GetAllByDeviceID(ctx, "device-poll", 5*time.Second)
+	if err != nil {
+		t.Fatalf("GetAllByDeviceID() error = %v", err)
+	}
+	if len(commands) != 1 || time.Since(start) > 2*time.Second {
+		t.Errorf("expected the new command before the wait, got %d commands in %v", len(commands), time.Since(start))
+	}
+}
+
+func TestRepository_GetAllByDeviceID_Cancelled(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	repo := NewCommandRepository(testRedisDB, testRedisDB)
+
+	// e.g. the server is shutting down, the long poll does not wait for the block
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := repo.GetAllByDeviceID(ctx, "device-cancelled", 5*time.Second)
+	if !errors.Is(err, context.Canceled) || time.Since(start) > 2*time.Second {
+		t.Errorf("expected the long poll to stop when cancelled, got %v in %v", err, time.Since(start))
+	}
+}
+
+func TestRepository_GetAllByDeviceID_LongPollsAreCapped(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	// a pool with a single connection, so a second long poll finds no free slot
+	opt := *testRedisDB.Options()
+	opt.PoolSize = 1
+	blockingDB := redis.NewClient(&opt)
+	defer blockingDB.Close()
+	repo := NewCommandRepository(testRedisDB, blockingDB)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = repo.GetAllByDeviceID(ctx, "device-capped-first", time.Second)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// the second poll waits without a connection and then gets what is pending
+	if err := repo.CreateOne(ctx, &Command{DeviceID: "device-capped-second", Type: TypeReboot}); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+	start := time.Now()
+	commands, err := repo.GetAllByDeviceID(ctx, "device-capped-second", 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("GetAllByDeviceID() error = %v", err)
+	}
+	if len(commands) != 1 || time.Since(start) > time.Second {
+		t.Errorf("expected the pending command at the end of the wait, got %d commands in %v", len(commands), time.Since(start))
+	}
+	if stats := blockingDB.PoolStats(); stats.TotalConns > 1 {
+		t.Errorf("expected at most one blocking connection, got %d", stats.TotalConns)
+	}
+	<-done
+}
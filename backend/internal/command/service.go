@@ -0,0 +1,149 @@
+package command
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+)
+
+// MaxWait is the longest a device can wait for a command, it must stay
+// below the timeouts of the proxies between the board and the server
+const MaxWait = 60 * time.Second
+
+var (
+	ErrInvalidCommand   = errors.New("invalid command")
+	ErrInvalidCommandID = errors.New("invalid command id")
+)
+
+// commandIDPattern is the format of the ids of the entries of a redis stream
+var commandIDPattern = regexp.MustCompile(`^\d+-\d+$`)
+
+type commandRepository interface {
+	CreateOne(ctx context.Context, command *Command) error
+	GetAllByDeviceID(ctx context.Context, deviceID string, wait time.Duration) ([]*Command, error)
+	DeleteMany(ctx context.Context, deviceID string, ids []string) (int, error)
+}
+
+type deviceService interface {
+	AuthorizeDevice(ctx context.Context, userID string, deviceID string, permission device.Permission) (*device.Device, error)
+	GetDeviceByID(ctx context.Context, deviceID string) (*device.Device, error)
+}
+
+// commandPublisher delivers the commands to the boards connected over MQTT
+type commandPublisher interface {
+	PublishCommand(command *Command)
+}
+
+type service struct {
+	commandRepo      commandRepository
+	deviceService    deviceService
+	commandPublisher commandPublisher
+}
+
+// NewCommandService creates the service, commandPublisher is nil when
+// MQTT is disabled and the boards only poll the HTTP endpoints
+func NewCommandService(commandRepo commandRepository, deviceService deviceService, commandPublisher commandPublisher) *service {
+	return &service{
+		commandRepo:      commandRepo,
+		deviceService:    deviceService,
+		commandPublisher: commandPublisher,
+	}
+}
+
+// SendCommand queues a command for a device of the user and publishes it
+// over MQTT, the command stays queued until the board acknowledges it
+func (s *service) SendCommand(ctx context.Context, userID string, deviceID string, commandType string, payload json.RawMessage) (*Command, error) {
+	payload, err := validatePayload(commandType, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.deviceService.AuthorizeDevice(ctx, userID, deviceID, device.PermissionControl)
+	if err != nil {
+		return nil, err
+	}
+
+	command := &Command{
+		DeviceID: deviceID,
+		Type:     commandType,
+		Payload:  payload,
+	}
+	err = s.commandRepo.CreateOne(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	if s.commandPublisher != nil {
+		s.commandPublisher.PublishCommand(command)
+	}
+	return command, nil
+}
+
+// GetPending returns the commands not yet acknowledged by the device,
+// waiting up to wait (at most MaxWait) when there are none
+func (s *service) GetPending(ctx context.Context, deviceID string, wait time.Duration) ([]*Command, error) {
+	// the token of a deleted device is still valid until it expires
+	_, err := s.deviceService.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.commandRepo.GetAllByDeviceID(ctx, deviceID, min(wait, MaxWait))
+}
+
+// Ack removes the commands executed by the device and returns how many were still pending,
+// acknowledging the same command twice is not an error
+func (s *service) Ack(ctx context.Context, deviceID string, ids []string) (int, error) {
+	for _, id := range ids {
+		if !commandIDPattern.MatchString(id) {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidCommandID, id)
+		}
+	}
+
+	return s.commandRepo.DeleteMany(ctx, deviceID, ids)
+}
+
+// validatePayload checks the payload against the type of the command and returns it normalized
+func validatePayload(commandType string, payload json.RawMessage) (json.RawMessage, error) {
+	payload = bytes.TrimSpace(payload)
+	empty := len(payload) == 0 || bytes.Equal(payload, []byte("null"))
+
+	switch commandType {
+	case TypeSetDuty:
+		var p struct {
+			DutyCycle *float64 `json:"duty_cycle"`
+		}
+		if empty || json.Unmarshal(payload, &p) != nil || p.DutyCycle == nil || *p.DutyCycle < 0 || *p.DutyCycle > 100 {
+			return nil, fmt.Errorf("%w: set_duty needs a duty_cycle between 0 and 100", ErrInvalidCommand)
+		}
+		return json.Marshal(p)
+	case TypeSetTarget:
+		var p struct {
+			Brightness *int `json:"brightness"`
+		}
+		if empty || json.Unmarshal(payload, &p) != nil || p.Brightness == nil || *p.Brightness < 0 || *p.Brightness > 100 {
+			return nil, fmt.Errorf("%w: set_target needs a brightness between 0 and 100", ErrInvalidCommand)
+		}
+		return json.Marshal(p)
+	case TypeReboot:
+		if !empty && !bytes.Equal(payload, []byte("{}")) {
+			return nil, fmt.Errorf("%w: reboot has no payload", ErrInvalidCommand)
+		}
+		return nil, nil
+	case TypeReconfigure:
+		var p map[string]any
+		if empty || json.Unmarshal(payload, &p) != nil || len(p) == 0 {
+			return nil, fmt.Errorf("%w: reconfigure needs an object with the new settings", ErrInvalidCommand)
+		}
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidCommand, commandType)
+	}
+}
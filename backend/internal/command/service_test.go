@@ -0,0 +1,218 @@
+package command_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_SendCommand(t *testing.T) {
+	tests := []struct {
+		name            string
+		commandType     string
+		payload         string
+		owner           bool
+		expectedPayload string
+		expectedError   error
+	}{
+		{
+			name:            "set_duty",
+			commandType:     command.TypeSetDuty,
+			payload:         `{"duty_cycle": 42.5, "extra": true}`,
+			owner:           true,
+			expectedPayload: `{"duty_cycle":42.5}`,
+		},
+		{
+			name:            "set_target",
+			commandType:     command.TypeSetTarget,
+			payload:         `{"brightness":0}`,
+			owner:           true,
+			expectedPayload: `{"brightness":0}`,
+		},
+		{
+			name:        "reboot",
+			commandType: command.TypeReboot,
+			owner:       true,
+		},
+		{
+			name:            "reconfigure",
+			commandType:     command.TypeReconfigure,
+			payload:         `{"sample_interval_ms":500}`,
+			owner:           true,
+			expectedPayload: `{"sample_interval_ms":500}`,
+		},
+		{
+			name:          "set_duty_out_of_range",
+			commandType:   command.TypeSetDuty,
+			payload:       `{"duty_cycle":101}`,
+			expectedError: command.ErrInvalidCommand,
+		},
+		{
+			name:          "set_target_missing_brightness",
+			commandType:   command.TypeSetTarget,
+			payload:       `{}`,
+			expectedError: command.ErrInvalidCommand,
+		},
+		{
+			name:          "reboot_with_payload",
+			commandType:   command.TypeReboot,
+			payload:       `{"delay":5}`,
+			expectedError: command.ErrInvalidCommand,
+		},
+		{
+			name:          "reconfigure_empty",
+			commandType:   command.TypeReconfigure,
+			payload:       `{}`,
+			expectedError: command.ErrInvalidCommand,
+		},
+		{
+			name:          "unknown_type",
+			commandType:   "self_destruct",
+			expectedError: command.ErrInvalidCommand,
+		},
+		{
+			name:          "not_owner",
+			commandType:   command.TypeReboot,
+			expectedError: device.ErrNotDeviceOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCommandRepo := mocks.NewMockcommandRepository(ctrl)
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			mockCommandPublisher := mocks.NewMockcommandPublisher(ctrl)
+			if tt.owner {
+				mockDeviceService.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionControl).Return(&device.Device{ID: "device-1"}, nil)
+				mockCommandRepo.EXPECT().CreateOne(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, c *command.Command) error {
+						c.ID = "1700000000000-0"
+						return nil
+					},
+				)
+				mockCommandPublisher.EXPECT().PublishCommand(gomock.Any()).Do(func(c *command.Command) {
+					if c.ID != "1700000000000-0" || c.Type != tt.commandType {
+						t.Errorf("unexpected published command %+v", c)
+					}
+				})
+			} else if errors.Is(tt.expectedError, device.ErrNotDeviceOwner) {
+				mockDeviceService.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionControl).Return(nil, device.ErrNotDeviceOwner)
+			}
+
+			s := command.NewCommandService(mockCommandRepo, mockDeviceService, mockCommandPublisher)
+			got, err := s.SendCommand(context.Background(), "user-1", "device-1", tt.commandType, json.RawMessage(tt.payload))
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got.ID == "" || got.DeviceID != "device-1" || got.Type != tt.commandType {
+				t.Errorf("unexpected command %+v", got)
+			}
+			if string(got.Payload) != tt.expectedPayload {
+				t.Errorf("expected payload %s, got %s", tt.expectedPayload, got.Payload)
+			}
+		})
+	}
+}
+
+func TestService_GetPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCommandRepo := mocks.NewMockcommandRepository(ctrl)
+	mockDeviceService := mocks.NewMockdeviceService(ctrl)
+	mockDeviceService.EXPECT().GetDeviceByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1"}, nil)
+	// the wait requested by the device is capped
+	mockCommandRepo.EXPECT().GetAllByDeviceID(gomock.Any(), "device-1", command.MaxWait).Return([]*command.Command{{ID: "1-0", Type: command.TypeReboot}}, nil)
+
+	s := command.NewCommandService(mockCommandRepo, mockDeviceService, nil)
+	commands, err := s.GetPending(context.Background(), "device-1", 10*time.Minute)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(commands) != 1 {
+		t.Errorf("expected 1 command, got %d", len(commands))
+	}
+}
+
+func TestService_GetPending_UnknownDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCommandRepo := mocks.NewMockcommandRepository(ctrl)
+	mockDeviceService := mocks.NewMockdeviceService(ctrl)
+	mockDeviceService.EXPECT().GetDeviceByID(gomock.Any(), "device-1").Return(nil, device.ErrDeviceNotFound)
+
+	s := command.NewCommandService(mockCommandRepo, mockDeviceService, nil)
+	_, err := s.GetPending(context.Background(), "device-1", time.Second)
+	if !errors.Is(err, device.ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestService_Ack(t *testing.T) {
+	tests := []struct {
+		name          string
+		ids           []string
+		setupMock     func(*mocks.MockcommandRepository)
+		expectedAcked int
+		expectedError error
+	}{
+		{
+			name: "success",
+			ids:  []string{"1700000000000-0", "1700000000001-0"},
+			setupMock: func(m *mocks.MockcommandRepository) {
+				m.EXPECT().DeleteMany(gomock.Any(), "device-1", []string{"1700000000000-0", "1700000000001-0"}).Return(2, nil)
+			},
+			expectedAcked: 2,
+		},
+		{
+			name:          "invalid_id",
+			ids:           []string{"1700000000000-0", "*"},
+			setupMock:     func(m *mocks.MockcommandRepository) {},
+			expectedError: command.ErrInvalidCommandID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCommandRepo := mocks.NewMockcommandRepository(ctrl)
+			tt.setupMock(mockCommandRepo)
+
+			s := command.NewCommandService(mockCommandRepo, mocks.NewMockdeviceService(ctrl), nil)
+			acked, err := s.Ack(context.Background(), "device-1", tt.ids)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if acked != tt.expectedAcked {
+				t.Errorf("expected %d acked, got %d", tt.expectedAcked, acked)
+			}
+		})
+	}
+}
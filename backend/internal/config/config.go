@@ -0,0 +1,286 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+)
+
+// Config is everything the server needs to start, it is loaded once in main
+// and the sections are passed to the constructors that need them
+type Config struct {
+	App      AppConfig      `config:"app"`
+	Server   ServerConfig   `config:"server"`
+	Postgres PostgresConfig `config:"postgres"`
+	Redis    RedisConfig    `config:"redis"`
+	Auth     AuthConfig     `config:"auth"`
+	Mail     MailConfig     `config:"mail"`
+	MQTT     MQTTConfig     `config:"mqtt"`
+}
+
+type AppConfig struct {
+	// Name is the issuer of the tokens and the name shown by the authenticator apps
+	Name string `config:"name" env:"APPLICATION_NAME"`
+}
+
+type ServerConfig struct {
+	Port int `config:"port" env:"BACKEND_PORT"`
+	// ReadTimeout is how long the client has to send the whole request
+	ReadTimeout time.Duration `config:"read_timeout" env:"BACKEND_READ_TIMEOUT"`
+	// WriteTimeout is how long a request can take, it must leave room for the long polling of the commands
+	WriteTimeout time.Duration `config:"write_timeout" env:"BACKEND_WRITE_TIMEOUT"`
+	// IdleTimeout is how long a keep-alive connection is kept open between two requests
+	IdleTimeout time.Duration `config:"idle_timeout" env:"BACKEND_IDLE_TIMEOUT"`
+	// ShutdownTimeout is how long the in-flight requests have to complete once the server is stopped
+	ShutdownTimeout time.Duration `config:"shutdown_timeout" env:"BACKEND_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies are the comma separated IPs or CIDRs of the proxies whose X-Forwarded-For
+	// gives the IP of the client, with none the IP of the connection is used
+	TrustedProxies string `config:"trusted_proxies" env:"BACKEND_TRUSTED_PROXIES"`
+	// MetricsToken is the bearer token Prometheus sends to read /metrics,
+	// without one the endpoint is not served
+	MetricsToken string `config:"metrics_token" env:"BACKEND_METRICS_TOKEN"`
+}
+
+type PostgresConfig struct {
+	Host     string `config:"host" env:"POSTGRES_HOST"`
+	Port     int    `config:"port" env:"POSTGRES_PORT"`
+	User     string `config:"user" env:"POSTGRES_USER"`
+	Password string `config:"password" env:"POSTGRES_PASSWORD"`
+	DB       string `config:"db" env:"POSTGRES_DB"`
+}
+
+type RedisConfig struct {
+	Host string `config:"host" env:"REDIS_HOST"`
+	Port int    `config:"port" env:"REDIS_PORT"`
+	// MaxLongPolls is how many boards can wait for a command at the same time,
+	// each one holds a connection of a pool kept apart from the other requests
+	MaxLongPolls int `config:"max_long_polls" env:"REDIS_MAX_LONG_POLLS"`
+}
+
+type AuthConfig struct {
+	// PasswordResetURL and EmailVerificationURL are the pages opened by the links
+	// in the emails, without them only the token is sent
+	PasswordResetURL     string `config:"password_reset_url" env:"PASSWORD_RESET_URL"`
+	EmailVerificationURL string `config:"email_verification_url" env:"EMAIL_VERIFICATION_URL"`
+	// UnverifiedEmailPolicy is one of allow, read_only and deny
+	UnverifiedEmailPolicy string `config:"unverified_email_policy" env:"UNVERIFIED_EMAIL_POLICY"`
+	// KeyRotationPeriod is how long a key signs the tokens before it is replaced
+	KeyRotationPeriod time.Duration `config:"key_rotation_period" env:"JWT_KEY_ROTATION_PERIOD"`
+}
+
+type MailConfig struct {
+	From string `config:"from" env:"MAIL_FROM"`
+	// Dir is where the emails are written when SMTPHost is not set,
+	// when it is empty the emails are dropped
+	Dir          string `config:"dir" env:"MAIL_DIR"`
+	SMTPHost     string `config:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `config:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `config:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `config:"smtp_password" env:"SMTP_PASSWORD"`
+}
+
+type MQTTConfig struct {
+	// BrokerURL enables MQTT, e.g. tcp://localhost:1883, when it is empty
+	// the boards use the HTTP endpoints only
+	BrokerURL string `config:"broker_url" env:"MQTT_BROKER_URL"`
+	// ClientID is the prefix of the client id, each instance adds a random suffix
+	ClientID string `config:"client_id" env:"MQTT_CLIENT_ID"`
+	Username string `config:"username" env:"MQTT_USERNAME"`
+	Password string `config:"password" env:"MQTT_PASSWORD"`
+}
+
+// configFileEnv names the optional YAML or TOML file, --config takes precedence
+const configFileEnv = "CONFIG_FILE"
+
+// Default returns the values used for everything that is not set
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            8080,
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    75 * time.Second,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Postgres: PostgresConfig{Port: 5432},
+		Redis:    RedisConfig{Port: 6379, MaxLongPolls: 100},
+		Auth: AuthConfig{
+			UnverifiedEmailPolicy: "allow",
+			KeyRotationPeriod:     signing_key.DefaultConfig().RotationPeriod,
+		},
+		Mail: MailConfig{
+			From:     "noreply@autolight.local",
+			SMTPPort: 587,
+		},
+		MQTT: MQTTConfig{ClientID: "autolight-backend"},
+	}
+}
+
+// Load reads the configuration, each source overrides the previous one:
+// the defaults, the file given with --config or CONFIG_FILE, the environment
+// and the command line flags, e.g. --postgres.host=localhost.
+// Every invalid field is reported in a single *ValidationError
+func Load(args []string) (*Config, error) {
+	config := Default()
+	fields := config.fields()
+
+	// the flags are parsed first since one of them names the file,
+	// but they are applied last so that they override everything else
+	flags := flag.NewFlagSet("autolight", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", os.Getenv(configFileEnv), "path of a YAML or TOML configuration file")
+	flagValues := map[string]string{}
+	for _, field := range fields {
+		path := field.path
+		flags.Func(path, fmt.Sprintf("overrides %s", field.env), func(value string) error {
+			flagValues[path] = value
+			return nil
+		})
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			flags.SetOutput(os.Stderr)
+			flags.PrintDefaults()
+		}
+		return nil, err
+	}
+
+	errs := &ValidationError{}
+	if *configFile != "" {
+		values, err := readFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		applyFile(fields, values, errs)
+	}
+	// an empty variable is the same as a missing one, as in .env.example
+	for _, field := range fields {
+		if value := os.Getenv(field.env); value != "" {
+			errs.add(field, field.set(value))
+		}
+	}
+	for _, field := range fields {
+		if value, ok := flagValues[field.path]; ok {
+			errs.add(field, field.set(value))
+		}
+	}
+
+	config.validate(errs)
+	if len(errs.Fields) > 0 {
+		return nil, errs
+	}
+	return config, nil
+}
+
+// FieldError is a field that is missing or that has an invalid value
+type FieldError struct {
+	// Field is the key in the file, e.g. postgres.host
+	Field string
+	// Env is the environment variable of the field, e.g. POSTGRES_HOST
+	Env     string
+	Message string
+}
+
+// ValidationError lists all the invalid fields, so that they can be fixed at once
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var builder strings.Builder
+	builder.WriteString("invalid configuration:")
+	for _, field := range e.Fields {
+		if field.Env == "" {
+			fmt.Fprintf(&builder, "\n  %s: %s", field.Field, field.Message)
+			continue
+		}
+		fmt.Fprintf(&builder, "\n  %s (%s): %s", field.Field, field.Env, field.Message)
+	}
+	return builder.String()
+}
+
+// add records the error of a field, only the first error of each field is kept
+// since a value that cannot be parsed would fail the validation as well
+func (e *ValidationError) add(field field, err error) {
+	if err == nil {
+		return
+	}
+	for _, existing := range e.Fields {
+		if existing.Field == field.path {
+			return
+		}
+	}
+	e.Fields = append(e.Fields, FieldError{Field: field.path, Env: field.env, Message: err.Error()})
+}
+
+// field is a value of the configuration that can be set from every source
+type field struct {
+	path  string
+	env   string
+	value reflect.Value
+}
+
+// fields lists the values of the sections in the order they are declared
+func (c *Config) fields() []field {
+	var fields []field
+	root := reflect.ValueOf(c).Elem()
+	for i := range root.NumField() {
+		section := root.Field(i)
+		sectionName := root.Type().Field(i).Tag.Get("config")
+		for j := range section.NumField() {
+			tag := section.Type().Field(j)
+			fields = append(fields, field{
+				path:  sectionName + "." + tag.Tag.Get("config"),
+				env:   tag.Tag.Get("env"),
+				value: section.Field(j),
+			})
+		}
+	}
+	return fields
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// set parses the value as the type of the field
+func (f field) set(value string) error {
+	value = strings.TrimSpace(value)
+	switch {
+	case f.value.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration such as 90s or 720h")
+		}
+		f.value.SetInt(int64(duration))
+	case f.value.Kind() == reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		f.value.SetInt(int64(number))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(value)
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// SplitList returns the trimmed items of a comma separated value
+func SplitList(value string) []string {
+	items := []string{}
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,289 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
+)
+
+// every variable read by Load, they are emptied so that the environment
+// of the machine running the tests does not change the results
+var configEnv = []string{
+	"CONFIG_FILE", "APPLICATION_NAME", "BACKEND_PORT",
+	"BACKEND_READ_TIMEOUT", "BACKEND_WRITE_TIMEOUT", "BACKEND_IDLE_TIMEOUT", "BACKEND_SHUTDOWN_TIMEOUT", "BACKEND_TRUSTED_PROXIES", "BACKEND_METRICS_TOKEN",
+	"POSTGRES_HOST", "POSTGRES_PORT", "POSTGRES_USER", "POSTGRES_PASSWORD", "POSTGRES_DB",
+	"REDIS_HOST", "REDIS_PORT", "REDIS_MAX_LONG_POLLS",
+	"PASSWORD_RESET_URL", "EMAIL_VERIFICATION_URL", "UNVERIFIED_EMAIL_POLICY", "JWT_KEY_ROTATION_PERIOD",
+	"MAIL_FROM", "MAIL_DIR", "SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD",
+	"MQTT_BROKER_URL", "MQTT_CLIENT_ID", "MQTT_USERNAME", "MQTT_PASSWORD",
+}
+
+// setupEnv empties the environment and sets only the required variables
+func setupEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range configEnv {
+		t.Setenv(name, "")
+	}
+	t.Setenv("APPLICATION_NAME", "Auto Light")
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_DB", "autolight")
+	t.Setenv("REDIS_HOST", "localhost")
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write the config file: %v", err)
+	}
+	return path
+}
+
+// invalidFields returns the fields listed by a *config.ValidationError
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a *config.ValidationError, got %v", err)
+	}
+	var fields []string
+	for _, field := range validationErr.Fields {
+		fields = append(fields, field.Field)
+	}
+	return fields
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setupEnv(t)
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if cfg.App.Name != "Auto Light" || cfg.Postgres.Host != "localhost" || cfg.Redis.Host != "localhost" {
+		t.Errorf("expected the values of the environment, got %+v", cfg)
+	}
+	if cfg.Server.Port != 8080 || cfg.Postgres.Port != 5432 || cfg.Redis.Port != 6379 || cfg.Mail.SMTPPort != 587 {
+		t.Errorf("expected the default ports, got %+v", cfg)
+	}
+	if cfg.Server.WriteTimeout != 75*time.Second || cfg.Server.ShutdownTimeout != 30*time.Second {
+		t.Errorf("expected the default timeouts, got %+v", cfg.Server)
+	}
+	if cfg.Auth.UnverifiedEmailPolicy != "allow" || cfg.Auth.KeyRotationPeriod != 720*time.Hour {
+		t.Errorf("expected the default auth config, got %+v", cfg.Auth)
+	}
+	if cfg.MQTT.BrokerURL != "" || cfg.MQTT.ClientID != "autolight-backend" {
+		t.Errorf("expected MQTT disabled, got %+v", cfg.MQTT)
+	}
+}
+
+func TestLoad_Sources(t *testing.T) {
+	tests := []struct {
+		name          string
+		fileName      string
+		file          string
+		env           map[string]string
+		args          []string
+		expectedHost  string
+		expectedPort  int
+		expectedDelay time.Duration
+	}{
+		{
+			name:     "yaml_file",
+			fileName: "config.yaml",
+			file: `
+postgres:
+  host: db.yaml
+  port: 5433
+auth:
+  key_rotation_period: 48h
+`,
+			env:           map[string]string{"POSTGRES_HOST": ""},
+			expectedHost:  "db.yaml",
+			expectedPort:  5433,
+			expectedDelay: 48 * time.Hour,
+		},
+		{
+			name:     "toml_file",
+			fileName: "config.toml",
+			file: `
+[postgres]
+host = "db.toml"
+port = 5434
+
+[auth]
+key_rotation_period = "72h"
+`,
+			env:           map[string]string{"POSTGRES_HOST": ""},
+			expectedHost:  "db.toml",
+			expectedPort:  5434,
+			expectedDelay: 72 * time.Hour,
+		},
+		{
+			name:          "env_overrides_file",
+			fileName:      "config.yml",
+			file:          "postgres:\n  host: db.yaml\n  port: 5433\n",
+			env:           map[string]string{"POSTGRES_HOST": "db.env"},
+			expectedHost:  "db.env",
+			expectedPort:  5433,
+			expectedDelay: 720 * time.Hour,
+		},
+		{
+			name:          "flags_override_env",
+			fileName:      "config.yml",
+			file:          "postgres:\n  host: db.yaml\n",
+			env:           map[string]string{"POSTGRES_HOST": "db.env", "POSTGRES_PORT": "5435"},
+			args:          []string{"--postgres.host=db.flag", "--auth.key_rotation_period", "24h"},
+			expectedHost:  "db.flag",
+			expectedPort:  5435,
+			expectedDelay: 24 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			path := writeFile(t, tt.fileName, tt.file)
+
+			cfg, err := config.Load(append([]string{"--config", path}, tt.args...))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if cfg.Postgres.Host != tt.expectedHost || cfg.Postgres.Port != tt.expectedPort {
+				t.Errorf("expected %s:%d, got %s:%d", tt.expectedHost, tt.expectedPort, cfg.Postgres.Host, cfg.Postgres.Port)
+			}
+			if cfg.Auth.KeyRotationPeriod != tt.expectedDelay {
+				t.Errorf("expected rotation period %s, got %s", tt.expectedDelay, cfg.Auth.KeyRotationPeriod)
+			}
+		})
+	}
+}
+
+func TestLoad_FileFromEnv(t *testing.T) {
+	setupEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "redis:\n  host: cache\n"))
+	t.Setenv("REDIS_HOST", "")
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Redis.Host != "cache" {
+		t.Errorf("expected the host of CONFIG_FILE, got %q", cfg.Redis.Host)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		file           string
+		env            map[string]string
+		args           []string
+		expectedFields []string
+	}{
+		{
+			name: "every_invalid_field_is_listed",
+			env: map[string]string{
+				"APPLICATION_NAME":        "",
+				"BACKEND_PORT":            "http",
+				"BACKEND_WRITE_TIMEOUT":   "30s",
+				"REDIS_PORT":              "70000",
+				"REDIS_MAX_LONG_POLLS":    "0",
+				"UNVERIFIED_EMAIL_POLICY": "sometimes",
+				"JWT_KEY_ROTATION_PERIOD": "a month",
+				"MAIL_FROM":               "not an address",
+				"PASSWORD_RESET_URL":      "ftp://example.com/reset",
+			},
+			expectedFields: []string{
+				"server.port", "server.write_timeout", "auth.key_rotation_period", "app.name", "redis.port", "redis.max_long_polls",
+				"auth.password_reset_url", "auth.unverified_email_policy", "mail.from",
+			},
+		},
+		{
+			name:           "rotation_shorter_than_activation",
+			args:           []string{"--auth.key_rotation_period=1m"},
+			expectedFields: []string{"auth.key_rotation_period"},
+		},
+		{
+			name:           "trusted_proxies_are_ips_or_cidrs",
+			env:            map[string]string{"BACKEND_TRUSTED_PROXIES": "10.0.0.0/8, 192.168.1.1, proxy.local"},
+			expectedFields: []string{"server.trusted_proxies"},
+		},
+		{
+			name:           "metrics_token_too_short",
+			env:            map[string]string{"BACKEND_METRICS_TOKEN": "prometheus"},
+			expectedFields: []string{"server.metrics_token"},
+		},
+		{
+			name:           "mqtt_requires_a_broker_url",
+			env:            map[string]string{"MQTT_BROKER_URL": "localhost:1883"},
+			args:           []string{"--mqtt.client_id="},
+			expectedFields: []string{"mqtt.broker_url", "mqtt.client_id"},
+		},
+		{
+			name:           "unknown_key_in_file",
+			file:           "postgres:\n  hostname: db\nlogging: debug\n",
+			expectedFields: []string{"postgres.hostname", "logging"},
+		},
+		{
+			name:           "nested_value_in_file",
+			file:           "redis:\n  port:\n    - 6379\n",
+			expectedFields: []string{"redis.port"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeFile(t, "config.yaml", tt.file)}, args...)
+			}
+
+			cfg, err := config.Load(args)
+			if cfg != nil {
+				t.Errorf("expected no config, got %+v", cfg)
+			}
+
+			fields := invalidFields(t, err)
+			slices.Sort(fields)
+			expected := slices.Clone(tt.expectedFields)
+			slices.Sort(expected)
+			if !slices.Equal(fields, expected) {
+				t.Errorf("expected invalid fields %v, got %v\n%v", expected, fields, err)
+			}
+		})
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	setupEnv(t)
+
+	if _, err := config.Load([]string{"--config", writeFile(t, "config.json", "{}")}); err == nil {
+		t.Errorf("expected an error for an unsupported file")
+	}
+	if _, err := config.Load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+	if _, err := config.Load([]string{"--postgres.hots=db"}); err == nil {
+		t.Errorf("expected an error for an unknown flag")
+	}
+	if _, err := config.Load([]string{"--help"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("expected flag.ErrHelp, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// readFile decodes the file into its sections, the format is chosen by the extension
+func readFile(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration file: %w", err)
+	}
+
+	values := map[string]any{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		return nil, fmt.Errorf("unsupported configuration file %s, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the configuration file: %w", err)
+	}
+	return values, nil
+}
+
+// applyFile sets the fields found in the file, the values are parsed as if
+// they were environment variables so that every source has the same rules.
+// A key that is not a field is reported, it is most likely a typo
+func applyFile(fields []field, values map[string]any, errs *ValidationError) {
+	byPath := make(map[string]field, len(fields))
+	for _, field := range fields {
+		byPath[field.path] = field
+	}
+
+	// the keys are sorted so that the errors are always listed in the same order
+	for _, sectionName := range slices.Sorted(maps.Keys(values)) {
+		section, ok := values[sectionName].(map[string]any)
+		if !ok {
+			errs.add(field{path: sectionName}, errors.New("must be a section"))
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(section)) {
+			path := sectionName + "." + key
+			target, ok := byPath[path]
+			if !ok {
+				errs.add(field{path: path}, errors.New("unknown key"))
+				continue
+			}
+			kind := reflect.ValueOf(section[key]).Kind()
+			if kind == reflect.Map || kind == reflect.Slice {
+				errs.add(target, errors.New("must be a single value"))
+				continue
+			}
+			errs.add(target, target.set(fmt.Sprint(section[key])))
+		}
+	}
+}
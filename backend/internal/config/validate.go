@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+)
+
+// the policies applied to the users that have not verified their email yet
+var unverifiedEmailPolicies = []string{"allow", "read_only", "deny"}
+
+// the metrics token is exposed on the public port, so it must not be guessable
+const metricsTokenMinLength = 32
+
+// validate checks every field and records all the invalid ones
+func (c *Config) validate(errs *ValidationError) {
+	byPath := map[string]field{}
+	for _, field := range c.fields() {
+		byPath[field.path] = field
+	}
+	check := func(path string, err error) {
+		errs.add(byPath[path], err)
+	}
+
+	check("app.name", required(c.App.Name))
+	check("server.port", port(c.Server.Port))
+	check("server.read_timeout", longerThan(c.Server.ReadTimeout, 0))
+	// a long polling request must be answered before the server drops it
+	check("server.write_timeout", longerThan(c.Server.WriteTimeout, command.MaxWait))
+	check("server.idle_timeout", longerThan(c.Server.IdleTimeout, 0))
+	check("server.shutdown_timeout", longerThan(c.Server.ShutdownTimeout, 0))
+	check("server.trusted_proxies", proxies(c.Server.TrustedProxies))
+	check("server.metrics_token", optionalSecret(c.Server.MetricsToken, metricsTokenMinLength))
+
+	check("postgres.host", required(c.Postgres.Host))
+	check("postgres.port", port(c.Postgres.Port))
+	check("postgres.user", required(c.Postgres.User))
+	check("postgres.db", required(c.Postgres.DB))
+
+	check("redis.host", required(c.Redis.Host))
+	check("redis.port", port(c.Redis.Port))
+	check("redis.max_long_polls", positive(c.Redis.MaxLongPolls))
+
+	check("auth.password_reset_url", optionalURL(c.Auth.PasswordResetURL, "http", "https"))
+	check("auth.email_verification_url", optionalURL(c.Auth.EmailVerificationURL, "http", "https"))
+	check("auth.unverified_email_policy", oneOf(c.Auth.UnverifiedEmailPolicy, unverifiedEmailPolicies))
+	// a period shorter than the activation delay would publish a new key at every refresh
+	check("auth.key_rotation_period", longerThan(c.Auth.KeyRotationPeriod, signing_key.DefaultConfig().ActivationDelay))
+
+	check("mail.from", emailAddress(c.Mail.From))
+	check("mail.smtp_port", port(c.Mail.SMTPPort))
+
+	if c.MQTT.BrokerURL != "" {
+		check("mqtt.broker_url", optionalURL(c.MQTT.BrokerURL, "tcp", "ssl", "tls", "ws", "wss", "mqtt", "mqtts"))
+		check("mqtt.client_id", required(c.MQTT.ClientID))
+	}
+}
+
+func required(value string) error {
+	if value == "" {
+		return errors.New("is required")
+	}
+	return nil
+}
+
+func port(value int) error {
+	if value < 1 || value > 65535 {
+		return errors.New("must be between 1 and 65535")
+	}
+	return nil
+}
+
+func positive(value int) error {
+	if value < 1 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+// proxies accepts a comma separated list of IPs and CIDRs, it can be empty
+func proxies(value string) error {
+	for _, proxy := range SplitList(value) {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			return fmt.Errorf("%q is not an IP or a CIDR", proxy)
+		}
+	}
+	return nil
+}
+
+// optionalSecret accepts an empty value or one of at least minLength characters
+func optionalSecret(value string, minLength int) error {
+	if value != "" && len(value) < minLength {
+		return fmt.Errorf("must be at least %d characters", minLength)
+	}
+	return nil
+}
+
+func oneOf(value string, allowed []string) error {
+	if !slices.Contains(allowed, value) {
+		return fmt.Errorf("must be one of %v", allowed)
+	}
+	return nil
+}
+
+func longerThan(value time.Duration, min time.Duration) error {
+	if value <= min {
+		return fmt.Errorf("must be longer than %s", min)
+	}
+	return nil
+}
+
+func emailAddress(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return errors.New("must be an email address")
+	}
+	return nil
+}
+
+// optionalURL accepts an empty value or an absolute URL with one of the schemes
+func optionalURL(value string, schemes ...string) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" || !slices.Contains(schemes, parsed.Scheme) {
+		return fmt.Errorf("must be an absolute URL with scheme %v", schemes)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	target "github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MocktargetRepository is a mock of targetRepository interface.
+type MocktargetRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocktargetRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MocktargetRepositoryMockRecorder is the mock recorder for MocktargetRepository.
+type MocktargetRepositoryMockRecorder struct {
+	mock *MocktargetRepository
+}
+
+// NewMocktargetRepository creates a new mock instance.
+func NewMocktargetRepository(ctrl *gomock.Controller) *MocktargetRepository {
+	mock := &MocktargetRepository{ctrl: ctrl}
+	mock.recorder = &MocktargetRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktargetRepository) EXPECT() *MocktargetRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetOneByDeviceID mocks base method.
+func (m *MocktargetRepository) GetOneByDeviceID(ctx context.Context, deviceID string) (*target.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByDeviceID", ctx, deviceID)
+	ret0, _ := ret[0].(*target.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByDeviceID indicates an expected call of GetOneByDeviceID.
+func (mr *MocktargetRepositoryMockRecorder) GetOneByDeviceID(ctx, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByDeviceID", reflect.TypeOf((*MocktargetRepository)(nil).GetOneByDeviceID), ctx, deviceID)
+}
@@ -0,0 +1,11 @@
+package control
+
+import (
+	"time"
+)
+
+// Sample is a lux reading fed to the controller
+type Sample struct {
+	Timestamp time.Time
+	Lux       float64
+}
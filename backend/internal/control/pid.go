@@ -0,0 +1,90 @@
+package control
+
+import (
+	"time"
+)
+
+// Gains are the coefficients of the proportional, integral and derivative terms,
+// the error is in lux and the output is a duty cycle in percent
+type Gains struct {
+	Kp float64
+	Ki float64
+	Kd float64
+}
+
+type PIDConfig struct {
+	Gains     Gains
+	OutputMin float64
+	OutputMax float64
+	// DerivativeTau is the time constant of the low pass filter applied to the
+	// derivative term, the lux sensor is noisy and an unfiltered derivative
+	// would make the lamp flicker, zero disables the filter
+	DerivativeTau time.Duration
+}
+
+// PID is a discrete PID controller, it is not safe for concurrent use
+type PID struct {
+	config PIDConfig
+	// integral is already multiplied by Ki, so it is in the unit of the output
+	// and it can be clamped to the output range to prevent the windup
+	integral        float64
+	derivative      float64
+	lastMeasurement float64
+	initialized     bool
+}
+
+func NewPID(config PIDConfig) *PID {
+	return &PID{config: config}
+}
+
+// Reset clears the state of the controller, the integral term is preloaded with
+// output so that the first update continues from what the lamp is doing (bumpless transfer)
+func (p *PID) Reset(output float64) {
+	p.integral = p.clamp(output)
+	p.derivative = 0
+	p.initialized = false
+}
+
+// Update returns the next output given the setpoint, the current measurement and
+// the time elapsed since the previous update
+func (p *PID) Update(setpoint float64, measurement float64, dt time.Duration) float64 {
+	gains := p.config.Gains
+	err := setpoint - measurement
+
+	if !p.initialized {
+		p.lastMeasurement = measurement
+		p.initialized = true
+	}
+
+	seconds := dt.Seconds()
+	if seconds > 0 {
+		// the derivative is computed on the measurement and not on the error,
+		// so a change of the setpoint does not produce a spike of the output
+		raw := -(measurement - p.lastMeasurement) / seconds
+		if tau := p.config.DerivativeTau.Seconds(); tau > 0 {
+			p.derivative += seconds / (tau + seconds) * (raw - p.derivative)
+		} else {
+			p.derivative = raw
+		}
+		p.lastMeasurement = measurement
+
+		// anti-windup: while the output is saturated the integral can only grow up
+		// to the value that saturates it, so it does not keep charging and
+		// the controller reacts as soon as the error changes sign
+		proportional := gains.Kp * err
+		derivative := gains.Kd * p.derivative
+		integral := p.integral + gains.Ki*err*seconds
+		if output := proportional + integral + derivative; output > p.config.OutputMax && err > 0 {
+			integral = max(p.integral, p.config.OutputMax-proportional-derivative)
+		} else if output < p.config.OutputMin && err < 0 {
+			integral = min(p.integral, p.config.OutputMin-proportional-derivative)
+		}
+		p.integral = p.clamp(integral)
+	}
+
+	return p.clamp(gains.Kp*err + p.integral + gains.Kd*p.derivative)
+}
+
+func (p *PID) clamp(value float64) float64 {
+	return min(max(value, p.config.OutputMin), p.config.OutputMax)
+}
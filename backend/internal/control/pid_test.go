@@ -0,0 +1,148 @@
+package control
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// room is a first order model of a desk lit by the lamp and by the ambient light
+type room struct {
+	ambient float64
+	// gain is how many lux the lamp adds for each percent of duty cycle
+	gain float64
+	tau  time.Duration
+	lux  float64
+}
+
+// step advances the model by dt keeping the duty cycle constant
+func (r *room) step(duty float64, dt time.Duration) float64 {
+	const substeps = 100
+	h := dt.Seconds() / substeps
+	for range substeps {
+		r.lux += h / r.tau.Seconds() * (r.ambient + r.gain*duty - r.lux)
+	}
+	return r.lux
+}
+
+func testPIDConfig() PIDConfig {
+	return DefaultConfig().PID
+}
+
+func simulate(pid *PID, r *room, setpoint float64, duty float64, steps int, noise func() float64) (float64, []float64) {
+	outputs := make([]float64, 0, steps)
+	for range steps {
+		measurement := r.step(duty, time.Second) + noise()
+		duty = pid.Update(setpoint, measurement, time.Second)
+		outputs = append(outputs, duty)
+	}
+	return duty, outputs
+}
+
+func noNoise() float64 { return 0 }
+
+func TestPID_ConvergesToSetpoint(t *testing.T) {
+	r := &room{ambient: 50, gain: 5, tau: 2 * time.Second, lux: 50}
+	pid := NewPID(testPIDConfig())
+
+	duty, _ := simulate(pid, r, 300, 0, 120, noNoise)
+
+	if math.Abs(r.lux-300) > 3 {
+		t.Errorf("expected lux close to 300, got %.2f", r.lux)
+	}
+	if math.Abs(duty-50) > 1 {
+		t.Errorf("expected duty cycle close to 50, got %.2f", duty)
+	}
+}
+
+func TestPID_OutputClamping(t *testing.T) {
+	r := &room{ambient: 50, gain: 5, tau: 2 * time.Second, lux: 50}
+	pid := NewPID(testPIDConfig())
+
+	// 800 lux cannot be reached, the lamp tops at 550
+	_, outputs := simulate(pid, r, 800, 0, 60, noNoise)
+	for i, output := range outputs {
+		if output < 0 || output > 100 {
+			t.Fatalf("output %d out of range: %.2f", i, output)
+		}
+	}
+	if outputs[len(outputs)-1] != 100 {
+		t.Errorf("expected output saturated at 100, got %.2f", outputs[len(outputs)-1])
+	}
+
+	// the sun comes in, even with the lamp off the desk is too bright
+	r.ambient = 900
+	_, outputs = simulate(pid, r, 300, 100, 60, noNoise)
+	if outputs[len(outputs)-1] != 0 {
+		t.Errorf("expected output saturated at 0, got %.2f", outputs[len(outputs)-1])
+	}
+}
+
+func TestPID_AntiWindup(t *testing.T) {
+	r := &room{ambient: 50, gain: 5, tau: 2 * time.Second, lux: 50}
+	pid := NewPID(testPIDConfig())
+
+	// a long saturation must not charge the integral term
+	duty, _ := simulate(pid, r, 800, 0, 600, noNoise)
+	if pid.integral > 100 {
+		t.Fatalf("integral wound up to %.2f", pid.integral)
+	}
+
+	// as soon as the setpoint is reachable again the lamp is dimmed without a long overshoot
+	simulate(pid, r, 300, duty, 20, noNoise)
+	if math.Abs(r.lux-300) > 15 {
+		t.Errorf("expected lux close to 300 after 20s, got %.2f", r.lux)
+	}
+}
+
+func TestPID_DerivativeFilter(t *testing.T) {
+	// only the derivative term is active, so the output moves only because of the noise of the sensor
+	maxDeviation := func(tau time.Duration) float64 {
+		config := testPIDConfig()
+		config.Gains = Gains{Kd: 0.05}
+		config.DerivativeTau = tau
+		pid := NewPID(config)
+		pid.Reset(50)
+		rng := rand.New(rand.NewSource(1))
+
+		deviation := 0.0
+		for range 300 {
+			output := pid.Update(300, 300+rng.NormFloat64()*10, time.Second)
+			deviation = max(deviation, math.Abs(output-50))
+		}
+		return deviation
+	}
+
+	unfiltered := maxDeviation(0)
+	filtered := maxDeviation(5 * time.Second)
+	if filtered >= unfiltered/2 {
+		t.Errorf("expected the filter to halve the noise on the output, got %.2f filtered and %.2f unfiltered", filtered, unfiltered)
+	}
+}
+
+func TestPID_NoDerivativeKickOnSetpointChange(t *testing.T) {
+	config := testPIDConfig()
+	config.Gains = Gains{Kp: 0.1, Ki: 0, Kd: 10}
+	pid := NewPID(config)
+	pid.Reset(50)
+
+	pid.Update(200, 200, time.Second)
+	output := pid.Update(300, 200, time.Second)
+
+	// only the proportional term reacts to the new setpoint
+	if math.Abs(output-60) > 1e-9 {
+		t.Errorf("expected output 60, got %.2f", output)
+	}
+}
+
+func TestPID_ResetIsBumpless(t *testing.T) {
+	pid := NewPID(testPIDConfig())
+	pid.Update(300, 0, time.Second)
+
+	pid.Reset(40)
+	output := pid.Update(300, 300, 0)
+	if output != 40 {
+		t.Errorf("expected output 40 after reset, got %.2f", output)
+	}
+}
@@ -0,0 +1,153 @@
+package control
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+)
+
+var (
+	ErrNoTarget = errors.New("the device has no brightness target")
+)
+
+type Config struct {
+	PID PIDConfig
+	// FullScaleLux is the illuminance that corresponds to a brightness target of 100
+	FullScaleLux float64
+	// MaxGap is the longest pause between two samples after which the state
+	// of the controller is considered stale and it is reset
+	MaxGap time.Duration
+}
+
+// DefaultConfig is tuned for a lamp that brings a desk to about 500 lux at full power
+func DefaultConfig() Config {
+	return Config{
+		PID: PIDConfig{
+			Gains:         Gains{Kp: 0.02, Ki: 0.08, Kd: 0},
+			OutputMin:     0,
+			OutputMax:     100,
+			DerivativeTau: 2 * time.Second,
+		},
+		FullScaleLux: 500,
+		MaxGap:       30 * time.Second,
+	}
+}
+
+type targetRepository interface {
+	GetOneByDeviceID(ctx context.Context, deviceID string) (*target.Target, error)
+}
+
+// loop is the state of the control loop of a single device
+type loop struct {
+	mu            sync.Mutex
+	pid           *PID
+	lastTimestamp time.Time
+	// lastUsed is when the loop was last updated, it is guarded by the mutex of the service
+	lastUsed time.Time
+}
+
+// service keeps the controllers in memory, after a restart each loop
+// starts again from the duty cycle reported by the device
+type service struct {
+	targetRepo targetRepository
+	config     Config
+
+	mu    sync.Mutex
+	loops map[string]*loop
+}
+
+func NewControlService(targetRepo targetRepository, config Config) *service {
+	return &service{
+		targetRepo: targetRepo,
+		config:     config,
+		loops:      make(map[string]*loop),
+	}
+}
+
+// Update feeds the samples of a device to its controller, in order, and returns
+// the duty cycle the lamp should apply next, dutyCycle is what the lamp was
+// doing while the samples were taken
+func (s *service) Update(ctx context.Context, deviceID string, dutyCycle float64, samples []Sample) (float64, error) {
+	t, err := s.targetRepo.GetOneByDeviceID(ctx, deviceID)
+	if err != nil {
+		return 0, err
+	}
+
+	l := s.getLoop(deviceID)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if t == nil {
+		// the next time a target is set the loop starts from scratch
+		l.lastTimestamp = time.Time{}
+		metrics.ControlError.DeleteLabelValues(deviceID)
+		return 0, ErrNoTarget
+	}
+
+	setpoint := float64(t.Brightness) / 100 * s.config.FullScaleLux
+	output := dutyCycle
+	for _, sample := range samples {
+		if !sample.Timestamp.After(l.lastTimestamp) {
+			continue
+		}
+		dt := sample.Timestamp.Sub(l.lastTimestamp)
+		if l.lastTimestamp.IsZero() || dt > s.config.MaxGap {
+			l.pid.Reset(dutyCycle)
+			dt = 0
+		}
+		output = l.pid.Update(setpoint, sample.Lux, dt)
+		l.lastTimestamp = sample.Timestamp
+		metrics.ControlError.WithLabelValues(deviceID).Set(setpoint - sample.Lux)
+	}
+	return output, nil
+}
+
+func (s *service) getLoop(deviceID string) *loop {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.loops[deviceID]
+	if !ok {
+		l = &loop{pid: NewPID(s.config.PID)}
+		s.loops[deviceID] = l
+	}
+	// marked while the service is locked, so that evictIdle cannot drop a loop being updated
+	l.lastUsed = time.Now()
+	return l
+}
+
+// Run evicts the idle loops every MaxGap until the context is done
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.MaxGap)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.evictIdle(now)
+		}
+	}
+}
+
+// evictIdle drops the loops not updated for longer than MaxGap, together with their error.
+// They would be reset by the next sample anyway, and the devices that have been
+// deleted or that have gone offline would otherwise stay in memory forever
+func (s *service) evictIdle(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for deviceID, l := range s.loops {
+		if now.Sub(l.lastUsed) > s.config.MaxGap {
+			delete(s.loops, deviceID)
+			metrics.ControlError.DeleteLabelValues(deviceID)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/control/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Update_ClosedLoop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTargetRepo := mocks.NewMocktargetRepository(ctrl)
+	// 60% of the 500 lux full scale
+	mockTargetRepo.EXPECT().GetOneByDeviceID(gomock.Any(), "device-1").Return(&target.Target{DeviceID: "device-1", Brightness: 60}, nil).AnyTimes()
+
+	s := NewControlService(mockTargetRepo, DefaultConfig())
+	r := &room{ambient: 50, gain: 5, tau: 2 * time.Second, lux: 50}
+
+	// the device uploads a batch of 5 samples taken at 1 Hz and then applies the answer
+	ts := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	duty := 0.0
+	for range 40 {
+		samples := make([]Sample, 0, 5)
+		for range 5 {
+			ts = ts.Add(time.Second)
+			samples = append(samples, Sample{Timestamp: ts, Lux: r.step(duty, time.Second)})
+		}
+		next, err := s.Update(context.Background(), "device-1", duty, samples)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		duty = next
+	}
+
+	if math.Abs(r.lux-300) > 5 {
+		t.Errorf("expected lux close to 300, got %.2f", r.lux)
+	}
+	// the gauge is the error at the last sample, so it converges as well
+	if got := testutil.ToFloat64(metrics.ControlError.WithLabelValues("device-1")); math.Abs(got) > 5 {
+		t.Errorf("expected a control error close to 0, got %.2f", got)
+	}
+}
+
+func TestService_Update_NoTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTargetRepo := mocks.NewMocktargetRepository(ctrl)
+	mockTargetRepo.EXPECT().GetOneByDeviceID(gomock.Any(), "device-1").Return(nil, nil)
+
+	s := NewControlService(mockTargetRepo, DefaultConfig())
+	_, err := s.Update(context.Background(), "device-1", 30, []Sample{{Timestamp: time.Now(), Lux: 100}})
+	if !errors.Is(err, ErrNoTarget) {
+		t.Errorf("expected ErrNoTarget, got %v", err)
+	}
+}
+
+func TestService_Update_ResetAfterGap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTargetRepo := mocks.NewMocktargetRepository(ctrl)
+	mockTargetRepo.EXPECT().GetOneByDeviceID(gomock.Any(), "device-1").Return(&target.Target{DeviceID: "device-1", Brightness: 60}, nil).AnyTimes()
+
+	s := NewControlService(mockTargetRepo, DefaultConfig())
+	ts := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// a dark room winds the loop up to full power
+	for i := range 30 {
+		_, err := s.Update(context.Background(), "device-1", 100, []Sample{{Timestamp: ts.Add(time.Duration(i) * time.Second), Lux: 0}})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// after a long pause the loop restarts from the duty cycle reported by the device
+	next, err := s.Update(context.Background(), "device-1", 20, []Sample{{Timestamp: ts.Add(time.Hour), Lux: 300}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if next != 20 {
+		t.Errorf("expected the loop to restart from 20, got %.2f", next)
+	}
+}
+
+func TestService_Update_TargetError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dbErr := errors.New("db error")
+	mockTargetRepo := mocks.NewMocktargetRepository(ctrl)
+	mockTargetRepo.EXPECT().GetOneByDeviceID(gomock.Any(), "device-1").Return(nil, dbErr)
+
+	s := NewControlService(mockTargetRepo, DefaultConfig())
+	_, err := s.Update(context.Background(), "device-1", 30, []Sample{{Timestamp: time.Now(), Lux: 100}})
+	if !errors.Is(err, dbErr) {
+		t.Errorf("expected db error, got %v", err)
+	}
+}
+
+func TestService_EvictIdle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTargetRepo := mocks.NewMocktargetRepository(ctrl)
+	mockTargetRepo.EXPECT().GetOneByDeviceID(gomock.Any(), gomock.Any()).Return(&target.Target{Brightness: 60}, nil).AnyTimes()
+
+	s := NewControlService(mockTargetRepo, DefaultConfig())
+	for _, deviceID := range []string{"idle-device", "active-device"} {
+		if _, err := s.Update(context.Background(), deviceID, 30, []Sample{{Timestamp: time.Now(), Lux: 100}}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	s.loops["idle-device"].lastUsed = time.Now().Add(-time.Hour)
+
+	s.evictIdle(time.Now())
+
+	// the loop and the error of the idle device are dropped, the active one is kept
+	if _, ok := s.loops["idle-device"]; ok {
+		t.Errorf("expected the idle loop to be evicted")
+	}
+	if _, ok := s.loops["active-device"]; !ok {
+		t.Errorf("expected the active loop to be kept")
+	}
+	if metrics.ControlError.DeleteLabelValues("idle-device") {
+		t.Errorf("expected the error of the idle device to be deleted")
+	}
+	if !metrics.ControlError.DeleteLabelValues("active-device") {
+		t.Errorf("expected the error of the active device to be kept")
+	}
+}
@@ -0,0 +1,373 @@
+package device
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	"github.com/gin-gonic/gin"
+)
+
+type deviceService interface {
+	CreateDevice(ctx context.Context, userID string, name string) (*Device, error)
+	GetDevices(ctx context.Context, userID string) ([]*Device, error)
+	GetDevice(ctx context.Context, userID string, deviceID string) (*Device, error)
+	RenameDevice(ctx context.Context, userID string, deviceID string, name string) (*Device, error)
+	DeleteDevice(ctx context.Context, userID string, deviceID string) error
+	ShareDevice(ctx context.Context, userID string, deviceID string, householdID string) (*Device, error)
+	UnshareDevice(ctx context.Context, userID string, deviceID string) (*Device, error)
+	CreatePairingCode(ctx context.Context, userID string, deviceName string, deviceID string) (*pairing_code.PairingCode, error)
+	ClaimDevice(ctx context.Context, code string, serial string) (*Device, string, error)
+	GetDeviceByID(ctx context.Context, deviceID string) (*Device, error)
+	AuthenticateDevice(ctx context.Context, deviceID string, secret string) (string, error)
+}
+
+type Controller struct {
+	service deviceService
+}
+
+func NewDeviceController(service deviceService) *Controller {
+	return &Controller{service: service}
+}
+
+type deviceURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type createDeviceRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+type renameDeviceRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+type shareDeviceRequest struct {
+	HouseholdID string `json:"household_id" binding:"required,uuid"`
+}
+
+// createPairingCodeRequest names the new device, or with DeviceID
+// attaches the board to a device that already exists
+type createPairingCodeRequest struct {
+	Name     string `json:"name" binding:"max=50"`
+	DeviceID string `json:"device_id" binding:"omitempty,uuid"`
+}
+
+type claimDeviceRequest struct {
+	Code   string `json:"code" binding:"required,max=16"`
+	Serial string `json:"serial" binding:"required,max=64,printascii"`
+}
+
+type deviceTokenRequest struct {
+	DeviceID     string `json:"device_id" binding:"required,uuid"`
+	DeviceSecret string `json:"device_secret" binding:"required,max=128"`
+}
+
+type deviceTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type pairingCodeResponse struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type claimDeviceResponse struct {
+	DeviceID     string `json:"device_id"`
+	Name         string `json:"name"`
+	DeviceSecret string `json:"device_secret"`
+}
+
+type deviceResponse struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	HouseholdID string    `json:"household_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (dc *Controller) CreateDevice(c *gin.Context) {
+	var request createDeviceRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid create device request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	device, err := dc.service.CreateDevice(ctx, userID, request.Name)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to create device")
+		return
+	}
+
+	slog.Info("device created successfully", "userID", userID, "deviceID", device.ID)
+	c.JSON(http.StatusCreated, toResponse(device))
+}
+
+func (dc *Controller) GetDevices(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	devices, err := dc.service.GetDevices(ctx, userID)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to list devices")
+		return
+	}
+
+	response := make([]deviceResponse, 0, len(devices))
+	for _, device := range devices {
+		response = append(response, toResponse(device))
+	}
+	c.JSON(http.StatusOK, gin.H{"devices": response})
+}
+
+func (dc *Controller) RenameDevice(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request renameDeviceRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid rename device request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	device, err := dc.service.RenameDevice(ctx, userID, uri.ID, request.Name)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to rename device")
+		return
+	}
+
+	slog.Info("device renamed successfully", "userID", userID, "deviceID", device.ID)
+	c.JSON(http.StatusOK, toResponse(device))
+}
+
+func (dc *Controller) DeleteDevice(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = dc.service.DeleteDevice(ctx, userID, uri.ID)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to delete device")
+		return
+	}
+
+	slog.Info("device deleted successfully", "userID", userID, "deviceID", uri.ID)
+	c.Status(http.StatusNoContent)
+}
+
+func (dc *Controller) ShareDevice(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request shareDeviceRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid share device request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	device, err := dc.service.ShareDevice(ctx, userID, uri.ID, request.HouseholdID)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to share device")
+		return
+	}
+
+	slog.Info("device shared successfully", "userID", userID, "deviceID", device.ID, "householdID", device.HouseholdID)
+	c.JSON(http.StatusOK, toResponse(device))
+}
+
+func (dc *Controller) UnshareDevice(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	device, err := dc.service.UnshareDevice(ctx, userID, uri.ID)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to unshare device")
+		return
+	}
+
+	slog.Info("device unshared successfully", "userID", userID, "deviceID", device.ID)
+	c.JSON(http.StatusOK, toResponse(device))
+}
+
+func (dc *Controller) CreatePairingCode(c *gin.Context) {
+	var request createPairingCodeRequest
+	// the body is optional, the device can be named later on
+	if c.Request.ContentLength != 0 {
+		err := c.ShouldBindJSON(&request)
+		if err != nil {
+			slog.Warn("invalid pairing code request payload", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	pairingCode, err := dc.service.CreatePairingCode(ctx, userID, request.Name, request.DeviceID)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to create pairing code")
+		return
+	}
+
+	slog.Info("pairing code created successfully", "userID", userID)
+	c.JSON(http.StatusCreated, pairingCodeResponse{
+		Code:      pairingCode.Code,
+		ExpiresAt: pairingCode.TTL,
+	})
+}
+
+func (dc *Controller) ClaimDevice(c *gin.Context) {
+	var request claimDeviceRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid claim device request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	device, secret, err := dc.service.ClaimDevice(ctx, request.Code, request.Serial)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPairingCode) {
+			slog.Warn("invalid pairing code", "serial", request.Serial)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, ErrSerialAlreadyClaimed) {
+			slog.Warn("serial already claimed", "serial", request.Serial)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		dc.handleDeviceError(c, err, "failed to claim device")
+		return
+	}
+
+	slog.Info("device claimed successfully", "userID", device.UserID, "deviceID", device.ID)
+	c.JSON(http.StatusCreated, claimDeviceResponse{
+		DeviceID:     device.ID,
+		Name:         device.Name,
+		DeviceSecret: secret,
+	})
+}
+
+func (dc *Controller) CreateDeviceToken(c *gin.Context) {
+	var request deviceTokenRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid device token request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	token, err := dc.service.AuthenticateDevice(ctx, request.DeviceID, request.DeviceSecret)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			slog.Warn("invalid device credentials", "deviceID", request.DeviceID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+		dc.handleDeviceError(c, err, "failed to authenticate device")
+		return
+	}
+
+	slog.Info("device authenticated successfully", "deviceID", request.DeviceID)
+	c.JSON(http.StatusOK, deviceTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(TokenTTL.Seconds()),
+	})
+}
+
+func (dc *Controller) GetCurrentDevice(c *gin.Context) {
+	ctx := c.Request.Context()
+	deviceID := c.GetString("deviceID")
+	device, err := dc.service.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		dc.handleDeviceError(c, err, "failed to get device")
+		return
+	}
+	c.JSON(http.StatusOK, toResponse(device))
+}
+
+func (dc *Controller) handleDeviceError(c *gin.Context, err error, message string) {
+	// a device owned by someone else is reported as not found,
+	// in this way we do not leak which device ids exist
+	if errors.Is(err, ErrDeviceNotFound) || errors.Is(err, ErrNotDeviceOwner) {
+		slog.Warn("device not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+		return
+	}
+	// the device is shared with the user, but its role is too low
+	if errors.Is(err, ErrPermissionDenied) {
+		slog.Warn("device action forbidden", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, household.ErrHouseholdNotFound) {
+		slog.Warn("household not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "household not found"})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
+
+func toResponse(device *Device) deviceResponse {
+	return deviceResponse{
+		ID:          device.ID,
+		Name:        device.Name,
+		HouseholdID: device.HouseholdID,
+		CreatedAt:   device.CreatedAt,
+	}
+}
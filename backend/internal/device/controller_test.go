@@ -0,0 +1,509 @@
+package device_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+/**
+ * newTestContext creates a gin.Context for an authenticated user,
+ * as if the request already went through the AuthMiddleware.
+ */
+func newTestContext(method, path string, body []byte, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = params
+	c.Set("userID", "user-1")
+	return c, w
+}
+
+const testDeviceID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+func TestController_CreateDevice(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockdeviceService)
+	}{
+		{
+			name:         "success",
+			body:         `{"name":"living room"}`,
+			expectedCode: http.StatusCreated,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().
+					CreateDevice(gomock.Any(), "user-1", "living room").
+					Return(&device.Device{ID: testDeviceID, UserID: "user-1", Name: "living room", CreatedAt: time.Now()}, nil)
+			},
+		},
+		{
+			name:         "missing_name",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "name_too_long",
+			body:         `{"name":"this is a very long device name that exceeds the fifty characters limit"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "timeout",
+			body:         `{"name":"living room"}`,
+			expectedCode: http.StatusRequestTimeout,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().CreateDevice(gomock.Any(), "user-1", "living room").Return(nil, context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockDeviceService)
+
+			dc := device.NewDeviceController(mockDeviceService)
+			c, w := newTestContext(http.MethodPost, "/devices", []byte(tt.body), nil)
+
+			dc.CreateDevice(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_GetDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDeviceService := mocks.NewMockdeviceService(ctrl)
+	mockDeviceService.EXPECT().
+		GetDevices(gomock.Any(), "user-1").
+		Return([]*device.Device{{ID: testDeviceID, UserID: "user-1", Name: "living room"}}, nil)
+
+	dc := device.NewDeviceController(mockDeviceService)
+	c, w := newTestContext(http.MethodGet, "/devices", nil, nil)
+
+	dc.GetDevices(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d want %d; body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(testDeviceID)) {
+		t.Errorf("expected body to contain the device id, got %s", w.Body.String())
+	}
+}
+
+func TestController_RenameDevice(t *testing.T) {
+	tests := []struct {
+		name         string
+		deviceID     string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockdeviceService)
+	}{
+		{
+			name:         "success",
+			deviceID:     testDeviceID,
+			body:         `{"name":"kitchen"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().
+					RenameDevice(gomock.Any(), "user-1", testDeviceID, "kitchen").
+					Return(&device.Device{ID: testDeviceID, UserID: "user-1", Name: "kitchen"}, nil)
+			},
+		},
+		{
+			name:         "invalid_id",
+			deviceID:     "not-a-uuid",
+			body:         `{"name":"kitchen"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "missing_name",
+			deviceID:     testDeviceID,
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "not_owner",
+			deviceID:     testDeviceID,
+			body:         `{"name":"kitchen"}`,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().
+					RenameDevice(gomock.Any(), "user-1", testDeviceID, "kitchen").
+					Return(nil, device.ErrNotDeviceOwner)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockDeviceService)
+
+			dc := device.NewDeviceController(mockDeviceService)
+			params := gin.Params{{Key: "id", Value: tt.deviceID}}
+			c, w := newTestContext(http.MethodPatch, "/devices/"+tt.deviceID, []byte(tt.body), params)
+
+			dc.RenameDevice(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_DeleteDevice(t *testing.T) {
+	tests := []struct {
+		name         string
+		deviceID     string
+		expectedCode int
+		setupMock    func(*mocks.MockdeviceService)
+	}{
+		{
+			name:         "success",
+			deviceID:     testDeviceID,
+			expectedCode: http.StatusNoContent,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().DeleteDevice(gomock.Any(), "user-1", testDeviceID).Return(nil)
+			},
+		},
+		{
+			name:         "not_found",
+			deviceID:     testDeviceID,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().DeleteDevice(gomock.Any(), "user-1", testDeviceID).Return(device.ErrDeviceNotFound)
+			},
+		},
+		{
+			name:         "shared_by_another_user",
+			deviceID:     testDeviceID,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().DeleteDevice(gomock.Any(), "user-1", testDeviceID).Return(device.ErrPermissionDenied)
+			},
+		},
+		{
+			name:         "invalid_id",
+			deviceID:     "42",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockDeviceService)
+
+			dc := device.NewDeviceController(mockDeviceService)
+			params := gin.Params{{Key: "id", Value: tt.deviceID}}
+			c, w := newTestContext(http.MethodDelete, "/devices/"+tt.deviceID, nil, params)
+
+			dc.DeleteDevice(c)
+
+			// c.Status does not flush the header on its own
+			c.Writer.WriteHeaderNow()
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_ShareDevice(t *testing.T) {
+	const householdID = "9b2f5c8e-3a41-4d6b-8f0e-2c7d1a9e4b53"
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockdeviceService)
+	}{
+		{
+			name:         "success",
+			body:         `{"household_id":"` + householdID + `"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().
+					ShareDevice(gomock.Any(), "user-1", testDeviceID, householdID).
+					Return(&device.Device{ID: testDeviceID, Name: "lamp", HouseholdID: householdID}, nil)
+			},
+		},
+		{
+			name:         "invalid_household_id",
+			body:         `{"household_id":"42"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "household_not_found",
+			body:         `{"household_id":"` + householdID + `"}`,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().ShareDevice(gomock.Any(), "user-1", testDeviceID, householdID).Return(nil, household.ErrHouseholdNotFound)
+			},
+		},
+		{
+			name:         "viewer",
+			body:         `{"household_id":"` + householdID + `"}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().ShareDevice(gomock.Any(), "user-1", testDeviceID, householdID).Return(nil, device.ErrPermissionDenied)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockDeviceService)
+
+			dc := device.NewDeviceController(mockDeviceService)
+			params := gin.Params{{Key: "id", Value: testDeviceID}}
+			c, w := newTestContext(http.MethodPut, "/devices/"+testDeviceID+"/household", []byte(tt.body), params)
+
+			dc.ShareDevice(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if tt.expectedCode == http.StatusOK && !bytes.Contains(w.Body.Bytes(), []byte(householdID)) {
+				t.Errorf("expected the household id in the response, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_UnshareDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDeviceService := mocks.NewMockdeviceService(ctrl)
+	mockDeviceService.EXPECT().
+		UnshareDevice(gomock.Any(), "user-1", testDeviceID).
+		Return(&device.Device{ID: testDeviceID, Name: "lamp"}, nil)
+
+	dc := device.NewDeviceController(mockDeviceService)
+	params := gin.Params{{Key: "id", Value: testDeviceID}}
+	c, w := newTestContext(http.MethodDelete, "/devices/"+testDeviceID+"/household", nil, params)
+
+	dc.UnshareDevice(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d want %d; body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("household_id")) {
+		t.Errorf("expected no household id in the response, got %s", w.Body.String())
+	}
+}
+
+func TestController_CreatePairingCode(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockdeviceService)
+	}{
+		{
+			name:         "new_device",
+			body:         `{"name":"living room"}`,
+			expectedCode: http.StatusCreated,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().CreatePairingCode(gomock.Any(), "user-1", "living room", "").
+					Return(&pairing_code.PairingCode{Code: "ABCD2345"}, nil)
+			},
+		},
+		{
+			name:         "existing_device",
+			body:         `{"device_id":"` + testDeviceID + `"}`,
+			expectedCode: http.StatusCreated,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().CreatePairingCode(gomock.Any(), "user-1", "", testDeviceID).
+					Return(&pairing_code.PairingCode{Code: "ABCD2345"}, nil)
+			},
+		},
+		{
+			name:         "invalid_device_id",
+			body:         `{"device_id":"kitchen"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "device_not_found",
+			body:         `{"device_id":"` + testDeviceID + `"}`,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().CreatePairingCode(gomock.Any(), "user-1", "", testDeviceID).Return(nil, device.ErrDeviceNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockDeviceService)
+
+			dc := device.NewDeviceController(mockDeviceService)
+			c, w := newTestContext(http.MethodPost, "/devices/pairing-codes", []byte(tt.body), nil)
+
+			dc.CreatePairingCode(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_ClaimDevice(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockdeviceService)
+	}{
+		{
+			name:         "success",
+			body:         `{"code":"ABCD2345","serial":"E6614103E7452D2F"}`,
+			expectedCode: http.StatusCreated,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().
+					ClaimDevice(gomock.Any(), "ABCD2345", "E6614103E7452D2F").
+					Return(&device.Device{ID: testDeviceID, UserID: "user-1", Name: "living room"}, "ds1.secret", nil)
+			},
+		},
+		{
+			name:         "missing_serial",
+			body:         `{"code":"ABCD2345"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "invalid_code",
+			body:         `{"code":"ABCD2345","serial":"E6614103E7452D2F"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().ClaimDevice(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, "", device.ErrInvalidPairingCode)
+			},
+		},
+		{
+			name:         "serial_already_claimed",
+			body:         `{"code":"ABCD2345","serial":"E6614103E7452D2F"}`,
+			expectedCode: http.StatusConflict,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().ClaimDevice(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, "", device.ErrSerialAlreadyClaimed)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockDeviceService)
+
+			dc := device.NewDeviceController(mockDeviceService)
+			c, w := newTestContext(http.MethodPost, "/devices/claim", []byte(tt.body), nil)
+
+			dc.ClaimDevice(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_CreateDeviceToken(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockdeviceService)
+	}{
+		{
+			name:         "success",
+			body:         `{"device_id":"` + testDeviceID + `","device_secret":"ds1.secret"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().AuthenticateDevice(gomock.Any(), testDeviceID, "ds1.secret").Return("device_jwt", nil)
+			},
+		},
+		{
+			name:         "invalid_device_id",
+			body:         `{"device_id":"42","device_secret":"ds1.secret"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockdeviceService) {},
+		},
+		{
+			name:         "invalid_credentials",
+			body:         `{"device_id":"` + testDeviceID + `","device_secret":"ds1.wrong"}`,
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MockdeviceService) {
+				m.EXPECT().AuthenticateDevice(gomock.Any(), testDeviceID, "ds1.wrong").Return("", device.ErrInvalidCredentials)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockDeviceService)
+
+			dc := device.NewDeviceController(mockDeviceService)
+			c, w := newTestContext(http.MethodPost, "/devices/token", []byte(tt.body), nil)
+
+			dc.CreateDeviceToken(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	device "github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	pairing_code "github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockdeviceService is a mock of deviceService interface.
+type MockdeviceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeviceServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockdeviceServiceMockRecorder is the mock recorder for MockdeviceService.
+type MockdeviceServiceMockRecorder struct {
+	mock *MockdeviceService
+}
+
+// NewMockdeviceService creates a new mock instance.
+func NewMockdeviceService(ctrl *gomock.Controller) *MockdeviceService {
+	mock := &MockdeviceService{ctrl: ctrl}
+	mock.recorder = &MockdeviceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeviceService) EXPECT() *MockdeviceServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthenticateDevice mocks base method.
+func (m *MockdeviceService) AuthenticateDevice(ctx context.Context, deviceID, secret string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateDevice", ctx, deviceID, secret)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateDevice indicates an expected call of AuthenticateDevice.
+func (mr *MockdeviceServiceMockRecorder) AuthenticateDevice(ctx, deviceID, secret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateDevice", reflect.TypeOf((*MockdeviceService)(nil).AuthenticateDevice), ctx, deviceID, secret)
+}
+
+// ClaimDevice mocks base method.
+func (m *MockdeviceService) ClaimDevice(ctx context.Context, code, serial string) (*device.Device, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDevice", ctx, code, serial)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ClaimDevice indicates an expected call of ClaimDevice.
+func (mr *MockdeviceServiceMockRecorder) ClaimDevice(ctx, code, serial any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDevice", reflect.TypeOf((*MockdeviceService)(nil).ClaimDevice), ctx, code, serial)
+}
+
+// CreateDevice mocks base method.
+func (m *MockdeviceService) CreateDevice(ctx context.Context, userID, name string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDevice", ctx, userID, name)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDevice indicates an expected call of CreateDevice.
+func (mr *MockdeviceServiceMockRecorder) CreateDevice(ctx, userID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDevice", reflect.TypeOf((*MockdeviceService)(nil).CreateDevice), ctx, userID, name)
+}
+
+// CreatePairingCode mocks base method.
+func (m *MockdeviceService) CreatePairingCode(ctx context.Context, userID, deviceName, deviceID string) (*pairing_code.PairingCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePairingCode", ctx, userID, deviceName, deviceID)
+	ret0, _ := ret[0].(*pairing_code.PairingCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePairingCode indicates an expected call of CreatePairingCode.
+func (mr *MockdeviceServiceMockRecorder) CreatePairingCode(ctx, userID, deviceName, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePairingCode", reflect.TypeOf((*MockdeviceService)(nil).CreatePairingCode), ctx, userID, deviceName, deviceID)
+}
+
+// DeleteDevice mocks base method.
+func (m *MockdeviceService) DeleteDevice(ctx context.Context, userID, deviceID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDevice", ctx, userID, deviceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDevice indicates an expected call of DeleteDevice.
+func (mr *MockdeviceServiceMockRecorder) DeleteDevice(ctx, userID, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDevice", reflect.TypeOf((*MockdeviceService)(nil).DeleteDevice), ctx, userID, deviceID)
+}
+
+// GetDevice mocks base method.
+func (m *MockdeviceService) GetDevice(ctx context.Context, userID, deviceID string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDevice", ctx, userID, deviceID)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDevice indicates an expected call of GetDevice.
+func (mr *MockdeviceServiceMockRecorder) GetDevice(ctx, userID, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevice", reflect.TypeOf((*MockdeviceService)(nil).GetDevice), ctx, userID, deviceID)
+}
+
+// GetDeviceByID mocks base method.
+func (m *MockdeviceService) GetDeviceByID(ctx context.Context, deviceID string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceByID", ctx, deviceID)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceByID indicates an expected call of GetDeviceByID.
+func (mr *MockdeviceServiceMockRecorder) GetDeviceByID(ctx, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceByID", reflect.TypeOf((*MockdeviceService)(nil).GetDeviceByID), ctx, deviceID)
+}
+
+// GetDevices mocks base method.
+func (m *MockdeviceService) GetDevices(ctx context.Context, userID string) ([]*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDevices", ctx, userID)
+	ret0, _ := ret[0].([]*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDevices indicates an expected call of GetDevices.
+func (mr *MockdeviceServiceMockRecorder) GetDevices(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevices", reflect.TypeOf((*MockdeviceService)(nil).GetDevices), ctx, userID)
+}
+
+// RenameDevice mocks base method.
+func (m *MockdeviceService) RenameDevice(ctx context.Context, userID, deviceID, name string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameDevice", ctx, userID, deviceID, name)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameDevice indicates an expected call of RenameDevice.
+func (mr *MockdeviceServiceMockRecorder) RenameDevice(ctx, userID, deviceID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameDevice", reflect.TypeOf((*MockdeviceService)(nil).RenameDevice), ctx, userID, deviceID, name)
+}
+
+// ShareDevice mocks base method.
+func (m *MockdeviceService) ShareDevice(ctx context.Context, userID, deviceID, householdID string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShareDevice", ctx, userID, deviceID, householdID)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ShareDevice indicates an expected call of ShareDevice.
+func (mr *MockdeviceServiceMockRecorder) ShareDevice(ctx, userID, deviceID, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShareDevice", reflect.TypeOf((*MockdeviceService)(nil).ShareDevice), ctx, userID, deviceID, householdID)
+}
+
+// UnshareDevice mocks base method.
+func (m *MockdeviceService) UnshareDevice(ctx context.Context, userID, deviceID string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnshareDevice", ctx, userID, deviceID)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnshareDevice indicates an expected call of UnshareDevice.
+func (mr *MockdeviceServiceMockRecorder) UnshareDevice(ctx, userID, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnshareDevice", reflect.TypeOf((*MockdeviceService)(nil).UnshareDevice), ctx, userID, deviceID)
+}
@@ -0,0 +1,305 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	device "github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	household "github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	pairing_code "github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	jwt "github.com/golang-jwt/jwt/v5"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockdeviceRepository is a mock of deviceRepository interface.
+type MockdeviceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeviceRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockdeviceRepositoryMockRecorder is the mock recorder for MockdeviceRepository.
+type MockdeviceRepositoryMockRecorder struct {
+	mock *MockdeviceRepository
+}
+
+// NewMockdeviceRepository creates a new mock instance.
+func NewMockdeviceRepository(ctrl *gomock.Controller) *MockdeviceRepository {
+	mock := &MockdeviceRepository{ctrl: ctrl}
+	mock.recorder = &MockdeviceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeviceRepository) EXPECT() *MockdeviceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateOne mocks base method.
+func (m *MockdeviceRepository) CreateOne(ctx context.Context, arg1 *device.Device) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockdeviceRepositoryMockRecorder) CreateOne(ctx, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockdeviceRepository)(nil).CreateOne), ctx, arg1)
+}
+
+// DeleteOneByID mocks base method.
+func (m *MockdeviceRepository) DeleteOneByID(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOneByID", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOneByID indicates an expected call of DeleteOneByID.
+func (mr *MockdeviceRepositoryMockRecorder) DeleteOneByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneByID", reflect.TypeOf((*MockdeviceRepository)(nil).DeleteOneByID), ctx, id)
+}
+
+// GetAllAccessibleByUserID mocks base method.
+func (m *MockdeviceRepository) GetAllAccessibleByUserID(ctx context.Context, userID string) ([]*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllAccessibleByUserID", ctx, userID)
+	ret0, _ := ret[0].([]*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllAccessibleByUserID indicates an expected call of GetAllAccessibleByUserID.
+func (mr *MockdeviceRepositoryMockRecorder) GetAllAccessibleByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllAccessibleByUserID", reflect.TypeOf((*MockdeviceRepository)(nil).GetAllAccessibleByUserID), ctx, userID)
+}
+
+// GetOneByID mocks base method.
+func (m *MockdeviceRepository) GetOneByID(ctx context.Context, id string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByID", ctx, id)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByID indicates an expected call of GetOneByID.
+func (mr *MockdeviceRepositoryMockRecorder) GetOneByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByID", reflect.TypeOf((*MockdeviceRepository)(nil).GetOneByID), ctx, id)
+}
+
+// GetOneBySerial mocks base method.
+func (m *MockdeviceRepository) GetOneBySerial(ctx context.Context, serial string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneBySerial", ctx, serial)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneBySerial indicates an expected call of GetOneBySerial.
+func (mr *MockdeviceRepositoryMockRecorder) GetOneBySerial(ctx, serial any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneBySerial", reflect.TypeOf((*MockdeviceRepository)(nil).GetOneBySerial), ctx, serial)
+}
+
+// UpdateOneCredentials mocks base method.
+func (m *MockdeviceRepository) UpdateOneCredentials(ctx context.Context, id, serial, secretHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOneCredentials", ctx, id, serial, secretHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOneCredentials indicates an expected call of UpdateOneCredentials.
+func (mr *MockdeviceRepositoryMockRecorder) UpdateOneCredentials(ctx, id, serial, secretHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOneCredentials", reflect.TypeOf((*MockdeviceRepository)(nil).UpdateOneCredentials), ctx, id, serial, secretHash)
+}
+
+// UpdateOneHousehold mocks base method.
+func (m *MockdeviceRepository) UpdateOneHousehold(ctx context.Context, id, householdID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOneHousehold", ctx, id, householdID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOneHousehold indicates an expected call of UpdateOneHousehold.
+func (mr *MockdeviceRepositoryMockRecorder) UpdateOneHousehold(ctx, id, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOneHousehold", reflect.TypeOf((*MockdeviceRepository)(nil).UpdateOneHousehold), ctx, id, householdID)
+}
+
+// UpdateOneName mocks base method.
+func (m *MockdeviceRepository) UpdateOneName(ctx context.Context, id, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOneName", ctx, id, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOneName indicates an expected call of UpdateOneName.
+func (mr *MockdeviceRepositoryMockRecorder) UpdateOneName(ctx, id, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOneName", reflect.TypeOf((*MockdeviceRepository)(nil).UpdateOneName), ctx, id, name)
+}
+
+// UpdateOneSecretHash mocks base method.
+func (m *MockdeviceRepository) UpdateOneSecretHash(ctx context.Context, id, secretHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOneSecretHash", ctx, id, secretHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOneSecretHash indicates an expected call of UpdateOneSecretHash.
+func (mr *MockdeviceRepositoryMockRecorder) UpdateOneSecretHash(ctx, id, secretHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOneSecretHash", reflect.TypeOf((*MockdeviceRepository)(nil).UpdateOneSecretHash), ctx, id, secretHash)
+}
+
+// MockhouseholdRepository is a mock of householdRepository interface.
+type MockhouseholdRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockhouseholdRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockhouseholdRepositoryMockRecorder is the mock recorder for MockhouseholdRepository.
+type MockhouseholdRepositoryMockRecorder struct {
+	mock *MockhouseholdRepository
+}
+
+// NewMockhouseholdRepository creates a new mock instance.
+func NewMockhouseholdRepository(ctrl *gomock.Controller) *MockhouseholdRepository {
+	mock := &MockhouseholdRepository{ctrl: ctrl}
+	mock.recorder = &MockhouseholdRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockhouseholdRepository) EXPECT() *MockhouseholdRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetRole mocks base method.
+func (m *MockhouseholdRepository) GetRole(ctx context.Context, householdID, userID string) (household.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRole", ctx, householdID, userID)
+	ret0, _ := ret[0].(household.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRole indicates an expected call of GetRole.
+func (mr *MockhouseholdRepositoryMockRecorder) GetRole(ctx, householdID, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRole", reflect.TypeOf((*MockhouseholdRepository)(nil).GetRole), ctx, householdID, userID)
+}
+
+// MockpairingCodeRepository is a mock of pairingCodeRepository interface.
+type MockpairingCodeRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockpairingCodeRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockpairingCodeRepositoryMockRecorder is the mock recorder for MockpairingCodeRepository.
+type MockpairingCodeRepositoryMockRecorder struct {
+	mock *MockpairingCodeRepository
+}
+
+// NewMockpairingCodeRepository creates a new mock instance.
+func NewMockpairingCodeRepository(ctrl *gomock.Controller) *MockpairingCodeRepository {
+	mock := &MockpairingCodeRepository{ctrl: ctrl}
+	mock.recorder = &MockpairingCodeRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockpairingCodeRepository) EXPECT() *MockpairingCodeRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ConsumeOneByCodeHash mocks base method.
+func (m *MockpairingCodeRepository) ConsumeOneByCodeHash(ctx context.Context, codeHash string) (*pairing_code.PairingCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOneByCodeHash", ctx, codeHash)
+	ret0, _ := ret[0].(*pairing_code.PairingCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOneByCodeHash indicates an expected call of ConsumeOneByCodeHash.
+func (mr *MockpairingCodeRepositoryMockRecorder) ConsumeOneByCodeHash(ctx, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOneByCodeHash", reflect.TypeOf((*MockpairingCodeRepository)(nil).ConsumeOneByCodeHash), ctx, codeHash)
+}
+
+// CreateOne mocks base method.
+func (m *MockpairingCodeRepository) CreateOne(ctx context.Context, pairingCode *pairing_code.PairingCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, pairingCode)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockpairingCodeRepositoryMockRecorder) CreateOne(ctx, pairingCode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockpairingCodeRepository)(nil).CreateOne), ctx, pairingCode)
+}
+
+// MocktokenSigner is a mock of tokenSigner interface.
+type MocktokenSigner struct {
+	ctrl     *gomock.Controller
+	recorder *MocktokenSignerMockRecorder
+	isgomock struct{}
+}
+
+// MocktokenSignerMockRecorder is the mock recorder for MocktokenSigner.
+type MocktokenSignerMockRecorder struct {
+	mock *MocktokenSigner
+}
+
+// NewMocktokenSigner creates a new mock instance.
+func NewMocktokenSigner(ctrl *gomock.Controller) *MocktokenSigner {
+	mock := &MocktokenSigner{ctrl: ctrl}
+	mock.recorder = &MocktokenSignerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktokenSigner) EXPECT() *MocktokenSignerMockRecorder {
+	return m.recorder
+}
+
+// Sign mocks base method.
+func (m *MocktokenSigner) Sign(claims jwt.Claims) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sign", claims)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sign indicates an expected call of Sign.
+func (mr *MocktokenSignerMockRecorder) Sign(claims any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sign", reflect.TypeOf((*MocktokenSigner)(nil).Sign), claims)
+}
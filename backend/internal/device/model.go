@@ -0,0 +1,16 @@
+package device
+
+import (
+	"time"
+)
+
+type Device struct {
+	ID     string
+	UserID string
+	// HouseholdID is empty when the device is not shared
+	HouseholdID string
+	Name        string
+	Serial      string
+	SecretHash  string
+	CreatedAt   time.Time
+}
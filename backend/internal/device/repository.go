@@ -0,0 +1,263 @@
+package device
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+type DeviceEntity struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	HouseholdID uuid.NullUUID
+	Name        string
+	Serial      sql.NullString
+	SecretHash  sql.NullString
+	CreatedAt   time.Time
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewDeviceRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateOne(ctx context.Context, device *Device) error {
+	deviceEntity, err := toEntity(device)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO device(id, user_id, name, serial, secret_hash, created_at)
+		VALUES($1, $2, $3, $4, $5, $6)
+	`
+	_, err = r.db.ExecContext(ctx, query, deviceEntity.ID, deviceEntity.UserID, deviceEntity.Name, deviceEntity.Serial, deviceEntity.SecretHash, deviceEntity.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// the caller needs to know the generated id to return it to the client
+	device.ID = deviceEntity.ID.String()
+	device.CreatedAt = deviceEntity.CreatedAt
+	return nil
+}
+
+func (r *repository) GetOneByID(ctx context.Context, id string) (*Device, error) {
+	// an id that is not a valid uuid cannot exist in the table
+	deviceID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil
+	}
+	query := `
+		SELECT id, user_id, household_id, name, serial, secret_hash, created_at
+		FROM device
+		WHERE id = $1
+	`
+	row := r.db.QueryRowContext(ctx, query, deviceID)
+
+	var device DeviceEntity
+	err = row.Scan(&device.ID, &device.UserID, &device.HouseholdID, &device.Name, &device.Serial, &device.SecretHash, &device.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return device.toDevice(), nil
+}
+
+func (r *repository) GetOneBySerial(ctx context.Context, serial string) (*Device, error) {
+	query := `
+		SELECT id, user_id, household_id, name, serial, secret_hash, created_at
+		FROM device
+		WHERE serial = $1
+	`
+	row := r.db.QueryRowContext(ctx, query, serial)
+
+	var device DeviceEntity
+	err := row.Scan(&device.ID, &device.UserID, &device.HouseholdID, &device.Name, &device.Serial, &device.SecretHash, &device.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return device.toDevice(), nil
+}
+
+// GetAllAccessibleByUserID returns the devices added by the user
+// and the ones shared with the households it is a member of
+func (r *repository) GetAllAccessibleByUserID(ctx context.Context, userID string) ([]*Device, error) {
+	query := `
+		SELECT id, user_id, household_id, name, serial, secret_hash, created_at
+		FROM device
+		WHERE user_id = $1
+			OR household_id IN (SELECT household_id FROM household_member WHERE user_id = $1)
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []*Device{}
+	for rows.Next() {
+		var device DeviceEntity
+		err := rows.Scan(&device.ID, &device.UserID, &device.HouseholdID, &device.Name, &device.Serial, &device.SecretHash, &device.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device.toDevice())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *repository) UpdateOneName(ctx context.Context, id string, name string) error {
+	query := `
+		UPDATE device
+		SET name = $2
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, name)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (r *repository) UpdateOneSecretHash(ctx context.Context, id string, secretHash string) error {
+	query := `
+		UPDATE device
+		SET secret_hash = $2
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, secretHash)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// UpdateOneCredentials attaches a board to a device created without one,
+// or replaces the board of the device
+func (r *repository) UpdateOneCredentials(ctx context.Context, id string, serial string, secretHash string) error {
+	query := `
+		UPDATE device
+		SET serial = $2, secret_hash = $3
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, serial, secretHash)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// UpdateOneHousehold shares the device with the household,
+// an empty householdID stops sharing it
+func (r *repository) UpdateOneHousehold(ctx context.Context, id string, householdID string) error {
+	var household uuid.NullUUID
+	if householdID != "" {
+		parsed, err := uuid.Parse(householdID)
+		if err != nil {
+			return err
+		}
+		household = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+	query := `
+		UPDATE device
+		SET household_id = $2
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, household)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+func (r *repository) DeleteOneByID(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM device
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result)
+}
+
+// checkAffected reports ErrDeviceNotFound when a statement did not touch any row,
+// this happens when the device was deleted between the read and the write
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+func (de *DeviceEntity) toDevice() *Device {
+	device := &Device{
+		ID:         de.ID.String(),
+		UserID:     de.UserID.String(),
+		Name:       de.Name,
+		Serial:     de.Serial.String,
+		SecretHash: de.SecretHash.String,
+		CreatedAt:  de.CreatedAt,
+	}
+	if de.HouseholdID.Valid {
+		device.HouseholdID = de.HouseholdID.UUID.String()
+	}
+	return device
+}
+
+func toEntity(device *Device) (*DeviceEntity, error) {
+	var id uuid.UUID
+	if device.ID == "" {
+		id = uuid.New()
+	} else {
+		var err error
+		id, err = uuid.Parse(device.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	userID, err := uuid.Parse(device.UserID)
+	if err != nil {
+		return nil, err
+	}
+	createdAt := device.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+	// devices created from the app have no board yet, so we store NULL
+	// instead of an empty string that would break the unique constraint
+	return &DeviceEntity{
+		ID:         id,
+		UserID:     userID,
+		Name:       device.Name,
+		Serial:     sql.NullString{String: device.Serial, Valid: device.Serial != ""},
+		SecretHash: sql.NullString{String: device.SecretHash, Valid: device.SecretHash != ""},
+		CreatedAt:  createdAt,
+	}, nil
+}
@@ -0,0 +1,172 @@
+package device
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	// the unit tests of this package do not need a container,
+	// so with -short we avoid starting it at all
+	flag.Parse()
+	if !testing.Short() {
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+// createTestUser inserts the owner of the devices, since device.user_id is a foreign key
+func createTestUser(ctx context.Context, t *testing.T) string {
+	t.Helper()
+	id := uuid.New()
+	_, err := testPostgresDB.ExecContext(ctx,
+		"INSERT INTO user_account(id, username, email, password, name, surname) VALUES($1, $2, $3, $4, $5, $6)",
+		id, id.String()[:8], id.String()+"@example.com", "hash", "test", "user",
+	)
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return id.String()
+}
+
+func TestRepository_CreateAndGet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewDeviceRepository(testPostgresDB)
+	userID := createTestUser(ctx, t)
+
+	device := &Device{UserID: userID, Name: "living room"}
+	if err := repo.CreateOne(ctx, device); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+	if device.ID == "" {
+		t.Fatalf("expected CreateOne to set the device id")
+	}
+
+	got, err := repo.GetOneByID(ctx, device.ID)
+	if err != nil {
+		t.Fatalf("GetOneByID() error = %v", err)
+	}
+	if got == nil || got.UserID != userID || got.Name != "living room" {
+		t.Errorf("GetOneByID() = %+v", got)
+	}
+
+	devices, err := repo.GetAllAccessibleByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetAllAccessibleByUserID() error = %v", err)
+	}
+	if len(devices) != 1 {
+		t.Errorf("expected 1 device, got %d", len(devices))
+	}
+
+	missing, err := repo.GetOneByID(ctx, uuid.NewString())
+	if err != nil || missing != nil {
+		t.Errorf("GetOneByID() on missing device = %+v, %v", missing, err)
+	}
+}
+
+func TestRepository_UpdateAndDelete(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewDeviceRepository(testPostgresDB)
+	userID := createTestUser(ctx, t)
+
+	device := &Device{UserID: userID, Name: "living room"}
+	if err := repo.CreateOne(ctx, device); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+
+	if err := repo.UpdateOneName(ctx, device.ID, "kitchen"); err != nil {
+		t.Fatalf("UpdateOneName() error = %v", err)
+	}
+	got, _ := repo.GetOneByID(ctx, device.ID)
+	if got == nil || got.Name != "kitchen" {
+		t.Errorf("expected renamed device, got %+v", got)
+	}
+
+	if err := repo.UpdateOneCredentials(ctx, device.ID, "E6614103E7452D31", "hash"); err != nil {
+		t.Fatalf("UpdateOneCredentials() error = %v", err)
+	}
+	got, _ = repo.GetOneBySerial(ctx, "E6614103E7452D31")
+	if got == nil || got.ID != device.ID || got.SecretHash != "hash" {
+		t.Errorf("expected the board attached to the device, got %+v", got)
+	}
+
+	if err := repo.DeleteOneByID(ctx, device.ID); err != nil {
+		t.Fatalf("DeleteOneByID() error = %v", err)
+	}
+	if err := repo.DeleteOneByID(ctx, device.ID); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound deleting twice, got %v", err)
+	}
+	if err := repo.UpdateOneName(ctx, device.ID, "garage"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound renaming a deleted device, got %v", err)
+	}
+}
+
+func TestRepository_SharedWithHousehold(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewDeviceRepository(testPostgresDB)
+	householdRepo := household.NewHouseholdRepository(testPostgresDB)
+	ownerID := createTestUser(ctx, t)
+	memberID := createTestUser(ctx, t)
+
+	home := &household.Household{Name: "home"}
+	if err := householdRepo.CreateOne(ctx, home, ownerID); err != nil {
+		t.Fatalf("CreateOne() household error = %v", err)
+	}
+	device := &Device{UserID: ownerID, Name: "living room"}
+	if err := repo.CreateOne(ctx, device); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+	if err := repo.UpdateOneHousehold(ctx, device.ID, home.ID); err != nil {
+		t.Fatalf("UpdateOneHousehold() error = %v", err)
+	}
+
+	// the device is not visible to the user until it joins the household
+	devices, err := repo.GetAllAccessibleByUserID(ctx, memberID)
+	if err != nil || len(devices) != 0 {
+		t.Fatalf("expected no devices before joining, got %d, %v", len(devices), err)
+	}
+	_, err = testPostgresDB.ExecContext(ctx,
+		"INSERT INTO household_member(household_id, user_id, role) VALUES($1, $2, 'viewer')",
+		home.ID, memberID,
+	)
+	if err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	devices, err = repo.GetAllAccessibleByUserID(ctx, memberID)
+	if err != nil || len(devices) != 1 || devices[0].HouseholdID != home.ID {
+		t.Fatalf("expected the shared device, got %+v, %v", devices, err)
+	}
+
+	if err := repo.UpdateOneHousehold(ctx, device.ID, ""); err != nil {
+		t.Fatalf("UpdateOneHousehold() error = %v", err)
+	}
+	got, _ := repo.GetOneByID(ctx, device.ID)
+	if got == nil || got.HouseholdID != "" {
+		t.Errorf("expected the device to not be shared, got %+v", got)
+	}
+	if err := repo.UpdateOneHousehold(ctx, uuid.NewString(), home.ID); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound, got %v", err)
+	}
+}
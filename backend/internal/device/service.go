@@ -0,0 +1,431 @@
+package device
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// TokenType and TokenAudience mark a JWT as issued to a board,
+	// they are checked by the middlewares to keep user and device tokens apart
+	TokenType     = "device"
+	TokenAudience = "autolight-device"
+	TokenTTL      = time.Hour
+
+	deviceSecretVersion = "ds1"
+	pairingCodeLength   = 8
+	pairingCodeTTL      = 10 * time.Minute
+	// the alphabet has no 0/O and 1/I so that the code is easy to type,
+	// and it has 32 symbols so that every random byte maps without bias
+	pairingCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	ErrNotDeviceOwner       = errors.New("device does not belong to the user")
+	ErrPermissionDenied     = errors.New("the role of the user does not allow the action on the device")
+	ErrInvalidPairingCode   = errors.New("invalid or expired pairing code")
+	ErrSerialAlreadyClaimed = errors.New("serial already claimed by another user")
+	ErrInvalidCredentials   = errors.New("invalid device credentials")
+)
+
+// Permission is what a user wants to do with a device
+type Permission int
+
+const (
+	// PermissionView allows to read the target, the readings and the live updates
+	PermissionView Permission = iota
+	// PermissionControl allows to change the target and to send commands
+	PermissionControl
+	// PermissionManage allows to rename, delete and stop sharing the device
+	PermissionManage
+)
+
+// the lowest household role that grants each permission,
+// the user that added the device has all of them
+var permissionRoles = map[Permission]household.Role{
+	PermissionView:    household.RoleViewer,
+	PermissionControl: household.RoleMember,
+	PermissionManage:  household.RoleAdmin,
+}
+
+type deviceRepository interface {
+	CreateOne(ctx context.Context, device *Device) error
+	GetOneByID(ctx context.Context, id string) (*Device, error)
+	GetAllAccessibleByUserID(ctx context.Context, userID string) ([]*Device, error)
+	GetOneBySerial(ctx context.Context, serial string) (*Device, error)
+	UpdateOneName(ctx context.Context, id string, name string) error
+	UpdateOneSecretHash(ctx context.Context, id string, secretHash string) error
+	UpdateOneCredentials(ctx context.Context, id string, serial string, secretHash string) error
+	UpdateOneHousehold(ctx context.Context, id string, householdID string) error
+	DeleteOneByID(ctx context.Context, id string) error
+}
+
+// householdRepository tells the role of a user in the household a device is shared with
+type householdRepository interface {
+	GetRole(ctx context.Context, householdID string, userID string) (household.Role, error)
+}
+
+type pairingCodeRepository interface {
+	CreateOne(ctx context.Context, pairingCode *pairing_code.PairingCode) error
+	ConsumeOneByCodeHash(ctx context.Context, codeHash string) (*pairing_code.PairingCode, error)
+}
+
+// tokenSigner signs the device tokens with the same keys of the user tokens
+type tokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+}
+
+type service struct {
+	deviceRepo      deviceRepository
+	pairingCodeRepo pairingCodeRepository
+	householdRepo   householdRepository
+	signer          tokenSigner
+	// issuer is the iss claim of the device tokens
+	issuer string
+}
+
+func NewDeviceService(deviceRepo deviceRepository, pairingCodeRepo pairingCodeRepository, householdRepo householdRepository, signer tokenSigner, issuer string) *service {
+	return &service{
+		deviceRepo:      deviceRepo,
+		pairingCodeRepo: pairingCodeRepo,
+		householdRepo:   householdRepo,
+		signer:          signer,
+		issuer:          issuer,
+	}
+}
+
+func (s *service) CreateDevice(ctx context.Context, userID string, name string) (*Device, error) {
+	device := &Device{
+		UserID: userID,
+		Name:   name,
+	}
+	err := s.deviceRepo.CreateOne(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// GetDevices returns the devices added by the user and the ones shared with it
+func (s *service) GetDevices(ctx context.Context, userID string) ([]*Device, error) {
+	return s.deviceRepo.GetAllAccessibleByUserID(ctx, userID)
+}
+
+func (s *service) GetDevice(ctx context.Context, userID string, deviceID string) (*Device, error) {
+	return s.AuthorizeDevice(ctx, userID, deviceID, PermissionView)
+}
+
+// AuthorizeDevice returns the device when the user has the permission on it, every
+// endpoint that acts on a device of a user goes through here
+func (s *service) AuthorizeDevice(ctx context.Context, userID string, deviceID string, permission Permission) (*Device, error) {
+	device, err := s.deviceRepo.GetOneByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	if device.UserID == userID {
+		return device, nil
+	}
+
+	// a device that is not shared with the user is reported as not owned,
+	// the controller decides how much of this to expose to the client
+	if device.HouseholdID == "" {
+		return nil, ErrNotDeviceOwner
+	}
+	role, err := s.householdRepo.GetRole(ctx, device.HouseholdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrNotDeviceOwner
+	}
+	if !role.AtLeast(permissionRoles[permission]) {
+		return nil, ErrPermissionDenied
+	}
+	return device, nil
+}
+
+// ShareDevice shares a device added by the user with a household it is a member of,
+// a viewer cannot share its devices since it could not control them
+func (s *service) ShareDevice(ctx context.Context, userID string, deviceID string, householdID string) (*Device, error) {
+	device, err := s.AuthorizeDevice(ctx, userID, deviceID, PermissionView)
+	if err != nil {
+		return nil, err
+	}
+	// the members of a household can only share their own devices
+	if device.UserID != userID {
+		return nil, ErrPermissionDenied
+	}
+
+	role, err := s.householdRepo.GetRole(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, household.ErrHouseholdNotFound
+	}
+	if !role.AtLeast(household.RoleMember) {
+		return nil, ErrPermissionDenied
+	}
+
+	err = s.deviceRepo.UpdateOneHousehold(ctx, device.ID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	device.HouseholdID = householdID
+	return device, nil
+}
+
+// UnshareDevice stops sharing the device, it can be done by
+// the user that added it and by the admins of the household
+func (s *service) UnshareDevice(ctx context.Context, userID string, deviceID string) (*Device, error) {
+	device, err := s.AuthorizeDevice(ctx, userID, deviceID, PermissionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.deviceRepo.UpdateOneHousehold(ctx, device.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	device.HouseholdID = ""
+	return device, nil
+}
+
+func (s *service) RenameDevice(ctx context.Context, userID string, deviceID string, name string) (*Device, error) {
+	device, err := s.AuthorizeDevice(ctx, userID, deviceID, PermissionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.deviceRepo.UpdateOneName(ctx, device.ID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	device.Name = name
+	return device, nil
+}
+
+func (s *service) DeleteDevice(ctx context.Context, userID string, deviceID string) error {
+	device, err := s.AuthorizeDevice(ctx, userID, deviceID, PermissionManage)
+	if err != nil {
+		return err
+	}
+	// the readings would be lost as well, so an admin of the
+	// household can only stop sharing the device of another user
+	if device.UserID != userID {
+		return ErrPermissionDenied
+	}
+	return s.deviceRepo.DeleteOneByID(ctx, device.ID)
+}
+
+// CreatePairingCode returns the code typed on the board, with a deviceID the board
+// is attached to that device of the user, otherwise a new device is created
+func (s *service) CreatePairingCode(ctx context.Context, userID string, deviceName string, deviceID string) (*pairing_code.PairingCode, error) {
+	if deviceID != "" {
+		// as for the delete, the board is replaced only by the user that added the device
+		device, err := s.AuthorizeDevice(ctx, userID, deviceID, PermissionManage)
+		if err != nil {
+			return nil, err
+		}
+		if device.UserID != userID {
+			return nil, ErrPermissionDenied
+		}
+	}
+
+	// the code is typed by hand on the board setup page, so it is short
+	// and it is protected by the short TTL and by being single-use
+	bytes := make([]byte, pairingCodeLength)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return nil, err
+	}
+	code := make([]byte, pairingCodeLength)
+	for i, b := range bytes {
+		code[i] = pairingCodeAlphabet[int(b)%len(pairingCodeAlphabet)]
+	}
+
+	pairingCode := &pairing_code.PairingCode{
+		Code:       string(code),
+		CodeHash:   hashSecret(string(code)),
+		UserID:     userID,
+		DeviceName: deviceName,
+		DeviceID:   deviceID,
+		TTL:        time.Now().Add(pairingCodeTTL),
+	}
+
+	err = s.pairingCodeRepo.CreateOne(ctx, pairingCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return pairingCode, nil
+}
+
+func (s *service) ClaimDevice(ctx context.Context, code string, serial string) (*Device, string, error) {
+	// be lenient with the way the code has been typed
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	// the code is consumed before anything else, so it cannot be reused
+	// even if the claim fails later on
+	pairingCode, err := s.pairingCodeRepo.ConsumeOneByCodeHash(ctx, hashSecret(code))
+	if err != nil {
+		if errors.Is(err, pairing_code.ErrCodeHashNotFound) {
+			return nil, "", ErrInvalidPairingCode
+		}
+		return nil, "", err
+	}
+
+	secret, secretHash, err := generateDeviceSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	device, err := s.deviceRepo.GetOneBySerial(ctx, serial)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if pairingCode.DeviceID != "" {
+		return s.attachBoard(ctx, pairingCode, device, serial, secret, secretHash)
+	}
+
+	// a board that is paired again by the same user (e.g. after a factory reset)
+	// keeps its device and only gets a new credential
+	if device != nil {
+		if device.UserID != pairingCode.UserID {
+			return nil, "", ErrSerialAlreadyClaimed
+		}
+		err = s.deviceRepo.UpdateOneSecretHash(ctx, device.ID, secretHash)
+		if err != nil {
+			return nil, "", err
+		}
+		device.SecretHash = secretHash
+		return device, secret, nil
+	}
+
+	name := pairingCode.DeviceName
+	if name == "" {
+		name = "Pico " + serial
+		if len(name) > 50 {
+			name = name[:50]
+		}
+	}
+	device = &Device{
+		UserID:     pairingCode.UserID,
+		Name:       name,
+		Serial:     serial,
+		SecretHash: secretHash,
+	}
+	err = s.deviceRepo.CreateOne(ctx, device)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return device, secret, nil
+}
+
+// attachBoard gives the serial and the credential of the board to the device
+// of the pairing code, current is the device that already has the serial
+func (s *service) attachBoard(ctx context.Context, pairingCode *pairing_code.PairingCode, current *Device, serial string, secret string, secretHash string) (*Device, string, error) {
+	if current != nil && current.ID != pairingCode.DeviceID {
+		return nil, "", ErrSerialAlreadyClaimed
+	}
+
+	device, err := s.deviceRepo.GetOneByID(ctx, pairingCode.DeviceID)
+	if err != nil {
+		return nil, "", err
+	}
+	// the device has been deleted since the code was created
+	if device == nil || device.UserID != pairingCode.UserID {
+		return nil, "", ErrInvalidPairingCode
+	}
+
+	err = s.deviceRepo.UpdateOneCredentials(ctx, device.ID, serial, secretHash)
+	if err != nil {
+		return nil, "", err
+	}
+	device.Serial = serial
+	device.SecretHash = secretHash
+	return device, secret, nil
+}
+
+func (s *service) GetDeviceByID(ctx context.Context, deviceID string) (*Device, error) {
+	device, err := s.deviceRepo.GetOneByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if device == nil {
+		return nil, ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+func (s *service) AuthenticateDevice(ctx context.Context, deviceID string, secret string) (string, error) {
+	if !strings.HasPrefix(secret, deviceSecretVersion+".") {
+		return "", ErrInvalidCredentials
+	}
+
+	device, err := s.deviceRepo.GetOneByID(ctx, deviceID)
+	if err != nil {
+		return "", err
+	}
+	// a device that has never been claimed has no secret and cannot authenticate
+	if device == nil || device.SecretHash == "" {
+		return "", ErrInvalidCredentials
+	}
+
+	secretHash := hashSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(device.SecretHash)) != 1 {
+		return "", ErrInvalidCredentials
+	}
+
+	return s.GenerateDeviceJWT(device.ID)
+}
+
+func (s *service) GenerateDeviceJWT(deviceID string) (string, error) {
+	// the audience and the type make sure this token is never accepted
+	// by the AuthMiddleware of the user endpoints
+	return s.signer.Sign(jwt.MapClaims{
+		"sub": deviceID,
+		"iss": s.issuer,
+		"aud": TokenAudience,
+		"typ": TokenType,
+		"exp": time.Now().Add(TokenTTL).Unix(),
+		"iat": time.Now().Unix(),
+	})
+}
+
+// generateDeviceSecret returns the credential given to the board and its hash,
+// only the hash is stored so a database leak does not expose the boards
+func generateDeviceSecret() (string, string, error) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", "", err
+	}
+	secret := fmt.Sprintf("%s.%s", deviceSecretVersion, base64.RawURLEncoding.EncodeToString(bytes))
+	return secret, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
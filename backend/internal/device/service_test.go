@@ -0,0 +1,759 @@
+package device_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_CreateDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		userID        string
+		deviceName    string
+		setupMock     func(*mocks.MockdeviceRepository)
+		expectedError error
+	}{
+		{
+			name:       "success",
+			userID:     "user-1",
+			deviceName: "living room",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().CreateOne(gomock.Any(), &device.Device{UserID: "user-1", Name: "living room"}).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "db_error",
+			userID:     "user-1",
+			deviceName: "living room",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().CreateOne(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			tt.setupMock(mockDeviceRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mocks.NewMockhouseholdRepository(ctrl), mocks.NewMocktokenSigner(ctrl), "TestApp")
+			got, err := s.CreateDevice(context.Background(), tt.userID, tt.deviceName)
+
+			if tt.expectedError != nil {
+				if err == nil || err.Error() != tt.expectedError.Error() {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got.UserID != tt.userID || got.Name != tt.deviceName {
+				t.Errorf("unexpected device %+v", got)
+			}
+		})
+	}
+}
+
+func TestService_GetDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		userID        string
+		deviceID      string
+		setupMock     func(*mocks.MockdeviceRepository)
+		expectedError error
+	}{
+		{
+			name:     "success",
+			userID:   "user-1",
+			deviceID: "device-1",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "not_found",
+			userID:   "user-1",
+			deviceID: "device-1",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(nil, nil)
+			},
+			expectedError: device.ErrDeviceNotFound,
+		},
+		{
+			name:     "other_user",
+			userID:   "user-1",
+			deviceID: "device-1",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2"}, nil)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+		{
+			name:     "db_error",
+			userID:   "user-1",
+			deviceID: "device-1",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			tt.setupMock(mockDeviceRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mocks.NewMockhouseholdRepository(ctrl), mocks.NewMocktokenSigner(ctrl), "TestApp")
+			_, err := s.GetDevice(context.Background(), tt.userID, tt.deviceID)
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_RenameDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockdeviceRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1", Name: "old"}, nil)
+				m.EXPECT().UpdateOneName(gomock.Any(), "device-1", "new").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "other_user",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", Name: "old"}, nil)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+		{
+			name: "deleted_concurrently",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1", Name: "old"}, nil)
+				m.EXPECT().UpdateOneName(gomock.Any(), "device-1", "new").Return(device.ErrDeviceNotFound)
+			},
+			expectedError: device.ErrDeviceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			tt.setupMock(mockDeviceRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mocks.NewMockhouseholdRepository(ctrl), mocks.NewMocktokenSigner(ctrl), "TestApp")
+			got, err := s.RenameDevice(context.Background(), "user-1", "device-1", "new")
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got.Name != "new" {
+				t.Errorf("expected name new, got %s", got.Name)
+			}
+		})
+	}
+}
+
+func TestService_DeleteDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockdeviceRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				m.EXPECT().DeleteOneByID(gomock.Any(), "device-1").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "not_found",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(nil, nil)
+			},
+			expectedError: device.ErrDeviceNotFound,
+		},
+		{
+			name: "other_user",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2"}, nil)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			tt.setupMock(mockDeviceRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mocks.NewMockhouseholdRepository(ctrl), mocks.NewMocktokenSigner(ctrl), "TestApp")
+			err := s.DeleteDevice(context.Background(), "user-1", "device-1")
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_AuthorizeDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		permission    device.Permission
+		setupMock     func(*mocks.MockdeviceRepository, *mocks.MockhouseholdRepository)
+		expectedError error
+	}{
+		{
+			name:       "owner",
+			permission: device.PermissionManage,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1", HouseholdID: "household-1"}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "not_shared",
+			permission: device.PermissionView,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2"}, nil)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+		{
+			name:       "not_a_member",
+			permission: device.PermissionView,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.Role(""), nil)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+		{
+			name:       "viewer_can_view",
+			permission: device.PermissionView,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleViewer, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "viewer_cannot_control",
+			permission: device.PermissionControl,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleViewer, nil)
+			},
+			expectedError: device.ErrPermissionDenied,
+		},
+		{
+			name:       "member_can_control",
+			permission: device.PermissionControl,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleMember, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "member_cannot_manage",
+			permission: device.PermissionManage,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleMember, nil)
+			},
+			expectedError: device.ErrPermissionDenied,
+		},
+		{
+			name:       "admin_can_manage",
+			permission: device.PermissionManage,
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			tt.setupMock(mockDeviceRepo, mockHouseholdRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mockHouseholdRepo, mocks.NewMocktokenSigner(ctrl), "TestApp")
+			got, err := s.AuthorizeDevice(context.Background(), "user-1", "device-1", tt.permission)
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if tt.expectedError == nil && got.ID != "device-1" {
+				t.Errorf("unexpected device %+v", got)
+			}
+		})
+	}
+}
+
+func TestService_ShareDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockdeviceRepository, *mocks.MockhouseholdRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleMember, nil)
+				d.EXPECT().UpdateOneHousehold(gomock.Any(), "device-1", "household-1").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "device_of_another_member",
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-2"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-2", "user-1").Return(household.RoleAdmin, nil)
+			},
+			expectedError: device.ErrPermissionDenied,
+		},
+		{
+			name: "not_a_member",
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.Role(""), nil)
+			},
+			expectedError: household.ErrHouseholdNotFound,
+		},
+		{
+			name: "viewer",
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleViewer, nil)
+			},
+			expectedError: device.ErrPermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			tt.setupMock(mockDeviceRepo, mockHouseholdRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mockHouseholdRepo, mocks.NewMocktokenSigner(ctrl), "TestApp")
+			got, err := s.ShareDevice(context.Background(), "user-1", "device-1", "household-1")
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if tt.expectedError == nil && got.HouseholdID != "household-1" {
+				t.Errorf("expected household-1, got %q", got.HouseholdID)
+			}
+		})
+	}
+}
+
+func TestService_UnshareDevice_ByAdmin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+	mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+	mockDeviceRepo.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+	mockHouseholdRepo.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+	mockDeviceRepo.EXPECT().UpdateOneHousehold(gomock.Any(), "device-1", "").Return(nil)
+
+	s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mockHouseholdRepo, mocks.NewMocktokenSigner(ctrl), "TestApp")
+	got, err := s.UnshareDevice(context.Background(), "user-1", "device-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.HouseholdID != "" {
+		t.Errorf("expected the device to not be shared, got %q", got.HouseholdID)
+	}
+}
+
+func TestService_DeleteDevice_SharedByAnotherUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// even the owner of the household cannot delete the device of another member
+	mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+	mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+	mockDeviceRepo.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+	mockHouseholdRepo.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+
+	s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mockHouseholdRepo, mocks.NewMocktokenSigner(ctrl), "TestApp")
+	err := s.DeleteDevice(context.Background(), "user-1", "device-1")
+	if !errors.Is(err, device.ErrPermissionDenied) {
+		t.Errorf("expected error %v, got %v", device.ErrPermissionDenied, err)
+	}
+}
+
+func TestService_CreatePairingCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+	mockPairingCodeRepo := mocks.NewMockpairingCodeRepository(ctrl)
+
+	var stored *pairing_code.PairingCode
+	mockPairingCodeRepo.EXPECT().CreateOne(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, pc *pairing_code.PairingCode) error {
+			stored = pc
+			return nil
+		},
+	)
+
+	s := device.NewDeviceService(mockDeviceRepo, mockPairingCodeRepo, mocks.NewMockhouseholdRepository(ctrl), mocks.NewMocktokenSigner(ctrl), "TestApp")
+	got, err := s.CreatePairingCode(context.Background(), "user-1", "living room", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(got.Code) != 8 || strings.ContainsAny(got.Code, "01IO") {
+		t.Errorf("unexpected pairing code %q", got.Code)
+	}
+	// only the hash of the code must reach the repository lookup key
+	sum := sha256.Sum256([]byte(got.Code))
+	if stored.CodeHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected code hash to be the sha256 of the code")
+	}
+	if stored.UserID != "user-1" || stored.DeviceName != "living room" || stored.DeviceID != "" {
+		t.Errorf("unexpected stored pairing code %+v", stored)
+	}
+}
+
+func TestService_CreatePairingCode_ForDevice(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockdeviceRepository, *mocks.MockhouseholdRepository, *mocks.MockpairingCodeRepository)
+		expectedError error
+	}{
+		{
+			name: "owner",
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository, p *mocks.MockpairingCodeRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				p.EXPECT().CreateOne(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, pc *pairing_code.PairingCode) error {
+						if pc.UserID != "user-1" || pc.DeviceID != "device-1" {
+							t.Errorf("unexpected stored pairing code %+v", pc)
+						}
+						return nil
+					},
+				)
+			},
+		},
+		{
+			name: "admin_of_the_household",
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository, p *mocks.MockpairingCodeRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-2", HouseholdID: "household-1"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+			},
+			expectedError: device.ErrPermissionDenied,
+		},
+		{
+			name: "unknown_device",
+			setupMock: func(d *mocks.MockdeviceRepository, h *mocks.MockhouseholdRepository, p *mocks.MockpairingCodeRepository) {
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(nil, nil)
+			},
+			expectedError: device.ErrDeviceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			mockPairingCodeRepo := mocks.NewMockpairingCodeRepository(ctrl)
+			tt.setupMock(mockDeviceRepo, mockHouseholdRepo, mockPairingCodeRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mockPairingCodeRepo, mockHouseholdRepo, mocks.NewMocktokenSigner(ctrl), "TestApp")
+			_, err := s.CreatePairingCode(context.Background(), "user-1", "", "device-1")
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_ClaimDevice(t *testing.T) {
+	codeHash := func(code string) string {
+		sum := sha256.Sum256([]byte(code))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name          string
+		code          string
+		setupMock     func(*mocks.MockdeviceRepository, *mocks.MockpairingCodeRepository)
+		expectedError error
+	}{
+		{
+			name: "new_device",
+			code: "abcd-2345",
+			setupMock: func(d *mocks.MockdeviceRepository, p *mocks.MockpairingCodeRepository) {
+				p.EXPECT().ConsumeOneByCodeHash(gomock.Any(), codeHash("ABCD2345")).
+					Return(&pairing_code.PairingCode{UserID: "user-1", DeviceName: "living room"}, nil)
+				d.EXPECT().GetOneBySerial(gomock.Any(), "E661").Return(nil, nil)
+				d.EXPECT().CreateOne(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, dev *device.Device) error {
+						if dev.UserID != "user-1" || dev.Name != "living room" || dev.Serial != "E661" || dev.SecretHash == "" {
+							t.Errorf("unexpected device %+v", dev)
+						}
+						dev.ID = "device-1"
+						return nil
+					},
+				)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "same_user_pairs_again",
+			code: "ABCD2345",
+			setupMock: func(d *mocks.MockdeviceRepository, p *mocks.MockpairingCodeRepository) {
+				p.EXPECT().ConsumeOneByCodeHash(gomock.Any(), codeHash("ABCD2345")).
+					Return(&pairing_code.PairingCode{UserID: "user-1"}, nil)
+				d.EXPECT().GetOneBySerial(gomock.Any(), "E661").Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				d.EXPECT().UpdateOneSecretHash(gomock.Any(), "device-1", gomock.Any()).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "serial_of_another_user",
+			code: "ABCD2345",
+			setupMock: func(d *mocks.MockdeviceRepository, p *mocks.MockpairingCodeRepository) {
+				p.EXPECT().ConsumeOneByCodeHash(gomock.Any(), codeHash("ABCD2345")).
+					Return(&pairing_code.PairingCode{UserID: "user-1"}, nil)
+				d.EXPECT().GetOneBySerial(gomock.Any(), "E661").Return(&device.Device{ID: "device-1", UserID: "user-2"}, nil)
+			},
+			expectedError: device.ErrSerialAlreadyClaimed,
+		},
+		{
+			name: "board_of_an_existing_device",
+			code: "ABCD2345",
+			setupMock: func(d *mocks.MockdeviceRepository, p *mocks.MockpairingCodeRepository) {
+				p.EXPECT().ConsumeOneByCodeHash(gomock.Any(), codeHash("ABCD2345")).
+					Return(&pairing_code.PairingCode{UserID: "user-1", DeviceID: "device-1"}, nil)
+				d.EXPECT().GetOneBySerial(gomock.Any(), "E661").Return(nil, nil)
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", UserID: "user-1", Name: "hall"}, nil)
+				d.EXPECT().UpdateOneCredentials(gomock.Any(), "device-1", "E661", gomock.Any()).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "board_of_another_device",
+			code: "ABCD2345",
+			setupMock: func(d *mocks.MockdeviceRepository, p *mocks.MockpairingCodeRepository) {
+				p.EXPECT().ConsumeOneByCodeHash(gomock.Any(), codeHash("ABCD2345")).
+					Return(&pairing_code.PairingCode{UserID: "user-1", DeviceID: "device-1"}, nil)
+				d.EXPECT().GetOneBySerial(gomock.Any(), "E661").Return(&device.Device{ID: "device-2", UserID: "user-1"}, nil)
+			},
+			expectedError: device.ErrSerialAlreadyClaimed,
+		},
+		{
+			name: "device_deleted_after_the_code",
+			code: "ABCD2345",
+			setupMock: func(d *mocks.MockdeviceRepository, p *mocks.MockpairingCodeRepository) {
+				p.EXPECT().ConsumeOneByCodeHash(gomock.Any(), codeHash("ABCD2345")).
+					Return(&pairing_code.PairingCode{UserID: "user-1", DeviceID: "device-1"}, nil)
+				d.EXPECT().GetOneBySerial(gomock.Any(), "E661").Return(nil, nil)
+				d.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(nil, nil)
+			},
+			expectedError: device.ErrInvalidPairingCode,
+		},
+		{
+			name: "unknown_code",
+			code: "ABCD2345",
+			setupMock: func(d *mocks.MockdeviceRepository, p *mocks.MockpairingCodeRepository) {
+				p.EXPECT().ConsumeOneByCodeHash(gomock.Any(), codeHash("ABCD2345")).
+					Return(nil, pairing_code.ErrCodeHashNotFound)
+			},
+			expectedError: device.ErrInvalidPairingCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			mockPairingCodeRepo := mocks.NewMockpairingCodeRepository(ctrl)
+			tt.setupMock(mockDeviceRepo, mockPairingCodeRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mockPairingCodeRepo, mocks.NewMockhouseholdRepository(ctrl), mocks.NewMocktokenSigner(ctrl), "TestApp")
+			got, secret, err := s.ClaimDevice(context.Background(), tt.code, "E661")
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !strings.HasPrefix(secret, "ds1.") {
+				t.Errorf("unexpected device secret %q", secret)
+			}
+			sum := sha256.Sum256([]byte(secret))
+			if got.SecretHash != hex.EncodeToString(sum[:]) {
+				t.Errorf("expected the stored hash to match the returned secret")
+			}
+		})
+	}
+}
+
+func TestService_AuthenticateDevice(t *testing.T) {
+	signingKeys := signing_key.NewSigningKeyService(signing_key.NewMemorySigningKeyRepository(), signing_key.DefaultConfig())
+	if err := signingKeys.Refresh(context.Background()); err != nil {
+		t.Fatalf("failed to create the signing key: %v", err)
+	}
+
+	const secret = "ds1.c2VjcmV0"
+	sum := sha256.Sum256([]byte(secret))
+	secretHash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name          string
+		secret        string
+		setupMock     func(*mocks.MockdeviceRepository)
+		expectedError error
+	}{
+		{
+			name:   "success",
+			secret: secret,
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", SecretHash: secretHash}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:   "wrong_secret",
+			secret: "ds1.d3Jvbmc",
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1", SecretHash: secretHash}, nil)
+			},
+			expectedError: device.ErrInvalidCredentials,
+		},
+		{
+			name:          "wrong_version",
+			secret:        "xx1.c2VjcmV0",
+			setupMock:     func(m *mocks.MockdeviceRepository) {},
+			expectedError: device.ErrInvalidCredentials,
+		},
+		{
+			name:   "never_claimed",
+			secret: secret,
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1"}, nil)
+			},
+			expectedError: device.ErrInvalidCredentials,
+		},
+		{
+			name:   "unknown_device",
+			secret: secret,
+			setupMock: func(m *mocks.MockdeviceRepository) {
+				m.EXPECT().GetOneByID(gomock.Any(), "device-1").Return(nil, nil)
+			},
+			expectedError: device.ErrInvalidCredentials,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceRepo := mocks.NewMockdeviceRepository(ctrl)
+			tt.setupMock(mockDeviceRepo)
+
+			s := device.NewDeviceService(mockDeviceRepo, mocks.NewMockpairingCodeRepository(ctrl), mocks.NewMockhouseholdRepository(ctrl), signingKeys, "TestApp")
+			tokenString, err := s.AuthenticateDevice(context.Background(), "device-1", tt.secret)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			// the token must be scoped to the devices
+			claims := jwt.MapClaims{}
+			_, err = jwt.ParseWithClaims(tokenString, claims, signingKeys.Keyfunc, jwt.WithAudience(device.TokenAudience))
+			if err != nil {
+				t.Fatalf("failed to parse device token: %v", err)
+			}
+			if claims["typ"] != device.TokenType || claims["sub"] != "device-1" || claims["iss"] != "TestApp" {
+				t.Errorf("unexpected claims %v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package email_verification_token
+
+import (
+	"time"
+)
+
+type EmailVerificationToken struct {
+	Token     string
+	TokenHash string
+	UserID    string
+	TTL       time.Time
+}
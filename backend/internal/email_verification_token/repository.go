@@ -0,0 +1,129 @@
+package email_verification_token
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrTokenHashNotFound = errors.New("token hash not found")
+
+type repository struct {
+	db *redis.Client
+}
+
+func NewEmailVerificationTokenRepository(db *redis.Client) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateOne(ctx context.Context, token *EmailVerificationToken) error {
+	// as for the password reset tokens we will save two records:
+	// 1. evt:{tokenHash} -> userID used as lookup when the email is verified
+	// 2. evu:{userID} -> {tokenHash} used to invalidate the previous token,
+	//    only the last link sent to the user can be used
+	evtKey := "evt:" + token.TokenHash
+	evuKey := "evu:" + token.UserID
+
+	lua := `
+		local evtKey = KEYS[1]
+		local evuKey = KEYS[2]
+		local userID = ARGV[1]
+		local tokenHash = ARGV[2]
+		local pxat = tonumber(ARGV[3])
+
+		local oldTokenHash = redis.call("GET", evuKey)
+		if oldTokenHash then
+			redis.call("DEL", "evt:" .. oldTokenHash)
+		end
+
+		redis.call("SET", evtKey, userID, "PXAT", pxat)
+		redis.call("SET", evuKey, tokenHash, "PXAT", pxat)
+
+		return 1
+	`
+
+	_, err := r.db.Eval(ctx, lua, []string{evtKey, evuKey}, token.UserID, token.TokenHash, token.TTL.UnixMilli()).Int64()
+	return err
+}
+
+// ConsumeOneByTokenHash returns the user of the token and deletes it in the
+// same step, so that a verification link can be used only once
+func (r *repository) ConsumeOneByTokenHash(ctx context.Context, tokenHash string) (string, error) {
+	evtKey := "evt:" + tokenHash
+
+	lua := `
+		local evtKey = KEYS[1]
+		local tokenHash = ARGV[1]
+
+		local userID = redis.call("GETDEL", evtKey)
+		if not userID then
+			return false
+		end
+
+		local evuKey = "evu:" .. userID
+		if redis.call("GET", evuKey) == tokenHash then
+			redis.call("DEL", evuKey)
+		end
+
+		return userID
+	`
+
+	userID, err := r.db.Eval(ctx, lua, []string{evtKey}, tokenHash).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrTokenHashNotFound
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+// DeleteOneByUserID invalidates the last link sent to the user, e.g. when
+// the email changes and the link points to the previous address
+func (r *repository) DeleteOneByUserID(ctx context.Context, userID string) error {
+	evuKey := "evu:" + userID
+
+	lua := `
+		local evuKey = KEYS[1]
+
+		local tokenHash = redis.call("GET", evuKey)
+		if tokenHash then
+			redis.call("DEL", "evt:" .. tokenHash, evuKey)
+		end
+
+		return 1
+	`
+
+	_, err := r.db.Eval(ctx, lua, []string{evuKey}).Int64()
+	return err
+}
+
+// AcquireResendSlot reserves the right to send another verification email to
+// the given address for the cooldown, when the slot is already taken it
+// returns how long the caller has to wait before trying again
+func (r *repository) AcquireResendSlot(ctx context.Context, emailHash string, cooldown time.Duration) (time.Duration, error) {
+	evrKey := "evr:" + emailHash
+
+	lua := `
+		local evrKey = KEYS[1]
+		local cooldown = tonumber(ARGV[1])
+
+		if redis.call("SET", evrKey, 1, "NX", "PX", cooldown) then
+			return 0
+		end
+
+		local remaining = redis.call("PTTL", evrKey)
+		if remaining < 0 then
+			return cooldown
+		end
+		return remaining
+	`
+
+	remaining, err := r.db.Eval(ctx, lua, []string{evrKey}, cooldown.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(remaining) * time.Millisecond, nil
+}
@@ -0,0 +1,229 @@
+package email_verification_token
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/redis/go-redis/v9"
+)
+
+var testRedisDB *redis.Client
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		redisConnectionStr := testutils.SetupRedis()
+		opt, _ := redis.ParseURL(redisConnectionStr)
+		testRedisDB = redis.NewClient(opt)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_ConsumeOneByTokenHash(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewEmailVerificationTokenRepository(testRedisDB)
+
+	createToken := func(tokenHash string, ttl time.Duration) error {
+		return repo.CreateOne(ctx, &EmailVerificationToken{
+			TokenHash: tokenHash,
+			UserID:    "user_verify",
+			TTL:       time.Now().Add(ttl),
+		})
+	}
+
+	tests := []struct {
+		name      string
+		tokenHash string
+		setupFunc func() error
+		want      string
+		wantErr   error
+	}{
+		{
+			name:      "found",
+			tokenHash: "hash_found",
+			setupFunc: func() error { return createToken("hash_found", time.Hour) },
+			want:      "user_verify",
+			wantErr:   nil,
+		},
+		{
+			name:      "not_found",
+			tokenHash: "hash_missing",
+			setupFunc: func() error { return nil },
+			wantErr:   ErrTokenHashNotFound,
+		},
+		{
+			name:      "already_used",
+			tokenHash: "hash_used",
+			setupFunc: func() error {
+				if err := createToken("hash_used", time.Hour); err != nil {
+					return err
+				}
+				_, err := repo.ConsumeOneByTokenHash(ctx, "hash_used")
+				return err
+			},
+			wantErr: ErrTokenHashNotFound,
+		},
+		{
+			name:      "replaced_by_newer_token",
+			tokenHash: "hash_old",
+			setupFunc: func() error {
+				if err := createToken("hash_old", time.Hour); err != nil {
+					return err
+				}
+				return createToken("hash_new", time.Hour)
+			},
+			wantErr: ErrTokenHashNotFound,
+		},
+		{
+			name:      "expired",
+			tokenHash: "hash_expired",
+			setupFunc: func() error {
+				if err := createToken("hash_expired", 50*time.Millisecond); err != nil {
+					return err
+				}
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			},
+			wantErr: ErrTokenHashNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+			if err := tt.setupFunc(); err != nil {
+				t.Fatalf("setupFunc failed: %v", err)
+			}
+
+			got, err := repo.ConsumeOneByTokenHash(ctx, tt.tokenHash)
+			if err != tt.wantErr {
+				t.Fatalf("ConsumeOneByTokenHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ConsumeOneByTokenHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteOneByUserID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewEmailVerificationTokenRepository(testRedisDB)
+
+	tests := []struct {
+		name      string
+		userID    string
+		setupFunc func() error
+	}{
+		{
+			name:   "outstanding_token",
+			userID: "user_verify",
+			setupFunc: func() error {
+				return repo.CreateOne(ctx, &EmailVerificationToken{
+					TokenHash: "hash_deleted",
+					UserID:    "user_verify",
+					TTL:       time.Now().Add(time.Hour),
+				})
+			},
+		},
+		{
+			name:      "no_token",
+			userID:    "user_without_token",
+			setupFunc: func() error { return nil },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+			if err := tt.setupFunc(); err != nil {
+				t.Fatalf("setupFunc failed: %v", err)
+			}
+
+			if err := repo.DeleteOneByUserID(ctx, tt.userID); err != nil {
+				t.Fatalf("DeleteOneByUserID() error = %v", err)
+			}
+			_, err := repo.ConsumeOneByTokenHash(ctx, "hash_deleted")
+			if !errors.Is(err, ErrTokenHashNotFound) {
+				t.Errorf("ConsumeOneByTokenHash() error = %v, want %v", err, ErrTokenHashNotFound)
+			}
+		})
+	}
+}
+
+func TestRepository_AcquireResendSlot(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewEmailVerificationTokenRepository(testRedisDB)
+
+	tests := []struct {
+		name      string
+		emailHash string
+		cooldown  time.Duration
+		setupFunc func() error
+		wantWait  bool
+	}{
+		{
+			name:      "first_request",
+			emailHash: "email_first",
+			cooldown:  time.Minute,
+			setupFunc: func() error { return nil },
+			wantWait:  false,
+		},
+		{
+			name:      "throttled",
+			emailHash: "email_throttled",
+			cooldown:  time.Minute,
+			setupFunc: func() error {
+				_, err := repo.AcquireResendSlot(ctx, "email_throttled", time.Minute)
+				return err
+			},
+			wantWait: true,
+		},
+		{
+			name:      "cooldown_elapsed",
+			emailHash: "email_elapsed",
+			cooldown:  time.Minute,
+			setupFunc: func() error {
+				_, err := repo.AcquireResendSlot(ctx, "email_elapsed", 50*time.Millisecond)
+				time.Sleep(100 * time.Millisecond)
+				return err
+			},
+			wantWait: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+			if err := tt.setupFunc(); err != nil {
+				t.Fatalf("setupFunc failed: %v", err)
+			}
+
+			retryAfter, err := repo.AcquireResendSlot(ctx, tt.emailHash, tt.cooldown)
+			if err != nil {
+				t.Fatalf("AcquireResendSlot() error = %v", err)
+			}
+			if tt.wantWait && (retryAfter <= 0 || retryAfter > tt.cooldown) {
+				t.Errorf("AcquireResendSlot() retryAfter = %v, want in (0, %v]", retryAfter, tt.cooldown)
+			}
+			if !tt.wantWait && retryAfter != 0 {
+				t.Errorf("AcquireResendSlot() retryAfter = %v, want 0", retryAfter)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package health
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type healthService interface {
+	Readiness(ctx context.Context) *Report
+}
+
+type Controller struct {
+	service healthService
+}
+
+func NewHealthController(service healthService) *Controller {
+	return &Controller{service: service}
+}
+
+type checkResponse struct {
+	Status           Status  `json:"status"`
+	LatencyMS        float64 `json:"latency_ms"`
+	Error            string  `json:"error,omitempty"`
+	MigrationVersion int64   `json:"migration_version,omitempty"`
+}
+
+type readinessResponse struct {
+	Status Status                   `json:"status"`
+	Checks map[string]checkResponse `json:"checks"`
+}
+
+// Healthz tells that the process is alive, it does not check the dependencies
+// so that an outage of the databases does not get the backend restarted
+func (hc *Controller) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusUp})
+}
+
+// Readyz tells whether the backend can serve the requests, a degraded backend
+// is still ready since it serves everything that does not need Redis
+func (hc *Controller) Readyz(c *gin.Context) {
+	report := hc.service.Readiness(c.Request.Context())
+
+	response := readinessResponse{Status: report.Status, Checks: map[string]checkResponse{}}
+	for name, check := range report.Checks {
+		response.Checks[name] = checkResponse{
+			Status:           check.Status,
+			LatencyMS:        float64(check.Latency.Microseconds()) / 1000,
+			Error:            check.Error,
+			MigrationVersion: check.MigrationVersion,
+		}
+	}
+
+	code := http.StatusOK
+	if report.Status == StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, response)
+}
@@ -0,0 +1,111 @@
+package health_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/health"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/health/mocks"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+func TestController_Healthz(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the liveness does not depend on the databases
+	hc := health.NewHealthController(mocks.NewMockhealthService(ctrl))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+	hc.Healthz(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d want %d; body=%s", w.Code, http.StatusOK, w.Body.String())
+	}
+}
+
+func TestController_Readyz(t *testing.T) {
+	tests := []struct {
+		name         string
+		report       *health.Report
+		expectedCode int
+	}{
+		{
+			name: "up",
+			report: &health.Report{Status: health.StatusUp, Checks: map[string]*health.Check{
+				health.Postgres: {Status: health.StatusUp, Latency: 1500 * time.Microsecond, MigrationVersion: 1},
+				health.Redis:    {Status: health.StatusUp, Latency: 300 * time.Microsecond},
+			}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "degraded",
+			report: &health.Report{Status: health.StatusDegraded, Checks: map[string]*health.Check{
+				health.Postgres: {Status: health.StatusUp, Latency: 1500 * time.Microsecond, MigrationVersion: 1},
+				health.Redis:    {Status: health.StatusDown, Latency: 2 * time.Second, Error: "timeout"},
+			}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "down",
+			report: &health.Report{Status: health.StatusDown, Checks: map[string]*health.Check{
+				health.Postgres: {Status: health.StatusDown, Latency: 2 * time.Second, Error: "timeout"},
+				health.Redis:    {Status: health.StatusUp, Latency: 300 * time.Microsecond},
+			}},
+			expectedCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHealthService := mocks.NewMockhealthService(ctrl)
+			mockHealthService.EXPECT().Readiness(gomock.Any()).Return(tt.report)
+
+			hc := health.NewHealthController(mockHealthService)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			hc.Readyz(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			var body struct {
+				Status string `json:"status"`
+				Checks map[string]struct {
+					Status           string  `json:"status"`
+					LatencyMS        float64 `json:"latency_ms"`
+					Error            string  `json:"error"`
+					MigrationVersion int64   `json:"migration_version"`
+				} `json:"checks"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode the body: %v", err)
+			}
+			if body.Status != string(tt.report.Status) || len(body.Checks) != 2 {
+				t.Errorf("unexpected body %s", w.Body.String())
+			}
+			for name, check := range tt.report.Checks {
+				got := body.Checks[name]
+				if got.Status != string(check.Status) || got.Error != check.Error || got.MigrationVersion != check.MigrationVersion {
+					t.Errorf("unexpected %s check %+v", name, got)
+				}
+				if got.LatencyMS != float64(check.Latency.Microseconds())/1000 {
+					t.Errorf("expected %s latency %v in ms, got %v", name, check.Latency, got.LatencyMS)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	health "github.com/AliceOrlandini/Auto-Light-Pi/internal/health"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockhealthService is a mock of healthService interface.
+type MockhealthService struct {
+	ctrl     *gomock.Controller
+	recorder *MockhealthServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockhealthServiceMockRecorder is the mock recorder for MockhealthService.
+type MockhealthServiceMockRecorder struct {
+	mock *MockhealthService
+}
+
+// NewMockhealthService creates a new mock instance.
+func NewMockhealthService(ctrl *gomock.Controller) *MockhealthService {
+	mock := &MockhealthService{ctrl: ctrl}
+	mock.recorder = &MockhealthServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockhealthService) EXPECT() *MockhealthServiceMockRecorder {
+	return m.recorder
+}
+
+// Readiness mocks base method.
+func (m *MockhealthService) Readiness(ctx context.Context) *health.Report {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Readiness", ctx)
+	ret0, _ := ret[0].(*health.Report)
+	return ret0
+}
+
+// Readiness indicates an expected call of Readiness.
+func (mr *MockhealthServiceMockRecorder) Readiness(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Readiness", reflect.TypeOf((*MockhealthService)(nil).Readiness), ctx)
+}
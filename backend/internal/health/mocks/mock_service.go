@@ -0,0 +1,133 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	redis "github.com/redis/go-redis/v9"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockpostgresDB is a mock of postgresDB interface.
+type MockpostgresDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockpostgresDBMockRecorder
+	isgomock struct{}
+}
+
+// MockpostgresDBMockRecorder is the mock recorder for MockpostgresDB.
+type MockpostgresDBMockRecorder struct {
+	mock *MockpostgresDB
+}
+
+// NewMockpostgresDB creates a new mock instance.
+func NewMockpostgresDB(ctrl *gomock.Controller) *MockpostgresDB {
+	mock := &MockpostgresDB{ctrl: ctrl}
+	mock.recorder = &MockpostgresDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockpostgresDB) EXPECT() *MockpostgresDBMockRecorder {
+	return m.recorder
+}
+
+// PingContext mocks base method.
+func (m *MockpostgresDB) PingContext(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PingContext", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PingContext indicates an expected call of PingContext.
+func (mr *MockpostgresDBMockRecorder) PingContext(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PingContext", reflect.TypeOf((*MockpostgresDB)(nil).PingContext), ctx)
+}
+
+// MockredisDB is a mock of redisDB interface.
+type MockredisDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockredisDBMockRecorder
+	isgomock struct{}
+}
+
+// MockredisDBMockRecorder is the mock recorder for MockredisDB.
+type MockredisDBMockRecorder struct {
+	mock *MockredisDB
+}
+
+// NewMockredisDB creates a new mock instance.
+func NewMockredisDB(ctrl *gomock.Controller) *MockredisDB {
+	mock := &MockredisDB{ctrl: ctrl}
+	mock.recorder = &MockredisDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockredisDB) EXPECT() *MockredisDBMockRecorder {
+	return m.recorder
+}
+
+// Ping mocks base method.
+func (m *MockredisDB) Ping(ctx context.Context) *redis.StatusCmd {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(*redis.StatusCmd)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockredisDBMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockredisDB)(nil).Ping), ctx)
+}
+
+// Mockmigrator is a mock of migrator interface.
+type Mockmigrator struct {
+	ctrl     *gomock.Controller
+	recorder *MockmigratorMockRecorder
+	isgomock struct{}
+}
+
+// MockmigratorMockRecorder is the mock recorder for Mockmigrator.
+type MockmigratorMockRecorder struct {
+	mock *Mockmigrator
+}
+
+// NewMockmigrator creates a new mock instance.
+func NewMockmigrator(ctrl *gomock.Controller) *Mockmigrator {
+	mock := &Mockmigrator{ctrl: ctrl}
+	mock.recorder = &MockmigratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockmigrator) EXPECT() *MockmigratorMockRecorder {
+	return m.recorder
+}
+
+// Version mocks base method.
+func (m *Mockmigrator) Version(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Version", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Version indicates an expected call of Version.
+func (mr *MockmigratorMockRecorder) Version(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Version", reflect.TypeOf((*Mockmigrator)(nil).Version), ctx)
+}
@@ -0,0 +1,36 @@
+package health
+
+import "time"
+
+// Status is the state of a dependency or of the whole backend
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+	// StatusDegraded means that Postgres is up but Redis is not, the requests
+	// that need Redis are refused at once while the others are served
+	StatusDegraded Status = "degraded"
+)
+
+// the dependencies checked by the readiness probe
+const (
+	Postgres = "postgres"
+	Redis    = "redis"
+)
+
+// Check is the result of the ping of a dependency
+type Check struct {
+	Status  Status
+	Latency time.Duration
+	// Error is a short reason, the full error is only logged
+	Error string
+	// MigrationVersion is the last migration applied to Postgres
+	MigrationVersion int64
+}
+
+// Report is the readiness of the backend with the check of each dependency
+type Report struct {
+	Status Status
+	Checks map[string]*Check
+}
@@ -0,0 +1,161 @@
+package health
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Config tells how the dependencies are checked
+type Config struct {
+	// CheckTimeout is how long a ping can take before the dependency is considered down
+	CheckTimeout time.Duration
+	// Interval is how often Run checks Redis, so that the requests that
+	// need it are refused at once when it is down instead of waiting for it
+	Interval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		CheckTimeout: 2 * time.Second,
+		Interval:     5 * time.Second,
+	}
+}
+
+type postgresDB interface {
+	PingContext(ctx context.Context) error
+}
+
+type redisDB interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+type migrator interface {
+	Version(ctx context.Context) (int64, error)
+}
+
+type service struct {
+	postgresDB postgresDB
+	redisDB    redisDB
+	migrator   migrator
+	config     Config
+
+	mu sync.RWMutex
+	// down are the dependencies that failed the last check
+	down map[string]bool
+}
+
+func NewHealthService(postgresDB postgresDB, redisDB redisDB, migrator migrator, config Config) *service {
+	return &service{
+		postgresDB: postgresDB,
+		redisDB:    redisDB,
+		migrator:   migrator,
+		config:     config,
+		down:       map[string]bool{},
+	}
+}
+
+// Readiness pings every dependency, the backend is down when Postgres is down
+// and degraded when only Redis is down
+func (s *service) Readiness(ctx context.Context) *Report {
+	var wg sync.WaitGroup
+	var postgresCheck, redisCheck *Check
+	wg.Go(func() { postgresCheck = s.checkPostgres(ctx) })
+	wg.Go(func() { redisCheck = s.checkRedis(ctx) })
+	wg.Wait()
+
+	report := &Report{
+		Status: StatusUp,
+		Checks: map[string]*Check{Postgres: postgresCheck, Redis: redisCheck},
+	}
+	switch {
+	case postgresCheck.Status == StatusDown:
+		report.Status = StatusDown
+	case redisCheck.Status == StatusDown:
+		report.Status = StatusDegraded
+	}
+	return report
+}
+
+// Available tells whether the dependency was up at the last check
+func (s *service) Available(dependency string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.down[dependency]
+}
+
+// Run checks Redis every Interval until the context is done
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkRedis(ctx)
+		}
+	}
+}
+
+func (s *service) checkPostgres(ctx context.Context) *Check {
+	ctx, cancel := context.WithTimeout(ctx, s.config.CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.postgresDB.PingContext(ctx)
+	check := s.record(Postgres, time.Since(start), err)
+	if err != nil {
+		return check
+	}
+
+	// the version is not part of the latency, it is a query and not a ping
+	version, err := s.migrator.Version(ctx)
+	if err != nil {
+		slog.Warn("failed to read the migration version", "error", err)
+		return check
+	}
+	check.MigrationVersion = version
+	return check
+}
+
+func (s *service) checkRedis(ctx context.Context) *Check {
+	ctx, cancel := context.WithTimeout(ctx, s.config.CheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.redisDB.Ping(ctx).Err()
+	return s.record(Redis, time.Since(start), err)
+}
+
+// record remembers the result of a check and logs when a dependency goes down or comes back
+func (s *service) record(dependency string, latency time.Duration, err error) *Check {
+	s.mu.Lock()
+	wasDown := s.down[dependency]
+	s.down[dependency] = err != nil
+	s.mu.Unlock()
+
+	if err == nil {
+		if wasDown {
+			slog.Info("dependency is back up", "dependency", dependency, "latency", latency)
+		}
+		return &Check{Status: StatusUp, Latency: latency}
+	}
+
+	if !wasDown {
+		slog.Error("dependency is down", "dependency", dependency, "error", err)
+	}
+	// the error can contain hosts and addresses, so only the kind of failure is reported
+	reason := "unreachable"
+	if errors.Is(err, context.DeadlineExceeded) {
+		reason = "timeout"
+	}
+	return &Check{Status: StatusDown, Latency: latency, Error: reason}
+}
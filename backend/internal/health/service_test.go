@@ -0,0 +1,153 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/health"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/health/mocks"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Readiness(t *testing.T) {
+	errConnection := errors.New("dial tcp 10.0.0.3:6379: connection refused")
+
+	tests := []struct {
+		name             string
+		postgresErr      error
+		redisErr         error
+		expectedStatus   health.Status
+		expectedPostgres health.Status
+		expectedRedis    health.Status
+		expectedRedisErr string
+		expectedVersion  int64
+	}{
+		{
+			name:             "up",
+			expectedStatus:   health.StatusUp,
+			expectedPostgres: health.StatusUp,
+			expectedRedis:    health.StatusUp,
+			expectedVersion:  3,
+		},
+		{
+			name:             "redis_down",
+			redisErr:         errConnection,
+			expectedStatus:   health.StatusDegraded,
+			expectedPostgres: health.StatusUp,
+			expectedRedis:    health.StatusDown,
+			expectedRedisErr: "unreachable",
+			expectedVersion:  3,
+		},
+		{
+			name:             "redis_timeout",
+			redisErr:         context.DeadlineExceeded,
+			expectedStatus:   health.StatusDegraded,
+			expectedPostgres: health.StatusUp,
+			expectedRedis:    health.StatusDown,
+			expectedRedisErr: "timeout",
+			expectedVersion:  3,
+		},
+		{
+			name:             "postgres_down",
+			postgresErr:      errConnection,
+			expectedStatus:   health.StatusDown,
+			expectedPostgres: health.StatusDown,
+			expectedRedis:    health.StatusUp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPostgres := mocks.NewMockpostgresDB(ctrl)
+			mockRedis := mocks.NewMockredisDB(ctrl)
+			mockMigrator := mocks.NewMockmigrator(ctrl)
+			mockPostgres.EXPECT().PingContext(gomock.Any()).Return(tt.postgresErr)
+			mockRedis.EXPECT().Ping(gomock.Any()).Return(redis.NewStatusResult("PONG", tt.redisErr))
+			if tt.postgresErr == nil {
+				mockMigrator.EXPECT().Version(gomock.Any()).Return(int64(3), nil)
+			}
+
+			s := health.NewHealthService(mockPostgres, mockRedis, mockMigrator, health.DefaultConfig())
+			report := s.Readiness(context.Background())
+
+			if report.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, report.Status)
+			}
+			postgres, redisCheck := report.Checks[health.Postgres], report.Checks[health.Redis]
+			if postgres.Status != tt.expectedPostgres || postgres.MigrationVersion != tt.expectedVersion {
+				t.Errorf("unexpected postgres check %+v", postgres)
+			}
+			if redisCheck.Status != tt.expectedRedis || redisCheck.Error != tt.expectedRedisErr {
+				t.Errorf("unexpected redis check %+v", redisCheck)
+			}
+
+			// the requests use the result of the last check
+			if s.Available(health.Redis) != (tt.redisErr == nil) {
+				t.Errorf("expected redis available %v", tt.redisErr == nil)
+			}
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// redis goes down and then comes back
+	mockRedis := mocks.NewMockredisDB(ctrl)
+	checked := make(chan struct{}, 2)
+	gomock.InOrder(
+		mockRedis.EXPECT().Ping(gomock.Any()).DoAndReturn(func(ctx context.Context) *redis.StatusCmd {
+			defer func() { checked <- struct{}{} }()
+			return redis.NewStatusResult("", errors.New("connection refused"))
+		}),
+		mockRedis.EXPECT().Ping(gomock.Any()).DoAndReturn(func(ctx context.Context) *redis.StatusCmd {
+			defer func() { checked <- struct{}{} }()
+			return redis.NewStatusResult("PONG", nil)
+		}).AnyTimes(),
+	)
+
+	config := health.DefaultConfig()
+	config.Interval = 10 * time.Millisecond
+	s := health.NewHealthService(mocks.NewMockpostgresDB(ctrl), mockRedis, mocks.NewMockmigrator(ctrl), config)
+	if !s.Available(health.Redis) {
+		t.Fatal("expected redis to be available before the first check")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(stopped)
+	}()
+
+	<-checked
+	// the result is recorded right after the ping returns
+	waitFor(t, func() bool { return !s.Available(health.Redis) })
+	<-checked
+	waitFor(t, func() bool { return s.Available(health.Redis) })
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop with the context")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
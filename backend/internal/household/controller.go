@@ -0,0 +1,510 @@
+package household
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type householdService interface {
+	CreateHousehold(ctx context.Context, userID string, name string) (*Membership, error)
+	GetHouseholds(ctx context.Context, userID string) ([]*Membership, error)
+	GetHousehold(ctx context.Context, userID string, householdID string) (*Membership, error)
+	RenameHousehold(ctx context.Context, userID string, householdID string, name string) (*Membership, error)
+	DeleteHousehold(ctx context.Context, userID string, householdID string) error
+	GetMembers(ctx context.Context, userID string, householdID string) ([]*Member, error)
+	UpdateMemberRole(ctx context.Context, userID string, householdID string, memberID string, role Role) error
+	RemoveMember(ctx context.Context, userID string, householdID string, memberID string) error
+	TransferOwnership(ctx context.Context, userID string, householdID string, newOwnerID string) error
+	Invite(ctx context.Context, userID string, householdID string, email string, username string, role Role) (*Invitation, error)
+	GetHouseholdInvitations(ctx context.Context, userID string, householdID string) ([]*Invitation, error)
+	RevokeInvitation(ctx context.Context, userID string, householdID string, invitationID string) error
+	GetInvitations(ctx context.Context, userID string) ([]*Invitation, error)
+	AcceptInvitation(ctx context.Context, userID string, invitationID string) (*Membership, error)
+	DeclineInvitation(ctx context.Context, userID string, invitationID string) error
+}
+
+type Controller struct {
+	service householdService
+}
+
+func NewHouseholdController(service householdService) *Controller {
+	return &Controller{service: service}
+}
+
+type householdURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type memberURI struct {
+	ID     string `uri:"id" binding:"required,uuid"`
+	UserID string `uri:"userID" binding:"required,uuid"`
+}
+
+type householdInvitationURI struct {
+	ID           string `uri:"id" binding:"required,uuid"`
+	InvitationID string `uri:"invitationID" binding:"required,uuid"`
+}
+
+type invitationURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type householdRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+type updateMemberRequest struct {
+	// the owner is changed with the transfer of the ownership
+	Role Role `json:"role" binding:"required,oneof=admin member viewer"`
+}
+
+type transferOwnershipRequest struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+}
+
+// inviteRequest names the invited user either by email or by username
+type inviteRequest struct {
+	Email    string `json:"email" binding:"required_without=Username,excluded_with=Username,omitempty,email"`
+	Username string `json:"username" binding:"required_without=Email,excluded_with=Email,omitempty,max=50"`
+	Role     Role   `json:"role" binding:"required,oneof=admin member viewer"`
+}
+
+type householdResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type memberResponse struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	Role     Role      `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type invitationResponse struct {
+	ID            string    `json:"id"`
+	HouseholdID   string    `json:"household_id"`
+	HouseholdName string    `json:"household_name,omitempty"`
+	InviteeID     string    `json:"invitee_id"`
+	InvitedBy     string    `json:"invited_by,omitempty"`
+	Role          Role      `json:"role"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (hc *Controller) CreateHousehold(c *gin.Context) {
+	var request householdRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid create household request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	membership, err := hc.service.CreateHousehold(ctx, userID, request.Name)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to create household")
+		return
+	}
+
+	slog.Info("household created successfully", "userID", userID, "householdID", membership.ID)
+	c.JSON(http.StatusCreated, toHouseholdResponse(membership))
+}
+
+func (hc *Controller) GetHouseholds(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	memberships, err := hc.service.GetHouseholds(ctx, userID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to list households")
+		return
+	}
+
+	response := make([]householdResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		response = append(response, toHouseholdResponse(membership))
+	}
+	c.JSON(http.StatusOK, gin.H{"households": response})
+}
+
+func (hc *Controller) GetHousehold(c *gin.Context) {
+	var uri householdURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid household id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	membership, err := hc.service.GetHousehold(ctx, userID, uri.ID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to get household")
+		return
+	}
+	c.JSON(http.StatusOK, toHouseholdResponse(membership))
+}
+
+func (hc *Controller) RenameHousehold(c *gin.Context) {
+	var uri householdURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid household id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request householdRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid rename household request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	membership, err := hc.service.RenameHousehold(ctx, userID, uri.ID, request.Name)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to rename household")
+		return
+	}
+
+	slog.Info("household renamed successfully", "userID", userID, "householdID", uri.ID)
+	c.JSON(http.StatusOK, toHouseholdResponse(membership))
+}
+
+func (hc *Controller) DeleteHousehold(c *gin.Context) {
+	var uri householdURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid household id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = hc.service.DeleteHousehold(ctx, userID, uri.ID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to delete household")
+		return
+	}
+
+	slog.Info("household deleted successfully", "userID", userID, "householdID", uri.ID)
+	c.Status(http.StatusNoContent)
+}
+
+func (hc *Controller) GetMembers(c *gin.Context) {
+	var uri householdURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid household id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	members, err := hc.service.GetMembers(ctx, userID, uri.ID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to list members")
+		return
+	}
+
+	response := make([]memberResponse, 0, len(members))
+	for _, member := range members {
+		response = append(response, memberResponse{
+			UserID:   member.UserID,
+			Username: member.Username,
+			Role:     member.Role,
+			JoinedAt: member.JoinedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"members": response})
+}
+
+func (hc *Controller) UpdateMember(c *gin.Context) {
+	var uri memberURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid member id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request updateMemberRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid update member request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = hc.service.UpdateMemberRole(ctx, userID, uri.ID, uri.UserID, request.Role)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to update member")
+		return
+	}
+
+	slog.Info("member role updated successfully", "userID", userID, "householdID", uri.ID, "memberID", uri.UserID, "role", request.Role)
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveMember removes a member, a user can also remove itself to leave the household
+func (hc *Controller) RemoveMember(c *gin.Context) {
+	var uri memberURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid member id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = hc.service.RemoveMember(ctx, userID, uri.ID, uri.UserID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to remove member")
+		return
+	}
+
+	slog.Info("member removed successfully", "userID", userID, "householdID", uri.ID, "memberID", uri.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+func (hc *Controller) TransferOwnership(c *gin.Context) {
+	var uri householdURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid household id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request transferOwnershipRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid transfer ownership request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = hc.service.TransferOwnership(ctx, userID, uri.ID, request.UserID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to transfer ownership")
+		return
+	}
+
+	slog.Info("household ownership transferred successfully", "userID", userID, "householdID", uri.ID, "newOwnerID", request.UserID)
+	c.Status(http.StatusNoContent)
+}
+
+func (hc *Controller) Invite(c *gin.Context) {
+	var uri householdURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid household id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request inviteRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid invite request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	invitation, err := hc.service.Invite(ctx, userID, uri.ID, request.Email, request.Username, request.Role)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to invite user")
+		return
+	}
+
+	slog.Info("user invited successfully", "userID", userID, "householdID", uri.ID, "inviteeID", invitation.InviteeID)
+	c.JSON(http.StatusCreated, toInvitationResponse(invitation))
+}
+
+func (hc *Controller) GetHouseholdInvitations(c *gin.Context) {
+	var uri householdURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid household id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	invitations, err := hc.service.GetHouseholdInvitations(ctx, userID, uri.ID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to list household invitations")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": toInvitationsResponse(invitations)})
+}
+
+func (hc *Controller) RevokeInvitation(c *gin.Context) {
+	var uri householdInvitationURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid invitation id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = hc.service.RevokeInvitation(ctx, userID, uri.ID, uri.InvitationID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to revoke invitation")
+		return
+	}
+
+	slog.Info("invitation revoked successfully", "userID", userID, "householdID", uri.ID, "invitationID", uri.InvitationID)
+	c.Status(http.StatusNoContent)
+}
+
+// GetInvitations lists the invitations received by the user
+func (hc *Controller) GetInvitations(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	invitations, err := hc.service.GetInvitations(ctx, userID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to list invitations")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"invitations": toInvitationsResponse(invitations)})
+}
+
+func (hc *Controller) AcceptInvitation(c *gin.Context) {
+	var uri invitationURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid invitation id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	membership, err := hc.service.AcceptInvitation(ctx, userID, uri.ID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to accept invitation")
+		return
+	}
+
+	slog.Info("invitation accepted successfully", "userID", userID, "householdID", membership.ID)
+	c.JSON(http.StatusOK, toHouseholdResponse(membership))
+}
+
+func (hc *Controller) DeclineInvitation(c *gin.Context) {
+	var uri invitationURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid invitation id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = hc.service.DeclineInvitation(ctx, userID, uri.ID)
+	if err != nil {
+		hc.handleHouseholdError(c, err, "failed to decline invitation")
+		return
+	}
+
+	slog.Info("invitation declined successfully", "userID", userID, "invitationID", uri.ID)
+	c.Status(http.StatusNoContent)
+}
+
+func (hc *Controller) handleHouseholdError(c *gin.Context, err error, message string) {
+	// a household of which the user is not a member is reported as
+	// not found, in this way we do not leak which household ids exist
+	if errors.Is(err, ErrHouseholdNotFound) {
+		slog.Warn("household not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "household not found"})
+		return
+	}
+	if errors.Is(err, ErrMemberNotFound) || errors.Is(err, ErrInvitationNotFound) || errors.Is(err, ErrInviteeNotFound) {
+		slog.Warn("household resource not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrInsufficientRole) {
+		slog.Warn("household action forbidden", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrOwnerCannotLeave) || errors.Is(err, ErrAlreadyMember) {
+		slog.Warn("household conflict", "error", err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrInvalidRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
+
+func toHouseholdResponse(membership *Membership) householdResponse {
+	return householdResponse{
+		ID:        membership.ID,
+		Name:      membership.Name,
+		Role:      membership.Role,
+		CreatedAt: membership.CreatedAt,
+	}
+}
+
+func toInvitationsResponse(invitations []*Invitation) []invitationResponse {
+	response := make([]invitationResponse, 0, len(invitations))
+	for _, invitation := range invitations {
+		response = append(response, toInvitationResponse(invitation))
+	}
+	return response
+}
+
+func toInvitationResponse(invitation *Invitation) invitationResponse {
+	return invitationResponse{
+		ID:            invitation.ID,
+		HouseholdID:   invitation.HouseholdID,
+		HouseholdName: invitation.HouseholdName,
+		InviteeID:     invitation.InviteeID,
+		InvitedBy:     invitation.InvitedBy,
+		Role:          invitation.Role,
+		CreatedAt:     invitation.CreatedAt,
+		ExpiresAt:     invitation.ExpiresAt,
+	}
+}
@@ -0,0 +1,335 @@
+package household_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household/mocks"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+const (
+	testHouseholdID = "9b2f5c8e-3a41-4d6b-8f0e-2c7d1a9e4b53"
+	testMemberID    = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+)
+
+/**
+ * newTestContext creates a gin.Context for an authenticated user,
+ * as if the request already went through the AuthMiddleware.
+ */
+func newTestContext(method, path string, body []byte, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = params
+	c.Set("userID", "user-1")
+	return c, w
+}
+
+func TestController_CreateHousehold(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockhouseholdService)
+	}{
+		{
+			name:         "success",
+			body:         `{"name":"home"}`,
+			expectedCode: http.StatusCreated,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().
+					CreateHousehold(gomock.Any(), "user-1", "home").
+					Return(&household.Membership{Household: household.Household{ID: testHouseholdID, Name: "home", CreatedAt: time.Now()}, Role: household.RoleOwner}, nil)
+			},
+		},
+		{
+			name:         "missing_name",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockhouseholdService) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdService := mocks.NewMockhouseholdService(ctrl)
+			tt.setupMock(mockHouseholdService)
+
+			hc := household.NewHouseholdController(mockHouseholdService)
+			c, w := newTestContext(http.MethodPost, "/households", []byte(tt.body), nil)
+
+			hc.CreateHousehold(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_GetHousehold(t *testing.T) {
+	tests := []struct {
+		name         string
+		householdID  string
+		expectedCode int
+		setupMock    func(*mocks.MockhouseholdService)
+	}{
+		{
+			name:         "success",
+			householdID:  testHouseholdID,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().
+					GetHousehold(gomock.Any(), "user-1", testHouseholdID).
+					Return(&household.Membership{Household: household.Household{ID: testHouseholdID, Name: "home"}, Role: household.RoleViewer}, nil)
+			},
+		},
+		{
+			name:         "not_a_member",
+			householdID:  testHouseholdID,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().GetHousehold(gomock.Any(), "user-1", testHouseholdID).Return(nil, household.ErrHouseholdNotFound)
+			},
+		},
+		{
+			name:         "invalid_id",
+			householdID:  "42",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockhouseholdService) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdService := mocks.NewMockhouseholdService(ctrl)
+			tt.setupMock(mockHouseholdService)
+
+			hc := household.NewHouseholdController(mockHouseholdService)
+			params := gin.Params{{Key: "id", Value: tt.householdID}}
+			c, w := newTestContext(http.MethodGet, "/households/"+tt.householdID, nil, params)
+
+			hc.GetHousehold(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_UpdateMember(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockhouseholdService)
+	}{
+		{
+			name:         "success",
+			body:         `{"role":"admin"}`,
+			expectedCode: http.StatusNoContent,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().UpdateMemberRole(gomock.Any(), "user-1", testHouseholdID, testMemberID, household.RoleAdmin).Return(nil)
+			},
+		},
+		{
+			name:         "owner_role",
+			body:         `{"role":"owner"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockhouseholdService) {},
+		},
+		{
+			name:         "insufficient_role",
+			body:         `{"role":"viewer"}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().UpdateMemberRole(gomock.Any(), "user-1", testHouseholdID, testMemberID, household.RoleViewer).Return(household.ErrInsufficientRole)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdService := mocks.NewMockhouseholdService(ctrl)
+			tt.setupMock(mockHouseholdService)
+
+			hc := household.NewHouseholdController(mockHouseholdService)
+			params := gin.Params{{Key: "id", Value: testHouseholdID}, {Key: "userID", Value: testMemberID}}
+			c, w := newTestContext(http.MethodPatch, "/households/"+testHouseholdID+"/members/"+testMemberID, []byte(tt.body), params)
+
+			hc.UpdateMember(c)
+
+			// c.Status does not flush the header on its own
+			c.Writer.WriteHeaderNow()
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_RemoveMember_OwnerLeaving(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockHouseholdService := mocks.NewMockhouseholdService(ctrl)
+	mockHouseholdService.EXPECT().RemoveMember(gomock.Any(), "user-1", testHouseholdID, testMemberID).Return(household.ErrOwnerCannotLeave)
+
+	hc := household.NewHouseholdController(mockHouseholdService)
+	params := gin.Params{{Key: "id", Value: testHouseholdID}, {Key: "userID", Value: testMemberID}}
+	c, w := newTestContext(http.MethodDelete, "/households/"+testHouseholdID+"/members/"+testMemberID, nil, params)
+
+	hc.RemoveMember(c)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("got %d want %d; body=%s", w.Code, http.StatusConflict, w.Body.String())
+	}
+}
+
+func TestController_Invite(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockhouseholdService)
+	}{
+		{
+			name:         "by_email",
+			body:         `{"email":"bob@example.com","role":"member"}`,
+			expectedCode: http.StatusCreated,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().
+					Invite(gomock.Any(), "user-1", testHouseholdID, "bob@example.com", "", household.RoleMember).
+					Return(&household.Invitation{ID: "invitation-1", HouseholdID: testHouseholdID, InviteeID: "user-2", Role: household.RoleMember}, nil)
+			},
+		},
+		{
+			name:         "by_username",
+			body:         `{"username":"bob","role":"viewer"}`,
+			expectedCode: http.StatusCreated,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().
+					Invite(gomock.Any(), "user-1", testHouseholdID, "", "bob", household.RoleViewer).
+					Return(&household.Invitation{ID: "invitation-1", HouseholdID: testHouseholdID, InviteeID: "user-2", Role: household.RoleViewer}, nil)
+			},
+		},
+		{
+			name:         "both_email_and_username",
+			body:         `{"email":"bob@example.com","username":"bob","role":"member"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockhouseholdService) {},
+		},
+		{
+			name:         "neither_email_nor_username",
+			body:         `{"role":"member"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockhouseholdService) {},
+		},
+		{
+			name:         "unknown_user",
+			body:         `{"email":"nobody@example.com","role":"member"}`,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().
+					Invite(gomock.Any(), "user-1", testHouseholdID, "nobody@example.com", "", household.RoleMember).
+					Return(nil, household.ErrInviteeNotFound)
+			},
+		},
+		{
+			name:         "already_member",
+			body:         `{"username":"bob","role":"member"}`,
+			expectedCode: http.StatusConflict,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().
+					Invite(gomock.Any(), "user-1", testHouseholdID, "", "bob", household.RoleMember).
+					Return(nil, household.ErrAlreadyMember)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdService := mocks.NewMockhouseholdService(ctrl)
+			tt.setupMock(mockHouseholdService)
+
+			hc := household.NewHouseholdController(mockHouseholdService)
+			params := gin.Params{{Key: "id", Value: testHouseholdID}}
+			c, w := newTestContext(http.MethodPost, "/households/"+testHouseholdID+"/invitations", []byte(tt.body), params)
+
+			hc.Invite(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_AcceptInvitation(t *testing.T) {
+	tests := []struct {
+		name         string
+		expectedCode int
+		setupMock    func(*mocks.MockhouseholdService)
+	}{
+		{
+			name:         "success",
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().
+					AcceptInvitation(gomock.Any(), "user-1", testMemberID).
+					Return(&household.Membership{Household: household.Household{ID: testHouseholdID, Name: "home"}, Role: household.RoleMember}, nil)
+			},
+		},
+		{
+			name:         "expired",
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockhouseholdService) {
+				m.EXPECT().AcceptInvitation(gomock.Any(), "user-1", testMemberID).Return(nil, household.ErrInvitationNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdService := mocks.NewMockhouseholdService(ctrl)
+			tt.setupMock(mockHouseholdService)
+
+			hc := household.NewHouseholdController(mockHouseholdService)
+			params := gin.Params{{Key: "id", Value: testMemberID}}
+			c, w := newTestContext(http.MethodPost, "/invitations/"+testMemberID+"/accept", nil, params)
+
+			hc.AcceptInvitation(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
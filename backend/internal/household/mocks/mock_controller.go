@@ -0,0 +1,261 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	household "github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockhouseholdService is a mock of householdService interface.
+type MockhouseholdService struct {
+	ctrl     *gomock.Controller
+	recorder *MockhouseholdServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockhouseholdServiceMockRecorder is the mock recorder for MockhouseholdService.
+type MockhouseholdServiceMockRecorder struct {
+	mock *MockhouseholdService
+}
+
+// NewMockhouseholdService creates a new mock instance.
+func NewMockhouseholdService(ctrl *gomock.Controller) *MockhouseholdService {
+	mock := &MockhouseholdService{ctrl: ctrl}
+	mock.recorder = &MockhouseholdServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockhouseholdService) EXPECT() *MockhouseholdServiceMockRecorder {
+	return m.recorder
+}
+
+// AcceptInvitation mocks base method.
+func (m *MockhouseholdService) AcceptInvitation(ctx context.Context, userID, invitationID string) (*household.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptInvitation", ctx, userID, invitationID)
+	ret0, _ := ret[0].(*household.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcceptInvitation indicates an expected call of AcceptInvitation.
+func (mr *MockhouseholdServiceMockRecorder) AcceptInvitation(ctx, userID, invitationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptInvitation", reflect.TypeOf((*MockhouseholdService)(nil).AcceptInvitation), ctx, userID, invitationID)
+}
+
+// CreateHousehold mocks base method.
+func (m *MockhouseholdService) CreateHousehold(ctx context.Context, userID, name string) (*household.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHousehold", ctx, userID, name)
+	ret0, _ := ret[0].(*household.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHousehold indicates an expected call of CreateHousehold.
+func (mr *MockhouseholdServiceMockRecorder) CreateHousehold(ctx, userID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHousehold", reflect.TypeOf((*MockhouseholdService)(nil).CreateHousehold), ctx, userID, name)
+}
+
+// DeclineInvitation mocks base method.
+func (m *MockhouseholdService) DeclineInvitation(ctx context.Context, userID, invitationID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeclineInvitation", ctx, userID, invitationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeclineInvitation indicates an expected call of DeclineInvitation.
+func (mr *MockhouseholdServiceMockRecorder) DeclineInvitation(ctx, userID, invitationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeclineInvitation", reflect.TypeOf((*MockhouseholdService)(nil).DeclineInvitation), ctx, userID, invitationID)
+}
+
+// DeleteHousehold mocks base method.
+func (m *MockhouseholdService) DeleteHousehold(ctx context.Context, userID, householdID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteHousehold", ctx, userID, householdID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteHousehold indicates an expected call of DeleteHousehold.
+func (mr *MockhouseholdServiceMockRecorder) DeleteHousehold(ctx, userID, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHousehold", reflect.TypeOf((*MockhouseholdService)(nil).DeleteHousehold), ctx, userID, householdID)
+}
+
+// GetHousehold mocks base method.
+func (m *MockhouseholdService) GetHousehold(ctx context.Context, userID, householdID string) (*household.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHousehold", ctx, userID, householdID)
+	ret0, _ := ret[0].(*household.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHousehold indicates an expected call of GetHousehold.
+func (mr *MockhouseholdServiceMockRecorder) GetHousehold(ctx, userID, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHousehold", reflect.TypeOf((*MockhouseholdService)(nil).GetHousehold), ctx, userID, householdID)
+}
+
+// GetHouseholdInvitations mocks base method.
+func (m *MockhouseholdService) GetHouseholdInvitations(ctx context.Context, userID, householdID string) ([]*household.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHouseholdInvitations", ctx, userID, householdID)
+	ret0, _ := ret[0].([]*household.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHouseholdInvitations indicates an expected call of GetHouseholdInvitations.
+func (mr *MockhouseholdServiceMockRecorder) GetHouseholdInvitations(ctx, userID, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHouseholdInvitations", reflect.TypeOf((*MockhouseholdService)(nil).GetHouseholdInvitations), ctx, userID, householdID)
+}
+
+// GetHouseholds mocks base method.
+func (m *MockhouseholdService) GetHouseholds(ctx context.Context, userID string) ([]*household.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHouseholds", ctx, userID)
+	ret0, _ := ret[0].([]*household.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHouseholds indicates an expected call of GetHouseholds.
+func (mr *MockhouseholdServiceMockRecorder) GetHouseholds(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHouseholds", reflect.TypeOf((*MockhouseholdService)(nil).GetHouseholds), ctx, userID)
+}
+
+// GetInvitations mocks base method.
+func (m *MockhouseholdService) GetInvitations(ctx context.Context, userID string) ([]*household.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitations", ctx, userID)
+	ret0, _ := ret[0].([]*household.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitations indicates an expected call of GetInvitations.
+func (mr *MockhouseholdServiceMockRecorder) GetInvitations(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitations", reflect.TypeOf((*MockhouseholdService)(nil).GetInvitations), ctx, userID)
+}
+
+// GetMembers mocks base method.
+func (m *MockhouseholdService) GetMembers(ctx context.Context, userID, householdID string) ([]*household.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembers", ctx, userID, householdID)
+	ret0, _ := ret[0].([]*household.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembers indicates an expected call of GetMembers.
+func (mr *MockhouseholdServiceMockRecorder) GetMembers(ctx, userID, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembers", reflect.TypeOf((*MockhouseholdService)(nil).GetMembers), ctx, userID, householdID)
+}
+
+// Invite mocks base method.
+func (m *MockhouseholdService) Invite(ctx context.Context, userID, householdID, email, username string, role household.Role) (*household.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Invite", ctx, userID, householdID, email, username, role)
+	ret0, _ := ret[0].(*household.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Invite indicates an expected call of Invite.
+func (mr *MockhouseholdServiceMockRecorder) Invite(ctx, userID, householdID, email, username, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invite", reflect.TypeOf((*MockhouseholdService)(nil).Invite), ctx, userID, householdID, email, username, role)
+}
+
+// RemoveMember mocks base method.
+func (m *MockhouseholdService) RemoveMember(ctx context.Context, userID, householdID, memberID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMember", ctx, userID, householdID, memberID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveMember indicates an expected call of RemoveMember.
+func (mr *MockhouseholdServiceMockRecorder) RemoveMember(ctx, userID, householdID, memberID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMember", reflect.TypeOf((*MockhouseholdService)(nil).RemoveMember), ctx, userID, householdID, memberID)
+}
+
+// RenameHousehold mocks base method.
+func (m *MockhouseholdService) RenameHousehold(ctx context.Context, userID, householdID, name string) (*household.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameHousehold", ctx, userID, householdID, name)
+	ret0, _ := ret[0].(*household.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameHousehold indicates an expected call of RenameHousehold.
+func (mr *MockhouseholdServiceMockRecorder) RenameHousehold(ctx, userID, householdID, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameHousehold", reflect.TypeOf((*MockhouseholdService)(nil).RenameHousehold), ctx, userID, householdID, name)
+}
+
+// RevokeInvitation mocks base method.
+func (m *MockhouseholdService) RevokeInvitation(ctx context.Context, userID, householdID, invitationID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeInvitation", ctx, userID, householdID, invitationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeInvitation indicates an expected call of RevokeInvitation.
+func (mr *MockhouseholdServiceMockRecorder) RevokeInvitation(ctx, userID, householdID, invitationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeInvitation", reflect.TypeOf((*MockhouseholdService)(nil).RevokeInvitation), ctx, userID, householdID, invitationID)
+}
+
+// TransferOwnership mocks base method.
+func (m *MockhouseholdService) TransferOwnership(ctx context.Context, userID, householdID, newOwnerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOwnership", ctx, userID, householdID, newOwnerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferOwnership indicates an expected call of TransferOwnership.
+func (mr *MockhouseholdServiceMockRecorder) TransferOwnership(ctx, userID, householdID, newOwnerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOwnership", reflect.TypeOf((*MockhouseholdService)(nil).TransferOwnership), ctx, userID, householdID, newOwnerID)
+}
+
+// UpdateMemberRole mocks base method.
+func (m *MockhouseholdService) UpdateMemberRole(ctx context.Context, userID, householdID, memberID string, role household.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMemberRole", ctx, userID, householdID, memberID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateMemberRole indicates an expected call of UpdateMemberRole.
+func (mr *MockhouseholdServiceMockRecorder) UpdateMemberRole(ctx, userID, householdID, memberID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMemberRole", reflect.TypeOf((*MockhouseholdService)(nil).UpdateMemberRole), ctx, userID, householdID, memberID, role)
+}
@@ -0,0 +1,328 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	household "github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	user "github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockhouseholdRepository is a mock of householdRepository interface.
+type MockhouseholdRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockhouseholdRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockhouseholdRepositoryMockRecorder is the mock recorder for MockhouseholdRepository.
+type MockhouseholdRepositoryMockRecorder struct {
+	mock *MockhouseholdRepository
+}
+
+// NewMockhouseholdRepository creates a new mock instance.
+func NewMockhouseholdRepository(ctrl *gomock.Controller) *MockhouseholdRepository {
+	mock := &MockhouseholdRepository{ctrl: ctrl}
+	mock.recorder = &MockhouseholdRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockhouseholdRepository) EXPECT() *MockhouseholdRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AcceptInvitation mocks base method.
+func (m *MockhouseholdRepository) AcceptInvitation(ctx context.Context, inviteeID, invitationID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptInvitation", ctx, inviteeID, invitationID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcceptInvitation indicates an expected call of AcceptInvitation.
+func (mr *MockhouseholdRepositoryMockRecorder) AcceptInvitation(ctx, inviteeID, invitationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptInvitation", reflect.TypeOf((*MockhouseholdRepository)(nil).AcceptInvitation), ctx, inviteeID, invitationID)
+}
+
+// CreateOne mocks base method.
+func (m *MockhouseholdRepository) CreateOne(ctx context.Context, arg1 *household.Household, ownerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, arg1, ownerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockhouseholdRepositoryMockRecorder) CreateOne(ctx, arg1, ownerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockhouseholdRepository)(nil).CreateOne), ctx, arg1, ownerID)
+}
+
+// DeclineInvitation mocks base method.
+func (m *MockhouseholdRepository) DeclineInvitation(ctx context.Context, inviteeID, invitationID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeclineInvitation", ctx, inviteeID, invitationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeclineInvitation indicates an expected call of DeclineInvitation.
+func (mr *MockhouseholdRepositoryMockRecorder) DeclineInvitation(ctx, inviteeID, invitationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeclineInvitation", reflect.TypeOf((*MockhouseholdRepository)(nil).DeclineInvitation), ctx, inviteeID, invitationID)
+}
+
+// DeleteInvitation mocks base method.
+func (m *MockhouseholdRepository) DeleteInvitation(ctx context.Context, householdID, invitationID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteInvitation", ctx, householdID, invitationID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteInvitation indicates an expected call of DeleteInvitation.
+func (mr *MockhouseholdRepositoryMockRecorder) DeleteInvitation(ctx, householdID, invitationID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteInvitation", reflect.TypeOf((*MockhouseholdRepository)(nil).DeleteInvitation), ctx, householdID, invitationID)
+}
+
+// DeleteMember mocks base method.
+func (m *MockhouseholdRepository) DeleteMember(ctx context.Context, householdID, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMember", ctx, householdID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMember indicates an expected call of DeleteMember.
+func (mr *MockhouseholdRepositoryMockRecorder) DeleteMember(ctx, householdID, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMember", reflect.TypeOf((*MockhouseholdRepository)(nil).DeleteMember), ctx, householdID, userID)
+}
+
+// DeleteOneByID mocks base method.
+func (m *MockhouseholdRepository) DeleteOneByID(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOneByID", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOneByID indicates an expected call of DeleteOneByID.
+func (mr *MockhouseholdRepositoryMockRecorder) DeleteOneByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneByID", reflect.TypeOf((*MockhouseholdRepository)(nil).DeleteOneByID), ctx, id)
+}
+
+// GetAllByUserID mocks base method.
+func (m *MockhouseholdRepository) GetAllByUserID(ctx context.Context, userID string) ([]*household.Membership, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllByUserID", ctx, userID)
+	ret0, _ := ret[0].([]*household.Membership)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllByUserID indicates an expected call of GetAllByUserID.
+func (mr *MockhouseholdRepositoryMockRecorder) GetAllByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByUserID", reflect.TypeOf((*MockhouseholdRepository)(nil).GetAllByUserID), ctx, userID)
+}
+
+// GetInvitationsByHouseholdID mocks base method.
+func (m *MockhouseholdRepository) GetInvitationsByHouseholdID(ctx context.Context, householdID string) ([]*household.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitationsByHouseholdID", ctx, householdID)
+	ret0, _ := ret[0].([]*household.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitationsByHouseholdID indicates an expected call of GetInvitationsByHouseholdID.
+func (mr *MockhouseholdRepositoryMockRecorder) GetInvitationsByHouseholdID(ctx, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitationsByHouseholdID", reflect.TypeOf((*MockhouseholdRepository)(nil).GetInvitationsByHouseholdID), ctx, householdID)
+}
+
+// GetInvitationsByInviteeID mocks base method.
+func (m *MockhouseholdRepository) GetInvitationsByInviteeID(ctx context.Context, inviteeID string) ([]*household.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInvitationsByInviteeID", ctx, inviteeID)
+	ret0, _ := ret[0].([]*household.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInvitationsByInviteeID indicates an expected call of GetInvitationsByInviteeID.
+func (mr *MockhouseholdRepositoryMockRecorder) GetInvitationsByInviteeID(ctx, inviteeID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInvitationsByInviteeID", reflect.TypeOf((*MockhouseholdRepository)(nil).GetInvitationsByInviteeID), ctx, inviteeID)
+}
+
+// GetMembers mocks base method.
+func (m *MockhouseholdRepository) GetMembers(ctx context.Context, householdID string) ([]*household.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembers", ctx, householdID)
+	ret0, _ := ret[0].([]*household.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembers indicates an expected call of GetMembers.
+func (mr *MockhouseholdRepositoryMockRecorder) GetMembers(ctx, householdID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembers", reflect.TypeOf((*MockhouseholdRepository)(nil).GetMembers), ctx, householdID)
+}
+
+// GetOneByID mocks base method.
+func (m *MockhouseholdRepository) GetOneByID(ctx context.Context, id string) (*household.Household, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByID", ctx, id)
+	ret0, _ := ret[0].(*household.Household)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByID indicates an expected call of GetOneByID.
+func (mr *MockhouseholdRepositoryMockRecorder) GetOneByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByID", reflect.TypeOf((*MockhouseholdRepository)(nil).GetOneByID), ctx, id)
+}
+
+// GetRole mocks base method.
+func (m *MockhouseholdRepository) GetRole(ctx context.Context, householdID, userID string) (household.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRole", ctx, householdID, userID)
+	ret0, _ := ret[0].(household.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRole indicates an expected call of GetRole.
+func (mr *MockhouseholdRepositoryMockRecorder) GetRole(ctx, householdID, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRole", reflect.TypeOf((*MockhouseholdRepository)(nil).GetRole), ctx, householdID, userID)
+}
+
+// TransferOwnership mocks base method.
+func (m *MockhouseholdRepository) TransferOwnership(ctx context.Context, householdID, ownerID, newOwnerID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOwnership", ctx, householdID, ownerID, newOwnerID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferOwnership indicates an expected call of TransferOwnership.
+func (mr *MockhouseholdRepositoryMockRecorder) TransferOwnership(ctx, householdID, ownerID, newOwnerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOwnership", reflect.TypeOf((*MockhouseholdRepository)(nil).TransferOwnership), ctx, householdID, ownerID, newOwnerID)
+}
+
+// UpdateMemberRole mocks base method.
+func (m *MockhouseholdRepository) UpdateMemberRole(ctx context.Context, householdID, userID string, role household.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMemberRole", ctx, householdID, userID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateMemberRole indicates an expected call of UpdateMemberRole.
+func (mr *MockhouseholdRepositoryMockRecorder) UpdateMemberRole(ctx, householdID, userID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMemberRole", reflect.TypeOf((*MockhouseholdRepository)(nil).UpdateMemberRole), ctx, householdID, userID, role)
+}
+
+// UpdateOneName mocks base method.
+func (m *MockhouseholdRepository) UpdateOneName(ctx context.Context, id, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOneName", ctx, id, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOneName indicates an expected call of UpdateOneName.
+func (mr *MockhouseholdRepositoryMockRecorder) UpdateOneName(ctx, id, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOneName", reflect.TypeOf((*MockhouseholdRepository)(nil).UpdateOneName), ctx, id, name)
+}
+
+// UpsertInvitation mocks base method.
+func (m *MockhouseholdRepository) UpsertInvitation(ctx context.Context, invitation *household.Invitation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertInvitation", ctx, invitation)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertInvitation indicates an expected call of UpsertInvitation.
+func (mr *MockhouseholdRepositoryMockRecorder) UpsertInvitation(ctx, invitation any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertInvitation", reflect.TypeOf((*MockhouseholdRepository)(nil).UpsertInvitation), ctx, invitation)
+}
+
+// MockuserRepository is a mock of userRepository interface.
+type MockuserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockuserRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockuserRepositoryMockRecorder is the mock recorder for MockuserRepository.
+type MockuserRepositoryMockRecorder struct {
+	mock *MockuserRepository
+}
+
+// NewMockuserRepository creates a new mock instance.
+func NewMockuserRepository(ctrl *gomock.Controller) *MockuserRepository {
+	mock := &MockuserRepository{ctrl: ctrl}
+	mock.recorder = &MockuserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockuserRepository) EXPECT() *MockuserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetOneByEmail mocks base method.
+func (m *MockuserRepository) GetOneByEmail(ctx context.Context, email string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByEmail", ctx, email)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByEmail indicates an expected call of GetOneByEmail.
+func (mr *MockuserRepositoryMockRecorder) GetOneByEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByEmail", reflect.TypeOf((*MockuserRepository)(nil).GetOneByEmail), ctx, email)
+}
+
+// GetOneByUsername mocks base method.
+func (m *MockuserRepository) GetOneByUsername(ctx context.Context, username string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByUsername", ctx, username)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByUsername indicates an expected call of GetOneByUsername.
+func (mr *MockuserRepositoryMockRecorder) GetOneByUsername(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByUsername", reflect.TypeOf((*MockuserRepository)(nil).GetOneByUsername), ctx, username)
+}
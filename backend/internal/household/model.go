@@ -0,0 +1,74 @@
+package household
+
+import (
+	"time"
+)
+
+// Role tells what a member can do in the household
+type Role string
+
+const (
+	// RoleOwner can do everything, including deleting the household
+	RoleOwner Role = "owner"
+	// RoleAdmin manages the members and the devices
+	RoleAdmin Role = "admin"
+	// RoleMember controls the devices
+	RoleMember Role = "member"
+	// RoleViewer can only watch the devices
+	RoleViewer Role = "viewer"
+)
+
+// the higher the rank, the more the role can do
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast reports whether the role has at least the rights of min
+func (r Role) AtLeast(min Role) bool {
+	return r.IsValid() && roleRanks[r] >= roleRanks[min]
+}
+
+// Outranks reports whether the role can manage a member with the other role
+func (r Role) Outranks(other Role) bool {
+	return r.IsValid() && roleRanks[r] > roleRanks[other]
+}
+
+type Household struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+// Membership is a household seen by one of its members
+type Membership struct {
+	Household
+	Role Role
+}
+
+type Member struct {
+	HouseholdID string
+	UserID      string
+	Username    string
+	Role        Role
+	JoinedAt    time.Time
+}
+
+type Invitation struct {
+	ID            string
+	HouseholdID   string
+	HouseholdName string
+	InviteeID     string
+	// InvitedBy is empty when the user that sent the invitation has been deleted
+	InvitedBy string
+	Role      Role
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,432 @@
+package household
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrHouseholdNotFound  = errors.New("household not found")
+	ErrMemberNotFound     = errors.New("member not found")
+	ErrInvitationNotFound = errors.New("invitation not found")
+)
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewHouseholdRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+// CreateOne creates the household together with its owner
+func (r *repository) CreateOne(ctx context.Context, household *Household, ownerID string) error {
+	id := uuid.New()
+	createdAt := time.Now().UTC()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// the rollback does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO household(id, name, created_at)
+		VALUES($1, $2, $3)
+	`
+	_, err = tx.ExecContext(ctx, query, id, household.Name, createdAt)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO household_member(household_id, user_id, role, created_at)
+		VALUES($1, $2, $3, $4)
+	`
+	_, err = tx.ExecContext(ctx, query, id, ownerID, RoleOwner, createdAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	household.ID = id.String()
+	household.CreatedAt = createdAt
+	return nil
+}
+
+func (r *repository) GetOneByID(ctx context.Context, id string) (*Household, error) {
+	query := `
+		SELECT id, name, created_at
+		FROM household
+		WHERE id = $1
+	`
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var household Household
+	err := row.Scan(&household.ID, &household.Name, &household.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &household, nil
+}
+
+// GetAllByUserID returns the households the user is a member of, with its role
+func (r *repository) GetAllByUserID(ctx context.Context, userID string) ([]*Membership, error) {
+	query := `
+		SELECT h.id, h.name, h.created_at, m.role
+		FROM household h
+		JOIN household_member m ON m.household_id = h.id
+		WHERE m.user_id = $1
+		ORDER BY h.created_at, h.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []*Membership{}
+	for rows.Next() {
+		var membership Membership
+		err := rows.Scan(&membership.ID, &membership.Name, &membership.CreatedAt, &membership.Role)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, &membership)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (r *repository) UpdateOneName(ctx context.Context, id string, name string) error {
+	query := `
+		UPDATE household
+		SET name = $2
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, name)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrHouseholdNotFound)
+}
+
+// DeleteOneByID deletes the household with its members and invitations,
+// the shared devices go back to the users that added them
+func (r *repository) DeleteOneByID(ctx context.Context, id string) error {
+	query := `
+		DELETE FROM household
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrHouseholdNotFound)
+}
+
+// GetRole returns an empty role when the user is not a member of the household
+func (r *repository) GetRole(ctx context.Context, householdID string, userID string) (Role, error) {
+	query := `
+		SELECT role
+		FROM household_member
+		WHERE household_id = $1 AND user_id = $2
+	`
+	row := r.db.QueryRowContext(ctx, query, householdID, userID)
+
+	var role Role
+	err := row.Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *repository) GetMembers(ctx context.Context, householdID string) ([]*Member, error) {
+	query := `
+		SELECT m.household_id, m.user_id, u.username, m.role, m.created_at
+		FROM household_member m
+		JOIN user_account u ON u.id = m.user_id
+		WHERE m.household_id = $1
+		ORDER BY m.created_at, u.username
+	`
+	rows, err := r.db.QueryContext(ctx, query, householdID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*Member{}
+	for rows.Next() {
+		var member Member
+		err := rows.Scan(&member.HouseholdID, &member.UserID, &member.Username, &member.Role, &member.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *repository) UpdateMemberRole(ctx context.Context, householdID string, userID string, role Role) error {
+	query := `
+		UPDATE household_member
+		SET role = $3
+		WHERE household_id = $1 AND user_id = $2
+	`
+	result, err := r.db.ExecContext(ctx, query, householdID, userID, role)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrMemberNotFound)
+}
+
+// DeleteMember removes the user from the household,
+// the devices it shared are not shared anymore
+func (r *repository) DeleteMember(ctx context.Context, householdID string, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM household_member
+		WHERE household_id = $1 AND user_id = $2
+	`
+	result, err := tx.ExecContext(ctx, query, householdID, userID)
+	if err != nil {
+		return err
+	}
+	err = checkAffected(result, ErrMemberNotFound)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE device
+		SET household_id = NULL
+		WHERE household_id = $1 AND user_id = $2
+	`
+	_, err = tx.ExecContext(ctx, query, householdID, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// TransferOwnership makes another member the owner, the previous owner becomes an admin
+func (r *repository) TransferOwnership(ctx context.Context, householdID string, ownerID string, newOwnerID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the owner is demoted first, there can be only one owner at a time
+	query := `
+		UPDATE household_member
+		SET role = $3
+		WHERE household_id = $1 AND user_id = $2 AND role = $4
+	`
+	result, err := tx.ExecContext(ctx, query, householdID, ownerID, RoleAdmin, RoleOwner)
+	if err != nil {
+		return err
+	}
+	err = checkAffected(result, ErrMemberNotFound)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE household_member
+		SET role = $3
+		WHERE household_id = $1 AND user_id = $2
+	`
+	result, err = tx.ExecContext(ctx, query, householdID, newOwnerID, RoleOwner)
+	if err != nil {
+		return err
+	}
+	err = checkAffected(result, ErrMemberNotFound)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpsertInvitation stores the invitation, replacing the one
+// already sent to the same user for the same household
+func (r *repository) UpsertInvitation(ctx context.Context, invitation *Invitation) error {
+	id := uuid.New()
+	createdAt := time.Now().UTC()
+	query := `
+		INSERT INTO household_invitation(id, household_id, invitee_id, invited_by, role, created_at, expires_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (household_id, invitee_id) DO UPDATE
+		SET id = EXCLUDED.id,
+			invited_by = EXCLUDED.invited_by,
+			role = EXCLUDED.role,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+	`
+	_, err := r.db.ExecContext(ctx, query, id, invitation.HouseholdID, invitation.InviteeID, invitation.InvitedBy, invitation.Role, createdAt, invitation.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	invitation.ID = id.String()
+	invitation.CreatedAt = createdAt
+	return nil
+}
+
+// GetInvitationsByInviteeID returns the invitations the user can still accept
+func (r *repository) GetInvitationsByInviteeID(ctx context.Context, inviteeID string) ([]*Invitation, error) {
+	query := `
+		SELECT i.id, i.household_id, h.name, i.invitee_id, i.invited_by, i.role, i.created_at, i.expires_at
+		FROM household_invitation i
+		JOIN household h ON h.id = i.household_id
+		WHERE i.invitee_id = $1 AND i.expires_at > now()
+		ORDER BY i.created_at, i.id
+	`
+	return r.queryInvitations(ctx, query, inviteeID)
+}
+
+// GetInvitationsByHouseholdID returns the pending invitations of the household
+func (r *repository) GetInvitationsByHouseholdID(ctx context.Context, householdID string) ([]*Invitation, error) {
+	query := `
+		SELECT i.id, i.household_id, h.name, i.invitee_id, i.invited_by, i.role, i.created_at, i.expires_at
+		FROM household_invitation i
+		JOIN household h ON h.id = i.household_id
+		WHERE i.household_id = $1 AND i.expires_at > now()
+		ORDER BY i.created_at, i.id
+	`
+	return r.queryInvitations(ctx, query, householdID)
+}
+
+// DeleteInvitation revokes an invitation of the household
+func (r *repository) DeleteInvitation(ctx context.Context, householdID string, invitationID string) error {
+	query := `
+		DELETE FROM household_invitation
+		WHERE household_id = $1 AND id = $2
+	`
+	result, err := r.db.ExecContext(ctx, query, householdID, invitationID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrInvitationNotFound)
+}
+
+// DeclineInvitation deletes an invitation sent to the user
+func (r *repository) DeclineInvitation(ctx context.Context, inviteeID string, invitationID string) error {
+	query := `
+		DELETE FROM household_invitation
+		WHERE invitee_id = $1 AND id = $2
+	`
+	result, err := r.db.ExecContext(ctx, query, inviteeID, invitationID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrInvitationNotFound)
+}
+
+// AcceptInvitation turns the invitation into a membership and returns the household,
+// a user that is already a member keeps its current role
+func (r *repository) AcceptInvitation(ctx context.Context, inviteeID string, invitationID string) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	// the invitation is deleted as soon as it is read, so that it can be used only once
+	query := `
+		DELETE FROM household_invitation
+		WHERE invitee_id = $1 AND id = $2 AND expires_at > now()
+		RETURNING household_id, role
+	`
+	var householdID string
+	var role Role
+	err = tx.QueryRowContext(ctx, query, inviteeID, invitationID).Scan(&householdID, &role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvitationNotFound
+		}
+		return "", err
+	}
+
+	query = `
+		INSERT INTO household_member(household_id, user_id, role, created_at)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (household_id, user_id) DO NOTHING
+	`
+	_, err = tx.ExecContext(ctx, query, householdID, inviteeID, role, time.Now().UTC())
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", err
+	}
+	return householdID, nil
+}
+
+func (r *repository) queryInvitations(ctx context.Context, query string, arg string) ([]*Invitation, error) {
+	rows, err := r.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*Invitation{}
+	for rows.Next() {
+		var invitation Invitation
+		var invitedBy sql.NullString
+		err := rows.Scan(&invitation.ID, &invitation.HouseholdID, &invitation.HouseholdName, &invitation.InviteeID, &invitedBy, &invitation.Role, &invitation.CreatedAt, &invitation.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		invitation.InvitedBy = invitedBy.String
+		invitations = append(invitations, &invitation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// checkAffected reports notFound when a statement did not touch any row
+func checkAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
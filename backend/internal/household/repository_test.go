@@ -0,0 +1,195 @@
+package household
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	// the unit tests of this package do not need a container,
+	// so with -short we avoid starting it at all
+	flag.Parse()
+	if !testing.Short() {
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+// createTestUser inserts a user, since the members are foreign keys to user_account
+func createTestUser(ctx context.Context, t *testing.T) string {
+	t.Helper()
+	id := uuid.New()
+	_, err := testPostgresDB.ExecContext(ctx,
+		"INSERT INTO user_account(id, username, email, password, name, surname) VALUES($1, $2, $3, $4, $5, $6)",
+		id, id.String()[:8], id.String()+"@example.com", "hash", "test", "user",
+	)
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return id.String()
+}
+
+func TestRepository_CreateAndMembers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewHouseholdRepository(testPostgresDB)
+	ownerID := createTestUser(ctx, t)
+	memberID := createTestUser(ctx, t)
+
+	household := &Household{Name: "home"}
+	if err := repo.CreateOne(ctx, household, ownerID); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+
+	role, err := repo.GetRole(ctx, household.ID, ownerID)
+	if err != nil || role != RoleOwner {
+		t.Fatalf("GetRole() = %q, %v, want owner", role, err)
+	}
+	role, err = repo.GetRole(ctx, household.ID, memberID)
+	if err != nil || role != "" {
+		t.Fatalf("GetRole() of a non member = %q, %v", role, err)
+	}
+
+	memberships, err := repo.GetAllByUserID(ctx, ownerID)
+	if err != nil || len(memberships) != 1 || memberships[0].Name != "home" {
+		t.Fatalf("GetAllByUserID() = %+v, %v", memberships, err)
+	}
+
+	// there can be only one owner per household
+	_, err = testPostgresDB.ExecContext(ctx,
+		"INSERT INTO household_member(household_id, user_id, role) VALUES($1, $2, 'owner')",
+		household.ID, memberID,
+	)
+	if err == nil {
+		t.Fatalf("expected a second owner to be rejected")
+	}
+	_, err = testPostgresDB.ExecContext(ctx,
+		"INSERT INTO household_member(household_id, user_id, role) VALUES($1, $2, 'viewer')",
+		household.ID, memberID,
+	)
+	if err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+
+	if err := repo.UpdateMemberRole(ctx, household.ID, memberID, RoleMember); err != nil {
+		t.Fatalf("UpdateMemberRole() error = %v", err)
+	}
+	members, err := repo.GetMembers(ctx, household.ID)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("GetMembers() = %+v, %v", members, err)
+	}
+
+	if err := repo.TransferOwnership(ctx, household.ID, ownerID, memberID); err != nil {
+		t.Fatalf("TransferOwnership() error = %v", err)
+	}
+	if role, _ := repo.GetRole(ctx, household.ID, memberID); role != RoleOwner {
+		t.Errorf("expected the new owner, got %q", role)
+	}
+	if role, _ := repo.GetRole(ctx, household.ID, ownerID); role != RoleAdmin {
+		t.Errorf("expected the previous owner to be an admin, got %q", role)
+	}
+
+	if err := repo.DeleteMember(ctx, household.ID, ownerID); err != nil {
+		t.Fatalf("DeleteMember() error = %v", err)
+	}
+	if err := repo.DeleteMember(ctx, household.ID, ownerID); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("expected ErrMemberNotFound removing twice, got %v", err)
+	}
+
+	if err := repo.DeleteOneByID(ctx, household.ID); err != nil {
+		t.Fatalf("DeleteOneByID() error = %v", err)
+	}
+	got, err := repo.GetOneByID(ctx, household.ID)
+	if err != nil || got != nil {
+		t.Errorf("GetOneByID() on deleted household = %+v, %v", got, err)
+	}
+}
+
+func TestRepository_Invitations(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewHouseholdRepository(testPostgresDB)
+	ownerID := createTestUser(ctx, t)
+	inviteeID := createTestUser(ctx, t)
+
+	household := &Household{Name: "home"}
+	if err := repo.CreateOne(ctx, household, ownerID); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+
+	invitation := &Invitation{
+		HouseholdID: household.ID,
+		InviteeID:   inviteeID,
+		InvitedBy:   ownerID,
+		Role:        RoleViewer,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+	if err := repo.UpsertInvitation(ctx, invitation); err != nil {
+		t.Fatalf("UpsertInvitation() error = %v", err)
+	}
+	// inviting again replaces the previous invitation
+	invitation.Role = RoleMember
+	if err := repo.UpsertInvitation(ctx, invitation); err != nil {
+		t.Fatalf("UpsertInvitation() error = %v", err)
+	}
+
+	invitations, err := repo.GetInvitationsByInviteeID(ctx, inviteeID)
+	if err != nil || len(invitations) != 1 {
+		t.Fatalf("GetInvitationsByInviteeID() = %+v, %v", invitations, err)
+	}
+	if invitations[0].Role != RoleMember || invitations[0].HouseholdName != "home" {
+		t.Errorf("unexpected invitation %+v", invitations[0])
+	}
+
+	// only the invitee can accept the invitation
+	if _, err := repo.AcceptInvitation(ctx, ownerID, invitations[0].ID); !errors.Is(err, ErrInvitationNotFound) {
+		t.Errorf("expected ErrInvitationNotFound, got %v", err)
+	}
+	householdID, err := repo.AcceptInvitation(ctx, inviteeID, invitations[0].ID)
+	if err != nil || householdID != household.ID {
+		t.Fatalf("AcceptInvitation() = %q, %v", householdID, err)
+	}
+	if role, _ := repo.GetRole(ctx, household.ID, inviteeID); role != RoleMember {
+		t.Errorf("expected the invitee to be a member, got %q", role)
+	}
+	if _, err := repo.AcceptInvitation(ctx, inviteeID, invitations[0].ID); !errors.Is(err, ErrInvitationNotFound) {
+		t.Errorf("expected the invitation to be used only once, got %v", err)
+	}
+
+	// an expired invitation is neither listed nor accepted
+	otherID := createTestUser(ctx, t)
+	expired := &Invitation{
+		HouseholdID: household.ID,
+		InviteeID:   otherID,
+		InvitedBy:   ownerID,
+		Role:        RoleViewer,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}
+	if err := repo.UpsertInvitation(ctx, expired); err != nil {
+		t.Fatalf("UpsertInvitation() error = %v", err)
+	}
+	invitations, err = repo.GetInvitationsByHouseholdID(ctx, household.ID)
+	if err != nil || len(invitations) != 0 {
+		t.Errorf("expected no pending invitations, got %+v, %v", invitations, err)
+	}
+	if _, err := repo.AcceptInvitation(ctx, otherID, expired.ID); !errors.Is(err, ErrInvitationNotFound) {
+		t.Errorf("expected ErrInvitationNotFound on an expired invitation, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package household
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+)
+
+// an invitation that is not answered within a week has to be sent again
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInsufficientRole = errors.New("role does not allow the action")
+	ErrOwnerCannotLeave = errors.New("the owner cannot leave the household")
+	ErrInviteeNotFound  = errors.New("invited user not found")
+	ErrAlreadyMember    = errors.New("user is already a member of the household")
+	ErrInvalidRole      = errors.New("invalid role")
+)
+
+type householdRepository interface {
+	CreateOne(ctx context.Context, household *Household, ownerID string) error
+	GetOneByID(ctx context.Context, id string) (*Household, error)
+	GetAllByUserID(ctx context.Context, userID string) ([]*Membership, error)
+	UpdateOneName(ctx context.Context, id string, name string) error
+	DeleteOneByID(ctx context.Context, id string) error
+	GetRole(ctx context.Context, householdID string, userID string) (Role, error)
+	GetMembers(ctx context.Context, householdID string) ([]*Member, error)
+	UpdateMemberRole(ctx context.Context, householdID string, userID string, role Role) error
+	DeleteMember(ctx context.Context, householdID string, userID string) error
+	TransferOwnership(ctx context.Context, householdID string, ownerID string, newOwnerID string) error
+	UpsertInvitation(ctx context.Context, invitation *Invitation) error
+	GetInvitationsByInviteeID(ctx context.Context, inviteeID string) ([]*Invitation, error)
+	GetInvitationsByHouseholdID(ctx context.Context, householdID string) ([]*Invitation, error)
+	DeleteInvitation(ctx context.Context, householdID string, invitationID string) error
+	DeclineInvitation(ctx context.Context, inviteeID string, invitationID string) error
+	AcceptInvitation(ctx context.Context, inviteeID string, invitationID string) (string, error)
+}
+
+// userRepository finds the invited users
+type userRepository interface {
+	GetOneByEmail(ctx context.Context, email string) (*user.User, error)
+	GetOneByUsername(ctx context.Context, username string) (*user.User, error)
+}
+
+type service struct {
+	householdRepo householdRepository
+	userRepo      userRepository
+}
+
+func NewHouseholdService(householdRepo householdRepository, userRepo userRepository) *service {
+	return &service{
+		householdRepo: householdRepo,
+		userRepo:      userRepo,
+	}
+}
+
+func (s *service) CreateHousehold(ctx context.Context, userID string, name string) (*Membership, error) {
+	household := &Household{Name: name}
+	err := s.householdRepo.CreateOne(ctx, household, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Membership{Household: *household, Role: RoleOwner}, nil
+}
+
+func (s *service) GetHouseholds(ctx context.Context, userID string) ([]*Membership, error) {
+	return s.householdRepo.GetAllByUserID(ctx, userID)
+}
+
+func (s *service) GetHousehold(ctx context.Context, userID string, householdID string) (*Membership, error) {
+	role, err := s.authorize(ctx, userID, householdID, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	household, err := s.householdRepo.GetOneByID(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	// the household has been deleted after the role was read
+	if household == nil {
+		return nil, ErrHouseholdNotFound
+	}
+	return &Membership{Household: *household, Role: role}, nil
+}
+
+func (s *service) RenameHousehold(ctx context.Context, userID string, householdID string, name string) (*Membership, error) {
+	_, err := s.authorize(ctx, userID, householdID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.householdRepo.UpdateOneName(ctx, householdID, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetHousehold(ctx, userID, householdID)
+}
+
+// DeleteHousehold removes the household, the shared devices
+// are kept by the users that added them
+func (s *service) DeleteHousehold(ctx context.Context, userID string, householdID string) error {
+	_, err := s.authorize(ctx, userID, householdID, RoleOwner)
+	if err != nil {
+		return err
+	}
+	return s.householdRepo.DeleteOneByID(ctx, householdID)
+}
+
+func (s *service) GetMembers(ctx context.Context, userID string, householdID string) ([]*Member, error) {
+	_, err := s.authorize(ctx, userID, householdID, RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.householdRepo.GetMembers(ctx, householdID)
+}
+
+// UpdateMemberRole changes the role of another member, a member can only be managed
+// by a higher role and can only be given a role lower than the one of who changes it
+func (s *service) UpdateMemberRole(ctx context.Context, userID string, householdID string, memberID string, role Role) error {
+	// the owner changes only with TransferOwnership
+	if !role.IsValid() || role == RoleOwner {
+		return ErrInvalidRole
+	}
+
+	actorRole, err := s.authorize(ctx, userID, householdID, RoleAdmin)
+	if err != nil {
+		return err
+	}
+	memberRole, err := s.memberRole(ctx, householdID, memberID)
+	if err != nil {
+		return err
+	}
+	if !actorRole.Outranks(memberRole) || !actorRole.Outranks(role) {
+		return ErrInsufficientRole
+	}
+
+	return s.householdRepo.UpdateMemberRole(ctx, householdID, memberID, role)
+}
+
+// RemoveMember removes another member, or the user itself when memberID is its own id
+func (s *service) RemoveMember(ctx context.Context, userID string, householdID string, memberID string) error {
+	if memberID == userID {
+		role, err := s.authorize(ctx, userID, householdID, RoleViewer)
+		if err != nil {
+			return err
+		}
+		// the household would be left without an owner
+		if role == RoleOwner {
+			return ErrOwnerCannotLeave
+		}
+		return s.householdRepo.DeleteMember(ctx, householdID, userID)
+	}
+
+	actorRole, err := s.authorize(ctx, userID, householdID, RoleAdmin)
+	if err != nil {
+		return err
+	}
+	memberRole, err := s.memberRole(ctx, householdID, memberID)
+	if err != nil {
+		return err
+	}
+	if !actorRole.Outranks(memberRole) {
+		return ErrInsufficientRole
+	}
+
+	return s.householdRepo.DeleteMember(ctx, householdID, memberID)
+}
+
+// TransferOwnership gives the household to another member, the owner stays as an admin
+func (s *service) TransferOwnership(ctx context.Context, userID string, householdID string, newOwnerID string) error {
+	_, err := s.authorize(ctx, userID, householdID, RoleOwner)
+	if err != nil {
+		return err
+	}
+	if newOwnerID == userID {
+		return nil
+	}
+	_, err = s.memberRole(ctx, householdID, newOwnerID)
+	if err != nil {
+		return err
+	}
+
+	return s.householdRepo.TransferOwnership(ctx, householdID, userID, newOwnerID)
+}
+
+// Invite sends an invitation to the user with the email or, when the email is empty,
+// with the username, the role given must be lower than the one of who invites
+func (s *service) Invite(ctx context.Context, userID string, householdID string, email string, username string, role Role) (*Invitation, error) {
+	if !role.IsValid() || role == RoleOwner {
+		return nil, ErrInvalidRole
+	}
+
+	actorRole, err := s.authorize(ctx, userID, householdID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if !actorRole.Outranks(role) {
+		return nil, ErrInsufficientRole
+	}
+
+	var invitee *user.User
+	if email != "" {
+		invitee, err = s.userRepo.GetOneByEmail(ctx, email)
+	} else {
+		invitee, err = s.userRepo.GetOneByUsername(ctx, username)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invitee == nil {
+		return nil, ErrInviteeNotFound
+	}
+
+	inviteeRole, err := s.householdRepo.GetRole(ctx, householdID, invitee.ID)
+	if err != nil {
+		return nil, err
+	}
+	if inviteeRole != "" {
+		return nil, ErrAlreadyMember
+	}
+
+	invitation := &Invitation{
+		HouseholdID: householdID,
+		InviteeID:   invitee.ID,
+		InvitedBy:   userID,
+		Role:        role,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+	err = s.householdRepo.UpsertInvitation(ctx, invitation)
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *service) GetHouseholdInvitations(ctx context.Context, userID string, householdID string) ([]*Invitation, error) {
+	_, err := s.authorize(ctx, userID, householdID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return s.householdRepo.GetInvitationsByHouseholdID(ctx, householdID)
+}
+
+func (s *service) RevokeInvitation(ctx context.Context, userID string, householdID string, invitationID string) error {
+	_, err := s.authorize(ctx, userID, householdID, RoleAdmin)
+	if err != nil {
+		return err
+	}
+	return s.householdRepo.DeleteInvitation(ctx, householdID, invitationID)
+}
+
+// GetInvitations returns the invitations received by the user
+func (s *service) GetInvitations(ctx context.Context, userID string) ([]*Invitation, error) {
+	return s.householdRepo.GetInvitationsByInviteeID(ctx, userID)
+}
+
+func (s *service) AcceptInvitation(ctx context.Context, userID string, invitationID string) (*Membership, error) {
+	householdID, err := s.householdRepo.AcceptInvitation(ctx, userID, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetHousehold(ctx, userID, householdID)
+}
+
+func (s *service) DeclineInvitation(ctx context.Context, userID string, invitationID string) error {
+	return s.householdRepo.DeclineInvitation(ctx, userID, invitationID)
+}
+
+// authorize returns the role of the user when it is at least min,
+// the households of the others are reported as not found
+func (s *service) authorize(ctx context.Context, userID string, householdID string, min Role) (Role, error) {
+	role, err := s.householdRepo.GetRole(ctx, householdID, userID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", ErrHouseholdNotFound
+	}
+	if !role.AtLeast(min) {
+		return "", ErrInsufficientRole
+	}
+	return role, nil
+}
+
+func (s *service) memberRole(ctx context.Context, householdID string, memberID string) (Role, error) {
+	role, err := s.householdRepo.GetRole(ctx, householdID, memberID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", ErrMemberNotFound
+	}
+	return role, nil
+}
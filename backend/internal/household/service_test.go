@@ -0,0 +1,442 @@
+package household_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_GetHousehold(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockhouseholdRepository)
+		expectedRole  household.Role
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleViewer, nil)
+				m.EXPECT().GetOneByID(gomock.Any(), "household-1").Return(&household.Household{ID: "household-1", Name: "home"}, nil)
+			},
+			expectedRole:  household.RoleViewer,
+			expectedError: nil,
+		},
+		{
+			name: "not_a_member",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.Role(""), nil)
+			},
+			expectedError: household.ErrHouseholdNotFound,
+		},
+		{
+			name: "deleted_concurrently",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				m.EXPECT().GetOneByID(gomock.Any(), "household-1").Return(nil, nil)
+			},
+			expectedError: household.ErrHouseholdNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			tt.setupMock(mockHouseholdRepo)
+
+			s := household.NewHouseholdService(mockHouseholdRepo, mocks.NewMockuserRepository(ctrl))
+			got, err := s.GetHousehold(context.Background(), "user-1", "household-1")
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if tt.expectedError == nil && (got.Name != "home" || got.Role != tt.expectedRole) {
+				t.Errorf("unexpected membership %+v", got)
+			}
+		})
+	}
+}
+
+func TestService_RenameHousehold_Member(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+	mockHouseholdRepo.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleMember, nil)
+
+	s := household.NewHouseholdService(mockHouseholdRepo, mocks.NewMockuserRepository(ctrl))
+	_, err := s.RenameHousehold(context.Background(), "user-1", "household-1", "new")
+	if !errors.Is(err, household.ErrInsufficientRole) {
+		t.Errorf("expected error %v, got %v", household.ErrInsufficientRole, err)
+	}
+}
+
+func TestService_DeleteHousehold(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockhouseholdRepository)
+		expectedError error
+	}{
+		{
+			name: "owner",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				m.EXPECT().DeleteOneByID(gomock.Any(), "household-1").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "admin",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			tt.setupMock(mockHouseholdRepo)
+
+			s := household.NewHouseholdService(mockHouseholdRepo, mocks.NewMockuserRepository(ctrl))
+			err := s.DeleteHousehold(context.Background(), "user-1", "household-1")
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_UpdateMemberRole(t *testing.T) {
+	tests := []struct {
+		name          string
+		role          household.Role
+		setupMock     func(*mocks.MockhouseholdRepository)
+		expectedError error
+	}{
+		{
+			name: "owner_promotes_member",
+			role: household.RoleAdmin,
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleMember, nil)
+				m.EXPECT().UpdateMemberRole(gomock.Any(), "household-1", "user-2", household.RoleAdmin).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "admin_cannot_promote_to_admin",
+			role: household.RoleAdmin,
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleMember, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+		{
+			name: "admin_cannot_demote_admin",
+			role: household.RoleViewer,
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleAdmin, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+		{
+			name: "member_cannot_manage",
+			role: household.RoleViewer,
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleMember, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+		{
+			name: "not_a_member",
+			role: household.RoleViewer,
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.Role(""), nil)
+			},
+			expectedError: household.ErrMemberNotFound,
+		},
+		{
+			name:          "owner_role",
+			role:          household.RoleOwner,
+			setupMock:     func(m *mocks.MockhouseholdRepository) {},
+			expectedError: household.ErrInvalidRole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			tt.setupMock(mockHouseholdRepo)
+
+			s := household.NewHouseholdService(mockHouseholdRepo, mocks.NewMockuserRepository(ctrl))
+			err := s.UpdateMemberRole(context.Background(), "user-1", "household-1", "user-2", tt.role)
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_RemoveMember(t *testing.T) {
+	tests := []struct {
+		name          string
+		memberID      string
+		setupMock     func(*mocks.MockhouseholdRepository)
+		expectedError error
+	}{
+		{
+			name:     "leave",
+			memberID: "user-1",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleViewer, nil)
+				m.EXPECT().DeleteMember(gomock.Any(), "household-1", "user-1").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "owner_cannot_leave",
+			memberID: "user-1",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+			},
+			expectedError: household.ErrOwnerCannotLeave,
+		},
+		{
+			name:     "admin_removes_viewer",
+			memberID: "user-2",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleViewer, nil)
+				m.EXPECT().DeleteMember(gomock.Any(), "household-1", "user-2").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "admin_cannot_remove_owner",
+			memberID: "user-2",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleOwner, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			tt.setupMock(mockHouseholdRepo)
+
+			s := household.NewHouseholdService(mockHouseholdRepo, mocks.NewMockuserRepository(ctrl))
+			err := s.RemoveMember(context.Background(), "user-1", "household-1", tt.memberID)
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_TransferOwnership(t *testing.T) {
+	tests := []struct {
+		name          string
+		newOwnerID    string
+		setupMock     func(*mocks.MockhouseholdRepository)
+		expectedError error
+	}{
+		{
+			name:       "success",
+			newOwnerID: "user-2",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleViewer, nil)
+				m.EXPECT().TransferOwnership(gomock.Any(), "household-1", "user-1", "user-2").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "to_itself",
+			newOwnerID: "user-1",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:       "not_the_owner",
+			newOwnerID: "user-2",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+		{
+			name:       "not_a_member",
+			newOwnerID: "user-2",
+			setupMock: func(m *mocks.MockhouseholdRepository) {
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				m.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.Role(""), nil)
+			},
+			expectedError: household.ErrMemberNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			tt.setupMock(mockHouseholdRepo)
+
+			s := household.NewHouseholdService(mockHouseholdRepo, mocks.NewMockuserRepository(ctrl))
+			err := s.TransferOwnership(context.Background(), "user-1", "household-1", tt.newOwnerID)
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_Invite(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		username      string
+		role          household.Role
+		setupMock     func(*mocks.MockhouseholdRepository, *mocks.MockuserRepository)
+		expectedError error
+	}{
+		{
+			name:  "by_email",
+			email: "bob@example.com",
+			role:  household.RoleMember,
+			setupMock: func(h *mocks.MockhouseholdRepository, u *mocks.MockuserRepository) {
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "bob@example.com").Return(&user.User{ID: "user-2"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.Role(""), nil)
+				h.EXPECT().UpsertInvitation(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:     "by_username",
+			username: "bob",
+			role:     household.RoleViewer,
+			setupMock: func(h *mocks.MockhouseholdRepository, u *mocks.MockuserRepository) {
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				u.EXPECT().GetOneByUsername(gomock.Any(), "bob").Return(&user.User{ID: "user-2"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.Role(""), nil)
+				h.EXPECT().UpsertInvitation(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:  "admin_cannot_invite_admin",
+			email: "bob@example.com",
+			role:  household.RoleAdmin,
+			setupMock: func(h *mocks.MockhouseholdRepository, u *mocks.MockuserRepository) {
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleAdmin, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+		{
+			name:  "member_cannot_invite",
+			email: "bob@example.com",
+			role:  household.RoleViewer,
+			setupMock: func(h *mocks.MockhouseholdRepository, u *mocks.MockuserRepository) {
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleMember, nil)
+			},
+			expectedError: household.ErrInsufficientRole,
+		},
+		{
+			name:  "unknown_user",
+			email: "nobody@example.com",
+			role:  household.RoleMember,
+			setupMock: func(h *mocks.MockhouseholdRepository, u *mocks.MockuserRepository) {
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "nobody@example.com").Return(nil, nil)
+			},
+			expectedError: household.ErrInviteeNotFound,
+		},
+		{
+			name:  "already_member",
+			email: "bob@example.com",
+			role:  household.RoleMember,
+			setupMock: func(h *mocks.MockhouseholdRepository, u *mocks.MockuserRepository) {
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-1").Return(household.RoleOwner, nil)
+				u.EXPECT().GetOneByEmail(gomock.Any(), "bob@example.com").Return(&user.User{ID: "user-2"}, nil)
+				h.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleViewer, nil)
+			},
+			expectedError: household.ErrAlreadyMember,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			tt.setupMock(mockHouseholdRepo, mockUserRepo)
+
+			s := household.NewHouseholdService(mockHouseholdRepo, mockUserRepo)
+			got, err := s.Invite(context.Background(), "user-1", "household-1", tt.email, tt.username, tt.role)
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Fatalf("expected error %v, got %v", tt.expectedError, err)
+			}
+			if tt.expectedError != nil {
+				return
+			}
+			if got.InviteeID != "user-2" || got.InvitedBy != "user-1" || got.Role != tt.role {
+				t.Errorf("unexpected invitation %+v", got)
+			}
+			if !got.ExpiresAt.After(time.Now().Add(6 * 24 * time.Hour)) {
+				t.Errorf("expected the invitation to last a week, expires at %v", got.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestService_AcceptInvitation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockHouseholdRepo := mocks.NewMockhouseholdRepository(ctrl)
+	mockHouseholdRepo.EXPECT().AcceptInvitation(gomock.Any(), "user-2", "invitation-1").Return("household-1", nil)
+	mockHouseholdRepo.EXPECT().GetRole(gomock.Any(), "household-1", "user-2").Return(household.RoleMember, nil)
+	mockHouseholdRepo.EXPECT().GetOneByID(gomock.Any(), "household-1").Return(&household.Household{ID: "household-1", Name: "home"}, nil)
+
+	s := household.NewHouseholdService(mockHouseholdRepo, mocks.NewMockuserRepository(ctrl))
+	got, err := s.AcceptInvitation(context.Background(), "user-2", "invitation-1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.ID != "household-1" || got.Role != household.RoleMember {
+		t.Errorf("unexpected membership %+v", got)
+	}
+}
@@ -0,0 +1,248 @@
+package live
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeTimeout = 10 * time.Second
+	// the client must answer a ping within pongTimeout, otherwise the connection is dropped
+	pongTimeout  = 60 * time.Second
+	pingInterval = 50 * time.Second
+	// the client is not expected to send anything besides the control frames
+	maxClientMessageSize = 512
+	// how often an open stream checks that the user can still watch the device
+	authorizeInterval = time.Minute
+)
+
+var upgrader = websocket.Upgrader{
+	// the token is sent in the Authorization header and not in a cookie,
+	// so a page of another origin cannot open a stream on behalf of the user
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+type liveService interface {
+	Subscribe(ctx context.Context, userID string, deviceID string) (<-chan []byte, func() error, error)
+	Authorize(ctx context.Context, userID string, deviceID string, jti string) error
+}
+
+type Controller struct {
+	service liveService
+	// closing is closed by Shutdown to end the open streams
+	closing      chan struct{}
+	mu           sync.Mutex
+	shuttingDown bool
+	streams      sync.WaitGroup
+	// authorizeInterval is a field so that the tests can shorten it
+	authorizeInterval time.Duration
+}
+
+func NewLiveController(service liveService) *Controller {
+	return &Controller{
+		service:           service,
+		closing:           make(chan struct{}),
+		authorizeInterval: authorizeInterval,
+	}
+}
+
+// Close tells the open streams to end and refuses the new ones
+func (lc *Controller) Close() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if !lc.shuttingDown {
+		lc.shuttingDown = true
+		close(lc.closing)
+	}
+}
+
+// Shutdown closes the open streams telling the clients to reconnect and waits for
+// them to end, the connections are hijacked so the HTTP server does not track them
+func (lc *Controller) Shutdown(ctx context.Context) error {
+	lc.Close()
+
+	done := make(chan struct{})
+	go func() {
+		lc.streams.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// track counts a new stream, it fails once the shutdown has started
+func (lc *Controller) track() bool {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.shuttingDown {
+		return false
+	}
+	lc.streams.Add(1)
+	return true
+}
+
+type deviceURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// Live upgrades the request to a WebSocket and pushes the events of the device as JSON text messages
+func (lc *Controller) Live(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !lc.track() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server shutting down"})
+		return
+	}
+	defer lc.streams.Done()
+
+	// the subscription is done before the upgrade, so the errors are still plain HTTP responses
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	messages, closeSubscription, err := lc.service.Subscribe(ctx, userID, uri.ID)
+	if err != nil {
+		lc.handleLiveError(c, err, "failed to subscribe to live events")
+		return
+	}
+	defer closeSubscription()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader has already answered with an HTTP error
+		slog.Warn("failed to upgrade to websocket", "error", err)
+		return
+	}
+	defer conn.Close()
+	slog.Info("live stream opened", "userID", userID, "deviceID", uri.ID)
+
+	// reading is needed to process the pongs and to notice when the client goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(maxClientMessageSize)
+		_ = conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// the token is checked only at the upgrade, so the stream ends when the token expires
+	// and the app has to reconnect with a fresh one, meanwhile the access is checked again
+	// periodically so that a revoked token or a removed member stops receiving the events
+	var expired <-chan time.Time
+	if expiresAt := c.GetTime("tokenExpiresAt"); !expiresAt.IsZero() {
+		expiry := time.NewTimer(time.Until(expiresAt))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+	jti := c.GetString("jti")
+	authorize := time.NewTicker(lc.authorizeInterval)
+	defer authorize.Stop()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			slog.Info("live stream closed", "userID", userID, "deviceID", uri.ID)
+			return
+		case <-lc.closing:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+			return
+		case <-expired:
+			slog.Info("live stream token expired", "userID", userID, "deviceID", uri.ID)
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token expired"))
+			return
+		case <-authorize.C:
+			authorizeCtx, cancel := context.WithTimeout(ctx, writeTimeout)
+			err := lc.service.Authorize(authorizeCtx, userID, uri.ID, jti)
+			cancel()
+			if err != nil {
+				_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+				if isAccessRevoked(err) {
+					slog.Info("live stream access revoked", "userID", userID, "deviceID", uri.ID, "error", err)
+					_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "access revoked"))
+					return
+				}
+				// the access cannot be confirmed, e.g. redis is down, the client should reconnect
+				slog.Error("failed to authorize live stream", "userID", userID, "deviceID", uri.ID, "error", err)
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "stream interrupted"))
+				return
+			}
+		case message, ok := <-messages:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if !ok {
+				// the subscription is gone, e.g. redis has been restarted, the client should reconnect
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "stream interrupted"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				slog.Warn("failed to write live event", "deviceID", uri.ID, "error", err)
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// isAccessRevoked tells if the user can no longer watch the device
+func isAccessRevoked(err error) bool {
+	return errors.Is(err, ErrTokenRevoked) ||
+		errors.Is(err, device.ErrDeviceNotFound) ||
+		errors.Is(err, device.ErrNotDeviceOwner) ||
+		errors.Is(err, device.ErrPermissionDenied)
+}
+
+func (lc *Controller) handleLiveError(c *gin.Context, err error, message string) {
+	if errors.Is(err, device.ErrDeviceNotFound) || errors.Is(err, device.ErrNotDeviceOwner) {
+		slog.Warn("device not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+		return
+	}
+	if errors.Is(err, device.ErrPermissionDenied) {
+		slog.Warn("device action forbidden", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
@@ -0,0 +1,288 @@
+package live_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/live"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/live/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+const testDeviceID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+/**
+ * newTestServer serves the live endpoint for a user authenticated with a token
+ * that expires at expiresAt, a real server is needed since the connection is
+ * hijacked by the upgrade.
+ */
+func newTestServer(t *testing.T, lc *live.Controller, expiresAt time.Time) string {
+	t.Helper()
+
+	router := gin.New()
+	router.GET("/devices/:id/live", func(c *gin.Context) {
+		c.Set("userID", "user-1")
+		c.Set("jti", "jti-1")
+		c.Set("tokenExpiresAt", expiresAt)
+	}, lc.Live)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestController_Live(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	messages := make(chan []byte, 1)
+	closed := make(chan struct{})
+	mockLiveService := mocks.NewMockliveService(ctrl)
+	mockLiveService.EXPECT().Subscribe(gomock.Any(), "user-1", testDeviceID).Return(messages, func() error {
+		close(closed)
+		return nil
+	}, nil)
+
+	url := newTestServer(t, live.NewLiveController(mockLiveService), time.Now().Add(time.Hour))
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/devices/"+testDeviceID+"/live", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	messages <- []byte(`{"type":"output","device_id":"` + testDeviceID + `","data":{"duty_cycle":62.5}}`)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read the event: %v", err)
+	}
+	if messageType != websocket.TextMessage || !strings.Contains(string(message), `"duty_cycle":62.5`) {
+		t.Errorf("unexpected message %d %s", messageType, message)
+	}
+
+	// when the app goes away the subscription is released
+	conn.Close()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription was not closed")
+	}
+}
+
+func TestController_Live_SubscriptionInterrupted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	messages := make(chan []byte)
+	mockLiveService := mocks.NewMockliveService(ctrl)
+	mockLiveService.EXPECT().Subscribe(gomock.Any(), "user-1", testDeviceID).Return(messages, func() error { return nil }, nil)
+
+	url := newTestServer(t, live.NewLiveController(mockLiveService), time.Now().Add(time.Hour))
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/devices/"+testDeviceID+"/live", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	close(messages)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+		t.Errorf("expected a try again later close, got %v", err)
+	}
+}
+
+func TestController_Live_TokenExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	closed := make(chan struct{})
+	mockLiveService := mocks.NewMockliveService(ctrl)
+	mockLiveService.EXPECT().Subscribe(gomock.Any(), "user-1", testDeviceID).Return(make(chan []byte), func() error {
+		close(closed)
+		return nil
+	}, nil)
+
+	url := newTestServer(t, live.NewLiveController(mockLiveService), time.Now().Add(200*time.Millisecond))
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/devices/"+testDeviceID+"/live", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// the app has to reconnect with a fresh token
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Errorf("expected a policy violation close, got %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription was not closed")
+	}
+}
+
+func TestController_Live_Authorize(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{
+			name:         "logged_out",
+			err:          live.ErrTokenRevoked,
+			expectedCode: websocket.ClosePolicyViolation,
+		},
+		{
+			name:         "removed_from_household",
+			err:          device.ErrNotDeviceOwner,
+			expectedCode: websocket.ClosePolicyViolation,
+		},
+		{
+			name:         "device_deleted",
+			err:          device.ErrDeviceNotFound,
+			expectedCode: websocket.ClosePolicyViolation,
+		},
+		{
+			name:         "redis_down",
+			err:          errors.New("redis error"),
+			expectedCode: websocket.CloseTryAgainLater,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLiveService := mocks.NewMockliveService(ctrl)
+			mockLiveService.EXPECT().Subscribe(gomock.Any(), "user-1", testDeviceID).Return(make(chan []byte), func() error { return nil }, nil)
+			// the first check passes, the access is lost while the stream is open
+			gomock.InOrder(
+				mockLiveService.EXPECT().Authorize(gomock.Any(), "user-1", testDeviceID, "jti-1").Return(nil),
+				mockLiveService.EXPECT().Authorize(gomock.Any(), "user-1", testDeviceID, "jti-1").Return(tt.err),
+			)
+
+			url := newTestServer(t, live.NewLiveControllerWithInterval(mockLiveService, 50*time.Millisecond), time.Now().Add(time.Hour))
+			conn, _, err := websocket.DefaultDialer.Dial(url+"/devices/"+testDeviceID+"/live", nil)
+			if err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			defer conn.Close()
+
+			_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, _, err = conn.ReadMessage()
+			if !websocket.IsCloseError(err, tt.expectedCode) {
+				t.Errorf("expected close %d, got %v", tt.expectedCode, err)
+			}
+		})
+	}
+}
+
+func TestController_Live_Shutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	closed := make(chan struct{})
+	mockLiveService := mocks.NewMockliveService(ctrl)
+	mockLiveService.EXPECT().Subscribe(gomock.Any(), "user-1", testDeviceID).Return(make(chan []byte), func() error {
+		close(closed)
+		return nil
+	}, nil)
+
+	lc := live.NewLiveController(mockLiveService)
+	router := gin.New()
+	router.GET("/devices/:id/live", func(c *gin.Context) {
+		c.Set("userID", "user-1")
+	}, lc.Live)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/devices/" + testDeviceID + "/live"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- lc.Shutdown(ctx)
+	}()
+
+	// the client is told to reconnect, to another instance or once the server is back
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("expected a going away close, got %v", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	select {
+	case <-closed:
+	default:
+		t.Error("expected the subscription to be closed before Shutdown returns")
+	}
+
+	// no new stream is opened during the shutdown
+	_, response, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || response == nil || response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %v", http.StatusServiceUnavailable, response)
+	}
+}
+
+func TestController_Live_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		deviceID     string
+		expectedCode int
+		setupMock    func(*mocks.MockliveService)
+	}{
+		{
+			name:         "invalid_device_id",
+			deviceID:     "42",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockliveService) {},
+		},
+		{
+			name:         "not_owner",
+			deviceID:     testDeviceID,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MockliveService) {
+				m.EXPECT().Subscribe(gomock.Any(), "user-1", testDeviceID).Return(nil, nil, device.ErrNotDeviceOwner)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLiveService := mocks.NewMockliveService(ctrl)
+			tt.setupMock(mockLiveService)
+
+			url := newTestServer(t, live.NewLiveController(mockLiveService), time.Now().Add(time.Hour))
+			_, response, err := websocket.DefaultDialer.Dial(url+"/devices/"+tt.deviceID+"/live", nil)
+			if err == nil {
+				t.Fatal("expected the upgrade to fail")
+			}
+			if response == nil || response.StatusCode != tt.expectedCode {
+				t.Fatalf("expected status %d, got %v", tt.expectedCode, response)
+			}
+		})
+	}
+}
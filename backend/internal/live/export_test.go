@@ -0,0 +1,10 @@
+package live
+
+import "time"
+
+// NewLiveControllerWithInterval lets the tests check the access of the open streams more often
+func NewLiveControllerWithInterval(service liveService, interval time.Duration) *Controller {
+	lc := NewLiveController(service)
+	lc.authorizeInterval = interval
+	return lc
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockliveService is a mock of liveService interface.
+type MockliveService struct {
+	ctrl     *gomock.Controller
+	recorder *MockliveServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockliveServiceMockRecorder is the mock recorder for MockliveService.
+type MockliveServiceMockRecorder struct {
+	mock *MockliveService
+}
+
+// NewMockliveService creates a new mock instance.
+func NewMockliveService(ctrl *gomock.Controller) *MockliveService {
+	mock := &MockliveService{ctrl: ctrl}
+	mock.recorder = &MockliveServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockliveService) EXPECT() *MockliveServiceMockRecorder {
+	return m.recorder
+}
+
+// Authorize mocks base method.
+func (m *MockliveService) Authorize(ctx context.Context, userID, deviceID, jti string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", ctx, userID, deviceID, jti)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Authorize indicates an expected call of Authorize.
+func (mr *MockliveServiceMockRecorder) Authorize(ctx, userID, deviceID, jti any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockliveService)(nil).Authorize), ctx, userID, deviceID, jti)
+}
+
+// Subscribe mocks base method.
+func (m *MockliveService) Subscribe(ctx context.Context, userID, deviceID string) (<-chan []byte, func() error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", ctx, userID, deviceID)
+	ret0, _ := ret[0].(<-chan []byte)
+	ret1, _ := ret[1].(func() error)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockliveServiceMockRecorder) Subscribe(ctx, userID, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockliveService)(nil).Subscribe), ctx, userID, deviceID)
+}
@@ -0,0 +1,150 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	device "github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockliveRepository is a mock of liveRepository interface.
+type MockliveRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockliveRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockliveRepositoryMockRecorder is the mock recorder for MockliveRepository.
+type MockliveRepositoryMockRecorder struct {
+	mock *MockliveRepository
+}
+
+// NewMockliveRepository creates a new mock instance.
+func NewMockliveRepository(ctrl *gomock.Controller) *MockliveRepository {
+	mock := &MockliveRepository{ctrl: ctrl}
+	mock.recorder = &MockliveRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockliveRepository) EXPECT() *MockliveRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Publish mocks base method.
+func (m *MockliveRepository) Publish(ctx context.Context, deviceID string, messages [][]byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, deviceID, messages)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockliveRepositoryMockRecorder) Publish(ctx, deviceID, messages any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockliveRepository)(nil).Publish), ctx, deviceID, messages)
+}
+
+// Subscribe mocks base method.
+func (m *MockliveRepository) Subscribe(ctx context.Context, deviceID string) (<-chan []byte, func() error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", ctx, deviceID)
+	ret0, _ := ret[0].(<-chan []byte)
+	ret1, _ := ret[1].(func() error)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Subscribe indicates an expected call of Subscribe.
+func (mr *MockliveRepositoryMockRecorder) Subscribe(ctx, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockliveRepository)(nil).Subscribe), ctx, deviceID)
+}
+
+// MockdeviceService is a mock of deviceService interface.
+type MockdeviceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeviceServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockdeviceServiceMockRecorder is the mock recorder for MockdeviceService.
+type MockdeviceServiceMockRecorder struct {
+	mock *MockdeviceService
+}
+
+// NewMockdeviceService creates a new mock instance.
+func NewMockdeviceService(ctrl *gomock.Controller) *MockdeviceService {
+	mock := &MockdeviceService{ctrl: ctrl}
+	mock.recorder = &MockdeviceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeviceService) EXPECT() *MockdeviceServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthorizeDevice mocks base method.
+func (m *MockdeviceService) AuthorizeDevice(ctx context.Context, userID, deviceID string, permission device.Permission) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeDevice", ctx, userID, deviceID, permission)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeDevice indicates an expected call of AuthorizeDevice.
+func (mr *MockdeviceServiceMockRecorder) AuthorizeDevice(ctx, userID, deviceID, permission any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeDevice", reflect.TypeOf((*MockdeviceService)(nil).AuthorizeDevice), ctx, userID, deviceID, permission)
+}
+
+// MocktokenDenylist is a mock of tokenDenylist interface.
+type MocktokenDenylist struct {
+	ctrl     *gomock.Controller
+	recorder *MocktokenDenylistMockRecorder
+	isgomock struct{}
+}
+
+// MocktokenDenylistMockRecorder is the mock recorder for MocktokenDenylist.
+type MocktokenDenylistMockRecorder struct {
+	mock *MocktokenDenylist
+}
+
+// NewMocktokenDenylist creates a new mock instance.
+func NewMocktokenDenylist(ctrl *gomock.Controller) *MocktokenDenylist {
+	mock := &MocktokenDenylist{ctrl: ctrl}
+	mock.recorder = &MocktokenDenylistMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktokenDenylist) EXPECT() *MocktokenDenylistMockRecorder {
+	return m.recorder
+}
+
+// Exists mocks base method.
+func (m *MocktokenDenylist) Exists(ctx context.Context, jti string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", ctx, jti)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists.
+func (mr *MocktokenDenylistMockRecorder) Exists(ctx, jti any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MocktokenDenylist)(nil).Exists), ctx, jti)
+}
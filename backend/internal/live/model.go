@@ -0,0 +1,35 @@
+package live
+
+import (
+	"time"
+)
+
+const (
+	EventReading = "reading"
+	EventTarget  = "target"
+	EventOutput  = "output"
+)
+
+// Event is what the app receives on the live stream of a device, Data depends on the type:
+// reading {lux, raw, duty_cycle}, target {brightness, set_by} and output {duty_cycle}
+type Event struct {
+	Type     string    `json:"type"`
+	DeviceID string    `json:"device_id"`
+	Time     time.Time `json:"time"`
+	Data     any       `json:"data"`
+}
+
+type readingData struct {
+	Lux       float64 `json:"lux"`
+	Raw       int     `json:"raw"`
+	DutyCycle float64 `json:"duty_cycle"`
+}
+
+type targetData struct {
+	Brightness int    `json:"brightness"`
+	SetBy      string `json:"set_by,omitempty"`
+}
+
+type outputData struct {
+	DutyCycle float64 `json:"duty_cycle"`
+}
@@ -0,0 +1,61 @@
+package live
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// bufferSize is how many events can wait for a slow client
+const bufferSize = 64
+
+type repository struct {
+	db *redis.Client
+}
+
+func NewLiveRepository(db *redis.Client) *repository {
+	return &repository{db: db}
+}
+
+// the events of a device are published on the channel live:{deviceID}, every
+// replica of the backend subscribes for the clients connected to it, so an
+// event reaches the app whatever replica received the reading
+
+func (r *repository) Publish(ctx context.Context, deviceID string, messages [][]byte) error {
+	_, err := r.db.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, message := range messages {
+			pipe.Publish(ctx, "live:"+deviceID, message)
+		}
+		return nil
+	})
+	return err
+}
+
+// Subscribe returns the messages published for the device until close is called
+func (r *repository) Subscribe(ctx context.Context, deviceID string) (<-chan []byte, func() error, error) {
+	pubsub := r.db.Subscribe(ctx, "live:"+deviceID)
+
+	// wait for the confirmation, otherwise the events published
+	// right after the client connects could be lost
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		_ = pubsub.Close()
+		return nil, nil, err
+	}
+
+	messages := make(chan []byte, bufferSize)
+	go func() {
+		defer close(messages)
+		for message := range pubsub.Channel() {
+			// a live view has no use for old events, so when the
+			// client cannot keep up they are dropped instead of queued
+			select {
+			case messages <- []byte(message.Payload):
+			default:
+			}
+		}
+	}()
+	return messages, pubsub.Close, nil
+}
@@ -0,0 +1,76 @@
+package live
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/redis/go-redis/v9"
+)
+
+var testRedisDB *redis.Client
+
+func TestMain(m *testing.M) {
+	// the unit tests of this package do not need a container,
+	// so with -short we avoid starting it at all
+	flag.Parse()
+	if !testing.Short() {
+		redisConnectionStr := testutils.SetupRedis()
+		opt, _ := redis.ParseURL(redisConnectionStr)
+		testRedisDB = redis.NewClient(opt)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_PublishSubscribe(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+
+	// two replicas of the backend, each with its own client
+	publisher := NewLiveRepository(testRedisDB)
+	subscriber := NewLiveRepository(redis.NewClient(testRedisDB.Options()))
+
+	messages, closeSubscription, err := subscriber.Subscribe(ctx, "device-live")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	err = publisher.Publish(ctx, "device-live", [][]byte{[]byte("first"), []byte("second")})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	// the events of other devices are not received
+	err = publisher.Publish(ctx, "device-other", [][]byte{[]byte("other")})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case message := <-messages:
+			if string(message) != expected {
+				t.Errorf("expected %s, got %s", expected, message)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %s not received", expected)
+		}
+	}
+
+	if err := closeSubscription(); err != nil {
+		t.Fatalf("close error = %v", err)
+	}
+	select {
+	case message, ok := <-messages:
+		if ok {
+			t.Errorf("unexpected message %s after close", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the channel was not closed")
+	}
+}
@@ -0,0 +1,133 @@
+package live
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+)
+
+// ErrTokenRevoked is returned when the token a stream was opened with has been revoked
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+type liveRepository interface {
+	Publish(ctx context.Context, deviceID string, messages [][]byte) error
+	Subscribe(ctx context.Context, deviceID string) (<-chan []byte, func() error, error)
+}
+
+// deviceService is used to check that the user can access the device
+type deviceService interface {
+	AuthorizeDevice(ctx context.Context, userID string, deviceID string, permission device.Permission) (*device.Device, error)
+}
+
+// tokenDenylist tells if an access token has been revoked, e.g. on logout
+type tokenDenylist interface {
+	Exists(ctx context.Context, jti string) (bool, error)
+}
+
+type service struct {
+	liveRepo      liveRepository
+	deviceService deviceService
+	tokenDenylist tokenDenylist
+}
+
+func NewLiveService(liveRepo liveRepository, deviceService deviceService, tokenDenylist tokenDenylist) *service {
+	return &service{
+		liveRepo:      liveRepo,
+		deviceService: deviceService,
+		tokenDenylist: tokenDenylist,
+	}
+}
+
+// the publish methods are called after the data has been stored, a live view
+// that misses an event must not make the request that produced it fail,
+// so the errors are only logged
+
+func (s *service) PublishReadings(ctx context.Context, deviceID string, readings []*telemetry.Reading) {
+	events := make([]*Event, 0, len(readings))
+	for _, reading := range readings {
+		events = append(events, &Event{
+			Type:     EventReading,
+			DeviceID: deviceID,
+			Time:     reading.Timestamp,
+			Data:     readingData{Lux: reading.Lux, Raw: reading.Raw, DutyCycle: reading.DutyCycle},
+		})
+	}
+	s.publish(ctx, deviceID, events)
+}
+
+func (s *service) PublishOutput(ctx context.Context, deviceID string, dutyCycle float64) {
+	s.publish(ctx, deviceID, []*Event{{
+		Type:     EventOutput,
+		DeviceID: deviceID,
+		Time:     time.Now(),
+		Data:     outputData{DutyCycle: dutyCycle},
+	}})
+}
+
+func (s *service) PublishTarget(ctx context.Context, t *target.Target) {
+	s.publish(ctx, t.DeviceID, []*Event{{
+		Type:     EventTarget,
+		DeviceID: t.DeviceID,
+		Time:     time.Now(),
+		Data:     targetData{Brightness: t.Brightness, SetBy: t.SetBy},
+	}})
+}
+
+// Subscribe returns the events of a device of the user until close is called
+func (s *service) Subscribe(ctx context.Context, userID string, deviceID string) (<-chan []byte, func() error, error) {
+	_, err := s.deviceService.AuthorizeDevice(ctx, userID, deviceID, device.PermissionView)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.liveRepo.Subscribe(ctx, deviceID)
+}
+
+// Authorize tells if the user can still watch the device with the token of the
+// given jti, the token is checked by the middleware only when the stream is opened
+// so the open streams call it periodically to notice a logout, the deletion of the
+// account or the removal from the household
+func (s *service) Authorize(ctx context.Context, userID string, deviceID string, jti string) error {
+	// the tokens issued before the jti was introduced cannot be revoked
+	if jti != "" {
+		revoked, err := s.tokenDenylist.Exists(ctx, jti)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+	}
+
+	_, err := s.deviceService.AuthorizeDevice(ctx, userID, deviceID, device.PermissionView)
+	return err
+}
+
+func (s *service) publish(ctx context.Context, deviceID string, events []*Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	messages := make([][]byte, 0, len(events))
+	for _, event := range events {
+		message, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("failed to encode live event", "deviceID", deviceID, "type", event.Type, "error", err)
+			return
+		}
+		messages = append(messages, message)
+	}
+
+	err := s.liveRepo.Publish(ctx, deviceID, messages)
+	if err != nil {
+		slog.Error("failed to publish live events", "deviceID", deviceID, "error", err)
+	}
+}
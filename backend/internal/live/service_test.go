@@ -0,0 +1,206 @@
+package live_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/live"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/live/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	"go.uber.org/mock/gomock"
+)
+
+// decodeEvents checks the messages published and returns them decoded
+func decodeEvents(t *testing.T, messages [][]byte) []map[string]any {
+	t.Helper()
+	events := make([]map[string]any, 0, len(messages))
+	for _, message := range messages {
+		var event map[string]any
+		if err := json.Unmarshal(message, &event); err != nil {
+			t.Fatalf("invalid event %s: %v", message, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestService_PublishReadings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ts := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockLiveRepo := mocks.NewMockliveRepository(ctrl)
+	mockLiveRepo.EXPECT().Publish(gomock.Any(), "device-1", gomock.Len(2)).DoAndReturn(
+		func(ctx context.Context, deviceID string, messages [][]byte) error {
+			events := decodeEvents(t, messages)
+			data, _ := events[1]["data"].(map[string]any)
+			if events[1]["type"] != live.EventReading || events[1]["device_id"] != "device-1" || events[1]["time"] != "2026-01-01T00:00:01Z" {
+				t.Errorf("unexpected event %v", events[1])
+			}
+			if data["lux"] != 121.0 || data["raw"] != 2050.0 || data["duty_cycle"] != 40.0 {
+				t.Errorf("unexpected data %v", data)
+			}
+			return nil
+		},
+	)
+
+	s := live.NewLiveService(mockLiveRepo, mocks.NewMockdeviceService(ctrl), mocks.NewMocktokenDenylist(ctrl))
+	s.PublishReadings(context.Background(), "device-1", []*telemetry.Reading{
+		{DeviceID: "device-1", Timestamp: ts, Lux: 120, Raw: 2048, DutyCycle: 40},
+		{DeviceID: "device-1", Timestamp: ts.Add(time.Second), Lux: 121, Raw: 2050, DutyCycle: 40},
+	})
+}
+
+func TestService_PublishTargetAndOutput(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLiveRepo := mocks.NewMockliveRepository(ctrl)
+	gomock.InOrder(
+		mockLiveRepo.EXPECT().Publish(gomock.Any(), "device-1", gomock.Len(1)).DoAndReturn(
+			func(ctx context.Context, deviceID string, messages [][]byte) error {
+				event := decodeEvents(t, messages)[0]
+				data, _ := event["data"].(map[string]any)
+				if event["type"] != live.EventTarget || data["brightness"] != 40.0 || data["set_by"] != "user-1" {
+					t.Errorf("unexpected target event %v", event)
+				}
+				return nil
+			},
+		),
+		// a failure is only logged, the caller has nothing to do about it
+		mockLiveRepo.EXPECT().Publish(gomock.Any(), "device-1", gomock.Len(1)).DoAndReturn(
+			func(ctx context.Context, deviceID string, messages [][]byte) error {
+				event := decodeEvents(t, messages)[0]
+				data, _ := event["data"].(map[string]any)
+				if event["type"] != live.EventOutput || data["duty_cycle"] != 62.5 {
+					t.Errorf("unexpected output event %v", event)
+				}
+				return errors.New("redis down")
+			},
+		),
+	)
+
+	s := live.NewLiveService(mockLiveRepo, mocks.NewMockdeviceService(ctrl), mocks.NewMocktokenDenylist(ctrl))
+	s.PublishTarget(context.Background(), &target.Target{DeviceID: "device-1", Brightness: 40, SetBy: "user-1"})
+	s.PublishOutput(context.Background(), "device-1", 62.5)
+}
+
+func TestService_PublishReadings_Empty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// nothing is sent to redis
+	s := live.NewLiveService(mocks.NewMockliveRepository(ctrl), mocks.NewMockdeviceService(ctrl), mocks.NewMocktokenDenylist(ctrl))
+	s.PublishReadings(context.Background(), "device-1", nil)
+}
+
+func TestService_Subscribe(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockliveRepository, *mocks.MockdeviceService)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(r *mocks.MockliveRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1"}, nil)
+				r.EXPECT().Subscribe(gomock.Any(), "device-1").Return(make(chan []byte), func() error { return nil }, nil)
+			},
+		},
+		{
+			name: "not_owner",
+			setupMock: func(r *mocks.MockliveRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(nil, device.ErrNotDeviceOwner)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockLiveRepo := mocks.NewMockliveRepository(ctrl)
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockLiveRepo, mockDeviceService)
+
+			s := live.NewLiveService(mockLiveRepo, mockDeviceService, mocks.NewMocktokenDenylist(ctrl))
+			messages, closeSubscription, err := s.Subscribe(context.Background(), "user-1", "device-1")
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil || messages == nil || closeSubscription == nil {
+				t.Fatalf("expected a subscription, got error %v", err)
+			}
+		})
+	}
+}
+
+func TestService_Authorize(t *testing.T) {
+	tests := []struct {
+		name          string
+		jti           string
+		setupMock     func(*mocks.MockdeviceService, *mocks.MocktokenDenylist)
+		expectedError error
+	}{
+		{
+			name: "success",
+			jti:  "jti-1",
+			setupMock: func(d *mocks.MockdeviceService, l *mocks.MocktokenDenylist) {
+				l.EXPECT().Exists(gomock.Any(), "jti-1").Return(false, nil)
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1"}, nil)
+			},
+		},
+		{
+			name: "token_revoked",
+			jti:  "jti-1",
+			setupMock: func(d *mocks.MockdeviceService, l *mocks.MocktokenDenylist) {
+				l.EXPECT().Exists(gomock.Any(), "jti-1").Return(true, nil)
+			},
+			expectedError: live.ErrTokenRevoked,
+		},
+		{
+			name: "removed_from_household",
+			jti:  "jti-1",
+			setupMock: func(d *mocks.MockdeviceService, l *mocks.MocktokenDenylist) {
+				l.EXPECT().Exists(gomock.Any(), "jti-1").Return(false, nil)
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(nil, device.ErrNotDeviceOwner)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+		{
+			name: "token_without_jti",
+			setupMock: func(d *mocks.MockdeviceService, l *mocks.MocktokenDenylist) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1"}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			mockTokenDenylist := mocks.NewMocktokenDenylist(ctrl)
+			tt.setupMock(mockDeviceService, mockTokenDenylist)
+
+			s := live.NewLiveService(mocks.NewMockliveRepository(ctrl), mockDeviceService, mockTokenDenylist)
+			err := s.Authorize(context.Background(), "user-1", "device-1", tt.jti)
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
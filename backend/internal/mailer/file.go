@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer is meant for local development and tests, instead of sending
+// the emails it writes them in a directory or, without one, drops them
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, message *Message) error {
+	err := validateAddress(message.To)
+	if err != nil {
+		return err
+	}
+
+	if m.dir == "" {
+		// the body is not logged, it carries the links with the reset and verification tokens
+		slog.Info("email not sent, no mail server configured", "to", message.To, "subject", message.Subject)
+		return nil
+	}
+
+	now := time.Now()
+	// the name keeps the files sorted by date and does not contain the address
+	name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405"), now.UnixNano())
+	path := filepath.Join(m.dir, name)
+	err = os.WriteFile(path, message.format(m.from, now), 0o600)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("email written to file", "to", message.To, "subject", message.Subject, "path", path)
+	return nil
+}
@@ -0,0 +1,75 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mailer"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	m := mailer.NewFileMailer(dir, "noreply@autolight.local")
+
+	err := m.Send(context.Background(), &mailer.Message{
+		To:      "mario@example.com",
+		Subject: "Reset your password",
+		Body:    "first line\nsecond line",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one email in %s, got %v (%v)", dir, files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("failed to read the email: %v", err)
+	}
+
+	for _, want := range []string{
+		"From: noreply@autolight.local\r\n",
+		"To: mario@example.com\r\n",
+		"Subject: Reset your password\r\n",
+		"\r\n\r\nfirst line\r\nsecond line\r\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("expected the email to contain %q, got %q", want, content)
+		}
+	}
+}
+
+func TestFileMailer_SendWithoutDirectory(t *testing.T) {
+	m := mailer.NewFileMailer("", "noreply@autolight.local")
+
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(previous)
+
+	err := m.Send(context.Background(), &mailer.Message{To: "mario@example.com", Subject: "Hello", Body: "https://autolight.local/reset-password?token=pr1.secret"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	// the links in the body would let whoever reads the log take over the account
+	if strings.Contains(logs.String(), "pr1.secret") {
+		t.Errorf("expected the body to be left out of the log, got %q", logs.String())
+	}
+}
+
+func TestFileMailer_SendInvalidAddress(t *testing.T) {
+	m := mailer.NewFileMailer(t.TempDir(), "noreply@autolight.local")
+
+	// a new line in the address would add a header to the email
+	err := m.Send(context.Background(), &mailer.Message{To: "mario@example.com\r\nBcc: eve@example.com", Subject: "Hello"})
+	if err == nil {
+		t.Fatal("expected an error for an address with a new line")
+	}
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// Mailer sends the emails of the application, like the password reset links
+type Mailer interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+type Message struct {
+	To      string
+	Subject string
+	// Body is sent as plain text
+	Body string
+}
+
+// format builds the message as defined by RFC 5322, the subject is
+// encoded since it could contain characters that are not ASCII
+func (m *Message) format(from string, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+
+	// the lines of the body must end with CRLF
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if !strings.HasSuffix(body, "\n") {
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+// validateAddress refuses the addresses that could inject new headers
+func validateAddress(address string) error {
+	if address == "" || strings.ContainsAny(address, "\r\n") {
+		return fmt.Errorf("invalid email address %q", address)
+	}
+	return nil
+}
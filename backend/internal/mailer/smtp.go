@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// the timeout used when the context of the request has no deadline
+const defaultSMTPTimeout = 30 * time.Second
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	err := validateAddress(message.To)
+	if err != nil {
+		return err
+	}
+
+	// smtp.SendMail does not accept a context, so the connection
+	// is opened here and the deadline of the context is applied to it
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.config.Host, m.config.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultSMTPTimeout)
+	}
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// the credentials are never sent in clear, STARTTLS is used whenever the server supports it
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: m.config.Host})
+		if err != nil {
+			return err
+		}
+	}
+	if m.config.Username != "" {
+		// PlainAuth refuses to send the password on a connection without TLS unless the server is localhost
+		err = client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(m.config.From)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(message.To)
+	if err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(message.format(m.config.From, time.Now()))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
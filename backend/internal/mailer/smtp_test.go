@@ -0,0 +1,124 @@
+package mailer_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mailer"
+)
+
+// fakeSMTPServer accepts a single email and sends what it received on the channel
+func fakeSMTPServer(t *testing.T) (string, string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var transcript strings.Builder
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			transcript.WriteString(line)
+
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(command, "DATA"):
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					transcript.WriteString(dataLine)
+				}
+				reply("250 OK")
+			case strings.HasPrefix(command, "QUIT"):
+				reply("221 bye")
+				received <- transcript.String()
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port, received
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	host, port, received := fakeSMTPServer(t)
+	m := mailer.NewSMTPMailer(mailer.SMTPConfig{
+		Host: host,
+		Port: port,
+		From: "noreply@autolight.local",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.Send(ctx, &mailer.Message{
+		To:      "mario@example.com",
+		Subject: "Reset your password",
+		Body:    "use this link",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	select {
+	case transcript := <-received:
+		for _, want := range []string{
+			"MAIL FROM:<noreply@autolight.local>",
+			"RCPT TO:<mario@example.com>",
+			"Subject: Reset your password",
+			"use this link",
+		} {
+			if !strings.Contains(transcript, want) {
+				t.Errorf("expected the transcript to contain %q, got %q", want, transcript)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the email was not received")
+	}
+}
+
+func TestSMTPMailer_SendServerUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	m := mailer.NewSMTPMailer(mailer.SMTPConfig{Host: host, Port: port, From: "noreply@autolight.local"})
+	err = m.Send(context.Background(), &mailer.Message{To: "mario@example.com", Subject: "Hello", Body: "body"})
+	if err == nil {
+		t.Fatal("expected an error when the server is not reachable")
+	}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterPostgres exposes the stats of the connection pool, the open, idle and
+// in use connections and how long the queries waited for one
+func RegisterPostgres(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+type onlineDevices interface {
+	CountOnlineDevices(ctx context.Context, since time.Time) (int, error)
+}
+
+// onlineCollector counts the online devices at every scrape, the count comes from the
+// database so that it is the same on every instance and survives the restarts
+type onlineCollector struct {
+	devices onlineDevices
+	window  time.Duration
+	timeout time.Duration
+	desc    *prometheus.Desc
+}
+
+// RegisterOnlineDevices exposes the number of devices that uploaded a reading in the last window
+func RegisterOnlineDevices(devices onlineDevices, window time.Duration) {
+	Registry.MustRegister(newOnlineCollector(devices, window))
+}
+
+func newOnlineCollector(devices onlineDevices, window time.Duration) *onlineCollector {
+	return &onlineCollector{
+		devices: devices,
+		window:  window,
+		timeout: 2 * time.Second,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "devices", "online"),
+			"Devices that uploaded a reading in the last "+window.String()+".",
+			nil, nil,
+		),
+	}
+}
+
+func (c *onlineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *onlineCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	count, err := c.devices.CountOnlineDevices(ctx, time.Now().Add(-c.window))
+	if err != nil {
+		// the gauge is left out, so that the rest of the scrape still succeeds
+		slog.Warn("failed to count the online devices", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count))
+}
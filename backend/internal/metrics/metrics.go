@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric of the backend
+const namespace = "autolight"
+
+// Registry holds the metrics served by Handler, it is not the default registry
+// of the library so that only the metrics registered here are served
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var (
+	// HTTPRequests counts the requests by the route that served them, not by their
+	// path, so that the ids in the paths do not create a series for each resource
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time to serve the HTTP requests by method and route, the long polling and the WebSockets included.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route"})
+
+	// LoginAttempts counts the logins by method (username, email or mfa) and by outcome
+	LoginAttempts = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "login_attempts_total",
+		Help:      "Login attempts by method and outcome.",
+	}, []string{"method", "outcome"})
+	// BcryptDuration is the cost of the password hashes, the operation is hash or compare
+	BcryptDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "bcrypt_duration_seconds",
+		Help:      "Time spent hashing and comparing the passwords.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 0.75, 1, 1.5, 2, 3, 5},
+	}, []string{"operation"})
+
+	RedisCommandDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_duration_seconds",
+		Help:      "Time to run the Redis commands and pipelines, by command.",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+	}, []string{"command"})
+	// RedisCommandErrors does not count redis.Nil, a missing key is not a failure
+	RedisCommandErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "redis",
+		Name:      "command_errors_total",
+		Help:      "Redis commands that failed, by command.",
+	}, []string{"command"})
+
+	// TelemetryReadings counts the uploaded readings, the result is accepted or rejected,
+	// its rate is the ingest rate of the whole fleet
+	TelemetryReadings = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "telemetry",
+		Name:      "readings_total",
+		Help:      "Uploaded readings by result.",
+	}, []string{"result"})
+	// ControlError is how far each room is from its target after the last update of its controller
+	ControlError = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "control",
+		Name:      "error_lux",
+		Help:      "Target minus measured illuminance at the last update of the controller, by device.",
+	}, []string{"device_id"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisHook(t *testing.T) {
+	errConnection := errors.New("connection refused")
+	tests := []struct {
+		name           string
+		err            error
+		expectedErrors float64
+	}{
+		{name: "success"},
+		{name: "missing_key", err: redis.Nil},
+		{name: "failure", err: errConnection, expectedErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := "get_" + tt.name
+			process := NewRedisHook().ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+				return tt.err
+			})
+
+			series := testutil.CollectAndCount(RedisCommandDuration)
+
+			err := process(context.Background(), redis.NewCmd(context.Background(), command, "key"))
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected the error of the command, got %v", err)
+			}
+			// every command gets its own series
+			if got := testutil.CollectAndCount(RedisCommandDuration) - series; got != 1 {
+				t.Errorf("expected the duration of %s, got %d new series", command, got)
+			}
+			if got := testutil.ToFloat64(RedisCommandErrors.WithLabelValues(command)); got != tt.expectedErrors {
+				t.Errorf("expected %v errors, got %v", tt.expectedErrors, got)
+			}
+		})
+	}
+}
+
+// fakeOnlineDevices returns count or err and records the window it was asked for
+type fakeOnlineDevices struct {
+	count int
+	err   error
+	since time.Time
+}
+
+func (f *fakeOnlineDevices) CountOnlineDevices(ctx context.Context, since time.Time) (int, error) {
+	f.since = since
+	return f.count, f.err
+}
+
+func TestOnlineCollector(t *testing.T) {
+	devices := &fakeOnlineDevices{count: 3}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newOnlineCollector(devices, 5*time.Minute))
+
+	expected := `
+		# HELP autolight_devices_online Devices that uploaded a reading in the last 5m0s.
+		# TYPE autolight_devices_online gauge
+		autolight_devices_online 3
+	`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+	if window := time.Since(devices.since); window < 5*time.Minute || window > 6*time.Minute {
+		t.Errorf("expected a window of 5 minutes, got %s", window)
+	}
+
+	// a failed query leaves the gauge out instead of failing the whole scrape
+	devices.err = errors.New("connection refused")
+	if got, err := testutil.GatherAndCount(registry); err != nil || got != 0 {
+		t.Errorf("expected no series and no error, got %d, %v", got, err)
+	}
+}
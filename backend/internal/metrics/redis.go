@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisHook times the commands sent by a Redis client
+type redisHook struct{}
+
+// NewRedisHook returns the hook to add to the Redis client with AddHook,
+// the blocking commands such as XREAD include the time spent waiting
+func NewRedisHook() redis.Hook {
+	return redisHook{}
+}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observeRedis(cmd.Name(), time.Since(start), err)
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		// the commands of a pipeline share a single round trip
+		observeRedis("pipeline", time.Since(start), err)
+		return err
+	}
+}
+
+func observeRedis(command string, duration time.Duration, err error) {
+	RedisCommandDuration.WithLabelValues(command).Observe(duration.Seconds())
+	if err != nil && !errors.Is(err, redis.Nil) {
+		RedisCommandErrors.WithLabelValues(command).Inc()
+	}
+}
@@ -0,0 +1,184 @@
+package mfa
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	"github.com/gin-gonic/gin"
+)
+
+type mfaService interface {
+	Enroll(ctx context.Context, userID string) (*Enrollment, error)
+	Activate(ctx context.Context, userID string, code string) error
+	Disable(ctx context.Context, userID string, code string) error
+}
+
+// passwordChecker verifies the password of the user, the failures count
+// towards the lockout of the logins
+type passwordChecker interface {
+	CheckPassword(ctx context.Context, userID string, password string) error
+}
+
+type Controller struct {
+	service   mfaService
+	passwords passwordChecker
+}
+
+func NewMFAController(service mfaService, passwords passwordChecker) *Controller {
+	return &Controller{
+		service:   service,
+		passwords: passwords,
+	}
+}
+
+// the password is asked again to enroll and to activate, otherwise a stolen
+// access token would be enough to bind another authenticator to the account
+
+type enrollRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+type activateRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Code            string `json:"code" binding:"required,max=32"`
+}
+
+type disableRequest struct {
+	// the code is not needed to drop an enrollment that has not been activated
+	Code string `json:"code" binding:"max=32"`
+}
+
+type enrollmentResponse struct {
+	Secret        string   `json:"secret"`
+	OTPAuthURI    string   `json:"otpauth_uri"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (mc *Controller) Enroll(c *gin.Context) {
+	var request enrollRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid enroll two-factor request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = mc.passwords.CheckPassword(ctx, userID, request.CurrentPassword)
+	if err != nil {
+		mc.handleMFAError(c, err, "failed to check the password")
+		return
+	}
+
+	enrollment, err := mc.service.Enroll(ctx, userID)
+	if err != nil {
+		mc.handleMFAError(c, err, "failed to enroll two-factor authentication")
+		return
+	}
+
+	slog.Info("two-factor authentication enrollment started", "userID", userID)
+	c.JSON(http.StatusOK, enrollmentResponse{
+		Secret:        enrollment.Secret,
+		OTPAuthURI:    enrollment.URI,
+		RecoveryCodes: enrollment.RecoveryCodes,
+	})
+}
+
+func (mc *Controller) Activate(c *gin.Context) {
+	var request activateRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid activate two-factor request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = mc.passwords.CheckPassword(ctx, userID, request.CurrentPassword)
+	if err != nil {
+		mc.handleMFAError(c, err, "failed to check the password")
+		return
+	}
+
+	err = mc.service.Activate(ctx, userID, request.Code)
+	if err != nil {
+		mc.handleMFAError(c, err, "failed to activate two-factor authentication")
+		return
+	}
+
+	slog.Info("two-factor authentication enabled", "userID", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication enabled"})
+}
+
+func (mc *Controller) Disable(c *gin.Context) {
+	var request disableRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid disable two-factor request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	err = mc.service.Disable(ctx, userID, request.Code)
+	if err != nil {
+		mc.handleMFAError(c, err, "failed to disable two-factor authentication")
+		return
+	}
+
+	slog.Info("two-factor authentication disabled", "userID", userID)
+	c.Status(http.StatusNoContent)
+}
+
+func (mc *Controller) handleMFAError(c *gin.Context, err error, message string) {
+	if errors.Is(err, user.ErrPasswordRequired) {
+		slog.Warn("two-factor change without the current password", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "current_password is required"})
+		return
+	}
+	if errors.Is(err, user.ErrInvalidPassword) {
+		// not 401, the client must not think that the session has expired
+		slog.Warn("invalid current password", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": "invalid password"})
+		return
+	}
+	var lockedErr *user.AccountLockedError
+	if errors.As(err, &lockedErr) {
+		slog.Warn("password check on a locked account", "error", err)
+		seconds := int((lockedErr.RetryAfter + time.Second - 1) / time.Second)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed attempts, retry later"})
+		return
+	}
+	if errors.Is(err, ErrInvalidCode) {
+		slog.Warn("invalid two-factor code", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+	if errors.Is(err, ErrAlreadyEnabled) || errors.Is(err, ErrNotEnrolled) || errors.Is(err, ErrNotEnabled) {
+		slog.Warn("two-factor authentication in the wrong state", "error", err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
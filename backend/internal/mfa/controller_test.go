@@ -0,0 +1,272 @@
+package mfa_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+/**
+ * newTestContext creates a gin.Context for an authenticated user,
+ * as if the request already went through the AuthMiddleware.
+ */
+func newTestContext(method, path string, body []byte) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Set("userID", "user-1")
+	return c, w
+}
+
+func TestController_Enroll(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockmfaService, *mocks.MockpasswordChecker)
+	}{
+		{
+			name:         "success",
+			body:         `{"current_password":"Testtest123"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(nil)
+				m.EXPECT().Enroll(gomock.Any(), "user-1").Return(&mfa.Enrollment{
+					Secret:        "SECRET",
+					URI:           "otpauth://totp/Auto%20Light:mario@example.com?secret=SECRET",
+					RecoveryCodes: []string{"abcde-fghjk"},
+				}, nil)
+			},
+		},
+		{
+			name:         "missing_password",
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {},
+		},
+		{
+			name:         "wrong_password",
+			body:         `{"current_password":"wrong"}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "wrong").Return(user.ErrInvalidPassword)
+			},
+		},
+		{
+			name:         "account_locked",
+			body:         `{"current_password":"Testtest123"}`,
+			expectedCode: http.StatusTooManyRequests,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(&user.AccountLockedError{RetryAfter: time.Minute})
+			},
+		},
+		{
+			name:         "already_enabled",
+			body:         `{"current_password":"Testtest123"}`,
+			expectedCode: http.StatusConflict,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(nil)
+				m.EXPECT().Enroll(gomock.Any(), "user-1").Return(nil, mfa.ErrAlreadyEnabled)
+			},
+		},
+		{
+			name:         "internal_error",
+			body:         `{"current_password":"Testtest123"}`,
+			expectedCode: http.StatusInternalServerError,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(nil)
+				m.EXPECT().Enroll(gomock.Any(), "user-1").Return(nil, fmt.Errorf("some error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFAService := mocks.NewMockmfaService(ctrl)
+			mockPasswordChecker := mocks.NewMockpasswordChecker(ctrl)
+			tt.setupMock(mockMFAService, mockPasswordChecker)
+
+			mc := mfa.NewMFAController(mockMFAService, mockPasswordChecker)
+			c, w := newTestContext(http.MethodPost, "/mfa/totp/enroll", []byte(tt.body))
+
+			mc.Enroll(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			var response struct {
+				Secret        string   `json:"secret"`
+				OTPAuthURI    string   `json:"otpauth_uri"`
+				RecoveryCodes []string `json:"recovery_codes"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if response.Secret != "SECRET" || response.OTPAuthURI == "" || len(response.RecoveryCodes) != 1 {
+				t.Errorf("unexpected response %+v", response)
+			}
+		})
+	}
+}
+
+func TestController_Activate(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockmfaService, *mocks.MockpasswordChecker)
+	}{
+		{
+			name:         "success",
+			body:         `{"current_password":"Testtest123","code":"123456"}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(nil)
+				m.EXPECT().Activate(gomock.Any(), "user-1", "123456").Return(nil)
+			},
+		},
+		{
+			name:         "missing_code",
+			body:         `{"current_password":"Testtest123"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {},
+		},
+		{
+			name:         "missing_password",
+			body:         `{"code":"123456"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {},
+		},
+		{
+			name:         "wrong_password",
+			body:         `{"current_password":"wrong","code":"123456"}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "wrong").Return(user.ErrInvalidPassword)
+			},
+		},
+		{
+			name:         "invalid_code",
+			body:         `{"current_password":"Testtest123","code":"000000"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(nil)
+				m.EXPECT().Activate(gomock.Any(), "user-1", "000000").Return(mfa.ErrInvalidCode)
+			},
+		},
+		{
+			name:         "not_enrolled",
+			body:         `{"current_password":"Testtest123","code":"123456"}`,
+			expectedCode: http.StatusConflict,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(nil)
+				m.EXPECT().Activate(gomock.Any(), "user-1", "123456").Return(mfa.ErrNotEnrolled)
+			},
+		},
+		{
+			name:         "timeout",
+			body:         `{"current_password":"Testtest123","code":"123456"}`,
+			expectedCode: http.StatusRequestTimeout,
+			setupMock: func(m *mocks.MockmfaService, p *mocks.MockpasswordChecker) {
+				p.EXPECT().CheckPassword(gomock.Any(), "user-1", "Testtest123").Return(nil)
+				m.EXPECT().Activate(gomock.Any(), "user-1", "123456").Return(context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFAService := mocks.NewMockmfaService(ctrl)
+			mockPasswordChecker := mocks.NewMockpasswordChecker(ctrl)
+			tt.setupMock(mockMFAService, mockPasswordChecker)
+
+			mc := mfa.NewMFAController(mockMFAService, mockPasswordChecker)
+			c, w := newTestContext(http.MethodPost, "/mfa/totp/activate", []byte(tt.body))
+
+			mc.Activate(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_Disable(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MockmfaService)
+	}{
+		{
+			name:         "success",
+			body:         `{"code":"123456"}`,
+			expectedCode: http.StatusNoContent,
+			setupMock: func(m *mocks.MockmfaService) {
+				m.EXPECT().Disable(gomock.Any(), "user-1", "123456").Return(nil)
+			},
+		},
+		{
+			name:         "invalid_code",
+			body:         `{"code":"000000"}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MockmfaService) {
+				m.EXPECT().Disable(gomock.Any(), "user-1", "000000").Return(mfa.ErrInvalidCode)
+			},
+		},
+		{
+			name:         "not_enabled",
+			body:         `{"code":"123456"}`,
+			expectedCode: http.StatusConflict,
+			setupMock: func(m *mocks.MockmfaService) {
+				m.EXPECT().Disable(gomock.Any(), "user-1", "123456").Return(mfa.ErrNotEnabled)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFAService := mocks.NewMockmfaService(ctrl)
+			tt.setupMock(mockMFAService)
+
+			mc := mfa.NewMFAController(mockMFAService, mocks.NewMockpasswordChecker(ctrl))
+			c, w := newTestContext(http.MethodPost, "/mfa/totp/disable", []byte(tt.body))
+
+			mc.Disable(c)
+			c.Writer.WriteHeaderNow()
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package mfa
+
+import (
+	"time"
+)
+
+// TestIssuer is the issuer of the services created by NewMFAServiceWithClock
+const TestIssuer = "Auto Light"
+
+// NewMFAServiceWithClock is NewMFAService with a fixed clock,
+// so that the codes of the app can be computed in advance
+func NewMFAServiceWithClock(mfaRepo mfaRepository, userRepo userRepository, now func() time.Time) *service {
+	s := NewMFAService(mfaRepo, userRepo, TestIssuer)
+	s.now = now
+	return s
+}
+
+// TOTPCode is the code of the authenticator app for the given secret at the given time
+func TOTPCode(secret string, t time.Time) string {
+	key, _ := totpEncoding.DecodeString(secret)
+	return hotp(key, uint64(totpStep(t)))
+}
+
+// TOTPStep is the time step of the given time
+func TOTPStep(t time.Time) int64 {
+	return totpStep(t)
+}
+
+// HashRecoveryCode is the hash stored for a recovery code
+func HashRecoveryCode(code string) string {
+	return hashRecoveryCode(normalizeCode(code))
+}
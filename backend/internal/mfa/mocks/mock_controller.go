@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	mfa "github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockmfaService is a mock of mfaService interface.
+type MockmfaService struct {
+	ctrl     *gomock.Controller
+	recorder *MockmfaServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockmfaServiceMockRecorder is the mock recorder for MockmfaService.
+type MockmfaServiceMockRecorder struct {
+	mock *MockmfaService
+}
+
+// NewMockmfaService creates a new mock instance.
+func NewMockmfaService(ctrl *gomock.Controller) *MockmfaService {
+	mock := &MockmfaService{ctrl: ctrl}
+	mock.recorder = &MockmfaServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmfaService) EXPECT() *MockmfaServiceMockRecorder {
+	return m.recorder
+}
+
+// Activate mocks base method.
+func (m *MockmfaService) Activate(ctx context.Context, userID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Activate", ctx, userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Activate indicates an expected call of Activate.
+func (mr *MockmfaServiceMockRecorder) Activate(ctx, userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Activate", reflect.TypeOf((*MockmfaService)(nil).Activate), ctx, userID, code)
+}
+
+// Disable mocks base method.
+func (m *MockmfaService) Disable(ctx context.Context, userID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", ctx, userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockmfaServiceMockRecorder) Disable(ctx, userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockmfaService)(nil).Disable), ctx, userID, code)
+}
+
+// Enroll mocks base method.
+func (m *MockmfaService) Enroll(ctx context.Context, userID string) (*mfa.Enrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enroll", ctx, userID)
+	ret0, _ := ret[0].(*mfa.Enrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enroll indicates an expected call of Enroll.
+func (mr *MockmfaServiceMockRecorder) Enroll(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enroll", reflect.TypeOf((*MockmfaService)(nil).Enroll), ctx, userID)
+}
+
+// MockpasswordChecker is a mock of passwordChecker interface.
+type MockpasswordChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockpasswordCheckerMockRecorder
+	isgomock struct{}
+}
+
+// MockpasswordCheckerMockRecorder is the mock recorder for MockpasswordChecker.
+type MockpasswordCheckerMockRecorder struct {
+	mock *MockpasswordChecker
+}
+
+// NewMockpasswordChecker creates a new mock instance.
+func NewMockpasswordChecker(ctrl *gomock.Controller) *MockpasswordChecker {
+	mock := &MockpasswordChecker{ctrl: ctrl}
+	mock.recorder = &MockpasswordCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockpasswordChecker) EXPECT() *MockpasswordCheckerMockRecorder {
+	return m.recorder
+}
+
+// CheckPassword mocks base method.
+func (m *MockpasswordChecker) CheckPassword(ctx context.Context, userID, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPassword", ctx, userID, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckPassword indicates an expected call of CheckPassword.
+func (mr *MockpasswordCheckerMockRecorder) CheckPassword(ctx, userID, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPassword", reflect.TypeOf((*MockpasswordChecker)(nil).CheckPassword), ctx, userID, password)
+}
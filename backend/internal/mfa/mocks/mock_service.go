@@ -0,0 +1,168 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	mfa "github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	user "github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockmfaRepository is a mock of mfaRepository interface.
+type MockmfaRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockmfaRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockmfaRepositoryMockRecorder is the mock recorder for MockmfaRepository.
+type MockmfaRepositoryMockRecorder struct {
+	mock *MockmfaRepository
+}
+
+// NewMockmfaRepository creates a new mock instance.
+func NewMockmfaRepository(ctrl *gomock.Controller) *MockmfaRepository {
+	mock := &MockmfaRepository{ctrl: ctrl}
+	mock.recorder = &MockmfaRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockmfaRepository) EXPECT() *MockmfaRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ConsumeRecoveryCode mocks base method.
+func (m *MockmfaRepository) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConsumeRecoveryCode indicates an expected call of ConsumeRecoveryCode.
+func (mr *MockmfaRepositoryMockRecorder) ConsumeRecoveryCode(ctx, userID, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeRecoveryCode", reflect.TypeOf((*MockmfaRepository)(nil).ConsumeRecoveryCode), ctx, userID, codeHash)
+}
+
+// CreateOne mocks base method.
+func (m *MockmfaRepository) CreateOne(ctx context.Context, totp *mfa.TOTP, recoveryCodeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOne", ctx, totp, recoveryCodeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOne indicates an expected call of CreateOne.
+func (mr *MockmfaRepositoryMockRecorder) CreateOne(ctx, totp, recoveryCodeHashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOne", reflect.TypeOf((*MockmfaRepository)(nil).CreateOne), ctx, totp, recoveryCodeHashes)
+}
+
+// DeleteOneByUserID mocks base method.
+func (m *MockmfaRepository) DeleteOneByUserID(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOneByUserID", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOneByUserID indicates an expected call of DeleteOneByUserID.
+func (mr *MockmfaRepositoryMockRecorder) DeleteOneByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOneByUserID", reflect.TypeOf((*MockmfaRepository)(nil).DeleteOneByUserID), ctx, userID)
+}
+
+// EnableOne mocks base method.
+func (m *MockmfaRepository) EnableOne(ctx context.Context, userID string, step int64, enabledAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableOne", ctx, userID, step, enabledAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableOne indicates an expected call of EnableOne.
+func (mr *MockmfaRepositoryMockRecorder) EnableOne(ctx, userID, step, enabledAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableOne", reflect.TypeOf((*MockmfaRepository)(nil).EnableOne), ctx, userID, step, enabledAt)
+}
+
+// GetOneByUserID mocks base method.
+func (m *MockmfaRepository) GetOneByUserID(ctx context.Context, userID string) (*mfa.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByUserID", ctx, userID)
+	ret0, _ := ret[0].(*mfa.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByUserID indicates an expected call of GetOneByUserID.
+func (mr *MockmfaRepositoryMockRecorder) GetOneByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByUserID", reflect.TypeOf((*MockmfaRepository)(nil).GetOneByUserID), ctx, userID)
+}
+
+// UpdateOneLastUsedStep mocks base method.
+func (m *MockmfaRepository) UpdateOneLastUsedStep(ctx context.Context, userID string, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOneLastUsedStep", ctx, userID, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOneLastUsedStep indicates an expected call of UpdateOneLastUsedStep.
+func (mr *MockmfaRepositoryMockRecorder) UpdateOneLastUsedStep(ctx, userID, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOneLastUsedStep", reflect.TypeOf((*MockmfaRepository)(nil).UpdateOneLastUsedStep), ctx, userID, step)
+}
+
+// MockuserRepository is a mock of userRepository interface.
+type MockuserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockuserRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockuserRepositoryMockRecorder is the mock recorder for MockuserRepository.
+type MockuserRepositoryMockRecorder struct {
+	mock *MockuserRepository
+}
+
+// NewMockuserRepository creates a new mock instance.
+func NewMockuserRepository(ctrl *gomock.Controller) *MockuserRepository {
+	mock := &MockuserRepository{ctrl: ctrl}
+	mock.recorder = &MockuserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockuserRepository) EXPECT() *MockuserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetOneByID mocks base method.
+func (m *MockuserRepository) GetOneByID(ctx context.Context, id string) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByID", ctx, id)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByID indicates an expected call of GetOneByID.
+func (mr *MockuserRepositoryMockRecorder) GetOneByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByID", reflect.TypeOf((*MockuserRepository)(nil).GetOneByID), ctx, id)
+}
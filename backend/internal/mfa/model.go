@@ -0,0 +1,28 @@
+package mfa
+
+import (
+	"time"
+)
+
+// TOTP is the second factor of a user, it is pending until EnabledAt is set
+type TOTP struct {
+	UserID string
+	Secret string
+	// LastUsedStep is the time step of the last accepted code,
+	// a code cannot be used again to login a second time
+	LastUsedStep int64
+	EnabledAt    *time.Time
+	CreatedAt    time.Time
+}
+
+func (t *TOTP) IsEnabled() bool {
+	return t.EnabledAt != nil
+}
+
+// Enrollment is what the user needs to configure the authenticator app,
+// the recovery codes are shown only once and then just their hashes are kept
+type Enrollment struct {
+	Secret        string
+	URI           string
+	RecoveryCodes []string
+}
@@ -0,0 +1,200 @@
+package mfa
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTOTPNotFound         = errors.New("totp not found")
+	ErrStepAlreadyUsed      = errors.New("totp step already used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+)
+
+type TOTPEntity struct {
+	UserID       uuid.UUID
+	Secret       string
+	LastUsedStep int64
+	EnabledAt    sql.NullTime
+	CreatedAt    time.Time
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+// CreateOne stores a new pending TOTP together with its recovery codes,
+// a previous pending enrollment of the user is replaced
+func (r *repository) CreateOne(ctx context.Context, totp *TOTP, recoveryCodeHashes []string) error {
+	userID, err := uuid.Parse(totp.UserID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// the rollback does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO user_mfa(user_id, totp_secret, last_used_step, enabled_at, created_at)
+		VALUES($1, $2, 0, NULL, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret,
+			last_used_step = 0,
+			enabled_at = NULL,
+			created_at = EXCLUDED.created_at
+	`
+	_, err = tx.ExecContext(ctx, query, userID, totp.Secret, totp.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM mfa_recovery_code WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO mfa_recovery_code(user_id, code_hash) VALUES($1, $2)",
+			userID, codeHash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) GetOneByUserID(ctx context.Context, userID string) (*TOTP, error) {
+	// an id that is not a valid uuid cannot exist in the table
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, nil
+	}
+	query := `
+		SELECT user_id, totp_secret, last_used_step, enabled_at, created_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var totp TOTPEntity
+	err = row.Scan(&totp.UserID, &totp.Secret, &totp.LastUsedStep, &totp.EnabledAt, &totp.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return totp.toTOTP(), nil
+}
+
+// EnableOne turns on the second factor and records the step of the code
+// that proved the enrollment, so that it cannot be used again to login
+func (r *repository) EnableOne(ctx context.Context, userID string, step int64, enabledAt time.Time) error {
+	query := `
+		UPDATE user_mfa
+		SET enabled_at = $3, last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, step, enabledAt)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTOTPNotFound
+	}
+	return nil
+}
+
+// UpdateOneLastUsedStep accepts a step only if it is later than the last one,
+// in the same statement so that two requests cannot use the same code
+func (r *repository) UpdateOneLastUsedStep(ctx context.Context, userID string, step int64) error {
+	query := `
+		UPDATE user_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrStepAlreadyUsed
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode marks the code as used, each recovery code works once
+func (r *repository) ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) error {
+	query := `
+		UPDATE mfa_recovery_code
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// DeleteOneByUserID removes the second factor, the recovery codes are
+// removed by the cascade
+func (r *repository) DeleteOneByUserID(ctx context.Context, userID string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM user_mfa WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTOTPNotFound
+	}
+	return nil
+}
+
+func (te *TOTPEntity) toTOTP() *TOTP {
+	totp := &TOTP{
+		UserID:       te.UserID.String(),
+		Secret:       te.Secret,
+		LastUsedStep: te.LastUsedStep,
+		CreatedAt:    te.CreatedAt,
+	}
+	if te.EnabledAt.Valid {
+		enabledAt := te.EnabledAt.Time
+		totp.EnabledAt = &enabledAt
+	}
+	return totp
+}
@@ -0,0 +1,158 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	// the unit tests of this package do not need a container,
+	// so with -short we avoid starting it at all
+	flag.Parse()
+	if !testing.Short() {
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+// createTestUser inserts the owner of the second factor, since user_mfa.user_id is a foreign key
+func createTestUser(ctx context.Context, t *testing.T) string {
+	t.Helper()
+	id := uuid.New()
+	_, err := testPostgresDB.ExecContext(ctx,
+		"INSERT INTO user_account(id, username, email, password, name, surname) VALUES($1, $2, $3, $4, $5, $6)",
+		id, id.String()[:8], id.String()+"@example.com", "hash", "test", "user",
+	)
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	return id.String()
+}
+
+func TestRepository_EnrollAndEnable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewMFARepository(testPostgresDB)
+	userID := createTestUser(ctx, t)
+
+	got, err := repo.GetOneByUserID(ctx, userID)
+	if err != nil || got != nil {
+		t.Fatalf("GetOneByUserID() before enrollment = %v, %v", got, err)
+	}
+
+	// a second enrollment replaces the first one
+	for _, secret := range []string{"FIRSTSECRET", "SECONDSECRET"} {
+		err = repo.CreateOne(ctx, &TOTP{UserID: userID, Secret: secret, CreatedAt: time.Now()}, []string{"hash_" + secret})
+		if err != nil {
+			t.Fatalf("CreateOne() error = %v", err)
+		}
+	}
+
+	got, err = repo.GetOneByUserID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetOneByUserID() error = %v", err)
+	}
+	if got.Secret != "SECONDSECRET" || got.IsEnabled() {
+		t.Fatalf("unexpected totp %+v", got)
+	}
+	if err := repo.ConsumeRecoveryCode(ctx, userID, "hash_FIRSTSECRET"); !errors.Is(err, ErrRecoveryCodeNotFound) {
+		t.Errorf("expected the old recovery codes to be removed, got %v", err)
+	}
+
+	if err := repo.EnableOne(ctx, userID, 100, time.Now()); err != nil {
+		t.Fatalf("EnableOne() error = %v", err)
+	}
+	if err := repo.EnableOne(ctx, userID, 101, time.Now()); !errors.Is(err, ErrTOTPNotFound) {
+		t.Errorf("expected an enabled totp not to be enabled again, got %v", err)
+	}
+
+	got, _ = repo.GetOneByUserID(ctx, userID)
+	if !got.IsEnabled() || got.LastUsedStep != 100 {
+		t.Errorf("unexpected totp after enable %+v", got)
+	}
+}
+
+func TestRepository_UpdateOneLastUsedStep(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewMFARepository(testPostgresDB)
+	userID := createTestUser(ctx, t)
+
+	if err := repo.CreateOne(ctx, &TOTP{UserID: userID, Secret: "SECRET", CreatedAt: time.Now()}, nil); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+	if err := repo.EnableOne(ctx, userID, 100, time.Now()); err != nil {
+		t.Fatalf("EnableOne() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		step    int64
+		wantErr error
+	}{
+		{name: "same_step_as_enable", step: 100, wantErr: ErrStepAlreadyUsed},
+		{name: "later_step", step: 101, wantErr: nil},
+		{name: "same_step_twice", step: 101, wantErr: ErrStepAlreadyUsed},
+		{name: "earlier_step", step: 99, wantErr: ErrStepAlreadyUsed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.UpdateOneLastUsedStep(ctx, userID, tt.step)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateOneLastUsedStep() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepository_RecoveryCodesAndDelete(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewMFARepository(testPostgresDB)
+	userID := createTestUser(ctx, t)
+
+	if err := repo.CreateOne(ctx, &TOTP{UserID: userID, Secret: "SECRET", CreatedAt: time.Now()}, []string{"hash_a", "hash_b"}); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+
+	if err := repo.ConsumeRecoveryCode(ctx, userID, "hash_a"); err != nil {
+		t.Fatalf("ConsumeRecoveryCode() error = %v", err)
+	}
+	// each recovery code can be used only once
+	if err := repo.ConsumeRecoveryCode(ctx, userID, "hash_a"); !errors.Is(err, ErrRecoveryCodeNotFound) {
+		t.Errorf("expected ErrRecoveryCodeNotFound, got %v", err)
+	}
+	if err := repo.ConsumeRecoveryCode(ctx, createTestUser(ctx, t), "hash_b"); !errors.Is(err, ErrRecoveryCodeNotFound) {
+		t.Errorf("expected the code of another user to be refused, got %v", err)
+	}
+
+	if err := repo.DeleteOneByUserID(ctx, userID); err != nil {
+		t.Fatalf("DeleteOneByUserID() error = %v", err)
+	}
+	if err := repo.DeleteOneByUserID(ctx, userID); !errors.Is(err, ErrTOTPNotFound) {
+		t.Errorf("expected ErrTOTPNotFound, got %v", err)
+	}
+	if err := repo.ConsumeRecoveryCode(ctx, userID, "hash_b"); !errors.Is(err, ErrRecoveryCodeNotFound) {
+		t.Errorf("expected the recovery codes to be deleted with the totp, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package mfa
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+)
+
+const (
+	recoveryCodeCount = 10
+	// each symbol carries 5 bits, so a code has 50 bits of entropy,
+	// more than enough since every login ticket allows only a few attempts
+	recoveryCodeLength = 10
+	// the same alphabet of the pairing codes, easy to copy by hand
+	recoveryCodeAlphabet = "abcdefghjklmnpqrstuvwxyz23456789"
+)
+
+var (
+	ErrAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrNotEnrolled    = errors.New("two-factor authentication not enrolled")
+	ErrNotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrInvalidCode    = errors.New("invalid two-factor code")
+)
+
+type mfaRepository interface {
+	CreateOne(ctx context.Context, totp *TOTP, recoveryCodeHashes []string) error
+	GetOneByUserID(ctx context.Context, userID string) (*TOTP, error)
+	EnableOne(ctx context.Context, userID string, step int64, enabledAt time.Time) error
+	UpdateOneLastUsedStep(ctx context.Context, userID string, step int64) error
+	ConsumeRecoveryCode(ctx context.Context, userID string, codeHash string) error
+	DeleteOneByUserID(ctx context.Context, userID string) error
+}
+
+type userRepository interface {
+	GetOneByID(ctx context.Context, id string) (*user.User, error)
+}
+
+type service struct {
+	mfaRepo  mfaRepository
+	userRepo userRepository
+	// issuer is the name shown by the authenticator app
+	issuer string
+	// now is the clock used to check the codes, the tests replace it
+	now func() time.Time
+}
+
+func NewMFAService(mfaRepo mfaRepository, userRepo userRepository, issuer string) *service {
+	return &service{
+		mfaRepo:  mfaRepo,
+		userRepo: userRepo,
+		issuer:   issuer,
+		now:      time.Now,
+	}
+}
+
+// Enroll starts the configuration of the authenticator app, the second factor
+// is not required to login until the user proves to have it with Activate
+func (s *service) Enroll(ctx context.Context, userID string) (*Enrollment, error) {
+	totp, err := s.mfaRepo.GetOneByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// an enabled second factor must be disabled first, with a valid code
+	if totp != nil && totp.IsEnabled() {
+		return nil, ErrAlreadyEnabled
+	}
+
+	account, err := s.userRepo.GetOneByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, user.ErrUserNotFound
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodes, recoveryCodeHashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mfaRepo.CreateOne(ctx, &TOTP{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: s.now(),
+	}, recoveryCodeHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:        secret,
+		URI:           totpURI(s.issuer, account.Email, secret),
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+func (s *service) Activate(ctx context.Context, userID string, code string) error {
+	totp, err := s.mfaRepo.GetOneByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if totp == nil {
+		return ErrNotEnrolled
+	}
+	if totp.IsEnabled() {
+		return ErrAlreadyEnabled
+	}
+
+	// only a code of the app is accepted, the recovery codes
+	// do not prove that the app has been configured
+	step, ok := validateTOTP(totp.Secret, normalizeCode(code), s.now())
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	err = s.mfaRepo.EnableOne(ctx, userID, step, s.now())
+	if err != nil {
+		// the enrollment has been replaced or activated in the meantime
+		if errors.Is(err, ErrTOTPNotFound) {
+			return ErrNotEnrolled
+		}
+		return err
+	}
+	return nil
+}
+
+// Verify checks the second factor of a user, the code is either
+// one of the authenticator app or one of the recovery codes
+func (s *service) Verify(ctx context.Context, userID string, code string) error {
+	totp, err := s.mfaRepo.GetOneByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if totp == nil || !totp.IsEnabled() {
+		return ErrNotEnabled
+	}
+	return s.verifyCode(ctx, totp, code)
+}
+
+func (s *service) Disable(ctx context.Context, userID string, code string) error {
+	totp, err := s.mfaRepo.GetOneByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if totp == nil {
+		return ErrNotEnabled
+	}
+
+	// a pending enrollment protects nothing, so it can be dropped without a code
+	if totp.IsEnabled() {
+		err = s.verifyCode(ctx, totp, code)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.mfaRepo.DeleteOneByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrTOTPNotFound) {
+			return ErrNotEnabled
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *service) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	totp, err := s.mfaRepo.GetOneByUserID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return totp != nil && totp.IsEnabled(), nil
+}
+
+func (s *service) verifyCode(ctx context.Context, totp *TOTP, code string) error {
+	code = normalizeCode(code)
+
+	if isTOTPCode(code) {
+		step, ok := validateTOTP(totp.Secret, code, s.now())
+		if !ok {
+			return ErrInvalidCode
+		}
+		// whoever saw the code cannot use it again while it is still valid
+		err := s.mfaRepo.UpdateOneLastUsedStep(ctx, totp.UserID, step)
+		if err != nil {
+			if errors.Is(err, ErrStepAlreadyUsed) {
+				return fmt.Errorf("%w: code already used", ErrInvalidCode)
+			}
+			return err
+		}
+		return nil
+	}
+
+	err := s.mfaRepo.ConsumeRecoveryCode(ctx, totp.UserID, hashRecoveryCode(code))
+	if err != nil {
+		if errors.Is(err, ErrRecoveryCodeNotFound) {
+			return ErrInvalidCode
+		}
+		return err
+	}
+
+	// the user has probably lost the phone, it is worth keeping track of it
+	slog.Warn("security event: recovery code used",
+		"event", "mfa_recovery_code_used",
+		"userID", totp.UserID,
+	)
+	return nil
+}
+
+// newRecoveryCodes returns the codes to show to the user and the hashes to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	bytes := make([]byte, recoveryCodeLength)
+	for range recoveryCodeCount {
+		_, err := rand.Read(bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		// the alphabet has 32 symbols so that every random byte maps without bias
+		var code strings.Builder
+		for i, b := range bytes {
+			if i == recoveryCodeLength/2 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+		}
+		codes = append(codes, code.String())
+		hashes = append(hashes, hashRecoveryCode(normalizeCode(code.String())))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode accepts the codes as the user types them, with spaces,
+// dashes or capital letters
+func normalizeCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// the recovery codes are random enough that a plain hash is sufficient
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,374 @@
+package mfa_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
+	"go.uber.org/mock/gomock"
+)
+
+// the secret and the clock are fixed, so the codes of the app are known in advance
+const testSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+var testNow = time.Unix(1234567890, 0)
+
+func testClock() time.Time {
+	return testNow
+}
+
+func pendingTOTP() *mfa.TOTP {
+	return &mfa.TOTP{UserID: "user-1", Secret: testSecret}
+}
+
+func enabledTOTP() *mfa.TOTP {
+	enabledAt := testNow.Add(-time.Hour)
+	return &mfa.TOTP{UserID: "user-1", Secret: testSecret, EnabledAt: &enabledAt}
+}
+
+func TestService_Enroll(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MockmfaRepository, *mocks.MockuserRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(m *mocks.MockmfaRepository, u *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(nil, nil)
+				u.EXPECT().GetOneByID(gomock.Any(), "user-1").Return(&user.User{ID: "user-1", Email: "mario@example.com"}, nil)
+				m.EXPECT().
+					CreateOne(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, totp *mfa.TOTP, recoveryCodeHashes []string) error {
+						if totp.UserID != "user-1" || totp.Secret == "" || totp.IsEnabled() {
+							t.Errorf("unexpected totp %+v", totp)
+						}
+						if len(recoveryCodeHashes) != 10 {
+							t.Errorf("expected 10 recovery codes, got %d", len(recoveryCodeHashes))
+						}
+						return nil
+					})
+			},
+		},
+		{
+			name: "replace_pending_enrollment",
+			setupMock: func(m *mocks.MockmfaRepository, u *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(pendingTOTP(), nil)
+				u.EXPECT().GetOneByID(gomock.Any(), "user-1").Return(&user.User{ID: "user-1", Email: "mario@example.com"}, nil)
+				m.EXPECT().CreateOne(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "already_enabled",
+			setupMock: func(m *mocks.MockmfaRepository, u *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+			},
+			expectedError: mfa.ErrAlreadyEnabled,
+		},
+		{
+			name: "user_not_found",
+			setupMock: func(m *mocks.MockmfaRepository, u *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(nil, nil)
+				u.EXPECT().GetOneByID(gomock.Any(), "user-1").Return(nil, nil)
+			},
+			expectedError: user.ErrUserNotFound,
+		},
+		{
+			name: "db_error",
+			setupMock: func(m *mocks.MockmfaRepository, u *mocks.MockuserRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFARepo := mocks.NewMockmfaRepository(ctrl)
+			mockUserRepo := mocks.NewMockuserRepository(ctrl)
+			tt.setupMock(mockMFARepo, mockUserRepo)
+
+			s := mfa.NewMFAServiceWithClock(mockMFARepo, mockUserRepo, testClock)
+			got, err := s.Enroll(context.Background(), "user-1")
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			uri, err := url.Parse(got.URI)
+			if err != nil || uri.Query().Get("secret") != got.Secret || uri.Query().Get("issuer") != mfa.TestIssuer || !strings.HasSuffix(uri.Path, ":mario@example.com") {
+				t.Errorf("unexpected otpauth uri %q", got.URI)
+			}
+			if len(got.RecoveryCodes) != 10 {
+				t.Errorf("expected 10 recovery codes, got %d", len(got.RecoveryCodes))
+			}
+		})
+	}
+}
+
+func TestService_Activate(t *testing.T) {
+	tests := []struct {
+		name          string
+		code          string
+		setupMock     func(*mocks.MockmfaRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			code: mfa.TOTPCode(testSecret, testNow),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(pendingTOTP(), nil)
+				m.EXPECT().EnableOne(gomock.Any(), "user-1", mfa.TOTPStep(testNow), testNow).Return(nil)
+			},
+		},
+		{
+			name: "code_with_spaces",
+			code: " " + mfa.TOTPCode(testSecret, testNow)[:3] + " " + mfa.TOTPCode(testSecret, testNow)[3:],
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(pendingTOTP(), nil)
+				m.EXPECT().EnableOne(gomock.Any(), "user-1", mfa.TOTPStep(testNow), testNow).Return(nil)
+			},
+		},
+		{
+			name: "invalid_code",
+			code: mfa.TOTPCode(testSecret, testNow.Add(-5*time.Minute)),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(pendingTOTP(), nil)
+			},
+			expectedError: mfa.ErrInvalidCode,
+		},
+		{
+			name: "not_enrolled",
+			code: "123456",
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(nil, nil)
+			},
+			expectedError: mfa.ErrNotEnrolled,
+		},
+		{
+			name: "already_enabled",
+			code: mfa.TOTPCode(testSecret, testNow),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+			},
+			expectedError: mfa.ErrAlreadyEnabled,
+		},
+		{
+			name: "replaced_meanwhile",
+			code: mfa.TOTPCode(testSecret, testNow),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(pendingTOTP(), nil)
+				m.EXPECT().EnableOne(gomock.Any(), "user-1", gomock.Any(), gomock.Any()).Return(mfa.ErrTOTPNotFound)
+			},
+			expectedError: mfa.ErrNotEnrolled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFARepo := mocks.NewMockmfaRepository(ctrl)
+			tt.setupMock(mockMFARepo)
+
+			s := mfa.NewMFAServiceWithClock(mockMFARepo, mocks.NewMockuserRepository(ctrl), testClock)
+			err := s.Activate(context.Background(), "user-1", tt.code)
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_Verify(t *testing.T) {
+	tests := []struct {
+		name          string
+		code          string
+		setupMock     func(*mocks.MockmfaRepository)
+		expectedError error
+	}{
+		{
+			name: "totp_code",
+			code: mfa.TOTPCode(testSecret, testNow),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+				m.EXPECT().UpdateOneLastUsedStep(gomock.Any(), "user-1", mfa.TOTPStep(testNow)).Return(nil)
+			},
+		},
+		{
+			name: "totp_code_of_previous_step",
+			code: mfa.TOTPCode(testSecret, testNow.Add(-30*time.Second)),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+				m.EXPECT().UpdateOneLastUsedStep(gomock.Any(), "user-1", mfa.TOTPStep(testNow)-1).Return(nil)
+			},
+		},
+		{
+			name: "totp_code_reused",
+			code: mfa.TOTPCode(testSecret, testNow),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+				m.EXPECT().UpdateOneLastUsedStep(gomock.Any(), "user-1", gomock.Any()).Return(mfa.ErrStepAlreadyUsed)
+			},
+			expectedError: mfa.ErrInvalidCode,
+		},
+		{
+			name: "totp_code_expired",
+			code: mfa.TOTPCode(testSecret, testNow.Add(-2*time.Minute)),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+			},
+			expectedError: mfa.ErrInvalidCode,
+		},
+		{
+			name: "recovery_code",
+			code: "ABCDE-FGHJK",
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+				m.EXPECT().ConsumeRecoveryCode(gomock.Any(), "user-1", mfa.HashRecoveryCode("abcdefghjk")).Return(nil)
+			},
+		},
+		{
+			name: "recovery_code_already_used",
+			code: "abcde-fghjk",
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+				m.EXPECT().ConsumeRecoveryCode(gomock.Any(), "user-1", gomock.Any()).Return(mfa.ErrRecoveryCodeNotFound)
+			},
+			expectedError: mfa.ErrInvalidCode,
+		},
+		{
+			name: "not_enabled",
+			code: mfa.TOTPCode(testSecret, testNow),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(pendingTOTP(), nil)
+			},
+			expectedError: mfa.ErrNotEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFARepo := mocks.NewMockmfaRepository(ctrl)
+			tt.setupMock(mockMFARepo)
+
+			s := mfa.NewMFAServiceWithClock(mockMFARepo, mocks.NewMockuserRepository(ctrl), testClock)
+			err := s.Verify(context.Background(), "user-1", tt.code)
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_Disable(t *testing.T) {
+	tests := []struct {
+		name          string
+		code          string
+		setupMock     func(*mocks.MockmfaRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			code: mfa.TOTPCode(testSecret, testNow),
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+				m.EXPECT().UpdateOneLastUsedStep(gomock.Any(), "user-1", gomock.Any()).Return(nil)
+				m.EXPECT().DeleteOneByUserID(gomock.Any(), "user-1").Return(nil)
+			},
+		},
+		{
+			name: "pending_without_code",
+			code: "",
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(pendingTOTP(), nil)
+				m.EXPECT().DeleteOneByUserID(gomock.Any(), "user-1").Return(nil)
+			},
+		},
+		{
+			name: "invalid_code",
+			code: "000000",
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(enabledTOTP(), nil)
+			},
+			expectedError: mfa.ErrInvalidCode,
+		},
+		{
+			name: "not_enabled",
+			code: "000000",
+			setupMock: func(m *mocks.MockmfaRepository) {
+				m.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(nil, nil)
+			},
+			expectedError: mfa.ErrNotEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFARepo := mocks.NewMockmfaRepository(ctrl)
+			tt.setupMock(mockMFARepo)
+
+			s := mfa.NewMFAServiceWithClock(mockMFARepo, mocks.NewMockuserRepository(ctrl), testClock)
+			err := s.Disable(context.Background(), "user-1", tt.code)
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestService_IsEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		totp     *mfa.TOTP
+		expected bool
+	}{
+		{name: "not_enrolled", totp: nil, expected: false},
+		{name: "pending", totp: pendingTOTP(), expected: false},
+		{name: "enabled", totp: enabledTOTP(), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockMFARepo := mocks.NewMockmfaRepository(ctrl)
+			mockMFARepo.EXPECT().GetOneByUserID(gomock.Any(), "user-1").Return(tt.totp, nil)
+
+			s := mfa.NewMFAServiceWithClock(mockMFARepo, mocks.NewMockuserRepository(ctrl), testClock)
+			got, err := s.IsEnabled(context.Background(), "user-1")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
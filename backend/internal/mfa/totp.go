@@ -0,0 +1,99 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// the parameters are the defaults of RFC 6238,
+// they are the only ones supported by every authenticator app
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// a code is accepted also one step before and after the current one,
+	// to tolerate the drift of the clock of the phone and the time to type it
+	totpSkew = 1
+	// RFC 4226 recommends a secret as long as the output of HMAC-SHA1
+	totpSecretSize = 20
+)
+
+// the secrets are shown to the user without padding, as the apps expect them
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpStep is the number of periods elapsed since the Unix epoch
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod/time.Second)
+}
+
+// hotp is the HMAC-based one time password of RFC 4226,
+// TOTP is the same algorithm with the time step as counter
+func hotp(key []byte, counter uint64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation: the last nibble chooses where
+	// the 31 bits of the code are taken from
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range totpDigits {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
+
+// validateTOTP checks the code against the steps around now and returns
+// the step it belongs to, so that the caller can refuse to accept it twice
+func validateTOTP(secret string, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected := hotp(key, uint64(step))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI is the otpauth URI encoded in the QR code scanned by the apps,
+// the format is the one documented by Google Authenticator
+func totpURI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
@@ -0,0 +1,107 @@
+package mfa
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// the SHA1 seed of the test vectors of RFC 6238
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTP_RFC6238Vectors(t *testing.T) {
+	// the RFC lists codes of 8 digits, the last 6 are the codes of 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got := hotp([]byte("12345678901234567890"), uint64(totpStep(time.Unix(tt.unix, 0))))
+		if got != tt.want {
+			t.Errorf("hotp() at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	// a fixed clock, the code of this instant is 005924
+	now := time.Unix(1234567890, 0)
+	step := totpStep(now)
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current_step", secret: rfcSecret, code: "005924", wantStep: step, wantOK: true},
+		{name: "previous_step", secret: rfcSecret, code: hotp(key, uint64(step-1)), wantStep: step - 1, wantOK: true},
+		{name: "next_step", secret: rfcSecret, code: hotp(key, uint64(step+1)), wantStep: step + 1, wantOK: true},
+		{name: "too_old", secret: rfcSecret, code: hotp(key, uint64(step-2)), wantOK: false},
+		{name: "too_new", secret: rfcSecret, code: hotp(key, uint64(step+2)), wantOK: false},
+		{name: "wrong_code", secret: rfcSecret, code: "123456", wantOK: false},
+		{name: "wrong_length", secret: rfcSecret, code: "5924", wantOK: false},
+		{name: "lowercase_secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: "005924", wantStep: step, wantOK: true},
+		{name: "invalid_secret", secret: "not base32!", code: "005924", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := validateTOTP(tt.secret, tt.code, now)
+			if gotOK != tt.wantOK {
+				t.Fatalf("validateTOTP() ok = %v, want %v", gotOK, tt.wantOK)
+			}
+			if gotOK && gotStep != tt.wantStep {
+				t.Errorf("validateTOTP() step = %d, want %d", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestNewTOTPSecret(t *testing.T) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("newTOTPSecret() error = %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("the secret is not valid base32: %v", err)
+	}
+	if len(key) != totpSecretSize {
+		t.Errorf("expected a secret of %d bytes, got %d", totpSecretSize, len(key))
+	}
+
+	other, _ := newTOTPSecret()
+	if other == secret {
+		t.Error("expected two different secrets")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("Auto Light", "mario@example.com", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid uri %q: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("unexpected uri %q", uri)
+	}
+	if parsed.Path != "/Auto Light:mario@example.com" {
+		t.Errorf("unexpected label %q", parsed.Path)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Auto Light" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("unexpected parameters %v", query)
+	}
+}
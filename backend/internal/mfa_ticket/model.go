@@ -0,0 +1,14 @@
+package mfa_ticket
+
+import (
+	"time"
+)
+
+// MFATicket is given to a user that has entered the right password, it is
+// exchanged for the session once the second factor is verified
+type MFATicket struct {
+	Ticket     string
+	TicketHash string
+	UserID     string
+	TTL        time.Time
+}
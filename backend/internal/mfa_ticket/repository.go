@@ -0,0 +1,72 @@
+package mfa_ticket
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrTicketHashNotFound = errors.New("ticket hash not found")
+
+type repository struct {
+	db *redis.Client
+}
+
+func NewMFATicketRepository(db *redis.Client) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateOne(ctx context.Context, ticket *MFATicket) error {
+	// mft:{ticketHash} -> hash with the user and the number of codes tried
+	mftKey := "mft:" + ticket.TicketHash
+
+	lua := `
+		local mftKey = KEYS[1]
+		redis.call("HSET", mftKey, "user_id", ARGV[1], "attempts", 0)
+		redis.call("PEXPIREAT", mftKey, tonumber(ARGV[2]))
+		return 1
+	`
+
+	_, err := r.db.Eval(ctx, lua, []string{mftKey}, ticket.UserID, ticket.TTL.UnixMilli()).Int64()
+	return err
+}
+
+// AttemptOne returns the user of the ticket and counts an attempt to verify
+// the second factor, once maxAttempts is exceeded the ticket is deleted so
+// that the codes cannot be guessed
+func (r *repository) AttemptOne(ctx context.Context, ticketHash string, maxAttempts int) (string, error) {
+	mftKey := "mft:" + ticketHash
+
+	lua := `
+		local mftKey = KEYS[1]
+		local maxAttempts = tonumber(ARGV[1])
+
+		local userID = redis.call("HGET", mftKey, "user_id")
+		if not userID then
+			return false
+		end
+
+		local attempts = redis.call("HINCRBY", mftKey, "attempts", 1)
+		if attempts > maxAttempts then
+			redis.call("DEL", mftKey)
+			return false
+		end
+
+		return userID
+	`
+
+	userID, err := r.db.Eval(ctx, lua, []string{mftKey}, maxAttempts).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrTicketHashNotFound
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+// DeleteOne removes the ticket once it has been used, it is idempotent
+func (r *repository) DeleteOne(ctx context.Context, ticketHash string) error {
+	return r.db.Del(ctx, "mft:"+ticketHash).Err()
+}
@@ -0,0 +1,136 @@
+package mfa_ticket
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/redis/go-redis/v9"
+)
+
+var testRedisDB *redis.Client
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		redisConnectionStr := testutils.SetupRedis()
+		opt, _ := redis.ParseURL(redisConnectionStr)
+		testRedisDB = redis.NewClient(opt)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_AttemptOne(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewMFATicketRepository(testRedisDB)
+
+	createTicket := func(ticketHash string, ttl time.Duration) error {
+		return repo.CreateOne(ctx, &MFATicket{
+			TicketHash: ticketHash,
+			UserID:     "user_mfa",
+			TTL:        time.Now().Add(ttl),
+		})
+	}
+
+	tests := []struct {
+		name       string
+		ticketHash string
+		setupFunc  func() error
+		want       string
+		wantErr    error
+	}{
+		{
+			name:       "found",
+			ticketHash: "hash_found",
+			setupFunc:  func() error { return createTicket("hash_found", time.Minute) },
+			want:       "user_mfa",
+			wantErr:    nil,
+		},
+		{
+			name:       "not_found",
+			ticketHash: "hash_missing",
+			setupFunc:  func() error { return nil },
+			wantErr:    ErrTicketHashNotFound,
+		},
+		{
+			name:       "last_attempt",
+			ticketHash: "hash_last",
+			setupFunc: func() error {
+				if err := createTicket("hash_last", time.Minute); err != nil {
+					return err
+				}
+				for range 2 {
+					if _, err := repo.AttemptOne(ctx, "hash_last", 3); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			want:    "user_mfa",
+			wantErr: nil,
+		},
+		{
+			name:       "too_many_attempts",
+			ticketHash: "hash_exhausted",
+			setupFunc: func() error {
+				if err := createTicket("hash_exhausted", time.Minute); err != nil {
+					return err
+				}
+				for range 3 {
+					if _, err := repo.AttemptOne(ctx, "hash_exhausted", 3); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			wantErr: ErrTicketHashNotFound,
+		},
+		{
+			name:       "deleted",
+			ticketHash: "hash_deleted",
+			setupFunc: func() error {
+				if err := createTicket("hash_deleted", time.Minute); err != nil {
+					return err
+				}
+				return repo.DeleteOne(ctx, "hash_deleted")
+			},
+			wantErr: ErrTicketHashNotFound,
+		},
+		{
+			name:       "expired",
+			ticketHash: "hash_expired",
+			setupFunc: func() error {
+				if err := createTicket("hash_expired", 50*time.Millisecond); err != nil {
+					return err
+				}
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			},
+			wantErr: ErrTicketHashNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+			if err := tt.setupFunc(); err != nil {
+				t.Fatalf("setupFunc failed: %v", err)
+			}
+
+			got, err := repo.AttemptOne(ctx, tt.ticketHash, 3)
+			if err != tt.wantErr {
+				t.Fatalf("AttemptOne() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("AttemptOne() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,21 +1,33 @@
 package middleware
 
 import (
-	"errors"
+	"context"
+	"log/slog"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// readOnlyScope is the scope of the access tokens issued to the users
+// that have not verified their email when the policy is read only
+const readOnlyScope = "read_only"
 
-	// the JWT secret is a random 32 byte string to improve security
-	// since the attacker could potentially brute force the token
-	secret := []byte(os.Getenv("JWT_SECRET"))
+// TokenDenylist tells if an access token has been revoked before its expiration
+type TokenDenylist interface {
+	Exists(ctx context.Context, jti string) (bool, error)
+}
 
+// TokenKeys returns the public key that verifies a token, chosen by the kid
+// in its header, the tokens signed with other algorithms are refused
+type TokenKeys interface {
+	Keyfunc(token *jwt.Token) (any, error)
+}
+
+func AuthMiddleware(keys TokenKeys, denylist TokenDenylist) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// check if in the request there is an authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -37,12 +49,7 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// extract the token from the authorization header
 		tokenString := parts[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.New("unexpected signing method")
-			}
-			return secret, nil
-		})
+		token, err := jwt.Parse(tokenString, keys.Keyfunc)
 
 		if err != nil || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -52,16 +59,71 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			// a board token is signed with the same key, so we must
+			// explicitly refuse it on the user endpoints
+			typ, _ := claims["typ"].(string)
+			aud, _ := claims.GetAudience()
 			sub, ok := claims["sub"].(string)
-			if !ok {
+			if !ok || typ == device.TokenType || slices.Contains(aud, device.TokenAudience) {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 					"error": "invalid token claims",
 				})
 				return
 			}
+
+			// the tokens of a user that logged out are refused until they expire
+			jti, _ := claims["jti"].(string)
+			if jti != "" {
+				denied, err := denylist.Exists(c.Request.Context(), jti)
+				if err != nil {
+					slog.Error("failed to check the token denylist", "error", err)
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+						"error": "internal server error",
+					})
+					return
+				}
+				if denied {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+						"error": "token has been revoked",
+					})
+					return
+				}
+			}
+
+			// the session is used on logout to revoke only the refresh token of this login
+			sid, _ := claims["sid"].(string)
+			scope, _ := claims["scope"].(string)
+
 			c.Set("userID", sub)
+			c.Set("sessionID", sid)
+			c.Set("jti", jti)
+			c.Set("readOnly", scope == readOnlyScope)
+			if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+				c.Set("tokenExpiresAt", exp.Time)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// WriteAccessMiddleware refuses the requests that change something when they are
+// made with a read only token, it must be used after the AuthMiddleware
+func WriteAccessMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		if c.GetBool("readOnly") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "email not verified, the account is read only",
+			})
+			return
 		}
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
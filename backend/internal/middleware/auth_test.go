@@ -1,24 +1,50 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 	"time"
 
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 func init() { gin.SetMode(gin.TestMode) }
 
-func TestAuthMiddleware(t *testing.T) {
-	// Set the environment variable for the secret
-	os.Setenv("JWT_SECRET", "supersecret")
+// testSigner signs the tokens of the tests and verifies them as the keyring does
+type testSigner interface {
+	TokenKeys
+	Sign(claims jwt.Claims) (string, error)
+}
+
+// testKeys is the keyring of the server, otherKeys plays the keys of another issuer
+var testKeys, otherKeys = newTestKeys(), newTestKeys()
+
+func newTestKeys() testSigner {
+	keys := signing_key.NewSigningKeyService(signing_key.NewMemorySigningKeyRepository(), signing_key.DefaultConfig())
+	if err := keys.Refresh(context.Background()); err != nil {
+		panic(err)
+	}
+	return keys
+}
+
+// fakeDenylist is the denylist of the tokens revoked by a logout
+type fakeDenylist struct {
+	denied map[string]bool
+	err    error
+}
+
+func (f *fakeDenylist) Exists(ctx context.Context, jti string) (bool, error) {
+	return f.denied[jti], f.err
+}
 
+func TestAuthMiddleware(t *testing.T) {
 	// Helper function to create a token for testing
-	createToken := func(userID string, secret string, expired bool, method jwt.SigningMethod) string {
+	createToken := func(userID string, keys testSigner, expired bool) string {
 		claims := jwt.MapClaims{
 			"sub": userID,
 			"exp": time.Now().Add(time.Hour).Unix(),
@@ -27,8 +53,7 @@ func TestAuthMiddleware(t *testing.T) {
 			claims["exp"] = time.Now().Add(-time.Hour).Unix()
 		}
 
-		token := jwt.NewWithClaims(method, claims)
-		signedString, _ := token.SignedString([]byte(secret))
+		signedString, _ := keys.Sign(claims)
 		return signedString
 	}
 
@@ -42,7 +67,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "success",
 			setupHeader: func() string {
-				return "Bearer " + createToken("user123", "supersecret", false, jwt.SigningMethodHS256)
+				return "Bearer " + createToken("user123", testKeys, false)
 			},
 			expectedStatus: http.StatusOK,
 			expectedUserID: "user123",
@@ -68,19 +93,70 @@ func TestAuthMiddleware(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
-			name: "wrong_secret",
+			name: "unknown_key",
 			setupHeader: func() string {
-				return "Bearer " + createToken("user123", "wrongsecret", false, jwt.SigningMethodHS256)
+				return "Bearer " + createToken("user123", otherKeys, false)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "hmac_token_rejected",
+			setupHeader: func() string {
+				// the tokens signed with the old shared secret are no longer accepted
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+					"sub": "user123",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				})
+				signedString, _ := token.SignedString([]byte("supersecret"))
+				return "Bearer " + signedString
 			},
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
 			name: "expired_token",
 			setupHeader: func() string {
-				return "Bearer " + createToken("user123", "supersecret", true, jwt.SigningMethodHS256)
+				return "Bearer " + createToken("user123", testKeys, true)
 			},
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			name: "device_token_rejected",
+			setupHeader: func() string {
+				signedString, _ := testKeys.Sign(jwt.MapClaims{
+					"sub": "device123",
+					"aud": "autolight-device",
+					"typ": "device",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				})
+				return "Bearer " + signedString
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "revoked_token",
+			setupHeader: func() string {
+				signedString, _ := testKeys.Sign(jwt.MapClaims{
+					"sub": "user123",
+					"jti": "revoked-jti",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				})
+				return "Bearer " + signedString
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "not_revoked_token_with_jti",
+			setupHeader: func() string {
+				signedString, _ := testKeys.Sign(jwt.MapClaims{
+					"sub": "user123",
+					"jti": "valid-jti",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				})
+				return "Bearer " + signedString
+			},
+			expectedStatus: http.StatusOK,
+			expectedUserID: "user123",
+		},
 		{
 			name: "wrong_signing_method_none",
 			setupHeader: func() string {
@@ -98,11 +174,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Initialize the middleware again to pick up the env var if needed, 
-			// though typical implementation reads it once.
-			// If AuthMiddleware reads os.Getenv outside the returned function, 
-			// it must be called after Setenv.
-			middleware := AuthMiddleware()
+			middleware := AuthMiddleware(testKeys, &fakeDenylist{denied: map[string]bool{"revoked-jti": true}})
 
 			w := httptest.NewRecorder()
 			c, _ := gin.CreateTestContext(w)
@@ -138,4 +210,101 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestAuthMiddleware_SessionID(t *testing.T) {
+	signedString, _ := testKeys.Sign(jwt.MapClaims{
+		"sub": "user123",
+		"sid": "session-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signedString)
+	c.Request = req
 
+	AuthMiddleware(testKeys, &fakeDenylist{})(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if sessionID := c.GetString("sessionID"); sessionID != "session-1" {
+		t.Errorf("expected sessionID session-1, got %q", sessionID)
+	}
+}
+
+func TestAuthMiddleware_DenylistUnavailable(t *testing.T) {
+	signedString, _ := testKeys.Sign(jwt.MapClaims{
+		"sub": "user123",
+		"jti": "some-jti",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signedString)
+	c.Request = req
+
+	// when it is not possible to know if the token is revoked, the request is refused
+	AuthMiddleware(testKeys, &fakeDenylist{err: errors.New("redis down")})(c)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if _, exists := c.Get("userID"); exists {
+		t.Error("expected no userID in the context")
+	}
+}
+
+func TestWriteAccessMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		readOnly       bool
+		expectedStatus int
+	}{
+		{name: "read_only_get", method: http.MethodGet, readOnly: true, expectedStatus: http.StatusOK},
+		{name: "read_only_post", method: http.MethodPost, readOnly: true, expectedStatus: http.StatusForbidden},
+		{name: "read_only_delete", method: http.MethodDelete, readOnly: true, expectedStatus: http.StatusForbidden},
+		{name: "full_access_post", method: http.MethodPost, readOnly: false, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request, _ = http.NewRequest(tt.method, "/", nil)
+			c.Set("readOnly", tt.readOnly)
+
+			WriteAccessMiddleware()(c)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_ReadOnlyScope(t *testing.T) {
+	signedString, _ := testKeys.Sign(jwt.MapClaims{
+		"sub":   "user123",
+		"scope": "read_only",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signedString)
+	c.Request = req
+
+	AuthMiddleware(testKeys, &fakeDenylist{})(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !c.GetBool("readOnly") {
+		t.Error("expected the token to be read only")
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dependencyRetryAfter is about the interval of the health checks,
+// by then the client finds out whether the dependency is back
+const dependencyRetryAfter = 5 * time.Second
+
+// Dependencies tells whether a dependency was up at the last health check
+type Dependencies interface {
+	Available(dependency string) bool
+}
+
+// DependencyMiddleware rejects with 503 the requests that need a dependency that is
+// down, so that they fail at once instead of waiting for the timeouts of the client
+func DependencyMiddleware(dependencies Dependencies, dependency string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !dependencies.Available(dependency) {
+			slog.Warn("dependency down, request rejected", "dependency", dependency, "path", c.FullPath())
+			SetRetryAfter(c, dependencyRetryAfter)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "service temporarily unavailable, retry later",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeDependencies reports as down the dependencies in down
+type fakeDependencies struct {
+	down map[string]bool
+}
+
+func (f *fakeDependencies) Available(dependency string) bool {
+	return !f.down[dependency]
+}
+
+func TestDependencyMiddleware(t *testing.T) {
+	tests := []struct {
+		name               string
+		dependencies       *fakeDependencies
+		expectedStatus     int
+		expectedRetryAfter string
+	}{
+		{
+			name:           "available",
+			dependencies:   &fakeDependencies{},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "other_dependency_down",
+			dependencies:   &fakeDependencies{down: map[string]bool{"postgres": true}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:               "down",
+			dependencies:       &fakeDependencies{down: map[string]bool{"redis": true}},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedRetryAfter: "5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/login/email", DependencyMiddleware(tt.dependencies, "redis"), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/login/email", nil)
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.expectedRetryAfter {
+				t.Errorf("expected Retry-After %q, got %q", tt.expectedRetryAfter, got)
+			}
+		})
+	}
+}
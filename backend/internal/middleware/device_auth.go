@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DeviceAuthMiddleware accepts only the tokens issued to a board,
+// a user token is rejected even if its signature is valid
+func DeviceAuthMiddleware(keys TokenKeys) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authorization header required",
+			})
+			return
+		}
+
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "authorization header format must be Bearer {token}",
+			})
+			return
+		}
+
+		// the audience is verified by the parser, a token without it fails here
+		token, err := jwt.Parse(parts[1], keys.Keyfunc, jwt.WithAudience(device.TokenAudience))
+
+		if err != nil || !token.Valid {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or expired token",
+			})
+			return
+		}
+
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid token claims",
+			})
+			return
+		}
+		typ, _ := claims["typ"].(string)
+		sub, ok := claims["sub"].(string)
+		if !ok || typ != device.TokenType {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid token claims",
+			})
+			return
+		}
+		c.Set("deviceID", sub)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestDeviceAuthMiddleware(t *testing.T) {
+	createToken := func(claims jwt.MapClaims, keys testSigner) string {
+		signedString, _ := keys.Sign(claims)
+		return "Bearer " + signedString
+	}
+
+	tests := []struct {
+		name             string
+		authHeader       string
+		expectedStatus   int
+		expectedDeviceID string
+	}{
+		{
+			name: "success",
+			authHeader: createToken(jwt.MapClaims{
+				"sub": "device123",
+				"aud": "autolight-device",
+				"typ": "device",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}, testKeys),
+			expectedStatus:   http.StatusOK,
+			expectedDeviceID: "device123",
+		},
+		{
+			name:           "missing_auth_header",
+			authHeader:     "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid_auth_header_format",
+			authHeader:     "Basic device:secret",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "user_token_rejected",
+			authHeader: createToken(jwt.MapClaims{
+				"sub": "user123",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}, testKeys),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong_type",
+			authHeader: createToken(jwt.MapClaims{
+				"sub": "device123",
+				"aud": "autolight-device",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}, testKeys),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown_key",
+			authHeader: createToken(jwt.MapClaims{
+				"sub": "device123",
+				"aud": "autolight-device",
+				"typ": "device",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}, otherKeys),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired_token",
+			authHeader: createToken(jwt.MapClaims{
+				"sub": "device123",
+				"aud": "autolight-device",
+				"typ": "device",
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			}, testKeys),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := DeviceAuthMiddleware(testKeys)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			c.Request = req
+
+			middleware(c)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("test %s: expected status %d, got %d", tt.name, tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				deviceID, exists := c.Get("deviceID")
+				if !exists {
+					t.Errorf("test %s: expected deviceID in context, got none", tt.name)
+				}
+				if deviceID != tt.expectedDeviceID {
+					t.Errorf("test %s: expected deviceID %s, got %v", tt.name, tt.expectedDeviceID, deviceID)
+				}
+				if _, exists := c.Get("userID"); exists {
+					t.Errorf("test %s: device token must not set userID", tt.name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels the requests that match no route, so that
+// the scanners probing random paths do not create new series
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware counts and times the requests by the route that served them
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAuthMiddleware lets through only the requests that carry the
+// token of Prometheus, the endpoint is on the same port as the API
+func MetricsAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, credentials, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		// the comparison takes the same time wherever the tokens differ
+		if !ok || !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(credentials), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or missing metrics token",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/devices/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	requests := []string{"/devices/1", "/devices/2", "/wp-login.php"}
+	deviceRequests := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/devices/:id", "204"))
+	unmatchedRequests := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404"))
+	for _, path := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// the requests are counted by route and not by path
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/devices/:id", "204")) - deviceRequests; got != 2 {
+		t.Errorf("expected 2 requests to the route, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")) - unmatchedRequests; got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+	if got := testutil.CollectAndCount(metrics.HTTPRequestDuration, "autolight_http_request_duration_seconds"); got < 2 {
+		t.Errorf("expected the durations of both routes, got %d series", got)
+	}
+}
+
+func TestMetricsAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token := "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  int
+	}{
+		{
+			name:          "valid_token",
+			authorization: "Bearer " + token,
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "scheme_is_case_insensitive",
+			authorization: "bearer " + token,
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:         "missing_header",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "wrong_token",
+			authorization: "Bearer " + token[:len(token)-1] + "0",
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:          "token_without_scheme",
+			authorization: token,
+			expectedCode:  http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/metrics", MetricsAuthMiddleware(token), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the bodies of the limited routes are small, the account is looked up
+// only in the first bytes so that a huge body is not read twice
+const maxAccountBodySize = 64 << 10
+
+// RateLimiter counts the requests of a key in a sliding window and tells
+// how long the caller has to wait once the limit is reached
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error)
+}
+
+// RateLimitRule is the limit applied to a group of routes
+type RateLimitRule struct {
+	// Name separates the budgets of the different routes
+	Name   string
+	Limit  int
+	Window time.Duration
+	// AccountFields are the fields of the JSON body that identify the account,
+	// when one of them is present the requests are also counted per account
+	AccountFields []string
+}
+
+// RateLimitMiddleware rejects with 429 the requests over the limit of the rule,
+// counted both per client IP and per account
+func RateLimitMiddleware(limiter RateLimiter, rule RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := []string{"ip:" + rule.Name + ":" + c.ClientIP()}
+		if account := accountIdentifier(c, rule.AccountFields); account != "" {
+			// the identifier is hashed so that the emails do not end up in the keys
+			sum := sha256.Sum256([]byte(account))
+			keys = append(keys, "account:"+rule.Name+":"+hex.EncodeToString(sum[:]))
+		}
+
+		for _, key := range keys {
+			retryAfter, err := limiter.Allow(c.Request.Context(), key, rule.Limit, rule.Window)
+			if err != nil {
+				// the limiter fails open, an outage of Redis must not lock out every user
+				slog.Error("failed to check rate limit", "rule", rule.Name, "error", err)
+				continue
+			}
+			if retryAfter > 0 {
+				slog.Warn("rate limit exceeded", "rule", rule.Name, "ip", c.ClientIP())
+				SetRetryAfter(c, retryAfter)
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error": "too many requests, retry later",
+				})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// SetRetryAfter sets the Retry-After header in seconds, rounded up
+// so that the client never retries too early
+func SetRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
+
+// accountIdentifier peeks the JSON body for the account of the request,
+// the body is restored so that the handler can still bind it
+func accountIdentifier(c *gin.Context, fields []string) string {
+	if len(fields) == 0 || c.Request.Body == nil {
+		return ""
+	}
+
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAccountBodySize))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(head), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var body map[string]any
+	if json.Unmarshal(head, &body) != nil {
+		return ""
+	}
+	for _, field := range fields {
+		if value, ok := body[field].(string); ok && strings.TrimSpace(value) != "" {
+			return field + ":" + strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	return ""
+}
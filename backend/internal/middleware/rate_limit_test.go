@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeRateLimiter blocks the keys in blocked and records the keys it was asked for
+type fakeRateLimiter struct {
+	blocked map[string]time.Duration
+	err     error
+	keys    []string
+}
+
+func (f *fakeRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	f.keys = append(f.keys, key)
+	if f.err != nil {
+		return 0, f.err
+	}
+	for prefix, retryAfter := range f.blocked {
+		if strings.HasPrefix(key, prefix) {
+			return retryAfter, nil
+		}
+	}
+	return 0, nil
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	rule := RateLimitRule{
+		Name:          "login",
+		Limit:         10,
+		Window:        time.Minute,
+		AccountFields: []string{"email", "username"},
+	}
+
+	tests := []struct {
+		name               string
+		body               string
+		limiter            *fakeRateLimiter
+		expectedStatus     int
+		expectedKeys       int
+		expectedRetryAfter string
+	}{
+		{
+			name:           "allowed_with_account",
+			body:           `{"email":"Mario@Example.com","password":"Testtest123"}`,
+			limiter:        &fakeRateLimiter{},
+			expectedStatus: http.StatusOK,
+			expectedKeys:   2,
+		},
+		{
+			name:           "allowed_without_account",
+			body:           `{"ticket":"mt1.ticket","code":"123456"}`,
+			limiter:        &fakeRateLimiter{},
+			expectedStatus: http.StatusOK,
+			expectedKeys:   1,
+		},
+		{
+			name:           "invalid_json_is_limited_by_ip",
+			body:           `{"email":`,
+			limiter:        &fakeRateLimiter{},
+			expectedStatus: http.StatusOK,
+			expectedKeys:   1,
+		},
+		{
+			name:               "ip_blocked",
+			body:               `{"email":"mario@example.com"}`,
+			limiter:            &fakeRateLimiter{blocked: map[string]time.Duration{"ip:login:": 1500 * time.Millisecond}},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedKeys:       1,
+			expectedRetryAfter: "2",
+		},
+		{
+			name:               "account_blocked",
+			body:               `{"username":"mario"}`,
+			limiter:            &fakeRateLimiter{blocked: map[string]time.Duration{"account:login:": 30 * time.Second}},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedKeys:       2,
+			expectedRetryAfter: "30",
+		},
+		{
+			name:           "limiter_unavailable_fails_open",
+			body:           `{"email":"mario@example.com"}`,
+			limiter:        &fakeRateLimiter{err: errors.New("redis down")},
+			expectedStatus: http.StatusOK,
+			expectedKeys:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			var handlerBody string
+			router.POST("/login", RateLimitMiddleware(tt.limiter, rule), func(c *gin.Context) {
+				// the handler must still be able to read the whole body
+				body, _ := io.ReadAll(c.Request.Body)
+				handlerBody = string(body)
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(tt.body))
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if len(tt.limiter.keys) != tt.expectedKeys {
+				t.Errorf("expected %d keys, got %v", tt.expectedKeys, tt.limiter.keys)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.expectedRetryAfter {
+				t.Errorf("expected Retry-After %q, got %q", tt.expectedRetryAfter, got)
+			}
+			if tt.expectedStatus == http.StatusOK && handlerBody != tt.body {
+				t.Errorf("expected handler body %q, got %q", tt.body, handlerBody)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware_AccountIsNormalized(t *testing.T) {
+	limiter := &fakeRateLimiter{}
+	rule := RateLimitRule{Name: "login", Limit: 10, Window: time.Minute, AccountFields: []string{"email"}}
+	router := gin.New()
+	router.POST("/login", RateLimitMiddleware(limiter, rule), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, body := range []string{`{"email":"Mario@Example.com"}`, `{"email":" mario@example.com "}`} {
+		req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(body))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(limiter.keys) != 4 || limiter.keys[1] != limiter.keys[3] {
+		t.Errorf("expected the same account key for both requests, got %v", limiter.keys)
+	}
+	if strings.Contains(limiter.keys[1], "mario") {
+		t.Errorf("expected the account to be hashed, got %v", limiter.keys[1])
+	}
+}
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS SIGNING_KEY;
+DROP TABLE IF EXISTS READING;
+DROP TABLE IF EXISTS DEVICE_TARGET;
+DROP TABLE IF EXISTS DEVICE;
+DROP TABLE IF EXISTS HOUSEHOLD_INVITATION;
+DROP TABLE IF EXISTS HOUSEHOLD_MEMBER;
+DROP TABLE IF EXISTS HOUSEHOLD;
+DROP TABLE IF EXISTS MFA_RECOVERY_CODE;
+DROP TABLE IF EXISTS USER_MFA;
+DROP TABLE IF EXISTS USER_ACCOUNT;
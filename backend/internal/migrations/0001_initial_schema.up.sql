@@ -0,0 +1,110 @@
+-- the schema as it was before the migrations were introduced, every statement
+-- is idempotent so that the databases created by the old init script can be migrated,
+-- the columns added to an existing table are also added with ALTER TABLE since
+-- CREATE TABLE IF NOT EXISTS does not change a table that is already there
+CREATE TABLE IF NOT EXISTS USER_ACCOUNT (
+  id UUID PRIMARY KEY,
+  username VARCHAR(50) UNIQUE NOT NULL,
+  email VARCHAR(254) UNIQUE NOT NULL,
+  password TEXT NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  surname VARCHAR(50) NOT NULL,
+  email_verified_at TIMESTAMPTZ
+);
+
+ALTER TABLE USER_ACCOUNT ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+-- the TOTP second factor of a user, it is enabled only once the
+-- user proves to have configured the authenticator app
+CREATE TABLE IF NOT EXISTS USER_MFA (
+  user_id UUID PRIMARY KEY REFERENCES USER_ACCOUNT(id) ON DELETE CASCADE,
+  totp_secret TEXT NOT NULL,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  enabled_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS MFA_RECOVERY_CODE (
+  user_id UUID NOT NULL REFERENCES USER_MFA(user_id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at TIMESTAMPTZ,
+  PRIMARY KEY (user_id, code_hash)
+);
+
+-- a group of users that share their devices, every member has a role
+-- and there is exactly one owner
+CREATE TABLE IF NOT EXISTS HOUSEHOLD (
+  id UUID PRIMARY KEY,
+  name VARCHAR(50) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS HOUSEHOLD_MEMBER (
+  household_id UUID NOT NULL REFERENCES HOUSEHOLD(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES USER_ACCOUNT(id) ON DELETE CASCADE,
+  role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'admin', 'member', 'viewer')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (household_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS household_member_user_id_idx ON HOUSEHOLD_MEMBER(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS household_member_owner_idx ON HOUSEHOLD_MEMBER(household_id) WHERE role = 'owner';
+
+-- an invited user becomes a member only once it accepts,
+-- a new invitation to the same user replaces the previous one
+CREATE TABLE IF NOT EXISTS HOUSEHOLD_INVITATION (
+  id UUID PRIMARY KEY,
+  household_id UUID NOT NULL REFERENCES HOUSEHOLD(id) ON DELETE CASCADE,
+  invitee_id UUID NOT NULL REFERENCES USER_ACCOUNT(id) ON DELETE CASCADE,
+  invited_by UUID REFERENCES USER_ACCOUNT(id) ON DELETE SET NULL,
+  role VARCHAR(10) NOT NULL CHECK (role IN ('admin', 'member', 'viewer')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  UNIQUE (household_id, invitee_id)
+);
+
+CREATE INDEX IF NOT EXISTS household_invitation_invitee_id_idx ON HOUSEHOLD_INVITATION(invitee_id);
+
+-- user_id is who added the device, household_id is set when
+-- the device is shared with the members of a household
+CREATE TABLE IF NOT EXISTS DEVICE (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES USER_ACCOUNT(id) ON DELETE CASCADE,
+  household_id UUID REFERENCES HOUSEHOLD(id) ON DELETE SET NULL,
+  name VARCHAR(50) NOT NULL,
+  serial VARCHAR(64) UNIQUE,
+  secret_hash TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE DEVICE ADD COLUMN IF NOT EXISTS household_id UUID REFERENCES HOUSEHOLD(id) ON DELETE SET NULL;
+ALTER TABLE DEVICE ADD COLUMN IF NOT EXISTS serial VARCHAR(64) UNIQUE;
+ALTER TABLE DEVICE ADD COLUMN IF NOT EXISTS secret_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS device_user_id_idx ON DEVICE(user_id);
+CREATE INDEX IF NOT EXISTS device_household_id_idx ON DEVICE(household_id);
+
+CREATE TABLE IF NOT EXISTS DEVICE_TARGET (
+  device_id UUID PRIMARY KEY REFERENCES DEVICE(id) ON DELETE CASCADE,
+  brightness SMALLINT NOT NULL CHECK (brightness BETWEEN 0 AND 100),
+  set_by UUID REFERENCES USER_ACCOUNT(id) ON DELETE SET NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS READING (
+  device_id UUID NOT NULL REFERENCES DEVICE(id) ON DELETE CASCADE,
+  ts TIMESTAMPTZ NOT NULL,
+  lux REAL NOT NULL,
+  raw INTEGER NOT NULL,
+  duty_cycle REAL NOT NULL,
+  PRIMARY KEY (device_id, ts)
+);
+
+-- the keys that sign the access tokens, a new key is published some time
+-- before it starts signing so that every verifier already knows it
+CREATE TABLE IF NOT EXISTS SIGNING_KEY (
+  kid TEXT PRIMARY KEY,
+  private_key BYTEA NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  activates_at TIMESTAMPTZ NOT NULL
+);
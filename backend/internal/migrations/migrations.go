@@ -0,0 +1,306 @@
+package migrations
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// files are the migrations, named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// a new migration takes the next version and is never changed once released
+//
+//go:embed *.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	ErrInvalidMigrations = errors.New("invalid migrations")
+	ErrNoMigration       = errors.New("no migration to roll back")
+	ErrUnknownVersion    = errors.New("migration unknown to this binary")
+)
+
+// Migration is a numbered change of the schema, Up applies it and Down reverts it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status tells whether a migration has been applied, AppliedAt is nil when it is pending
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator of the embedded migrations
+func NewMigrator(db *sql.DB) (*migrator, error) {
+	migrations, err := parse(files)
+	if err != nil {
+		return nil, err
+	}
+	return &migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns the applied ones,
+// each migration runs in its own transaction together with its version
+func (m *migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			err = run(ctx, conn, migration, migration.Up,
+				"INSERT INTO schema_migrations(version, name) VALUES($1, $2)", migration.Version, migration.Name)
+			if err != nil {
+				return err
+			}
+			slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last applied migration
+func (m *migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return ErrNoMigration
+		}
+		last := slices.Max(slices.Collect(maps.Keys(versions)))
+		index := slices.IndexFunc(m.migrations, func(migration Migration) bool {
+			return migration.Version == last
+		})
+		// the database has been migrated by a newer binary
+		if index < 0 {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, last)
+		}
+
+		migration := m.migrations[index]
+		err = run(ctx, conn, migration, migration.Down,
+			"DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		if err != nil {
+			return err
+		}
+		slog.Info("reverted migration", "version", migration.Version, "name", migration.Name)
+		reverted = &migration
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists the migrations known to this binary and the ones found in
+// the database only, ordered by version
+func (m *migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	versions, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := versions[migration.Version]; ok {
+			status.AppliedAt = &row.appliedAt
+			delete(versions, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, row := range versions {
+		statuses = append(statuses, Status{Version: version, Name: row.name, AppliedAt: &row.appliedAt})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return statuses, nil
+}
+
+// Version returns the last applied migration, 0 when none has been applied
+func (m *migrator) Version(ctx context.Context) (int64, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	versions, err := appliedVersions(ctx, conn)
+	if err != nil || len(versions) == 0 {
+		return 0, err
+	}
+	return slices.Max(slices.Collect(maps.Keys(versions))), nil
+}
+
+// withLock runs fn holding the advisory lock of the migrations, so that when more
+// instances start at the same time only one of them applies the pending migrations
+func (m *migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	// the lock belongs to the session, so every query must use the same connection
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock(hashtext('schema_migrations'))")
+	if err != nil {
+		return err
+	}
+	// the connection goes back to the pool, so the lock must be released explicitly
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock(hashtext('schema_migrations'))")
+
+	query := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`
+	_, err = conn.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// run executes the statements of a migration and updates schema_migrations in the
+// same transaction, so that a failed migration leaves the database as it was
+func run(ctx context.Context, conn *sql.Conn, migration Migration, statements string, query string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// the rollback does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, statements)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type appliedRow struct {
+	name      string
+	appliedAt time.Time
+}
+
+// appliedVersions reads schema_migrations, a database that has never
+// been migrated has no table and no applied version
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]appliedRow, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[int64]appliedRow{}
+	if !exists {
+		return versions, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var row appliedRow
+		err = rows.Scan(&version, &row.name, &row.appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		versions[version] = row
+	}
+	return versions, rows.Err()
+}
+
+// parse reads the migrations of fsys ordered by version, every
+// version must have both the up and the down file
+func parse(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidMigrations, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: invalid version in %s", ErrInvalidMigrations, entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d used by %s and %s", ErrInvalidMigrations, version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: version %d needs both the up and the down file", ErrInvalidMigrations, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return migrations, nil
+}
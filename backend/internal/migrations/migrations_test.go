@@ -0,0 +1,228 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/migrations"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	// the parsing tests do not need a container,
+	// so with -short we avoid starting it at all
+	flag.Parse()
+	if !testing.Short() {
+		// the container is already migrated by testutils
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+func tableExists(ctx context.Context, t *testing.T, table string) bool {
+	t.Helper()
+	var exists bool
+	err := testPostgresDB.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	if err != nil {
+		t.Fatalf("failed to check table %s: %v", table, err)
+	}
+	return exists
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	migrator, err := migrations.NewMigrator(testPostgresDB)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil || len(statuses) == 0 {
+		t.Fatalf("Status() = %+v, %v", statuses, err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("expected %d_%s to be applied", status.Version, status.Name)
+		}
+	}
+	last := statuses[len(statuses)-1].Version
+	if version, err := migrator.Version(ctx); err != nil || version != last {
+		t.Errorf("Version() = %d, %v, want %d", version, err, last)
+	}
+
+	// applying again does nothing
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("Up() on a migrated database = %+v, %v", applied, err)
+	}
+
+	// reverting everything leaves only schema_migrations
+	for range statuses {
+		if _, err := migrator.Down(ctx); err != nil {
+			t.Fatalf("Down() error = %v", err)
+		}
+	}
+	if tableExists(ctx, t, "user_account") {
+		t.Errorf("expected user_account to be dropped")
+	}
+	if _, err := migrator.Down(ctx); !errors.Is(err, migrations.ErrNoMigration) {
+		t.Errorf("Down() on an empty database error = %v, want %v", err, migrations.ErrNoMigration)
+	}
+	if version, err := migrator.Version(ctx); err != nil || version != 0 {
+		t.Errorf("Version() = %d, %v, want 0", version, err)
+	}
+
+	applied, err = migrator.Up(ctx)
+	if err != nil || len(applied) != len(statuses) {
+		t.Fatalf("Up() = %+v, %v, want %d migrations", applied, err, len(statuses))
+	}
+	if !tableExists(ctx, t, "user_account") {
+		t.Errorf("expected user_account to be created again")
+	}
+}
+
+func TestMigrator_ConcurrentUp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	migrator, err := migrations.NewMigrator(testPostgresDB)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for range statuses {
+		if _, err := migrator.Down(ctx); err != nil {
+			t.Fatalf("Down() error = %v", err)
+		}
+	}
+
+	// as if more instances started together, every migration is applied exactly once
+	const runners = 4
+	var wg sync.WaitGroup
+	results := make(chan int, runners)
+	errs := make(chan error, runners)
+	for range runners {
+		wg.Go(func() {
+			migrator, err := migrations.NewMigrator(testPostgresDB)
+			if err != nil {
+				errs <- err
+				return
+			}
+			applied, err := migrator.Up(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			results <- len(applied)
+		})
+	}
+	wg.Wait()
+	close(results)
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Up() error = %v", err)
+	}
+	total := 0
+	for count := range results {
+		total += count
+	}
+	if total != len(statuses) {
+		t.Errorf("expected %d migrations applied in total, got %d", len(statuses), total)
+	}
+}
+
+// TestMigrator_UpFromInitScript migrates the databases created by the old init
+// script, whose tables exist already but miss the columns added since then
+func TestMigrator_UpFromInitScript(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	tests := []struct {
+		name        string
+		schema      string
+		withDevices bool
+	}{
+		{name: "baseline", schema: "testdata/baseline_schema.sql"},
+		{name: "device_registry", schema: "testdata/device_registry_schema.sql", withDevices: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			migrator, err := migrations.NewMigrator(testPostgresDB)
+			if err != nil {
+				t.Fatalf("NewMigrator() error = %v", err)
+			}
+			statuses, err := migrator.Status(ctx)
+			if err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			for range statuses {
+				if _, err := migrator.Down(ctx); err != nil {
+					t.Fatalf("Down() error = %v", err)
+				}
+			}
+
+			// the database as the init script left it, with some data
+			schema, err := os.ReadFile(tt.schema)
+			if err != nil {
+				t.Fatalf("failed to read the schema: %v", err)
+			}
+			if _, err := testPostgresDB.ExecContext(ctx, string(schema)); err != nil {
+				t.Fatalf("failed to create the old schema: %v", err)
+			}
+			userID, deviceID := uuid.NewString(), uuid.NewString()
+			_, err = testPostgresDB.ExecContext(ctx,
+				"INSERT INTO user_account(id, username, email, password, name, surname) VALUES($1, $2, $3, $4, $5, $6)",
+				userID, userID[:8], userID+"@example.com", "hash", "test", "user",
+			)
+			if err != nil {
+				t.Fatalf("failed to insert the user: %v", err)
+			}
+			if tt.withDevices {
+				_, err = testPostgresDB.ExecContext(ctx, "INSERT INTO device(id, user_id, name) VALUES($1, $2, $3)", deviceID, userID, "lamp")
+				if err != nil {
+					t.Fatalf("failed to insert the device: %v", err)
+				}
+			}
+
+			applied, err := migrator.Up(ctx)
+			if err != nil || len(applied) != len(statuses) {
+				t.Fatalf("Up() = %+v, %v, want %d migrations", applied, err, len(statuses))
+			}
+
+			// the data is kept and the columns read by the repositories exist
+			var emailVerifiedAt sql.NullTime
+			err = testPostgresDB.QueryRowContext(ctx, "SELECT email_verified_at FROM user_account WHERE id = $1", userID).Scan(&emailVerifiedAt)
+			if err != nil || emailVerifiedAt.Valid {
+				t.Errorf("expected the user with an unverified email, got %v, %v", emailVerifiedAt, err)
+			}
+			if tt.withDevices {
+				var serial, householdID sql.NullString
+				err = testPostgresDB.QueryRowContext(ctx, "SELECT serial, household_id FROM device WHERE id = $1", deviceID).Scan(&serial, &householdID)
+				if err != nil || serial.Valid || householdID.Valid {
+					t.Errorf("expected the device without serial and household, got %v, %v, %v", serial, householdID, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name             string
+		files            fstest.MapFS
+		expectedVersions []int64
+		expectedError    error
+	}{
+		{
+			name: "ordered_by_version",
+			files: fstest.MapFS{
+				"0010_add_index.up.sql":        {Data: []byte("CREATE INDEX a ON b(c);")},
+				"0010_add_index.down.sql":      {Data: []byte("DROP INDEX a;")},
+				"0002_add_table.up.sql":        {Data: []byte("CREATE TABLE b (c INT);")},
+				"0002_add_table.down.sql":      {Data: []byte("DROP TABLE b;")},
+				"0001_initial_schema.up.sql":   {Data: []byte("CREATE TABLE a (c INT);")},
+				"0001_initial_schema.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+			expectedVersions: []int64{1, 2, 10},
+		},
+		{
+			name: "missing_down",
+			files: fstest.MapFS{
+				"0001_initial_schema.up.sql": {Data: []byte("CREATE TABLE a (c INT);")},
+			},
+			expectedError: ErrInvalidMigrations,
+		},
+		{
+			name: "same_version_different_name",
+			files: fstest.MapFS{
+				"0001_initial_schema.up.sql": {Data: []byte("CREATE TABLE a (c INT);")},
+				"0001_other.down.sql":        {Data: []byte("DROP TABLE a;")},
+			},
+			expectedError: ErrInvalidMigrations,
+		},
+		{
+			name: "unexpected_file",
+			files: fstest.MapFS{
+				"schema.sql": {Data: []byte("CREATE TABLE a (c INT);")},
+			},
+			expectedError: ErrInvalidMigrations,
+		},
+		{
+			name: "version_zero",
+			files: fstest.MapFS{
+				"0000_initial_schema.up.sql":   {Data: []byte("CREATE TABLE a (c INT);")},
+				"0000_initial_schema.down.sql": {Data: []byte("DROP TABLE a;")},
+			},
+			expectedError: ErrInvalidMigrations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := parse(tt.files)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(migrations) != len(tt.expectedVersions) {
+				t.Fatalf("expected %d migrations, got %d", len(tt.expectedVersions), len(migrations))
+			}
+			for i, migration := range migrations {
+				if migration.Version != tt.expectedVersions[i] {
+					t.Errorf("expected version %d at %d, got %d", tt.expectedVersions[i], i, migration.Version)
+				}
+				if migration.Up == "" || migration.Down == "" {
+					t.Errorf("expected both the up and the down statements of %d", migration.Version)
+				}
+			}
+		})
+	}
+}
+
+// the embedded migrations are checked here, since a wrong file
+// would otherwise be noticed only when the server starts
+func TestParse_Embedded(t *testing.T) {
+	migrations, err := parse(files)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Errorf("expected the versions to have no gaps, got %d at %d", migration.Version, i)
+		}
+	}
+}
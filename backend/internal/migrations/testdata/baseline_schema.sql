@@ -1,3 +1,4 @@
+-- db-init/schema.sql of the baseline, the volumes created before the migrations have this schema
 CREATE TABLE IF NOT EXISTS USER_ACCOUNT (
   id UUID PRIMARY KEY,
   username VARCHAR(50) UNIQUE NOT NULL,
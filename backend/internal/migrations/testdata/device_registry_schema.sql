@@ -0,0 +1,18 @@
+-- db-init/schema.sql once the devices were added, before their serial and their household
+CREATE TABLE IF NOT EXISTS USER_ACCOUNT (
+  id UUID PRIMARY KEY,
+  username VARCHAR(50) UNIQUE NOT NULL,
+  email VARCHAR(254) UNIQUE NOT NULL,
+  password TEXT NOT NULL,
+  name VARCHAR(50) NOT NULL,
+  surname VARCHAR(50) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS DEVICE (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES USER_ACCOUNT(id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS device_user_id_idx ON DEVICE(user_id);
@@ -0,0 +1,221 @@
+package mqtt
+
+//go:generate mockgen -source=bridge.go -destination=mocks/mock_bridge.go -package=mocks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// the topics are autolight/<deviceID>/telemetry for the readings sent by the boards
+// and autolight/<deviceID>/cmd for the commands sent by the backend, the backend
+// trusts the device id in the topic, so the broker must allow each board to
+// publish and subscribe only under its own prefix. The backends subscribe to the
+// telemetry as a shared subscription, so with more than one replica each reading
+// is delivered to only one of them
+const (
+	topicPrefix       = "autolight/"
+	telemetrySuffix   = "/telemetry"
+	commandSuffix     = "/cmd"
+	telemetryTopic    = "$share/autolight/" + topicPrefix + "+" + telemetrySuffix
+	qos               = 1
+	connectTimeout    = 10 * time.Second
+	disconnectQuiesce = 250 // milliseconds
+)
+
+// handlerTimeout bounds the database work done for a single message
+const handlerTimeout = 10 * time.Second
+
+type telemetryService interface {
+	Ingest(ctx context.Context, deviceID string, dutyCycle float64, samples []*telemetry.Reading) (*telemetry.IngestResult, error)
+}
+
+type Config struct {
+	// BrokerURL is the address of the broker, e.g. tcp://localhost:1883
+	BrokerURL string
+	// ClientID is followed by a random suffix, the broker drops the previous
+	// session of a client id so every replica needs its own
+	ClientID string
+	Username string
+	Password string
+}
+
+// the payloads have the same shape and the same rules of the HTTP endpoints
+
+type sampleMessage struct {
+	Timestamp time.Time `json:"timestamp" binding:"required"`
+	Lux       *float64  `json:"lux" binding:"required,min=0"`
+	Raw       *int      `json:"raw" binding:"required,min=0,max=65535"`
+}
+
+type telemetryMessage struct {
+	DutyCycle *float64        `json:"duty_cycle" binding:"required,min=0,max=100"`
+	Samples   []sampleMessage `json:"samples" binding:"required,min=1,max=500,dive"`
+}
+
+type commandMessage struct {
+	ID        string          `json:"id,omitempty"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Bridge connects the backend to an MQTT broker as an alternative to the HTTP endpoints of the boards
+type Bridge struct {
+	client           paho.Client
+	telemetryService telemetryService
+}
+
+func NewBridge(config Config, telemetryService telemetryService) *Bridge {
+	b := &Bridge{telemetryService: telemetryService}
+
+	options := paho.NewClientOptions().
+		AddBroker(config.BrokerURL).
+		SetClientID(config.ClientID + "-" + instanceSuffix()).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectTimeout(connectTimeout).
+		// the subscription is renewed on every (re)connection
+		SetOnConnectHandler(b.subscribe).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn("connection to the MQTT broker lost", "error", err)
+		})
+	b.client = paho.NewClient(options)
+
+	return b
+}
+
+// Connect opens the connection to the broker and subscribes to the telemetry of all the boards
+func (b *Bridge) Connect(ctx context.Context) error {
+	token := b.client.Connect()
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bridge) Close() {
+	b.client.Disconnect(disconnectQuiesce)
+}
+
+// PublishCommand sends a command to a board, it does not wait for the broker to
+// acknowledge it, so it can be called from the handler of a message
+func (b *Bridge) PublishCommand(cmd *command.Command) {
+	payload, err := json.Marshal(commandMessage{
+		ID:        cmd.ID,
+		Type:      cmd.Type,
+		Payload:   cmd.Payload,
+		CreatedAt: cmd.CreatedAt,
+	})
+	if err != nil {
+		slog.Error("failed to encode MQTT command", "deviceID", cmd.DeviceID, "error", err)
+		return
+	}
+
+	token := b.client.Publish(topicPrefix+cmd.DeviceID+commandSuffix, qos, false, payload)
+	go func() {
+		<-token.Done()
+		if err := token.Error(); err != nil {
+			slog.Error("failed to publish MQTT command", "deviceID", cmd.DeviceID, "type", cmd.Type, "error", err)
+		}
+	}()
+}
+
+func (b *Bridge) subscribe(client paho.Client) {
+	token := client.Subscribe(telemetryTopic, qos, b.handleTelemetry)
+	go func() {
+		<-token.Done()
+		if err := token.Error(); err != nil {
+			slog.Error("failed to subscribe to MQTT telemetry", "error", err)
+			return
+		}
+		slog.Info("subscribed to MQTT telemetry", "topic", telemetryTopic)
+	}()
+}
+
+func (b *Bridge) handleTelemetry(_ paho.Client, message paho.Message) {
+	deviceID, ok := deviceIDFromTopic(message.Topic())
+	if !ok {
+		slog.Warn("invalid MQTT telemetry topic", "topic", message.Topic())
+		return
+	}
+
+	var request telemetryMessage
+	err := json.Unmarshal(message.Payload(), &request)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(&request)
+	}
+	if err != nil {
+		slog.Warn("invalid MQTT telemetry payload", "deviceID", deviceID, "error", err)
+		return
+	}
+
+	samples := make([]*telemetry.Reading, 0, len(request.Samples))
+	for _, sample := range request.Samples {
+		samples = append(samples, &telemetry.Reading{
+			Timestamp: sample.Timestamp,
+			Lux:       *sample.Lux,
+			Raw:       *sample.Raw,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+	defer cancel()
+	result, err := b.telemetryService.Ingest(ctx, deviceID, *request.DutyCycle, samples)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			slog.Warn("MQTT telemetry timeout", "deviceID", deviceID, "error", err)
+			return
+		}
+		slog.Error("failed to ingest MQTT telemetry", "deviceID", deviceID, "error", err)
+		return
+	}
+
+	if result.Rejected > 0 {
+		slog.Warn("telemetry samples rejected", "deviceID", deviceID, "accepted", result.Accepted, "rejected", result.Rejected)
+	}
+	// over HTTP the next duty cycle is in the response, here it becomes a command
+	if result.DutyCycle != nil {
+		payload, _ := json.Marshal(map[string]float64{"duty_cycle": *result.DutyCycle})
+		b.PublishCommand(&command.Command{
+			DeviceID:  deviceID,
+			Type:      command.TypeSetDuty,
+			Payload:   payload,
+			CreatedAt: time.Now(),
+		})
+	}
+}
+
+// deviceIDFromTopic extracts the device id from autolight/<deviceID>/telemetry
+func deviceIDFromTopic(topic string) (string, bool) {
+	deviceID, ok := strings.CutPrefix(topic, topicPrefix)
+	if !ok {
+		return "", false
+	}
+	deviceID, ok = strings.CutSuffix(deviceID, telemetrySuffix)
+	if !ok || deviceID == "" || strings.Contains(deviceID, "/") {
+		return "", false
+	}
+	return deviceID, true
+}
+
+// instanceSuffix tells apart the clients of the replicas of the backend
+func instanceSuffix() string {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return hex.EncodeToString(suffix)
+}
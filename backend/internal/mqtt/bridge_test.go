@@ -0,0 +1,264 @@
+package mqtt_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mqtt"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mqtt/mocks"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"go.uber.org/mock/gomock"
+)
+
+const testDeviceID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+/**
+ * startBroker runs an in-process MQTT broker on a random local port,
+ * the inline client of the broker plays the role of the boards.
+ */
+func startBroker(t *testing.T) (*mochi.Server, string) {
+	t.Helper()
+
+	server := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.DiscardHandler),
+	})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatalf("failed to add the auth hook: %v", err)
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := server.AddListener(tcp); err != nil {
+		t.Fatalf("failed to add the listener: %v", err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatalf("failed to start the broker: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	return server, "tcp://" + tcp.Address()
+}
+
+func connectBridge(t *testing.T, brokerURL string, telemetryService *mocks.MocktelemetryService) *mqtt.Bridge {
+	t.Helper()
+
+	bridge := mqtt.NewBridge(mqtt.Config{BrokerURL: brokerURL, ClientID: "backend-test"}, telemetryService)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bridge.Connect(ctx); err != nil {
+		t.Fatalf("failed to connect the bridge: %v", err)
+	}
+	t.Cleanup(bridge.Close)
+
+	return bridge
+}
+
+// subscribeCommands collects the commands published for the test device
+func subscribeCommands(t *testing.T, server *mochi.Server) <-chan map[string]any {
+	t.Helper()
+
+	commands := make(chan map[string]any, 10)
+	err := server.Subscribe("autolight/"+testDeviceID+"/cmd", 1, func(_ *mochi.Client, _ packets.Subscription, pk packets.Packet) {
+		var message map[string]any
+		if err := json.Unmarshal(pk.Payload, &message); err != nil {
+			t.Errorf("invalid command payload %s: %v", pk.Payload, err)
+			return
+		}
+		commands <- message
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe to the commands: %v", err)
+	}
+	return commands
+}
+
+// publishUntil publishes the message until the bridge has processed it, the
+// subscription of the bridge is completed asynchronously after the connection
+func publishUntil(t *testing.T, server *mochi.Server, topic string, payload string, done <-chan struct{}) {
+	t.Helper()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if err := server.Publish(topic, []byte(payload), false, 0); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+		select {
+		case <-done:
+			return
+		case <-deadline:
+			t.Fatal("the bridge did not process the telemetry")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestBridge_TelemetryToCommand(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server, brokerURL := startBroker(t)
+	commands := subscribeCommands(t, server)
+
+	done := make(chan struct{})
+	next := 62.5
+	mockTelemetryService := mocks.NewMocktelemetryService(ctrl)
+	mockTelemetryService.EXPECT().Ingest(gomock.Any(), testDeviceID, 40.0, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, deviceID string, dutyCycle float64, samples []*telemetry.Reading) (*telemetry.IngestResult, error) {
+			if len(samples) != 2 || samples[0].Lux != 120.5 || samples[1].Raw != 2050 {
+				t.Errorf("unexpected samples %+v %+v", samples[0], samples[1])
+			}
+			close(done)
+			return &telemetry.IngestResult{Accepted: 2, DutyCycle: &next}, nil
+		},
+	).Times(1)
+	connectBridge(t, brokerURL, mockTelemetryService)
+
+	payload := `{"duty_cycle":40,"samples":[` +
+		`{"timestamp":"2026-01-01T00:00:00Z","lux":120.5,"raw":2048},` +
+		`{"timestamp":"2026-01-01T00:00:01Z","lux":121,"raw":2050}]}`
+	publishUntil(t, server, "autolight/"+testDeviceID+"/telemetry", payload, done)
+
+	select {
+	case message := <-commands:
+		if message["type"] != command.TypeSetDuty {
+			t.Errorf("expected a set_duty command, got %v", message)
+		}
+		if payload, _ := message["payload"].(map[string]any); payload["duty_cycle"] != 62.5 {
+			t.Errorf("expected duty cycle 62.5, got %v", message["payload"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no command published")
+	}
+}
+
+func TestBridge_NoTargetNoCommand(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server, brokerURL := startBroker(t)
+	commands := subscribeCommands(t, server)
+
+	done := make(chan struct{})
+	mockTelemetryService := mocks.NewMocktelemetryService(ctrl)
+	mockTelemetryService.EXPECT().Ingest(gomock.Any(), testDeviceID, 40.0, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, deviceID string, dutyCycle float64, samples []*telemetry.Reading) (*telemetry.IngestResult, error) {
+			close(done)
+			return &telemetry.IngestResult{Accepted: 1}, nil
+		},
+	).Times(1)
+	connectBridge(t, brokerURL, mockTelemetryService)
+
+	payload := `{"duty_cycle":40,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":120.5,"raw":2048}]}`
+	publishUntil(t, server, "autolight/"+testDeviceID+"/telemetry", payload, done)
+
+	select {
+	case message := <-commands:
+		t.Errorf("expected no command, got %v", message)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestBridge_InvalidTelemetryIsDropped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server, brokerURL := startBroker(t)
+
+	// the invalid messages must never reach the service, the valid one is
+	// published last and proves that the others have been processed already
+	done := make(chan struct{})
+	mockTelemetryService := mocks.NewMocktelemetryService(ctrl)
+	mockTelemetryService.EXPECT().Ingest(gomock.Any(), "sentinel", gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, deviceID string, dutyCycle float64, samples []*telemetry.Reading) (*telemetry.IngestResult, error) {
+			select {
+			case <-done:
+			default:
+				close(done)
+			}
+			return &telemetry.IngestResult{Accepted: 1}, nil
+		},
+	).MinTimes(1)
+	connectBridge(t, brokerURL, mockTelemetryService)
+
+	invalid := []string{
+		`not json`,
+		`{"duty_cycle":140,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":1,"raw":1}]}`,
+		`{"duty_cycle":40,"samples":[]}`,
+		`{"duty_cycle":40,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":-1,"raw":1}]}`,
+	}
+	deadline := time.After(5 * time.Second)
+	for {
+		for _, payload := range invalid {
+			if err := server.Publish("autolight/"+testDeviceID+"/telemetry", []byte(payload), false, 0); err != nil {
+				t.Fatalf("failed to publish: %v", err)
+			}
+		}
+		valid := `{"duty_cycle":40,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":1,"raw":1}]}`
+		if err := server.Publish("autolight/sentinel/telemetry", []byte(valid), false, 0); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+		select {
+		case <-done:
+			return
+		case <-deadline:
+			t.Fatal("the bridge did not process the telemetry")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestBridge_ReplicasShareTheTelemetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server, brokerURL := startBroker(t)
+
+	var ingested atomic.Int32
+	mockTelemetryService := mocks.NewMocktelemetryService(ctrl)
+	mockTelemetryService.EXPECT().Ingest(gomock.Any(), testDeviceID, gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, deviceID string, dutyCycle float64, samples []*telemetry.Reading) (*telemetry.IngestResult, error) {
+			ingested.Add(1)
+			return &telemetry.IngestResult{Accepted: len(samples)}, nil
+		},
+	).AnyTimes()
+	// two replicas with the same configuration stay connected together
+	connectBridge(t, brokerURL, mockTelemetryService)
+	connectBridge(t, brokerURL, mockTelemetryService)
+
+	topic := "autolight/" + testDeviceID + "/telemetry"
+	deadline := time.After(5 * time.Second)
+	for {
+		subscribers := server.Topics.Subscribers(topic)
+		members := 0
+		for _, group := range subscribers.Shared {
+			members += len(group)
+		}
+		if members == 2 && len(subscribers.Subscriptions) == 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("expected both replicas in the shared subscription, got %+v", subscribers)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	payload := `{"duty_cycle":40,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":1,"raw":1}]}`
+	if err := server.Publish(topic, []byte(payload), false, 0); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	// the reading is ingested by only one of the replicas
+	time.Sleep(300 * time.Millisecond)
+	if got := ingested.Load(); got != 1 {
+		t.Errorf("expected the reading to be ingested once, got %d", got)
+	}
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: bridge.go
+//
+// Generated by this command:
+//
+//	mockgen -source=bridge.go -destination=mocks/mock_bridge.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	telemetry "github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MocktelemetryService is a mock of telemetryService interface.
+type MocktelemetryService struct {
+	ctrl     *gomock.Controller
+	recorder *MocktelemetryServiceMockRecorder
+	isgomock struct{}
+}
+
+// MocktelemetryServiceMockRecorder is the mock recorder for MocktelemetryService.
+type MocktelemetryServiceMockRecorder struct {
+	mock *MocktelemetryService
+}
+
+// NewMocktelemetryService creates a new mock instance.
+func NewMocktelemetryService(ctrl *gomock.Controller) *MocktelemetryService {
+	mock := &MocktelemetryService{ctrl: ctrl}
+	mock.recorder = &MocktelemetryServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktelemetryService) EXPECT() *MocktelemetryServiceMockRecorder {
+	return m.recorder
+}
+
+// Ingest mocks base method.
+func (m *MocktelemetryService) Ingest(ctx context.Context, deviceID string, dutyCycle float64, samples []*telemetry.Reading) (*telemetry.IngestResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ingest", ctx, deviceID, dutyCycle, samples)
+	ret0, _ := ret[0].(*telemetry.IngestResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ingest indicates an expected call of Ingest.
+func (mr *MocktelemetryServiceMockRecorder) Ingest(ctx, deviceID, dutyCycle, samples any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ingest", reflect.TypeOf((*MocktelemetryService)(nil).Ingest), ctx, deviceID, dutyCycle, samples)
+}
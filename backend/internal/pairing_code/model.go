@@ -0,0 +1,15 @@
+package pairing_code
+
+import (
+	"time"
+)
+
+type PairingCode struct {
+	Code       string
+	CodeHash   string
+	UserID     string
+	DeviceName string
+	// DeviceID is the device the board is attached to, empty for a new device
+	DeviceID string
+	TTL      time.Time
+}
@@ -0,0 +1,122 @@
+package pairing_code
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrCodeHashNotFound = errors.New("pairing code hash not found")
+)
+
+type pairingCodeEntity struct {
+	CodeHash   string
+	UserID     string
+	DeviceName string
+	DeviceID   string
+	TTL        time.Time
+}
+
+type repository struct {
+	db *redis.Client
+}
+
+func NewPairingCodeRepository(db *redis.Client) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateOne(ctx context.Context, pairingCode *PairingCode) error {
+	// as for the refresh tokens we save two records:
+	// 1. pch:{codeHash} -> {userID, deviceName, deviceID} used when the board claims the code
+	// 2. pcu:{userID} -> {codeHash} used to keep only one pending code per user
+
+	pairingCodeEntity := toEntity(pairingCode)
+
+	pcKey := "pch:" + pairingCodeEntity.CodeHash
+	pcuKey := "pcu:" + pairingCodeEntity.UserID
+
+	lua := `
+		local pcKey = KEYS[1]
+		local pcuKey = KEYS[2]
+		local userID = ARGV[1]
+		local codeHash = ARGV[2]
+		local deviceName = ARGV[3]
+		local deviceID = ARGV[4]
+		local pxat = tonumber(ARGV[5])
+
+		-- A new code replaces the one the user requested before
+		local oldCodeHash = redis.call("GET", pcuKey)
+		if oldCodeHash then
+			redis.call("DEL", "pch:" .. oldCodeHash)
+		end
+
+		redis.call("HSET", pcKey, "user_id", userID, "device_name", deviceName, "device_id", deviceID)
+		redis.call("PEXPIREAT", pcKey, pxat)
+		redis.call("SET", pcuKey, codeHash, "PXAT", pxat)
+
+		return 1
+	`
+
+	pxat := pairingCodeEntity.TTL.UnixMilli()
+	_, err := r.db.Eval(ctx, lua, []string{pcKey, pcuKey}, pairingCodeEntity.UserID, pairingCodeEntity.CodeHash, pairingCodeEntity.DeviceName, pairingCodeEntity.DeviceID, pxat).Int64()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *repository) ConsumeOneByCodeHash(ctx context.Context, codeHash string) (*PairingCode, error) {
+	// reading and deleting the code must be atomic, otherwise two boards
+	// presenting the same code at the same time could both claim it
+	pcKey := "pch:" + codeHash
+
+	lua := `
+		local pcKey = KEYS[1]
+		local codeHash = ARGV[1]
+
+		local values = redis.call("HMGET", pcKey, "user_id", "device_name", "device_id")
+		local userID = values[1]
+		if not userID then
+			return false
+		end
+		redis.call("DEL", pcKey)
+
+		-- The user mapping is removed only if it still points to this code
+		local pcuKey = "pcu:" .. userID
+		if redis.call("GET", pcuKey) == codeHash then
+			redis.call("DEL", pcuKey)
+		end
+
+		return {userID, values[2] or "", values[3] or ""}
+	`
+
+	values, err := r.db.Eval(ctx, lua, []string{pcKey}, codeHash).StringSlice()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrCodeHashNotFound
+		}
+		return nil, err
+	}
+
+	return &PairingCode{
+		CodeHash:   codeHash,
+		UserID:     values[0],
+		DeviceName: values[1],
+		DeviceID:   values[2],
+	}, nil
+}
+
+func toEntity(pc *PairingCode) *pairingCodeEntity {
+	return &pairingCodeEntity{
+		CodeHash:   pc.CodeHash,
+		UserID:     pc.UserID,
+		DeviceName: pc.DeviceName,
+		DeviceID:   pc.DeviceID,
+		TTL:        pc.TTL,
+	}
+}
@@ -0,0 +1,147 @@
+package pairing_code
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/redis/go-redis/v9"
+)
+
+var testRedisDB *redis.Client
+
+func TestMain(m *testing.M) {
+	redisConnectionStr := testutils.SetupRedis()
+	opt, _ := redis.ParseURL(redisConnectionStr)
+	testRedisDB = redis.NewClient(opt)
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_CreateOne(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewPairingCodeRepository(testRedisDB)
+
+	tests := []struct {
+		name        string
+		setupFunc   func(ctx context.Context, rdb *redis.Client) error
+		pairingCode PairingCode
+		verifyFunc  func(ctx context.Context, rdb *redis.Client, pc PairingCode) error
+	}{
+		{
+			name: "create_new_code_success",
+			pairingCode: PairingCode{
+				CodeHash:   "hash_success",
+				UserID:     "user_success",
+				DeviceName: "living room",
+				DeviceID:   "device_success",
+				TTL:        time.Now().Add(10 * time.Minute),
+			},
+			verifyFunc: func(ctx context.Context, rdb *redis.Client, pc PairingCode) error {
+				values, err := rdb.HGetAll(ctx, "pch:"+pc.CodeHash).Result()
+				if err != nil {
+					return fmt.Errorf("lookup by hash failed: %w", err)
+				}
+				if values["user_id"] != pc.UserID || values["device_name"] != pc.DeviceName || values["device_id"] != pc.DeviceID {
+					return fmt.Errorf("unexpected values %v", values)
+				}
+				hash, err := rdb.Get(ctx, "pcu:"+pc.UserID).Result()
+				if err != nil {
+					return fmt.Errorf("lookup by userID failed: %w", err)
+				}
+				if hash != pc.CodeHash {
+					return fmt.Errorf("expected hash %s, got %s", pc.CodeHash, hash)
+				}
+				return nil
+			},
+		},
+		{
+			name: "new_code_replaces_old_one",
+			setupFunc: func(ctx context.Context, rdb *redis.Client) error {
+				if err := rdb.Set(ctx, "pcu:user_replace", "old_hash", time.Hour).Err(); err != nil {
+					return err
+				}
+				return rdb.HSet(ctx, "pch:old_hash", "user_id", "user_replace").Err()
+			},
+			pairingCode: PairingCode{
+				CodeHash: "new_hash",
+				UserID:   "user_replace",
+				TTL:      time.Now().Add(10 * time.Minute),
+			},
+			verifyFunc: func(ctx context.Context, rdb *redis.Client, pc PairingCode) error {
+				exists, err := rdb.Exists(ctx, "pch:old_hash").Result()
+				if err != nil {
+					return err
+				}
+				if exists != 0 {
+					return fmt.Errorf("expected old code to be deleted")
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+
+			if tt.setupFunc != nil {
+				if err := tt.setupFunc(ctx, testRedisDB); err != nil {
+					t.Fatalf("setupFunc failed: %v", err)
+				}
+			}
+
+			if err := repo.CreateOne(ctx, &tt.pairingCode); err != nil {
+				t.Fatalf("CreateOne() error = %v", err)
+			}
+
+			if err := tt.verifyFunc(ctx, testRedisDB, tt.pairingCode); err != nil {
+				t.Errorf("verification failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestRepository_ConsumeOneByCodeHash(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewPairingCodeRepository(testRedisDB)
+	testRedisDB.FlushDB(ctx)
+
+	err := repo.CreateOne(ctx, &PairingCode{
+		CodeHash:   "hash_consume",
+		UserID:     "user_consume",
+		DeviceName: "kitchen",
+		DeviceID:   "device_consume",
+		TTL:        time.Now().Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+
+	got, err := repo.ConsumeOneByCodeHash(ctx, "hash_consume")
+	if err != nil {
+		t.Fatalf("ConsumeOneByCodeHash() error = %v", err)
+	}
+	if got.UserID != "user_consume" || got.DeviceName != "kitchen" || got.DeviceID != "device_consume" {
+		t.Errorf("unexpected pairing code %+v", got)
+	}
+
+	// the code is single-use
+	_, err = repo.ConsumeOneByCodeHash(ctx, "hash_consume")
+	if err != ErrCodeHashNotFound {
+		t.Errorf("expected ErrCodeHashNotFound on second use, got %v", err)
+	}
+	exists, _ := testRedisDB.Exists(ctx, "pcu:user_consume").Result()
+	if exists != 0 {
+		t.Errorf("expected user mapping to be deleted")
+	}
+}
@@ -0,0 +1,12 @@
+package password_reset_token
+
+import (
+	"time"
+)
+
+type PasswordResetToken struct {
+	Token     string
+	TokenHash string
+	UserID    string
+	TTL       time.Time
+}
@@ -0,0 +1,129 @@
+package password_reset_token
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrTokenHashNotFound = errors.New("token hash not found")
+
+type repository struct {
+	db *redis.Client
+}
+
+func NewPasswordResetTokenRepository(db *redis.Client) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateOne(ctx context.Context, token *PasswordResetToken) error {
+	// as for the refresh tokens we will save two records:
+	// 1. prt:{tokenHash} -> userID used as lookup when the password is reset
+	// 2. pru:{userID} -> {tokenHash} used to invalidate the previous token,
+	//    only the last link sent to the user can be used
+	prtKey := "prt:" + token.TokenHash
+	pruKey := "pru:" + token.UserID
+
+	lua := `
+		local prtKey = KEYS[1]
+		local pruKey = KEYS[2]
+		local userID = ARGV[1]
+		local tokenHash = ARGV[2]
+		local pxat = tonumber(ARGV[3])
+
+		local oldTokenHash = redis.call("GET", pruKey)
+		if oldTokenHash then
+			redis.call("DEL", "prt:" .. oldTokenHash)
+		end
+
+		redis.call("SET", prtKey, userID, "PXAT", pxat)
+		redis.call("SET", pruKey, tokenHash, "PXAT", pxat)
+
+		return 1
+	`
+
+	_, err := r.db.Eval(ctx, lua, []string{prtKey, pruKey}, token.UserID, token.TokenHash, token.TTL.UnixMilli()).Int64()
+	return err
+}
+
+// ConsumeOneByTokenHash returns the user of the token and deletes it in the
+// same step, so that a token can be used to reset the password only once
+func (r *repository) ConsumeOneByTokenHash(ctx context.Context, tokenHash string) (string, error) {
+	prtKey := "prt:" + tokenHash
+
+	lua := `
+		local prtKey = KEYS[1]
+		local tokenHash = ARGV[1]
+
+		local userID = redis.call("GETDEL", prtKey)
+		if not userID then
+			return false
+		end
+
+		local pruKey = "pru:" .. userID
+		if redis.call("GET", pruKey) == tokenHash then
+			redis.call("DEL", pruKey)
+		end
+
+		return userID
+	`
+
+	userID, err := r.db.Eval(ctx, lua, []string{prtKey}, tokenHash).Text()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrTokenHashNotFound
+		}
+		return "", err
+	}
+	return userID, nil
+}
+
+// DeleteOneByUserID invalidates the last link sent to the user, e.g. when
+// the email changes and the link points to the previous address
+func (r *repository) DeleteOneByUserID(ctx context.Context, userID string) error {
+	pruKey := "pru:" + userID
+
+	lua := `
+		local pruKey = KEYS[1]
+
+		local tokenHash = redis.call("GET", pruKey)
+		if tokenHash then
+			redis.call("DEL", "prt:" .. tokenHash, pruKey)
+		end
+
+		return 1
+	`
+
+	_, err := r.db.Eval(ctx, lua, []string{pruKey}).Int64()
+	return err
+}
+
+// AcquireResendSlot reserves the right to send another reset email to the
+// given address for the cooldown, when the slot is already taken it returns
+// how long the caller has to wait before trying again
+func (r *repository) AcquireResendSlot(ctx context.Context, emailHash string, cooldown time.Duration) (time.Duration, error) {
+	prrKey := "prr:" + emailHash
+
+	lua := `
+		local prrKey = KEYS[1]
+		local cooldown = tonumber(ARGV[1])
+
+		if redis.call("SET", prrKey, 1, "NX", "PX", cooldown) then
+			return 0
+		end
+
+		local remaining = redis.call("PTTL", prrKey)
+		if remaining < 0 then
+			return cooldown
+		end
+		return remaining
+	`
+
+	remaining, err := r.db.Eval(ctx, lua, []string{prrKey}, cooldown.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(remaining) * time.Millisecond, nil
+}
@@ -0,0 +1,118 @@
+package rate_limit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type repository struct {
+	db *redis.Client
+}
+
+func NewRateLimitRepository(db *redis.Client) *repository {
+	return &repository{db: db}
+}
+
+// Allow records a request in the sliding window of the key, when the
+// limit is already reached the request is not recorded and it returns
+// how long the caller has to wait before the oldest request leaves the window
+func (r *repository) Allow(ctx context.Context, key string, limit int, window time.Duration) (time.Duration, error) {
+	rlwKey := "rlw:" + key
+
+	// each request is a member of a sorted set scored by its time,
+	// the members older than the window are dropped before counting
+	lua := `
+		local rlwKey = KEYS[1]
+		local now = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local limit = tonumber(ARGV[3])
+		local member = ARGV[4]
+
+		redis.call("ZREMRANGEBYSCORE", rlwKey, "-inf", now - window)
+
+		if redis.call("ZCARD", rlwKey) >= limit then
+			local oldest = redis.call("ZRANGE", rlwKey, 0, 0, "WITHSCORES")
+			local remaining = tonumber(oldest[2]) + window - now
+			if remaining < 1 then
+				return 1
+			end
+			return remaining
+		end
+
+		redis.call("ZADD", rlwKey, now, member)
+		redis.call("PEXPIRE", rlwKey, window)
+		return 0
+	`
+
+	now := time.Now().UnixMilli()
+	remaining, err := r.db.Eval(ctx, lua, []string{rlwKey}, now, window.Milliseconds(), limit, uuid.NewString()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(remaining) * time.Millisecond, nil
+}
+
+// GetLockout returns how long the key is still locked, zero when it is not
+func (r *repository) GetLockout(ctx context.Context, key string) (time.Duration, error) {
+	remaining, err := r.db.PTTL(ctx, "rll:"+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// PTTL returns a negative duration when the key does not exist
+	if remaining < 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+// RecordFailure counts a failure of the key, from the threshold on every
+// failure locks the key for a time that doubles each time, up to maxLockout.
+// The failures are forgotten after memory without new ones
+func (r *repository) RecordFailure(ctx context.Context, key string, threshold int, baseLockout time.Duration, maxLockout time.Duration, memory time.Duration) (time.Duration, error) {
+	rlfKey := "rlf:" + key
+	rllKey := "rll:" + key
+
+	lua := `
+		local rlfKey = KEYS[1]
+		local rllKey = KEYS[2]
+		local memory = tonumber(ARGV[1])
+		local threshold = tonumber(ARGV[2])
+		local baseLockout = tonumber(ARGV[3])
+		local maxLockout = tonumber(ARGV[4])
+
+		local failures = redis.call("INCR", rlfKey)
+		redis.call("PEXPIRE", rlfKey, memory)
+
+		if failures < threshold then
+			return 0
+		end
+
+		local lockout = baseLockout * 2 ^ (failures - threshold)
+		if lockout > maxLockout then
+			lockout = maxLockout
+		end
+		lockout = math.floor(lockout)
+
+		redis.call("SET", rllKey, 1, "PX", lockout)
+		return lockout
+	`
+
+	lockout, err := r.db.Eval(ctx, lua, []string{rlfKey, rllKey},
+		memory.Milliseconds(),
+		threshold,
+		baseLockout.Milliseconds(),
+		maxLockout.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(lockout) * time.Millisecond, nil
+}
+
+// Reset forgets the failures of the key and lifts its lockout
+func (r *repository) Reset(ctx context.Context, key string) error {
+	return r.db.Del(ctx, "rlf:"+key, "rll:"+key).Err()
+}
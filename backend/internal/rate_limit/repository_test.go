@@ -0,0 +1,145 @@
+package rate_limit
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/redis/go-redis/v9"
+)
+
+var testRedisDB *redis.Client
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		redisConnectionStr := testutils.SetupRedis()
+		opt, _ := redis.ParseURL(redisConnectionStr)
+		testRedisDB = redis.NewClient(opt)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_Allow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewRateLimitRepository(testRedisDB)
+
+	tests := []struct {
+		name          string
+		key           string
+		limit         int
+		window        time.Duration
+		previous      int
+		expectBlocked bool
+	}{
+		{
+			name:          "under_limit",
+			key:           "ip:login:under",
+			limit:         3,
+			window:        time.Minute,
+			previous:      2,
+			expectBlocked: false,
+		},
+		{
+			name:          "limit_reached",
+			key:           "ip:login:reached",
+			limit:         3,
+			window:        time.Minute,
+			previous:      3,
+			expectBlocked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.Del(ctx, "rlw:"+tt.key)
+			for i := 0; i < tt.previous; i++ {
+				if _, err := repo.Allow(ctx, tt.key, tt.limit, tt.window); err != nil {
+					t.Fatalf("setup failed: %v", err)
+				}
+			}
+
+			retryAfter, err := repo.Allow(ctx, tt.key, tt.limit, tt.window)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectBlocked && (retryAfter <= 0 || retryAfter > tt.window) {
+				t.Errorf("expected retry after within %v, got %v", tt.window, retryAfter)
+			}
+			if !tt.expectBlocked && retryAfter != 0 {
+				t.Errorf("expected request to be allowed, got retry after %v", retryAfter)
+			}
+		})
+	}
+}
+
+func TestRepository_Allow_WindowSlides(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewRateLimitRepository(testRedisDB)
+	key := "ip:login:slides"
+	testRedisDB.Del(ctx, "rlw:"+key)
+
+	if retryAfter, _ := repo.Allow(ctx, key, 1, 200*time.Millisecond); retryAfter != 0 {
+		t.Fatalf("expected first request to be allowed, got %v", retryAfter)
+	}
+	if retryAfter, _ := repo.Allow(ctx, key, 1, 200*time.Millisecond); retryAfter == 0 {
+		t.Fatalf("expected second request to be blocked")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+
+	if retryAfter, _ := repo.Allow(ctx, key, 1, 200*time.Millisecond); retryAfter != 0 {
+		t.Errorf("expected request to be allowed once the window slid, got %v", retryAfter)
+	}
+}
+
+func TestRepository_RecordFailure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewRateLimitRepository(testRedisDB)
+	key := "user:lockout"
+	repo.Reset(ctx, key)
+
+	// the lockout starts at the third failure and doubles up to the maximum
+	expected := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		lockout, err := repo.RecordFailure(ctx, key, 3, time.Second, 5*time.Second, time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lockout != want {
+			t.Errorf("failure %d: expected lockout %v, got %v", i+1, want, lockout)
+		}
+	}
+
+	remaining, err := repo.GetLockout(ctx, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining <= 0 || remaining > 5*time.Second {
+		t.Errorf("expected remaining lockout within 5s, got %v", remaining)
+	}
+
+	if err := repo.Reset(ctx, key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	remaining, err = repo.GetLockout(ctx, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining != 0 {
+		t.Errorf("expected no lockout after reset, got %v", remaining)
+	}
+}
@@ -8,5 +8,19 @@ type RefreshToken struct {
 	RefreshToken     string
 	RefreshTokenHash string
 	UserID           string
+	SessionID        string
 	TTL              time.Time
-}
\ No newline at end of file
+}
+
+// Session is a login of a user from a client, it lives as long as
+// its refresh token keeps being rotated
+type Session struct {
+	ID         string
+	UserID     string
+	TokenHash  string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
@@ -5,19 +5,23 @@ package refresh_token
 import (
 	"context"
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 var (
-	ErrUserIDNotFound  = errors.New("user ID not found")
 	ErrTokenHashNotFound = errors.New("token hash not found")
+	ErrSessionNotFound   = errors.New("session not found")
 )
 
 type refreshTokenEntity struct {
 	RefreshTokenHash string
 	UserID           string
+	SessionID        string
 	TTL              time.Time
 }
 
@@ -29,86 +33,291 @@ func NewRefreshTokenRepository(db *redis.Client) *repository {
 	return &repository{db: db}
 }
 
-func (r *repository) CreateOne(ctx context.Context, refreshToken *RefreshToken) error {
-	// we will save two records:
-	// 1. rth:{tokenHash} -> userID used as lookup when a new request comes in
-	// 2. rtu:{userID} -> {tokenHash} used for the revocation of the token
+// before the sessions were introduced rtu:{userID} was a string holding the
+// hash of the only token of the user, that login is dropped the first time
+// the key is touched so that it can be turned into a set
+const dropLegacyLua = `
+	if redis.call("TYPE", KEYS[#KEYS])["ok"] == "string" then
+		local legacyHash = redis.call("GET", KEYS[#KEYS])
+		redis.call("DEL", "rth:" .. legacyHash, KEYS[#KEYS])
+	end
+`
+
+// the set of the sessions of a user must live as long as its longest session
+const extendSessionsLua = `
+	local expireTime = redis.call("PEXPIRETIME", KEYS[#KEYS])
+	if expireTime < pxat then
+		redis.call("PEXPIREAT", KEYS[#KEYS], pxat)
+	end
+`
+
+func (r *repository) CreateOne(ctx context.Context, refreshToken *RefreshToken, session *Session) error {
+	// we will save three records:
+	// 1. rth:{tokenHash} -> sessionID used as lookup when a new request comes in
+	// 2. rts:{sessionID} -> hash with the session metadata and its current token
+	// 3. rtu:{userID} -> set of sessionIDs used to list and revoke the sessions
 
 	refreshTokenEntity := toEntity(refreshToken)
 
 	rtKey := "rth:" + refreshTokenEntity.RefreshTokenHash
+	rtsKey := "rts:" + refreshTokenEntity.SessionID
 	rtuKey := "rtu:" + refreshTokenEntity.UserID
 
 	// we use a Lua script since we need to perform multiple operations atomically
-	lua := `
+	lua := dropLegacyLua + `
 		local rtKey = KEYS[1]
-		local ruKey = KEYS[2]
+		local rtsKey = KEYS[2]
+		local rtuKey = KEYS[3]
 		local userID = ARGV[1]
-		local tokenHash = ARGV[2]
-		local pxat   = tonumber(ARGV[3])
-
-		-- We need first to retreive the old token by lookup for the userID
-		local oldTokenHash = redis.call("GET", ruKey)
-		-- If there is an old token, we need to delete it
-		if oldTokenHash then
-			local oldrtKey = "rth:" .. oldTokenHash
-			redis.call("DEL", oldrtKey)
-			-- No need to delete ruKey because we overwrite it below
+		local sessionID = ARGV[2]
+		local tokenHash = ARGV[3]
+		local pxat = tonumber(ARGV[7])
+
+		redis.call("HSET", rtsKey,
+			"user_id", userID,
+			"token_hash", tokenHash,
+			"user_agent", ARGV[4],
+			"ip", ARGV[5],
+			"created_at", ARGV[6],
+			"last_used_at", ARGV[6],
+			"expires_at", ARGV[7])
+		redis.call("PEXPIREAT", rtsKey, pxat)
+		redis.call("SET", rtKey, sessionID, "PXAT", pxat)
+		redis.call("SADD", rtuKey, sessionID)
+	` + extendSessionsLua + `
+		return 1
+	`
+
+	pxat := refreshTokenEntity.TTL.UnixMilli()
+	_, err := r.db.Eval(ctx, lua, []string{rtKey, rtsKey, rtuKey},
+		refreshTokenEntity.UserID,
+		refreshTokenEntity.SessionID,
+		refreshTokenEntity.RefreshTokenHash,
+		session.UserAgent,
+		session.IP,
+		session.CreatedAt.UnixMilli(),
+		pxat,
+	).Int64()
+	if err != nil {
+		// if there is a Redis error, we consider it as an internal server error
+		return err
+	}
+	return nil
+}
+
+func (r *repository) RotateOne(ctx context.Context, oldTokenHash string, refreshToken *RefreshToken, session *Session) error {
+	refreshTokenEntity := toEntity(refreshToken)
+
+	oldRtKey := "rth:" + oldTokenHash
+	rtKey := "rth:" + refreshTokenEntity.RefreshTokenHash
+	rtsKey := "rts:" + refreshTokenEntity.SessionID
+	rtrKey := "rtr:" + oldTokenHash
+	rtuKey := "rtu:" + refreshTokenEntity.UserID
+
+	lua := `
+		local oldRtKey = KEYS[1]
+		local rtKey = KEYS[2]
+		local rtsKey = KEYS[3]
+		local rtrKey = KEYS[4]
+		local sessionID = ARGV[1]
+		local oldTokenHash = ARGV[2]
+		local tokenHash = ARGV[3]
+		local pxat = tonumber(ARGV[7])
+
+		-- if the session has been revoked or its token has already been
+		-- rotated by a concurrent request, the old token is not valid anymore
+		if redis.call("HGET", rtsKey, "token_hash") ~= oldTokenHash then
+			return 0
 		end
 
-		-- Insert new values
-		redis.call("SET", rtKey, userID, "PXAT", pxat)
-		redis.call("SET", ruKey, tokenHash, "PXAT", pxat)
+		-- the old token is remembered until it would have expired, so that
+		-- presenting it again is recognized as a reuse of the session
+		local oldPxat = tonumber(redis.call("HGET", rtsKey, "expires_at"))
+		if oldPxat then
+			redis.call("SET", rtrKey, sessionID, "PXAT", oldPxat)
+		end
 
+		redis.call("DEL", oldRtKey)
+		redis.call("SET", rtKey, sessionID, "PXAT", pxat)
+		redis.call("HSET", rtsKey,
+			"token_hash", tokenHash,
+			"user_agent", ARGV[4],
+			"ip", ARGV[5],
+			"last_used_at", ARGV[6],
+			"expires_at", ARGV[7])
+		redis.call("PEXPIREAT", rtsKey, pxat)
+	` + extendSessionsLua + `
 		return 1
 	`
 
 	pxat := refreshTokenEntity.TTL.UnixMilli()
-	_, err := r.db.Eval(ctx, lua, []string{rtKey, rtuKey}, refreshTokenEntity.UserID, refreshTokenEntity.RefreshTokenHash, pxat).Int64()
+	rotated, err := r.db.Eval(ctx, lua, []string{oldRtKey, rtKey, rtsKey, rtrKey, rtuKey},
+		refreshTokenEntity.SessionID,
+		oldTokenHash,
+		refreshTokenEntity.RefreshTokenHash,
+		session.UserAgent,
+		session.IP,
+		session.LastUsedAt.UnixMilli(),
+		pxat,
+	).Int64()
 	if err != nil {
-		// if there is a Redis error, we consider it as an internal server error
 		return err
 	}
+	if rotated == 0 {
+		return ErrTokenHashNotFound
+	}
 	return nil
 }
 
-func (r *repository) GetOneUserIDByTokenHash(ctx context.Context, tokenHash string) (string, error) {
+func (r *repository) GetOneByTokenHash(ctx context.Context, tokenHash string) (*Session, error) {
 	rtKey := "rth:" + tokenHash
-	userID, err := r.db.Get(ctx, rtKey).Result()
+	sessionID, err := r.db.Get(ctx, rtKey).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return "", ErrTokenHashNotFound
+			return nil, ErrTokenHashNotFound
 		}
-		return "", err
+		return nil, err
 	}
-	return userID, nil
+
+	fields, err := r.db.HGetAll(ctx, "rts:"+sessionID).Result()
+	if err != nil {
+		return nil, err
+	}
+	// the lookup of a token issued before the sessions were introduced
+	// holds a userID, so no session is found and the user must login again
+	if len(fields) == 0 || fields["token_hash"] != tokenHash {
+		return nil, ErrTokenHashNotFound
+	}
+
+	return toSession(sessionID, fields), nil
 }
 
-func (r *repository) GetOneTokenHashByUserID(ctx context.Context, userID string) (string, error) {
-	rtuKey := "rtu:" + userID
-	tokenHash, err := r.db.Get(ctx, rtuKey).Result()
+// GetOneByRotatedTokenHash returns the session of a token that has already
+// been rotated, as long as the session itself has not been closed
+func (r *repository) GetOneByRotatedTokenHash(ctx context.Context, tokenHash string) (*Session, error) {
+	rtrKey := "rtr:" + tokenHash
+	sessionID, err := r.db.Get(ctx, rtrKey).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return "", ErrUserIDNotFound
+			return nil, ErrTokenHashNotFound
 		}
-		return "", err
+		return nil, err
 	}
-	return tokenHash, nil
+
+	fields, err := r.db.HGetAll(ctx, "rts:"+sessionID).Result()
+	if err != nil {
+		return nil, err
+	}
+	// once the session is revoked its old tokens are just invalid,
+	// otherwise a stolen token could be used to logout the user forever
+	if len(fields) == 0 {
+		return nil, ErrTokenHashNotFound
+	}
+
+	return toSession(sessionID, fields), nil
 }
 
-func (r *repository) DeleteOneByUserID(ctx context.Context, userID string) error {
-	// we need to remove two records, 
-	// the second is obtained using the value from the first
+func (r *repository) GetAllByUserID(ctx context.Context, userID string) ([]*Session, error) {
+	rtuKey := "rtu:" + userID
+	sessionIDs, err := r.db.SMembers(ctx, rtuKey).Result()
+	if err != nil {
+		// a login issued before the sessions were introduced is not listed
+		if isWrongType(err) {
+			return []*Session{}, nil
+		}
+		return nil, err
+	}
+
+	pipe := r.db.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		cmds = append(cmds, pipe.HGetAll(ctx, "rts:"+sessionID))
+	}
+	if len(cmds) > 0 {
+		_, err = pipe.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sessions := make([]*Session, 0, len(sessionIDs))
+	expired := make([]any, 0)
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			expired = append(expired, sessionIDs[i])
+			continue
+		}
+		sessions = append(sessions, toSession(sessionIDs[i], fields))
+	}
+
+	// the sessions expire on their own, their ids are removed from the set lazily
+	if len(expired) > 0 {
+		err = r.db.SRem(ctx, rtuKey, expired...).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the most recently used sessions come first
+	slices.SortFunc(sessions, func(a, b *Session) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+	return sessions, nil
+}
+
+func (r *repository) DeleteOneBySessionID(ctx context.Context, userID string, sessionID string) error {
+	rtsKey := "rts:" + sessionID
 	rtuKey := "rtu:" + userID
 
 	lua := `
-		local rtuKey = KEYS[1]
-		local tokenHash = redis.call("GET", rtuKey)
-	
-		-- If tokenHash exists than we generate rtKey and delete it
+		local rtsKey = KEYS[1]
+		local rtuKey = KEYS[2]
+		local userID = ARGV[1]
+		local sessionID = ARGV[2]
+
+		-- a user can only revoke its own sessions
+		local owner = redis.call("HGET", rtsKey, "user_id")
+		if owner ~= userID then
+			return 0
+		end
+
+		local tokenHash = redis.call("HGET", rtsKey, "token_hash")
 		if tokenHash then
-				local rtKey = "rth:" .. tokenHash
-				redis.call("DEL", rtKey)
+			redis.call("DEL", "rth:" .. tokenHash)
+		end
+		redis.call("DEL", rtsKey)
+		redis.call("SREM", rtuKey, sessionID)
+
+		return 1
+	`
+
+	deleted, err := r.db.Eval(ctx, lua, []string{rtsKey, rtuKey}, userID, sessionID).Int64()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (r *repository) DeleteAllByUserID(ctx context.Context, userID string) error {
+	// every session of the user is removed together with its token,
+	// the ids of the sessions are obtained from the set
+	rtuKey := "rtu:" + userID
+
+	lua := dropLegacyLua + `
+		local rtuKey = KEYS[1]
+		local sessionIDs = redis.call("SMEMBERS", rtuKey)
+
+		for _, sessionID in ipairs(sessionIDs) do
+			local rtsKey = "rts:" .. sessionID
+			local tokenHash = redis.call("HGET", rtsKey, "token_hash")
+			if tokenHash then
+				redis.call("DEL", "rth:" .. tokenHash)
+			end
+			redis.call("DEL", rtsKey)
 		end
 
 		-- Then we delete the rtuKey
@@ -118,11 +327,44 @@ func (r *repository) DeleteOneByUserID(ctx context.Context, userID string) error
 		return 1
 	`
 
-	_, err := r.db.Eval(ctx, lua, []string{rtuKey}, userID).Result()
+	_, err := r.db.Eval(ctx, lua, []string{rtuKey}).Result()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteOthersByUserID removes every session of the user but the one
+// to keep, so that the user stays logged in where the request came from
+func (r *repository) DeleteOthersByUserID(ctx context.Context, userID string, keepSessionID string) error {
+	rtuKey := "rtu:" + userID
+
+	lua := dropLegacyLua + `
+		local rtuKey = KEYS[1]
+		local keepSessionID = ARGV[1]
+		local sessionIDs = redis.call("SMEMBERS", rtuKey)
+
+		for _, sessionID in ipairs(sessionIDs) do
+			if sessionID ~= keepSessionID then
+				local rtsKey = "rts:" .. sessionID
+				local tokenHash = redis.call("HGET", rtsKey, "token_hash")
+				if tokenHash then
+					redis.call("DEL", "rth:" .. tokenHash)
+				end
+				redis.call("DEL", rtsKey)
+				redis.call("SREM", rtuKey, sessionID)
+			end
+		end
+
+		return 1
+	`
+
+	_, err := r.db.Eval(ctx, lua, []string{rtuKey}, keepSessionID).Result()
 	if err != nil {
 		return err
 	}
-	
+
 	return nil
 }
 
@@ -130,6 +372,32 @@ func toEntity(rt *RefreshToken) *refreshTokenEntity {
 	return &refreshTokenEntity{
 		RefreshTokenHash: rt.RefreshTokenHash,
 		UserID:           rt.UserID,
+		SessionID:        rt.SessionID,
 		TTL:              rt.TTL,
 	}
-}
\ No newline at end of file
+}
+
+func toSession(sessionID string, fields map[string]string) *Session {
+	return &Session{
+		ID:         sessionID,
+		UserID:     fields["user_id"],
+		TokenHash:  fields["token_hash"],
+		UserAgent:  fields["user_agent"],
+		IP:         fields["ip"],
+		CreatedAt:  parseMillis(fields["created_at"]),
+		LastUsedAt: parseMillis(fields["last_used_at"]),
+		ExpiresAt:  parseMillis(fields["expires_at"]),
+	}
+}
+
+func parseMillis(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func isWrongType(err error) bool {
+	return strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
@@ -21,6 +21,25 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// createSession stores a session with its refresh token as a login would do
+func createSession(ctx context.Context, repo *repository, userID string, sessionID string, tokenHash string) error {
+	now := time.Now()
+	return repo.CreateOne(ctx,
+		&RefreshToken{
+			RefreshTokenHash: tokenHash,
+			UserID:           userID,
+			SessionID:        sessionID,
+			TTL:              now.Add(time.Hour),
+		},
+		&Session{
+			UserAgent:  "test-agent",
+			IP:         "192.0.2.1",
+			CreatedAt:  now,
+			LastUsedAt: now,
+		},
+	)
+}
+
 func TestRepository_CreateOne(t *testing.T) {
 	ctx := context.Background()
 	repo := NewRefreshTokenRepository(testRedisDB)
@@ -38,38 +57,78 @@ func TestRepository_CreateOne(t *testing.T) {
 				RefreshToken:     "hash_success",
 				RefreshTokenHash: "hash_success",
 				UserID:           "user_success",
+				SessionID:        "session_success",
 				TTL:              time.Now().Add(1 * time.Hour),
 			},
 			verifyFunc: func(ctx context.Context, rdb *redis.Client, rt RefreshToken) error {
-				// Verify lookup hash -> userID
-				userID, err := rdb.Get(ctx, "rth:"+rt.RefreshTokenHash).Result()
+				// Verify lookup hash -> sessionID
+				sessionID, err := rdb.Get(ctx, "rth:"+rt.RefreshTokenHash).Result()
 				if err != nil {
 					return fmt.Errorf("lookup by hash failed: %w", err)
 				}
-				if userID != rt.UserID {
-					return fmt.Errorf("expected userID %s, got %s", rt.UserID, userID)
+				if sessionID != rt.SessionID {
+					return fmt.Errorf("expected sessionID %s, got %s", rt.SessionID, sessionID)
 				}
 
-				// Verify lookup userID -> hash
-				hash, err := rdb.Get(ctx, "rtu:"+rt.UserID).Result()
+				// Verify the session metadata
+				fields, err := rdb.HGetAll(ctx, "rts:"+rt.SessionID).Result()
+				if err != nil {
+					return fmt.Errorf("lookup of the session failed: %w", err)
+				}
+				if fields["user_id"] != rt.UserID || fields["token_hash"] != rt.RefreshTokenHash || fields["user_agent"] != "test-agent" {
+					return fmt.Errorf("unexpected session fields %v", fields)
+				}
+
+				// Verify the session is in the set of the user
+				isMember, err := rdb.SIsMember(ctx, "rtu:"+rt.UserID, rt.SessionID).Result()
 				if err != nil {
 					return fmt.Errorf("lookup by userID failed: %w", err)
 				}
-				if hash != rt.RefreshTokenHash {
-					return fmt.Errorf("expected hash %s, got %s", rt.RefreshTokenHash, hash)
+				if !isMember {
+					return fmt.Errorf("expected session %s in the set of the user", rt.SessionID)
+				}
+				return nil
+			},
+			wantErr: false,
+		},
+		{
+			name: "new_login_keeps_other_sessions",
+			setupFunc: func(ctx context.Context, rdb *redis.Client) error {
+				return createSession(ctx, repo, "user_multi", "session_phone", "hash_phone")
+			},
+			refreshToken: RefreshToken{
+				RefreshToken:     "hash_laptop",
+				RefreshTokenHash: "hash_laptop",
+				UserID:           "user_multi",
+				SessionID:        "session_laptop",
+				TTL:              time.Now().Add(1 * time.Hour),
+			},
+			verifyFunc: func(ctx context.Context, rdb *redis.Client, rt RefreshToken) error {
+				members, err := rdb.SMembers(ctx, "rtu:"+rt.UserID).Result()
+				if err != nil {
+					return fmt.Errorf("failed to get the sessions: %w", err)
+				}
+				if len(members) != 2 {
+					return fmt.Errorf("expected 2 sessions, got %v", members)
+				}
+
+				// The token of the other session is still valid
+				_, err = rdb.Get(ctx, "rth:hash_phone").Result()
+				if err != nil {
+					return fmt.Errorf("expected the other token to be kept, got: %v", err)
 				}
 				return nil
 			},
 			wantErr: false,
 		},
 		{
-			name: "rotate_token_removes_old_one",
+			name: "legacy_token_is_dropped",
 			setupFunc: func(ctx context.Context, rdb *redis.Client) error {
-				// Pre-populate an old token for this user
-				if err := rdb.Set(ctx, "rtu:user_rotate", "old_hash", time.Hour).Err(); err != nil {
+				// Pre-populate a token stored before the sessions were introduced
+				if err := rdb.Set(ctx, "rtu:user_legacy", "old_hash", time.Hour).Err(); err != nil {
 					return err
 				}
-				if err := rdb.Set(ctx, "rth:old_hash", "user_rotate", time.Hour).Err(); err != nil {
+				if err := rdb.Set(ctx, "rth:old_hash", "user_legacy", time.Hour).Err(); err != nil {
 					return err
 				}
 				return nil
@@ -77,17 +136,17 @@ func TestRepository_CreateOne(t *testing.T) {
 			refreshToken: RefreshToken{
 				RefreshToken:     "new_hash",
 				RefreshTokenHash: "new_hash",
-				UserID:           "user_rotate",
+				UserID:           "user_legacy",
+				SessionID:        "session_new",
 				TTL:              time.Now().Add(1 * time.Hour),
 			},
 			verifyFunc: func(ctx context.Context, rdb *redis.Client, rt RefreshToken) error {
-				// New token should exist
-				newHash, err := rdb.Get(ctx, "rtu:"+rt.UserID).Result()
+				isMember, err := rdb.SIsMember(ctx, "rtu:"+rt.UserID, rt.SessionID).Result()
 				if err != nil {
-					return fmt.Errorf("failed to get new token: %w", err)
+					return fmt.Errorf("failed to get new session: %w", err)
 				}
-				if newHash != rt.RefreshTokenHash {
-					return fmt.Errorf("expected new hash %s, got %s", rt.RefreshTokenHash, newHash)
+				if !isMember {
+					return fmt.Errorf("expected session %s in the set of the user", rt.SessionID)
 				}
 
 				// Old token should be gone
@@ -112,7 +171,13 @@ func TestRepository_CreateOne(t *testing.T) {
 				}
 			}
 
-			err := repo.CreateOne(ctx, &tt.refreshToken)
+			now := time.Now()
+			err := repo.CreateOne(ctx, &tt.refreshToken, &Session{
+				UserAgent:  "test-agent",
+				IP:         "192.0.2.1",
+				CreatedAt:  now,
+				LastUsedAt: now,
+			})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateOne() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -126,7 +191,88 @@ func TestRepository_CreateOne(t *testing.T) {
 	}
 }
 
-func TestRepository_GetOneUserIDByTokenHash(t *testing.T) {
+func TestRepository_RotateOne(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewRefreshTokenRepository(testRedisDB)
+
+	tests := []struct {
+		name         string
+		oldTokenHash string
+		setupFunc    func() error
+		wantErr      error
+	}{
+		{
+			name:         "rotate_success",
+			oldTokenHash: "hash_old",
+			setupFunc: func() error {
+				return createSession(ctx, repo, "user_rotate", "session_rotate", "hash_old")
+			},
+			wantErr: nil,
+		},
+		{
+			name:         "already_rotated",
+			oldTokenHash: "hash_stale",
+			setupFunc: func() error {
+				return createSession(ctx, repo, "user_rotate", "session_rotate", "hash_old")
+			},
+			wantErr: ErrTokenHashNotFound,
+		},
+		{
+			name:         "session_revoked",
+			oldTokenHash: "hash_old",
+			setupFunc:    func() error { return nil },
+			wantErr:      ErrTokenHashNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+			if err := tt.setupFunc(); err != nil {
+				t.Fatalf("setupFunc failed: %v", err)
+			}
+
+			now := time.Now()
+			err := repo.RotateOne(ctx, tt.oldTokenHash,
+				&RefreshToken{
+					RefreshTokenHash: "hash_new",
+					UserID:           "user_rotate",
+					SessionID:        "session_rotate",
+					TTL:              now.Add(2 * time.Hour),
+				},
+				&Session{UserAgent: "new-agent", IP: "192.0.2.2", LastUsedAt: now},
+			)
+			if err != tt.wantErr {
+				t.Fatalf("RotateOne() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			// the old token is not valid anymore
+			_, err = repo.GetOneByTokenHash(ctx, "hash_old")
+			if err != ErrTokenHashNotFound {
+				t.Errorf("expected the old token to be deleted, got %v", err)
+			}
+
+			session, err := repo.GetOneByTokenHash(ctx, "hash_new")
+			if err != nil {
+				t.Fatalf("GetOneByTokenHash() error = %v", err)
+			}
+			if session.ID != "session_rotate" || session.UserAgent != "new-agent" || session.IP != "192.0.2.2" {
+				t.Errorf("unexpected session after rotation %+v", session)
+			}
+			if session.CreatedAt.After(session.LastUsedAt) {
+				t.Errorf("expected the creation time to be kept, got %+v", session)
+			}
+		})
+	}
+}
+
+func TestRepository_GetOneByTokenHash(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
@@ -144,7 +290,7 @@ func TestRepository_GetOneUserIDByTokenHash(t *testing.T) {
 			name:      "found",
 			tokenHash: "hash_found",
 			setupFunc: func() {
-				testRedisDB.Set(ctx, "rth:hash_found", "user_found", time.Hour)
+				createSession(ctx, repo, "user_found", "session_found", "hash_found")
 			},
 			want:    "user_found",
 			wantErr: nil,
@@ -156,6 +302,15 @@ func TestRepository_GetOneUserIDByTokenHash(t *testing.T) {
 			want:      "",
 			wantErr:   ErrTokenHashNotFound,
 		},
+		{
+			name:      "legacy_token",
+			tokenHash: "hash_legacy",
+			setupFunc: func() {
+				testRedisDB.Set(ctx, "rth:hash_legacy", "user_legacy", time.Hour)
+			},
+			want:    "",
+			wantErr: ErrTokenHashNotFound,
+		},
 	}
 
 	for _, tt := range tests {
@@ -163,47 +318,227 @@ func TestRepository_GetOneUserIDByTokenHash(t *testing.T) {
 			testRedisDB.FlushDB(ctx)
 			tt.setupFunc()
 
-			got, err := repo.GetOneUserIDByTokenHash(ctx, tt.tokenHash)
+			got, err := repo.GetOneByTokenHash(ctx, tt.tokenHash)
 			if err != tt.wantErr {
-				t.Errorf("GetOneUserIDByTokenHash() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("GetOneByTokenHash() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if got != tt.want {
-				t.Errorf("GetOneUserIDByTokenHash() = %v, want %v", got, tt.want)
+			if got != nil && got.UserID != tt.want {
+				t.Errorf("GetOneByTokenHash() = %v, want %v", got.UserID, tt.want)
 			}
 		})
 	}
 }
 
-func TestRepository_GetOneTokenHashByUserID(t *testing.T) {
+func TestRepository_GetOneByRotatedTokenHash(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
 	ctx := context.Background()
 	repo := NewRefreshTokenRepository(testRedisDB)
 
+	// rotateSession replaces the token hash_old of the session with hash_new
+	rotateSession := func() error {
+		if err := createSession(ctx, repo, "user_reuse", "session_reuse", "hash_old"); err != nil {
+			return err
+		}
+		return repo.RotateOne(ctx, "hash_old",
+			&RefreshToken{
+				RefreshTokenHash: "hash_new",
+				UserID:           "user_reuse",
+				SessionID:        "session_reuse",
+				TTL:              time.Now().Add(time.Hour),
+			},
+			&Session{LastUsedAt: time.Now()},
+		)
+	}
+
 	tests := []struct {
 		name      string
-		userID    string
-		setupFunc func()
+		tokenHash string
+		setupFunc func() error
 		want      string
 		wantErr   error
 	}{
 		{
-			name:   "found",
-			userID: "user_found",
+			name:      "rotated_token",
+			tokenHash: "hash_old",
+			setupFunc: rotateSession,
+			want:      "session_reuse",
+			wantErr:   nil,
+		},
+		{
+			name:      "current_token",
+			tokenHash: "hash_new",
+			setupFunc: rotateSession,
+			wantErr:   ErrTokenHashNotFound,
+		},
+		{
+			name:      "session_already_revoked",
+			tokenHash: "hash_old",
+			setupFunc: func() error {
+				if err := rotateSession(); err != nil {
+					return err
+				}
+				return repo.DeleteAllByUserID(ctx, "user_reuse")
+			},
+			wantErr: ErrTokenHashNotFound,
+		},
+		{
+			name:      "unknown_token",
+			tokenHash: "hash_missing",
+			setupFunc: func() error { return nil },
+			wantErr:   ErrTokenHashNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+			if err := tt.setupFunc(); err != nil {
+				t.Fatalf("setupFunc failed: %v", err)
+			}
+
+			got, err := repo.GetOneByRotatedTokenHash(ctx, tt.tokenHash)
+			if err != tt.wantErr {
+				t.Fatalf("GetOneByRotatedTokenHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != nil && (got.ID != tt.want || got.UserID != "user_reuse") {
+				t.Errorf("GetOneByRotatedTokenHash() = %+v, want session %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_GetAllByUserID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewRefreshTokenRepository(testRedisDB)
+
+	tests := []struct {
+		name      string
+		userID    string
+		setupFunc func()
+		wantIDs   []string
+	}{
+		{
+			name:   "most_recent_first",
+			userID: "user_list",
+			setupFunc: func() {
+				createSession(ctx, repo, "user_list", "session_old", "hash_old")
+				time.Sleep(5 * time.Millisecond)
+				createSession(ctx, repo, "user_list", "session_new", "hash_new")
+				createSession(ctx, repo, "user_other", "session_other", "hash_other")
+			},
+			wantIDs: []string{"session_new", "session_old"},
+		},
+		{
+			name:   "expired_sessions_are_skipped",
+			userID: "user_list",
+			setupFunc: func() {
+				createSession(ctx, repo, "user_list", "session_alive", "hash_alive")
+				testRedisDB.SAdd(ctx, "rtu:user_list", "session_expired")
+			},
+			wantIDs: []string{"session_alive"},
+		},
+		{
+			name:   "legacy_token",
+			userID: "user_legacy",
+			setupFunc: func() {
+				testRedisDB.Set(ctx, "rtu:user_legacy", "hash_legacy", time.Hour)
+			},
+			wantIDs: []string{},
+		},
+		{
+			name:      "no_sessions",
+			userID:    "user_ghost",
+			setupFunc: func() {},
+			wantIDs:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedisDB.FlushDB(ctx)
+			tt.setupFunc()
+
+			got, err := repo.GetAllByUserID(ctx, tt.userID)
+			if err != nil {
+				t.Fatalf("GetAllByUserID() error = %v", err)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("GetAllByUserID() returned %d sessions, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, session := range got {
+				if session.ID != tt.wantIDs[i] {
+					t.Errorf("session %d = %s, want %s", i, session.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRepository_DeleteOneBySessionID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewRefreshTokenRepository(testRedisDB)
+
+	tests := []struct {
+		name       string
+		userID     string
+		sessionID  string
+		setupFunc  func()
+		verifyFunc func() error
+		wantErr    error
+	}{
+		{
+			name:      "delete_success",
+			userID:    "user_delete",
+			sessionID: "session_delete",
 			setupFunc: func() {
-				testRedisDB.Set(ctx, "rtu:user_found", "hash_found", time.Hour)
+				createSession(ctx, repo, "user_delete", "session_delete", "hash_delete")
+				createSession(ctx, repo, "user_delete", "session_keep", "hash_keep")
+			},
+			verifyFunc: func() error {
+				_, err := testRedisDB.Get(ctx, "rth:hash_delete").Result()
+				if err != redis.Nil {
+					return fmt.Errorf("expected rth key to be deleted, got %v", err)
+				}
+				// the other session of the user is not affected
+				_, err = testRedisDB.Get(ctx, "rth:hash_keep").Result()
+				if err != nil {
+					return fmt.Errorf("expected the other session to be kept, got %v", err)
+				}
+				return nil
 			},
-			want:    "hash_found",
 			wantErr: nil,
 		},
 		{
-			name:      "not_found",
-			userID:    "user_missing",
+			name:      "session_of_another_user",
+			userID:    "user_thief",
+			sessionID: "session_victim",
+			setupFunc: func() {
+				createSession(ctx, repo, "user_victim", "session_victim", "hash_victim")
+			},
+			verifyFunc: func() error {
+				_, err := testRedisDB.Get(ctx, "rth:hash_victim").Result()
+				if err != nil {
+					return fmt.Errorf("expected the session to be kept, got %v", err)
+				}
+				return nil
+			},
+			wantErr: ErrSessionNotFound,
+		},
+		{
+			name:      "delete_non_existent",
+			userID:    "user_ghost",
+			sessionID: "session_ghost",
 			setupFunc: func() {},
-			want:      "",
-			wantErr:   ErrUserIDNotFound,
+			wantErr:   ErrSessionNotFound,
 		},
 	}
 
@@ -212,19 +547,21 @@ func TestRepository_GetOneTokenHashByUserID(t *testing.T) {
 			testRedisDB.FlushDB(ctx)
 			tt.setupFunc()
 
-			got, err := repo.GetOneTokenHashByUserID(ctx, tt.userID)
+			err := repo.DeleteOneBySessionID(ctx, tt.userID, tt.sessionID)
 			if err != tt.wantErr {
-				t.Errorf("GetOneTokenHashByUserID() error = %v, wantErr %v", err, tt.wantErr)
-				return
+				t.Errorf("DeleteOneBySessionID() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if got != tt.want {
-				t.Errorf("GetOneTokenHashByUserID() = %v, want %v", got, tt.want)
+
+			if tt.verifyFunc != nil {
+				if err := tt.verifyFunc(); err != nil {
+					t.Errorf("verification failed: %v", err)
+				}
 			}
 		})
 	}
 }
 
-func TestRepository_DeleteOneByUserID(t *testing.T) {
+func TestRepository_DeleteAllByUserID(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
 	}
@@ -241,18 +578,31 @@ func TestRepository_DeleteOneByUserID(t *testing.T) {
 			name:   "delete_success",
 			userID: "user_delete",
 			setupFunc: func() {
-				// Setup a full valid token pair
-				testRedisDB.Set(ctx, "rtu:user_delete", "hash_delete", time.Hour)
-				testRedisDB.Set(ctx, "rth:hash_delete", "user_delete", time.Hour)
+				createSession(ctx, repo, "user_delete", "session_1", "hash_1")
+				createSession(ctx, repo, "user_delete", "session_2", "hash_2")
 			},
 			verifyFunc: func() error {
-				// Verify user mapping is gone
-				_, err := testRedisDB.Get(ctx, "rtu:user_delete").Result()
-				if err != redis.Nil {
-					return fmt.Errorf("expected rtu key to be deleted, got %v", err)
+				for _, key := range []string{"rtu:user_delete", "rts:session_1", "rts:session_2", "rth:hash_1", "rth:hash_2"} {
+					exists, err := testRedisDB.Exists(ctx, key).Result()
+					if err != nil {
+						return err
+					}
+					if exists != 0 {
+						return fmt.Errorf("expected %s to be deleted", key)
+					}
 				}
-				// Verify hash mapping is gone
-				_, err = testRedisDB.Get(ctx, "rth:hash_delete").Result()
+				return nil
+			},
+		},
+		{
+			name:   "delete_legacy_token",
+			userID: "user_legacy",
+			setupFunc: func() {
+				testRedisDB.Set(ctx, "rtu:user_legacy", "hash_legacy", time.Hour)
+				testRedisDB.Set(ctx, "rth:hash_legacy", "user_legacy", time.Hour)
+			},
+			verifyFunc: func() error {
+				_, err := testRedisDB.Get(ctx, "rth:hash_legacy").Result()
 				if err != redis.Nil {
 					return fmt.Errorf("expected rth key to be deleted, got %v", err)
 				}
@@ -274,8 +624,8 @@ func TestRepository_DeleteOneByUserID(t *testing.T) {
 			testRedisDB.FlushDB(ctx)
 			tt.setupFunc()
 
-			if err := repo.DeleteOneByUserID(ctx, tt.userID); err != nil {
-				t.Errorf("DeleteOneByUserID() error = %v", err)
+			if err := repo.DeleteAllByUserID(ctx, tt.userID); err != nil {
+				t.Errorf("DeleteAllByUserID() error = %v", err)
 			}
 
 			if tt.verifyFunc != nil {
@@ -286,3 +636,49 @@ func TestRepository_DeleteOneByUserID(t *testing.T) {
 		})
 	}
 }
+
+func TestRepository_DeleteOthersByUserID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewRefreshTokenRepository(testRedisDB)
+
+	testRedisDB.FlushDB(ctx)
+	createSession(ctx, repo, "user_others", "session_keep", "hash_keep")
+	createSession(ctx, repo, "user_others", "session_1", "hash_1")
+	createSession(ctx, repo, "user_others", "session_2", "hash_2")
+	// the sessions of the other users are not touched
+	createSession(ctx, repo, "user_bystander", "session_3", "hash_3")
+
+	if err := repo.DeleteOthersByUserID(ctx, "user_others", "session_keep"); err != nil {
+		t.Fatalf("DeleteOthersByUserID() error = %v", err)
+	}
+
+	for _, key := range []string{"rts:session_1", "rts:session_2", "rth:hash_1", "rth:hash_2"} {
+		exists, err := testRedisDB.Exists(ctx, key).Result()
+		if err != nil {
+			t.Fatalf("Exists() error = %v", err)
+		}
+		if exists != 0 {
+			t.Errorf("expected %s to be deleted", key)
+		}
+	}
+	for _, key := range []string{"rts:session_keep", "rth:hash_keep", "rts:session_3", "rth:hash_3"} {
+		exists, err := testRedisDB.Exists(ctx, key).Result()
+		if err != nil {
+			t.Fatalf("Exists() error = %v", err)
+		}
+		if exists != 1 {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+
+	sessions, err := repo.GetAllByUserID(ctx, "user_others")
+	if err != nil {
+		t.Fatalf("GetAllByUserID() error = %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "session_keep" {
+		t.Errorf("GetAllByUserID() = %+v, want only session_keep", sessions)
+	}
+}
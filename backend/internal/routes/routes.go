@@ -2,30 +2,129 @@ package routes
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/auth"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/health"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/live"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/middleware"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(authController *auth.Controller) *gin.Engine {
+// the limits of the unauthenticated endpoints, bcrypt makes each login
+// expensive so they are kept low enough to not exhaust the CPU
+var (
+	loginRateLimit = middleware.RateLimitRule{
+		Name:          "login",
+		Limit:         10,
+		Window:        time.Minute,
+		AccountFields: []string{"email", "username"},
+	}
+	registerRateLimit = middleware.RateLimitRule{
+		Name:          "register",
+		Limit:         5,
+		Window:        time.Hour,
+		AccountFields: []string{"email"},
+	}
+	// every request sends an email, on top of the cooldown of each address
+	// the budget keeps a single client from mailing many addresses
+	forgotPasswordRateLimit = middleware.RateLimitRule{
+		Name:          "forgot_password",
+		Limit:         5,
+		Window:        time.Hour,
+		AccountFields: []string{"email"},
+	}
+	refreshRateLimit = middleware.RateLimitRule{
+		Name:   "refresh",
+		Limit:  30,
+		Window: time.Minute,
+	}
+)
+
+func SetupRoutes(authController *auth.Controller, mfaController *mfa.Controller, signingKeyController *signing_key.Controller, deviceController *device.Controller, householdController *household.Controller, targetController *target.Controller, telemetryController *telemetry.Controller, commandController *command.Controller, liveController *live.Controller, healthController *health.Controller, tokenKeys middleware.TokenKeys, tokenDenylist middleware.TokenDenylist, rateLimiter middleware.RateLimiter, dependencies middleware.Dependencies, metricsToken string) *gin.Engine {
 	// create a new gin router
 	router := gin.New()
+	// gin trusts the X-Forwarded-For of any client by default, which would let
+	// anybody pick the IP of the rate limits and of the sessions, the proxies
+	// in front of the server are added by the bootstrap
+	router.SetTrustedProxies(nil)
 	router.Use(gin.Logger())
+	// before the recovery, so that the panics are counted as 500
+	router.Use(middleware.MetricsMiddleware())
 	router.Use(gin.Recovery())
 
+	// the liveness and the readiness of the server, for the orchestrator and the load balancer
+	router.GET("/healthz", healthController.Healthz)
+	router.GET("/readyz", healthController.Readyz)
+	// the metrics for Prometheus, like the health checks they are not under /api,
+	// they are served only when a token is configured since the port is public
+	if metricsToken != "" {
+		router.GET("/metrics", middleware.MetricsAuthMiddleware(metricsToken), gin.WrapH(metrics.Handler()))
+	}
+
+	// the sessions, the rate limits and the commands are in Redis, while it is
+	// down these endpoints answer 503 at once instead of waiting for its timeouts
+	requireRedis := middleware.DependencyMiddleware(dependencies, health.Redis)
+
+	// the public keys that verify the access tokens, for the other services
+	router.GET("/.well-known/jwks.json", signingKeyController.GetJWKS)
+
 	// the main group is /api
 	api := router.Group("/api")
 	{
-		api.POST("/register", authController.Register)
-		api.POST("/login/email", authController.LoginByEmail)
-		api.POST("/login/username", authController.LoginByUsername)
-		api.POST("/refresh", authController.RefreshToken)
+		api.POST("/register", requireRedis, middleware.RateLimitMiddleware(rateLimiter, registerRateLimit), authController.Register)
+		api.POST("/refresh", requireRedis, middleware.RateLimitMiddleware(rateLimiter, refreshRateLimit), authController.RefreshToken)
+
+		// the login endpoints share the same budget, so that
+		// an attacker cannot alternate between them
+		login := api.Group("/login")
+		login.Use(requireRedis, middleware.RateLimitMiddleware(rateLimiter, loginRateLimit))
+		{
+			login.POST("/email", authController.LoginByEmail)
+			login.POST("/username", authController.LoginByUsername)
+			// the second step of the login for the users with 2FA enabled
+			login.POST("/mfa", authController.LoginMFA)
+		}
+
+		api.POST("/password/forgot", requireRedis, middleware.RateLimitMiddleware(rateLimiter, forgotPasswordRateLimit), authController.ForgotPassword)
+		api.POST("/password/reset", requireRedis, authController.ResetPassword)
+		api.GET("/verify-email", requireRedis, authController.VerifyEmail)
+		api.POST("/verify-email/resend", requireRedis, authController.ResendVerification)
+
+		// the boards have no user session, they prove themselves
+		// with the pairing code first and with their own secret afterwards,
+		// the token needs Postgres only so the boards keep reporting while Redis is down
+		api.POST("/devices/claim", requireRedis, deviceController.ClaimDevice)
+		api.POST("/devices/token", deviceController.CreateDeviceToken)
 
 		// the auth group is for authenticated users only
 		auth := api.Group("/")
-		auth.Use(middleware.AuthMiddleware())
+		auth.Use(requireRedis, middleware.AuthMiddleware(tokenKeys, tokenDenylist))
 		{
+			auth.POST("/logout", authController.Logout)
+			auth.GET("/sessions", authController.GetSessions)
+			auth.DELETE("/sessions/:id", authController.DeleteSession)
+
+			// the account is outside of the scoped group, a user that
+			// has not verified its email must be able to fix it
+			auth.GET("/me", authController.GetProfile)
+			auth.PATCH("/me", authController.UpdateProfile)
+			auth.POST("/me/password", authController.ChangePassword)
+			auth.DELETE("/me", authController.DeleteAccount)
+
+			// the TOTP is enabled only once the first code has been verified
+			auth.POST("/mfa/totp/enroll", mfaController.Enroll)
+			auth.POST("/mfa/totp/activate", mfaController.Activate)
+			auth.POST("/mfa/totp/disable", mfaController.Disable)
+
 			// endpoint to check if the user is authenticated
 			auth.GET("/ping", func(c *gin.Context) {
         userID := c.GetString("userID")
@@ -33,6 +132,66 @@ func SetupRoutes(authController *auth.Controller) *gin.Engine {
             "message": "Hello, user " + userID,
         })
 			})
+
+			// a user that has not verified its email can only read,
+			// when UNVERIFIED_EMAIL_POLICY is read_only
+			scoped := auth.Group("/")
+			scoped.Use(middleware.WriteAccessMiddleware())
+			{
+				// the devices of the user and the ones shared with its households
+				scoped.POST("/devices", deviceController.CreateDevice)
+				scoped.GET("/devices", deviceController.GetDevices)
+				scoped.PATCH("/devices/:id", deviceController.RenameDevice)
+				scoped.DELETE("/devices/:id", deviceController.DeleteDevice)
+				scoped.POST("/devices/pairing-codes", deviceController.CreatePairingCode)
+				scoped.PUT("/devices/:id/household", deviceController.ShareDevice)
+				scoped.DELETE("/devices/:id/household", deviceController.UnshareDevice)
+
+				// the households, what a member can do depends on its role
+				scoped.POST("/households", householdController.CreateHousehold)
+				scoped.GET("/households", householdController.GetHouseholds)
+				scoped.GET("/households/:id", householdController.GetHousehold)
+				scoped.PATCH("/households/:id", householdController.RenameHousehold)
+				scoped.DELETE("/households/:id", householdController.DeleteHousehold)
+				scoped.GET("/households/:id/members", householdController.GetMembers)
+				scoped.PATCH("/households/:id/members/:userID", householdController.UpdateMember)
+				scoped.DELETE("/households/:id/members/:userID", householdController.RemoveMember)
+				scoped.POST("/households/:id/owner", householdController.TransferOwnership)
+				scoped.POST("/households/:id/invitations", householdController.Invite)
+				scoped.GET("/households/:id/invitations", householdController.GetHouseholdInvitations)
+				scoped.DELETE("/households/:id/invitations/:invitationID", householdController.RevokeInvitation)
+
+				// the invitations received by the user
+				scoped.GET("/invitations", householdController.GetInvitations)
+				scoped.POST("/invitations/:id/accept", householdController.AcceptInvitation)
+				scoped.POST("/invitations/:id/decline", householdController.DeclineInvitation)
+
+				// the desired brightness of the room where the device is
+				scoped.PUT("/devices/:id/target", targetController.SetTarget)
+				scoped.GET("/devices/:id/target", targetController.GetTarget)
+
+				// the sensor history, downsampled for the charts of the app
+				scoped.GET("/devices/:id/readings", telemetryController.GetReadings)
+
+				// the commands are delivered when the device polls for them
+				scoped.POST("/devices/:id/commands", commandController.SendCommand)
+
+				// a WebSocket with the readings, the target changes and the controller output
+				scoped.GET("/devices/:id/live", liveController.Live)
+			}
+		}
+
+		// the device group is for the boards only, user tokens are rejected
+		deviceAuth := api.Group("/")
+		deviceAuth.Use(middleware.DeviceAuthMiddleware(tokenKeys))
+		{
+			deviceAuth.GET("/devices/me", deviceController.GetCurrentDevice)
+			deviceAuth.POST("/telemetry", telemetryController.UploadTelemetry)
+
+			// the boards are behind NAT, so they long-poll for their commands
+			// and acknowledge them once executed
+			deviceAuth.GET("/devices/me/commands", requireRedis, commandController.GetCommands)
+			deviceAuth.POST("/devices/me/commands/ack", requireRedis, commandController.AckCommands)
 		}
 	}
 
@@ -3,7 +3,10 @@ package routes
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -12,7 +15,25 @@ import (
 	"time"
 
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/auth"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/command"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/control"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/email_verification_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/health"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/household"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/live"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mfa_ticket"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/migrations"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/mailer"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/pairing_code"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/password_reset_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/rate_limit"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/refresh_token"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/token_denylist"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/user"
 	"github.com/gin-gonic/gin"
@@ -26,6 +47,9 @@ func init() { gin.SetMode(gin.TestMode) }
 var testPostgresDB *sql.DB
 var testRedisDB *redis.Client
 
+// the token Prometheus sends to read the metrics
+const testMetricsToken = "0123456789abcdef0123456789abcdef"
+
 func TestMain(m *testing.M) {
 	pgConnectionStr := testutils.SetupPostgres()
 	testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
@@ -42,22 +66,56 @@ func TestIntegrationRoutes(t *testing.T) {
 
 	ctx := context.Background()
 
-	// Setup env vars for JWT
-	// Note: We must set this before SetupRoutes is called, because AuthMiddleware reads 
-	// the environment variable during initialization.
-	os.Setenv("JWT_SECRET", "supersecret")
-	os.Setenv("APPLICATION_NAME", "TestApp")
+	// the tokens are signed with the keys stored in the database, as in production
+	signingKeyService := signing_key.NewSigningKeyService(signing_key.NewSigningKeyRepository(testPostgresDB), signing_key.DefaultConfig())
+	if err := signingKeyService.Refresh(ctx); err != nil {
+		t.Fatalf("failed to load the signing keys: %v", err)
+	}
 
 	// Initialize real repositories and services
 	userRepo := user.NewUserRepository(testPostgresDB)
 	rtRepo := refresh_token.NewRefreshTokenRepository(testRedisDB)
-	authService := auth.NewAuthService(userRepo, rtRepo)
+	tokenDenylistRepo := token_denylist.NewTokenDenylistRepository(testRedisDB)
+	passwordResetTokenRepo := password_reset_token.NewPasswordResetTokenRepository(testRedisDB)
+	emailVerificationTokenRepo := email_verification_token.NewEmailVerificationTokenRepository(testRedisDB)
+	mfaService := mfa.NewMFAService(mfa.NewMFARepository(testPostgresDB), userRepo, "TestApp")
+	mfaTicketRepo := mfa_ticket.NewMFATicketRepository(testRedisDB)
+	rateLimitRepo := rate_limit.NewRateLimitRepository(testRedisDB)
+	authService := auth.NewAuthService(userRepo, rtRepo, tokenDenylistRepo, passwordResetTokenRepo, emailVerificationTokenRepo, mfaTicketRepo, mfaService, rateLimitRepo, signingKeyService, mailer.NewFileMailer(t.TempDir(), "noreply@autolight.local"), auth.Config{Issuer: "TestApp"})
 	authController := auth.NewAuthController(authService)
+	mfaController := mfa.NewMFAController(mfaService, authService)
+	signingKeyController := signing_key.NewSigningKeyController(signingKeyService)
+	deviceRepo := device.NewDeviceRepository(testPostgresDB)
+	pairingCodeRepo := pairing_code.NewPairingCodeRepository(testRedisDB)
+	householdRepo := household.NewHouseholdRepository(testPostgresDB)
+	householdService := household.NewHouseholdService(householdRepo, userRepo)
+	householdController := household.NewHouseholdController(householdService)
+	deviceService := device.NewDeviceService(deviceRepo, pairingCodeRepo, householdRepo, signingKeyService, "TestApp")
+	deviceController := device.NewDeviceController(deviceService)
+	liveRepo := live.NewLiveRepository(testRedisDB)
+	liveService := live.NewLiveService(liveRepo, deviceService, tokenDenylistRepo)
+	liveController := live.NewLiveController(liveService)
+	targetRepo := target.NewTargetRepository(testPostgresDB)
+	targetService := target.NewTargetService(targetRepo, deviceService, liveService)
+	targetController := target.NewTargetController(targetService)
+	telemetryRepo := telemetry.NewTelemetryRepository(testPostgresDB)
+	controlService := control.NewControlService(targetRepo, control.DefaultConfig())
+	telemetryService := telemetry.NewTelemetryService(telemetryRepo, deviceService, controlService, liveService)
+	telemetryController := telemetry.NewTelemetryController(telemetryService)
+	commandRepo := command.NewCommandRepository(testRedisDB, testRedisDB)
+	commandService := command.NewCommandService(commandRepo, deviceService, nil)
+	commandController := command.NewCommandController(commandService)
+	migrator, err := migrations.NewMigrator(testPostgresDB)
+	if err != nil {
+		t.Fatalf("failed to load the migrations: %v", err)
+	}
+	healthService := health.NewHealthService(testPostgresDB, testRedisDB, migrator, health.DefaultConfig())
+	healthController := health.NewHealthController(healthService)
 
-	router := SetupRoutes(authController)
+	router := SetupRoutes(authController, mfaController, signingKeyController, deviceController, householdController, targetController, telemetryController, commandController, liveController, healthController, signingKeyService, tokenDenylistRepo, rateLimitRepo, healthService, testMetricsToken)
 
 	// Helper to create valid token for auth middleware tests
-	createToken := func(userID string, secret string, expired bool, method jwt.SigningMethod) string {
+	createToken := func(userID string, expired bool) string {
 		claims := jwt.MapClaims{
 			"sub": userID,
 			"exp": time.Now().Add(time.Hour).Unix(),
@@ -66,11 +124,58 @@ func TestIntegrationRoutes(t *testing.T) {
 			claims["exp"] = time.Now().Add(-time.Hour).Unix()
 		}
 
-		token := jwt.NewWithClaims(method, claims)
-		signedString, _ := token.SignedString([]byte(secret))
+		signedString, _ := signingKeyService.Sign(claims)
 		return signedString
 	}
 
+	// userIDByUsername returns the id of a user created by the setup of a case
+	userIDByUsername := func(username string) string {
+		var userID string
+		testPostgresDB.QueryRow("SELECT id FROM user_account WHERE username = $1", username).Scan(&userID)
+		return userID
+	}
+
+	// the sessions of the change password case, the first one makes the request
+	var keptSessionID, revokedSessionID string
+	// the account of the delete case, its rows are looked up after the request
+	var deletedUserID, deletedDeviceID string
+	// the pairing code of the device created without a board
+	var attachedDeviceID, attachPairingCode string
+	// the household cases share a device of its owner with a viewer
+	var householdOwnerID, householdViewerID, sharedHouseholdID, sharedDeviceID string
+	setupSharedDevice := func(ctx context.Context) error {
+		for _, username := range []string{"daisy", "yoshi"} {
+			testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE username = $1", username)
+			err := authService.Register(ctx, username, username+"@gmail.com", "Testtest123", username, username)
+			if err != nil {
+				return err
+			}
+		}
+		householdOwnerID = userIDByUsername("daisy")
+		householdViewerID = userIDByUsername("yoshi")
+
+		membership, err := householdService.CreateHousehold(ctx, householdOwnerID, "castle")
+		if err != nil {
+			return err
+		}
+		sharedHouseholdID = membership.ID
+		createdDevice, err := deviceService.CreateDevice(ctx, householdOwnerID, "hall")
+		if err != nil {
+			return err
+		}
+		sharedDeviceID = createdDevice.ID
+		_, err = deviceService.ShareDevice(ctx, householdOwnerID, sharedDeviceID, sharedHouseholdID)
+		if err != nil {
+			return err
+		}
+		invitation, err := householdService.Invite(ctx, householdOwnerID, sharedHouseholdID, "", "yoshi", household.RoleViewer)
+		if err != nil {
+			return err
+		}
+		_, err = householdService.AcceptInvitation(ctx, householdViewerID, invitation.ID)
+		return err
+	}
+
 	tests := []struct {
 		name           string
 		method         string
@@ -82,6 +187,49 @@ func TestIntegrationRoutes(t *testing.T) {
 		verifyResponse func(*testing.T, *httptest.ResponseRecorder)
 		checkDBDataPresence func(context.Context) (bool, error)
 	}{
+		{
+			name:           "healthz_route",
+			method:         "GET",
+			path:           "/healthz",
+			setupData:      func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "readyz_route",
+			method:         "GET",
+			path:           "/readyz",
+			setupData:      func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusOK,
+			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				body := w.Body.String()
+				if !strings.Contains(body, `"status":"up"`) || !strings.Contains(body, `"migration_version":1`) {
+					t.Errorf("expected every dependency up and the migration version, got %s", body)
+				}
+			},
+		},
+		{
+			name:           "metrics_route_unauthorized",
+			method:         "GET",
+			path:           "/metrics",
+			setupData:      func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:      "metrics_route",
+			method:    "GET",
+			path:      "/metrics",
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+testMetricsToken)
+			},
+			expectedStatus: http.StatusOK,
+			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				// the previous requests are counted by their route
+				if !strings.Contains(w.Body.String(), `autolight_http_requests_total{method="GET",route="/readyz",status="200"}`) {
+					t.Errorf("expected the requests to /readyz to be counted, got %s", w.Body.String())
+				}
+			},
+		},
 		{
 			name:   "register_route",
 			method: "POST",
@@ -128,7 +276,7 @@ func TestIntegrationRoutes(t *testing.T) {
 			method:         "GET",
 			path:           "/api/ping",
 			setupRequest: 	func(req *http.Request) {
-				req.Header.Set("Authorization", "Bearer " + createToken("user-uuid-123", "supersecret", true, jwt.SigningMethodHS256))
+				req.Header.Set("Authorization", "Bearer " + createToken("user-uuid-123", true))
 			},
 			setupData:      func(ctx context.Context) error { return nil },
 			expectedStatus: http.StatusUnauthorized,
@@ -139,7 +287,7 @@ func TestIntegrationRoutes(t *testing.T) {
 			path:   "/api/ping",
 			setupData: func(ctx context.Context) error { return nil },
 			setupRequest: func(req *http.Request) {
-				req.Header.Set("Authorization", "Bearer " + createToken("user-uuid-123", "supersecret", false, jwt.SigningMethodHS256))
+				req.Header.Set("Authorization", "Bearer " + createToken("user-uuid-123", false))
 			},
 			expectedStatus: http.StatusOK,
 			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
@@ -148,6 +296,522 @@ func TestIntegrationRoutes(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "create_device_route",
+			method: "POST",
+			path:   "/api/devices",
+			body:   `{"name":"living room"}`,
+			setupData: func(ctx context.Context) error {
+				testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE username = $1", "toad")
+				return authService.Register(ctx, "toad", "toad@gmail.com", "Testtest123", "toad", "mushroom")
+			},
+			setupRequest: func(req *http.Request) {
+				var userID string
+				testPostgresDB.QueryRow("SELECT id FROM user_account WHERE username = $1", "toad").Scan(&userID)
+				req.Header.Set("Authorization", "Bearer " + createToken(userID, false))
+			},
+			expectedStatus: http.StatusCreated,
+			checkDBDataPresence: func(ctx context.Context) (bool, error) {
+				var exists bool
+				err := testPostgresDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM device d JOIN user_account u ON u.id = d.user_id WHERE u.username = $1 AND d.name = $2)", "toad", "living room").Scan(&exists)
+				return exists, err
+			},
+		},
+		{
+			name:   "claim_device_route_invalid_code",
+			method: "POST",
+			path:   "/api/devices/claim",
+			body:   `{"code":"ABCD2345","serial":"E6614103E7452D2F"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "claim_device_route_attaches_the_board",
+			method: "POST",
+			path:   "/api/devices/claim",
+			setupData: func(ctx context.Context) error {
+				testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE username = $1", "koopa")
+				err := authService.Register(ctx, "koopa", "koopa@gmail.com", "Testtest123", "koopa", "troopa")
+				if err != nil {
+					return err
+				}
+				createdDevice, err := deviceService.CreateDevice(ctx, userIDByUsername("koopa"), "porch")
+				if err != nil {
+					return err
+				}
+				attachedDeviceID = createdDevice.ID
+				pairingCode, err := deviceService.CreatePairingCode(ctx, userIDByUsername("koopa"), "", attachedDeviceID)
+				if err != nil {
+					return err
+				}
+				attachPairingCode = pairingCode.Code
+				return nil
+			},
+			setupRequest: func(req *http.Request) {
+				body := `{"code":"` + attachPairingCode + `","serial":"E6614103E7452D30"}`
+				req.Body = io.NopCloser(strings.NewReader(body))
+				req.ContentLength = int64(len(body))
+			},
+			expectedStatus: http.StatusCreated,
+			checkDBDataPresence: func(ctx context.Context) (bool, error) {
+				var exists bool
+				err := testPostgresDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM device WHERE id = $1 AND serial = $2 AND secret_hash IS NOT NULL)", attachedDeviceID, "E6614103E7452D30").Scan(&exists)
+				return exists, err
+			},
+		},
+		{
+			name:   "device_route_rejects_user_token",
+			method: "GET",
+			path:   "/api/devices/me",
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer " + createToken("user-uuid-123", false))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "telemetry_route_rejects_user_token",
+			method: "POST",
+			path:   "/api/telemetry",
+			body:   `{"duty_cycle":50,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":120.5,"raw":2048}]}`,
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer " + createToken("user-uuid-123", false))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "commands_route_rejects_user_token",
+			method: "GET",
+			path:   "/api/devices/me/commands",
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer " + createToken("user-uuid-123", false))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "live_route_unauthorized",
+			method: "GET",
+			path:   "/api/devices/7c9e6679-7425-40de-944b-e07fc1f90ae7/live",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "logout_route_unauthorized",
+			method: "POST",
+			path:   "/api/logout",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "forgot_password_unknown_email",
+			method: "POST",
+			path:   "/api/password/forgot",
+			body:   `{"email":"ghost@example.com"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:   "forgot_password_rate_limited_per_ip",
+			method: "POST",
+			path:   "/api/password/forgot",
+			body:   `{"email":"victim@example.com"}`,
+			setupData: func(ctx context.Context) error {
+				// the client has already asked for the links of other addresses
+				key := "ip:forgot_password:198.51.100.8"
+				testRedisDB.Del(ctx, "rlw:"+key)
+				for i := 0; i < forgotPasswordRateLimit.Limit; i++ {
+					if _, err := rateLimitRepo.Allow(ctx, key, forgotPasswordRateLimit.Limit, forgotPasswordRateLimit.Window); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			setupRequest: func(req *http.Request) {
+				req.RemoteAddr = "198.51.100.8:51234"
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:   "reset_password_invalid_token",
+			method: "POST",
+			path:   "/api/password/reset",
+			body:   `{"token":"pr1.invalid","password":"NewPassword123"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "sessions_route_unauthorized",
+			method: "GET",
+			path:   "/api/sessions",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "verify_email_invalid_token",
+			method: "GET",
+			path:   "/api/verify-email?token=ev1.invalid",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "resend_verification_unknown_email",
+			method: "POST",
+			path:   "/api/verify-email/resend",
+			body:   `{"email":"nobody@example.com"}`,
+			setupData: func(ctx context.Context) error {
+				testRedisDB.FlushDB(ctx)
+				return nil
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:   "login_mfa_invalid_ticket",
+			method: "POST",
+			path:   "/api/login/mfa",
+			body:   `{"ticket":"mt1.invalid","code":"123456"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "mfa_enroll_route_unauthorized",
+			method: "POST",
+			path:   "/api/mfa/totp/enroll",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "jwks_route",
+			method: "GET",
+			path:   "/.well-known/jwks.json",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusOK,
+			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if !strings.Contains(w.Body.String(), `"crv":"Ed25519"`) {
+					t.Errorf("expected the signing key in the JWKS, got %s", w.Body.String())
+				}
+			},
+		},
+		{
+			name:   "read_only_token_cannot_create_device",
+			method: "POST",
+			path:   "/api/devices",
+			body:   `{"name":"kitchen"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				signedString, _ := signingKeyService.Sign(jwt.MapClaims{
+					"sub":   "user-uuid-123",
+					"scope": "read_only",
+					"exp":   time.Now().Add(time.Hour).Unix(),
+				})
+				req.Header.Set("Authorization", "Bearer "+signedString)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "login_rate_limited_per_account",
+			method: "POST",
+			path:   "/api/login/email",
+			body:   `{"email":"spammer@example.com","password":"Testtest123"}`,
+			setupData: func(ctx context.Context) error {
+				// the budget of the account is exhausted, the IP still has room
+				sum := sha256.Sum256([]byte("email:spammer@example.com"))
+				key := "account:login:" + hex.EncodeToString(sum[:])
+				testRedisDB.Del(ctx, "rlw:"+key)
+				for i := 0; i < loginRateLimit.Limit; i++ {
+					if _, err := rateLimitRepo.Allow(ctx, key, loginRateLimit.Limit, loginRateLimit.Window); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if w.Header().Get("Retry-After") == "" {
+					t.Errorf("expected Retry-After header")
+				}
+			},
+		},
+		{
+			name:   "login_rate_limited_per_ip_despite_forwarded_for",
+			method: "POST",
+			path:   "/api/login/email",
+			body:   `{"email":"forger@example.com","password":"Testtest123"}`,
+			setupData: func(ctx context.Context) error {
+				// the budget of the real address is exhausted, the forged one is untouched
+				key := "ip:login:198.51.100.7"
+				testRedisDB.Del(ctx, "rlw:"+key)
+				for i := 0; i < loginRateLimit.Limit; i++ {
+					if _, err := rateLimitRepo.Allow(ctx, key, loginRateLimit.Limit, loginRateLimit.Window); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			setupRequest: func(req *http.Request) {
+				req.RemoteAddr = "198.51.100.7:51234"
+				req.Header.Set("X-Forwarded-For", "203.0.113.9")
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:   "me_route_unauthorized",
+			method: "GET",
+			path:   "/api/me",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "get_me_route",
+			method: "GET",
+			path:   "/api/me",
+			setupData: func(ctx context.Context) error {
+				testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE username = $1", "daisy")
+				return authService.Register(ctx, "daisy", "daisy@gmail.com", "Testtest123", "daisy", "sarasa")
+			},
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("daisy"), false))
+			},
+			expectedStatus: http.StatusOK,
+			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if !strings.Contains(w.Body.String(), `"email":"daisy@gmail.com"`) || strings.Contains(w.Body.String(), "password") {
+					t.Errorf("unexpected profile %s", w.Body.String())
+				}
+			},
+		},
+		{
+			name:   "mfa_enroll_route_wrong_password",
+			method: "POST",
+			path:   "/api/mfa/totp/enroll",
+			body:   `{"current_password":"Wrongpass123"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("daisy"), false))
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "update_me_route_new_email_without_password",
+			method: "PATCH",
+			path:   "/api/me",
+			body:   `{"email":"daisy.sarasa@gmail.com"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("daisy"), false))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "update_me_route_new_email",
+			method: "PATCH",
+			path:   "/api/me",
+			body:   `{"email":"daisy.sarasa@gmail.com","surname":"of sarasaland","current_password":"Testtest123"}`,
+			setupData: func(ctx context.Context) error {
+				testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE email = $1", "daisy.sarasa@gmail.com")
+				return nil
+			},
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("daisy"), false))
+			},
+			expectedStatus: http.StatusOK,
+			checkDBDataPresence: func(ctx context.Context) (bool, error) {
+				// the new email has to be verified again
+				var exists bool
+				err := testPostgresDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM user_account WHERE username = $1 AND email = $2 AND surname = $3 AND email_verified_at IS NULL)", "daisy", "daisy.sarasa@gmail.com", "of sarasaland").Scan(&exists)
+				return exists, err
+			},
+		},
+		{
+			name:   "update_me_route_username_taken",
+			method: "PATCH",
+			path:   "/api/me",
+			body:   `{"username":"peach"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("daisy"), false))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "change_password_route",
+			method: "POST",
+			path:   "/api/me/password",
+			body:   `{"current_password":"Testtest123","new_password":"NewPassword123"}`,
+			setupData: func(ctx context.Context) error {
+				testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE username = $1", "wario")
+				err := authService.Register(ctx, "wario", "wario@gmail.com", "Testtest123", "wario", "wario")
+				if err != nil {
+					return err
+				}
+				userID := userIDByUsername("wario")
+				kept, err := authService.GenerateRefreshToken(ctx, userID, "test-agent", "192.0.2.1")
+				if err != nil {
+					return err
+				}
+				revoked, err := authService.GenerateRefreshToken(ctx, userID, "test-agent", "192.0.2.2")
+				if err != nil {
+					return err
+				}
+				keptSessionID, revokedSessionID = kept.SessionID, revoked.SessionID
+				return nil
+			},
+			setupRequest: func(req *http.Request) {
+				signedString, _ := signingKeyService.Sign(jwt.MapClaims{
+					"sub": userIDByUsername("wario"),
+					"sid": keptSessionID,
+					"exp": time.Now().Add(time.Hour).Unix(),
+				})
+				req.Header.Set("Authorization", "Bearer "+signedString)
+			},
+			expectedStatus: http.StatusOK,
+			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				// only the session of the request survives
+				sessions, err := authService.GetSessions(ctx, userIDByUsername("wario"))
+				if err != nil {
+					t.Fatalf("GetSessions() error = %v", err)
+				}
+				if len(sessions) != 1 || sessions[0].ID != keptSessionID || sessions[0].ID == revokedSessionID {
+					t.Errorf("expected only the session %s, got %+v", keptSessionID, sessions)
+				}
+
+				_, _, err = authService.LoginByUsername(ctx, "wario", "NewPassword123")
+				if err != nil {
+					t.Errorf("expected the new password to be accepted, got %v", err)
+				}
+			},
+		},
+		{
+			name:   "change_password_route_wrong_current_password",
+			method: "POST",
+			path:   "/api/me/password",
+			body:   `{"current_password":"Wrongpass123","new_password":"Another123"}`,
+			setupData: func(ctx context.Context) error { return nil },
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("wario"), false))
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "delete_me_route",
+			method: "DELETE",
+			path:   "/api/me",
+			body:   `{"password":"Testtest123"}`,
+			setupData: func(ctx context.Context) error {
+				testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE username = $1", "waluigi")
+				err := authService.Register(ctx, "waluigi", "waluigi@gmail.com", "Testtest123", "waluigi", "waluigi")
+				if err != nil {
+					return err
+				}
+				deletedUserID = userIDByUsername("waluigi")
+				_, err = authService.GenerateRefreshToken(ctx, deletedUserID, "test-agent", "192.0.2.1")
+				if err != nil {
+					return err
+				}
+				// a device with a reading, both deleted together with the account
+				createdDevice := &device.Device{UserID: deletedUserID, Name: "garage"}
+				err = deviceRepo.CreateOne(ctx, createdDevice)
+				if err != nil {
+					return err
+				}
+				deletedDeviceID = createdDevice.ID
+				_, err = testPostgresDB.ExecContext(ctx,
+					"INSERT INTO reading(device_id, ts, lux, raw, duty_cycle) VALUES($1, $2, $3, $4, $5)",
+					deletedDeviceID, time.Now(), 80.0, 300, 0.5,
+				)
+				return err
+			},
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(deletedUserID, false))
+			},
+			expectedStatus: http.StatusNoContent,
+			checkDBDataPresence: func(ctx context.Context) (bool, error) {
+				// the check is true when nothing of the account is left
+				var left bool
+				err := testPostgresDB.QueryRowContext(ctx, `
+					SELECT EXISTS(SELECT 1 FROM user_account WHERE id = $1)
+						OR EXISTS(SELECT 1 FROM device WHERE user_id = $1)
+						OR EXISTS(SELECT 1 FROM reading WHERE device_id = $2)
+				`, deletedUserID, deletedDeviceID).Scan(&left)
+				if err != nil || left {
+					return false, err
+				}
+				sessions, err := authService.GetSessions(ctx, deletedUserID)
+				return len(sessions) == 0, err
+			},
+		},
+		{
+			name:   "create_household_route",
+			method: "POST",
+			path:   "/api/households",
+			body:   `{"name":"mansion"}`,
+			setupData: func(ctx context.Context) error {
+				testPostgresDB.ExecContext(ctx, "DELETE FROM user_account WHERE username = $1", "rosalina")
+				return authService.Register(ctx, "rosalina", "rosalina@gmail.com", "Testtest123", "rosalina", "stars")
+			},
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("rosalina"), false))
+			},
+			expectedStatus: http.StatusCreated,
+			checkDBDataPresence: func(ctx context.Context) (bool, error) {
+				var exists bool
+				err := testPostgresDB.QueryRowContext(ctx, `
+					SELECT EXISTS(
+						SELECT 1 FROM household h
+						JOIN household_member m ON m.household_id = h.id
+						JOIN user_account u ON u.id = m.user_id
+						WHERE u.username = $1 AND h.name = $2 AND m.role = 'owner'
+					)
+				`, "rosalina", "mansion").Scan(&exists)
+				return exists, err
+			},
+		},
+		{
+			name:      "viewer_lists_shared_device",
+			method:    "GET",
+			path:      "/api/devices",
+			setupData: setupSharedDevice,
+			setupRequest: func(req *http.Request) {
+				req.Header.Set("Authorization", "Bearer "+createToken(householdViewerID, false))
+			},
+			expectedStatus: http.StatusOK,
+			verifyResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if !strings.Contains(w.Body.String(), sharedDeviceID) {
+					t.Errorf("expected the shared device in the list, got %s", w.Body.String())
+				}
+			},
+		},
+		{
+			name:      "viewer_cannot_set_target",
+			method:    "PUT",
+			path:      "/api/devices/{id}/target",
+			body:      `{"brightness":40}`,
+			setupData: setupSharedDevice,
+			setupRequest: func(req *http.Request) {
+				// the device id is known only after the setup
+				req.URL.Path = "/api/devices/" + sharedDeviceID + "/target"
+				req.Header.Set("Authorization", "Bearer "+createToken(householdViewerID, false))
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:      "household_route_not_a_member",
+			method:    "GET",
+			path:      "/api/households/{id}",
+			setupData: setupSharedDevice,
+			setupRequest: func(req *http.Request) {
+				req.URL.Path = "/api/households/" + sharedHouseholdID
+				req.Header.Set("Authorization", "Bearer "+createToken(userIDByUsername("rosalina"), false))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "list_devices_route_unauthorized",
+			method: "GET",
+			path:   "/api/devices",
+			setupData: func(ctx context.Context) error { return nil },
+			expectedStatus: http.StatusUnauthorized,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,28 @@
+package signing_key
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type signingKeyService interface {
+	JWKS() JWKSet
+}
+
+type Controller struct {
+	service signingKeyService
+}
+
+func NewSigningKeyController(service signingKeyService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetJWKS publishes the public keys so that other services can verify the
+// access tokens, the cache is shorter than the activation delay of a new key
+func (kc *Controller) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, kc.service.JWKS())
+}
@@ -0,0 +1,44 @@
+package signing_key_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key/mocks"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func TestController_GetJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMocksigningKeyService(ctrl)
+	mockService.EXPECT().JWKS().Return(signing_key.JWKSet{Keys: []signing_key.JWK{
+		{Kty: "OKP", Crv: "Ed25519", X: "x", Kid: "kid-1", Alg: "EdDSA", Use: "sig"},
+	}})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+	signing_key.NewSigningKeyController(mockService).GetJWKS(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d want %d", w.Code, http.StatusOK)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Errorf("expected a Cache-Control header")
+	}
+	var body signing_key.JWKSet
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if len(body.Keys) != 1 || body.Keys[0].Kid != "kid-1" {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
@@ -0,0 +1,10 @@
+package signing_key
+
+import "time"
+
+// NewSigningKeyServiceWithClock lets the tests move the time of the rotation
+func NewSigningKeyServiceWithClock(repo signingKeyRepository, config Config, now func() time.Time) *service {
+	s := NewSigningKeyService(repo, config)
+	s.now = now
+	return s
+}
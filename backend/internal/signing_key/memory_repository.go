@@ -0,0 +1,57 @@
+package signing_key
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// memoryRepository keeps the keys in memory, the tokens it signs do not
+// survive a restart so it is meant for the tests and the local development
+type memoryRepository struct {
+	mu   sync.Mutex
+	keys []*SigningKey
+}
+
+func NewMemorySigningKeyRepository() *memoryRepository {
+	return &memoryRepository{}
+}
+
+func (r *memoryRepository) GetAll(ctx context.Context) ([]*SigningKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := slices.Clone(r.keys)
+	slices.SortFunc(keys, func(a, b *SigningKey) int {
+		return a.ActivatesAt.Compare(b.ActivatesAt)
+	})
+	return keys, nil
+}
+
+func (r *memoryRepository) CreateOneIfDue(ctx context.Context, key *SigningKey, dueAfter time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.ActivatesAt.After(dueAfter) {
+			return false, nil
+		}
+	}
+	r.keys = append(r.keys, key)
+	return true, nil
+}
+
+func (r *memoryRepository) DeleteRetired(ctx context.Context, retiredBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := []*SigningKey{}
+	for _, k := range r.keys {
+		retired := slices.ContainsFunc(r.keys, func(n *SigningKey) bool {
+			return n.ActivatesAt.After(k.ActivatesAt) && !n.ActivatesAt.After(retiredBefore)
+		})
+		if !retired {
+			kept = append(kept, k)
+		}
+	}
+	r.keys = kept
+	return nil
+}
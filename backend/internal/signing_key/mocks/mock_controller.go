@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	signing_key "github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MocksigningKeyService is a mock of signingKeyService interface.
+type MocksigningKeyService struct {
+	ctrl     *gomock.Controller
+	recorder *MocksigningKeyServiceMockRecorder
+	isgomock struct{}
+}
+
+// MocksigningKeyServiceMockRecorder is the mock recorder for MocksigningKeyService.
+type MocksigningKeyServiceMockRecorder struct {
+	mock *MocksigningKeyService
+}
+
+// NewMocksigningKeyService creates a new mock instance.
+func NewMocksigningKeyService(ctrl *gomock.Controller) *MocksigningKeyService {
+	mock := &MocksigningKeyService{ctrl: ctrl}
+	mock.recorder = &MocksigningKeyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksigningKeyService) EXPECT() *MocksigningKeyServiceMockRecorder {
+	return m.recorder
+}
+
+// JWKS mocks base method.
+func (m *MocksigningKeyService) JWKS() signing_key.JWKSet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(signing_key.JWKSet)
+	return ret0
+}
+
+// JWKS indicates an expected call of JWKS.
+func (mr *MocksigningKeyServiceMockRecorder) JWKS() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MocksigningKeyService)(nil).JWKS))
+}
@@ -0,0 +1,87 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	signing_key "github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MocksigningKeyRepository is a mock of signingKeyRepository interface.
+type MocksigningKeyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocksigningKeyRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MocksigningKeyRepositoryMockRecorder is the mock recorder for MocksigningKeyRepository.
+type MocksigningKeyRepositoryMockRecorder struct {
+	mock *MocksigningKeyRepository
+}
+
+// NewMocksigningKeyRepository creates a new mock instance.
+func NewMocksigningKeyRepository(ctrl *gomock.Controller) *MocksigningKeyRepository {
+	mock := &MocksigningKeyRepository{ctrl: ctrl}
+	mock.recorder = &MocksigningKeyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocksigningKeyRepository) EXPECT() *MocksigningKeyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateOneIfDue mocks base method.
+func (m *MocksigningKeyRepository) CreateOneIfDue(ctx context.Context, key *signing_key.SigningKey, dueAfter time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOneIfDue", ctx, key, dueAfter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOneIfDue indicates an expected call of CreateOneIfDue.
+func (mr *MocksigningKeyRepositoryMockRecorder) CreateOneIfDue(ctx, key, dueAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOneIfDue", reflect.TypeOf((*MocksigningKeyRepository)(nil).CreateOneIfDue), ctx, key, dueAfter)
+}
+
+// DeleteRetired mocks base method.
+func (m *MocksigningKeyRepository) DeleteRetired(ctx context.Context, retiredBefore time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRetired", ctx, retiredBefore)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRetired indicates an expected call of DeleteRetired.
+func (mr *MocksigningKeyRepositoryMockRecorder) DeleteRetired(ctx, retiredBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRetired", reflect.TypeOf((*MocksigningKeyRepository)(nil).DeleteRetired), ctx, retiredBefore)
+}
+
+// GetAll mocks base method.
+func (m *MocksigningKeyRepository) GetAll(ctx context.Context) ([]*signing_key.SigningKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx)
+	ret0, _ := ret[0].([]*signing_key.SigningKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MocksigningKeyRepositoryMockRecorder) GetAll(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MocksigningKeyRepository)(nil).GetAll), ctx)
+}
@@ -0,0 +1,36 @@
+package signing_key
+
+import (
+	"crypto/ed25519"
+	"time"
+)
+
+// SigningKey is an Ed25519 key pair that signs the access tokens,
+// its ID is the kid in the header of the tokens
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	CreatedAt  time.Time
+	// ActivatesAt is when the key starts signing, it is published
+	// before so that every verifier already knows it by then
+	ActivatesAt time.Time
+}
+
+func (k *SigningKey) PublicKey() ed25519.PublicKey {
+	return k.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+// JWK is the public part of a signing key as defined by RFC 8037
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
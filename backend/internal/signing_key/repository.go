@@ -0,0 +1,111 @@
+package signing_key
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type SigningKeyEntity struct {
+	ID          string
+	Seed        []byte
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewSigningKeyRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+// GetAll returns the keys ordered by activation, the last active one is the signing key
+func (r *repository) GetAll(ctx context.Context) ([]*SigningKey, error) {
+	query := `
+		SELECT kid, private_key, created_at, activates_at
+		FROM signing_key
+		ORDER BY activates_at, created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*SigningKey{}
+	for rows.Next() {
+		var key SigningKeyEntity
+		err = rows.Scan(&key.ID, &key.Seed, &key.CreatedAt, &key.ActivatesAt)
+		if err != nil {
+			return nil, err
+		}
+		if len(key.Seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("signing key %s: invalid private key size %d", key.ID, len(key.Seed))
+		}
+		keys = append(keys, key.toSigningKey())
+	}
+	return keys, rows.Err()
+}
+
+// CreateOneIfDue stores the key only when no other key activates after
+// dueAfter, so that when more instances rotate at the same time only one
+// of them creates the new key. It tells whether the key has been stored
+func (r *repository) CreateOneIfDue(ctx context.Context, key *SigningKey, dueAfter time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	// the rollback does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	// the lock serializes the rotations and is released with the transaction
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('signing_key'))")
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		INSERT INTO signing_key(kid, private_key, created_at, activates_at)
+		SELECT $1, $2, $3, $4
+		WHERE NOT EXISTS (SELECT 1 FROM signing_key WHERE activates_at > $5)
+	`
+	result, err := tx.ExecContext(ctx, query, key.ID, []byte(key.PrivateKey.Seed()), key.CreatedAt, key.ActivatesAt, dueAfter)
+	if err != nil {
+		return false, err
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return created > 0, tx.Commit()
+}
+
+// DeleteRetired removes the keys replaced by a newer active key before retiredBefore,
+// by then every token they signed has expired
+func (r *repository) DeleteRetired(ctx context.Context, retiredBefore time.Time) error {
+	query := `
+		DELETE FROM signing_key k
+		WHERE EXISTS (
+			SELECT 1 FROM signing_key n
+			WHERE n.activates_at > k.activates_at AND n.activates_at <= $1
+		)
+	`
+	_, err := r.db.ExecContext(ctx, query, retiredBefore)
+	return err
+}
+
+func (ke *SigningKeyEntity) toSigningKey() *SigningKey {
+	return &SigningKey{
+		ID:          ke.ID,
+		PrivateKey:  ed25519.NewKeyFromSeed(ke.Seed),
+		CreatedAt:   ke.CreatedAt,
+		ActivatesAt: ke.ActivatesAt,
+	}
+}
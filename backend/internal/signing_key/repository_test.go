@@ -0,0 +1,83 @@
+package signing_key
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	// the unit tests of this package do not need a container,
+	// so with -short we avoid starting it at all
+	flag.Parse()
+	if !testing.Short() {
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_Rotation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewSigningKeyRepository(testPostgresDB)
+	if _, err := testPostgresDB.ExecContext(ctx, "DELETE FROM signing_key"); err != nil {
+		t.Fatalf("failed to clean the keys: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	first, _ := newSigningKey(now.Add(-48*time.Hour), now.Add(-48*time.Hour))
+	second, _ := newSigningKey(now.Add(-2*time.Hour), now.Add(-2*time.Hour))
+
+	created, err := repo.CreateOneIfDue(ctx, first, now.Add(-72*time.Hour))
+	if err != nil || !created {
+		t.Fatalf("CreateOneIfDue() = %v, %v", created, err)
+	}
+	// a key activating after dueAfter already exists, so nothing is created
+	created, err = repo.CreateOneIfDue(ctx, second, now.Add(-72*time.Hour))
+	if err != nil || created {
+		t.Fatalf("CreateOneIfDue() when not due = %v, %v", created, err)
+	}
+	created, err = repo.CreateOneIfDue(ctx, second, now.Add(-24*time.Hour))
+	if err != nil || !created {
+		t.Fatalf("CreateOneIfDue() when due = %v, %v", created, err)
+	}
+
+	keys, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != first.ID || keys[1].ID != second.ID {
+		t.Fatalf("expected the keys ordered by activation, got %+v", keys)
+	}
+	if !keys[0].PrivateKey.Equal(first.PrivateKey) {
+		t.Errorf("expected the private key to be stored")
+	}
+
+	// the first key has been replaced two hours ago, it is kept while
+	// the tokens it signed can still be valid
+	if err := repo.DeleteRetired(ctx, now.Add(-3*time.Hour)); err != nil {
+		t.Fatalf("DeleteRetired() error = %v", err)
+	}
+	if keys, _ := repo.GetAll(ctx); len(keys) != 2 {
+		t.Errorf("expected both keys to be kept, got %d", len(keys))
+	}
+	if err := repo.DeleteRetired(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("DeleteRetired() error = %v", err)
+	}
+	keys, _ = repo.GetAll(ctx)
+	if len(keys) != 1 || keys[0].ID != second.ID {
+		t.Errorf("expected only the active key to be kept, got %+v", keys)
+	}
+}
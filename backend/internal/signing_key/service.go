@@ -0,0 +1,214 @@
+package signing_key
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoSigningKey = errors.New("no active signing key")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+// Config tells how often the signing key is replaced
+type Config struct {
+	// RotationPeriod is how long a key signs before it is replaced
+	RotationPeriod time.Duration
+	// ActivationDelay is how long a new key is published before it starts
+	// signing, it must be longer than RefreshInterval and than the cache of
+	// the JWKS so that the other instances and services know it in time
+	ActivationDelay time.Duration
+	// TokenTTL is the longest lifetime of the signed tokens,
+	// a replaced key is still accepted for this time
+	TokenTTL time.Duration
+	// RefreshInterval is how often the keys are reloaded and rotated
+	RefreshInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		RotationPeriod:  30 * 24 * time.Hour,
+		ActivationDelay: 10 * time.Minute,
+		TokenTTL:        time.Hour,
+		RefreshInterval: time.Minute,
+	}
+}
+
+type signingKeyRepository interface {
+	GetAll(ctx context.Context) ([]*SigningKey, error)
+	CreateOneIfDue(ctx context.Context, key *SigningKey, dueAfter time.Time) (bool, error)
+	DeleteRetired(ctx context.Context, retiredBefore time.Time) error
+}
+
+type service struct {
+	repo   signingKeyRepository
+	config Config
+	now    func() time.Time
+
+	mu sync.RWMutex
+	// keys are ordered by activation, the last active one signs
+	keys []*SigningKey
+}
+
+func NewSigningKeyService(repo signingKeyRepository, config Config) *service {
+	return &service{
+		repo:   repo,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Refresh rotates the signing key when it is due and reloads the keys,
+// it must be called once before the first token is signed
+func (s *service) Refresh(ctx context.Context) error {
+	now := s.now()
+
+	keys, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the next key is created ActivationDelay before the current one is due,
+	// so a rotation is due when no key activates after dueAfter
+	dueAfter := now.Add(s.config.ActivationDelay).Add(-s.config.RotationPeriod)
+	if len(keys) == 0 || !keys[len(keys)-1].ActivatesAt.After(dueAfter) {
+		activatesAt := now.Add(s.config.ActivationDelay)
+		// on the first start there is no key to sign with meanwhile
+		if len(keys) == 0 {
+			activatesAt = now
+		}
+
+		key, err := newSigningKey(now, activatesAt)
+		if err != nil {
+			return err
+		}
+		created, err := s.repo.CreateOneIfDue(ctx, key, dueAfter)
+		if err != nil {
+			return err
+		}
+		if created {
+			slog.Info("signing key created", "kid", key.ID, "activatesAt", key.ActivatesAt)
+		}
+	}
+
+	// a replaced key is dropped once every token it signed has expired
+	err = s.repo.DeleteRetired(ctx, now.Add(-s.config.TokenTTL))
+	if err != nil {
+		return err
+	}
+	// another instance could have created a key meanwhile, so they are read again
+	keys, err = s.repo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the keys every RefreshInterval until the context is done
+func (s *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// the current keys stay valid, the refresh is tried again at the next tick
+			err := s.Refresh(ctx)
+			if err != nil {
+				slog.Error("failed to refresh the signing keys", "error", err)
+			}
+		}
+	}
+}
+
+// Sign returns the token with the claims signed by the current key
+func (s *service) Sign(claims jwt.Claims) (string, error) {
+	key := s.signingKey()
+	if key == nil {
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Keyfunc is the jwt.Keyfunc that returns the public key named by the kid of the token
+func (s *service) Keyfunc(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+		return nil, errors.New("unexpected signing method")
+	}
+	kid, _ := token.Header["kid"].(string)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key.PublicKey(), nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// JWKS returns the public keys that can verify the tokens, including
+// the next key that has been published but is not signing yet
+func (s *service) JWKS() JWKSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range s.keys {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.PublicKey()),
+			Kid: key.ID,
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Use: "sig",
+		})
+	}
+	return set
+}
+
+func (s *service) signingKey() *SigningKey {
+	now := s.now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.keys) - 1; i >= 0; i-- {
+		if !s.keys[i].ActivatesAt.After(now) {
+			return s.keys[i]
+		}
+	}
+	return nil
+}
+
+func newSigningKey(createdAt time.Time, activatesAt time.Time) (*SigningKey, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:          uuid.NewString(),
+		PrivateKey:  privateKey,
+		CreatedAt:   createdAt,
+		ActivatesAt: activatesAt,
+	}, nil
+}
@@ -0,0 +1,213 @@
+package signing_key_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/signing_key/mocks"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/mock/gomock"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func parse(t *testing.T, tokenString string, keyfunc jwt.Keyfunc, now time.Time) (*jwt.Token, error) {
+	t.Helper()
+	return jwt.Parse(tokenString, keyfunc, jwt.WithTimeFunc(func() time.Time { return now }))
+}
+
+func TestService_SignBeforeRefresh(t *testing.T) {
+	s := signing_key.NewSigningKeyService(signing_key.NewMemorySigningKeyRepository(), signing_key.DefaultConfig())
+
+	_, err := s.Sign(jwt.MapClaims{"sub": "user123"})
+	if !errors.Is(err, signing_key.ErrNoSigningKey) {
+		t.Errorf("expected error %v, got %v", signing_key.ErrNoSigningKey, err)
+	}
+}
+
+func TestService_SignAndVerify(t *testing.T) {
+	c := &clock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := signing_key.NewSigningKeyServiceWithClock(signing_key.NewMemorySigningKeyRepository(), signing_key.DefaultConfig(), c.Now)
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tokenString, err := s.Sign(jwt.MapClaims{"sub": "user123", "exp": c.now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	token, err := parse(t, tokenString, s.Keyfunc, c.now)
+	if err != nil || !token.Valid {
+		t.Fatalf("expected a valid token, got %v", err)
+	}
+	if token.Method.Alg() != "EdDSA" {
+		t.Errorf("expected alg EdDSA, got %v", token.Method.Alg())
+	}
+	jwks := s.JWKS()
+	if len(jwks.Keys) != 1 || token.Header["kid"] != jwks.Keys[0].Kid {
+		t.Errorf("expected the kid of the token to be published, got %v and %+v", token.Header["kid"], jwks)
+	}
+	if jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].Crv != "Ed25519" || jwks.Keys[0].X == "" {
+		t.Errorf("unexpected jwk %+v", jwks.Keys[0])
+	}
+}
+
+func TestService_Keyfunc(t *testing.T) {
+	s := signing_key.NewSigningKeyService(signing_key.NewMemorySigningKeyRepository(), signing_key.DefaultConfig())
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	other := signing_key.NewSigningKeyService(signing_key.NewMemorySigningKeyRepository(), signing_key.DefaultConfig())
+	if err := other.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	claims := jwt.MapClaims{"sub": "user123", "exp": time.Now().Add(time.Hour).Unix()}
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("supersecret"))
+	otherToken, _ := other.Sign(claims)
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	noKidToken, _ := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(privateKey)
+
+	tests := []struct {
+		name        string
+		tokenString string
+	}{
+		{name: "hmac_token", tokenString: hmacToken},
+		{name: "unknown_kid", tokenString: otherToken},
+		{name: "missing_kid", tokenString: noKidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Parse(tt.tokenString, s.Keyfunc)
+			if err == nil {
+				t.Errorf("expected the token to be rejected")
+			}
+		})
+	}
+}
+
+func TestService_Rotation(t *testing.T) {
+	ctx := context.Background()
+	config := signing_key.DefaultConfig()
+	c := &clock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	s := signing_key.NewSigningKeyServiceWithClock(signing_key.NewMemorySigningKeyRepository(), config, c.Now)
+
+	sign := func() (string, string) {
+		t.Helper()
+		tokenString, err := s.Sign(jwt.MapClaims{"sub": "user123", "exp": c.now.Add(config.TokenTTL).Unix()})
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		token, _, _ := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+		return tokenString, token.Header["kid"].(string)
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	_, oldKid := sign()
+
+	// just before the rotation is due nothing changes
+	c.now = c.now.Add(config.RotationPeriod - config.ActivationDelay - time.Second)
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got := len(s.JWKS().Keys); got != 1 {
+		t.Fatalf("expected 1 key before the rotation, got %d", got)
+	}
+
+	// the next key is published but the old one keeps signing until it activates
+	c.now = c.now.Add(2 * time.Second)
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got := len(s.JWKS().Keys); got != 2 {
+		t.Fatalf("expected 2 keys after the rotation, got %d", got)
+	}
+	oldToken, kid := sign()
+	if kid != oldKid {
+		t.Errorf("expected the old key to sign until the new one activates, got %v", kid)
+	}
+
+	// once active the new key signs and the old tokens are still accepted
+	c.now = c.now.Add(config.ActivationDelay)
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	newToken, newKid := sign()
+	if newKid == oldKid {
+		t.Fatalf("expected the new key to sign")
+	}
+	if _, err := parse(t, oldToken, s.Keyfunc, c.now); err != nil {
+		t.Errorf("expected the old token to be still valid, got %v", err)
+	}
+
+	// after the longest token lifetime the old key is dropped
+	c.now = c.now.Add(config.TokenTTL + time.Second)
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if got := len(s.JWKS().Keys); got != 1 {
+		t.Fatalf("expected the old key to be removed, got %d keys", got)
+	}
+	if _, err := jwt.Parse(oldToken, s.Keyfunc, jwt.WithoutClaimsValidation()); !errors.Is(err, signing_key.ErrUnknownKey) {
+		t.Errorf("expected error %v, got %v", signing_key.ErrUnknownKey, err)
+	}
+	if _, err := jwt.Parse(newToken, s.Keyfunc, jwt.WithoutClaimsValidation()); err != nil {
+		t.Errorf("expected the new token to be valid, got %v", err)
+	}
+}
+
+func TestService_ConcurrentRotation(t *testing.T) {
+	ctx := context.Background()
+	repo := signing_key.NewMemorySigningKeyRepository()
+	c := &clock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	// two instances share the same keys, only one of them creates the first key
+	first := signing_key.NewSigningKeyServiceWithClock(repo, signing_key.DefaultConfig(), c.Now)
+	second := signing_key.NewSigningKeyServiceWithClock(repo, signing_key.DefaultConfig(), c.Now)
+	if err := first.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if err := second.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tokenString, err := first.Sign(jwt.MapClaims{"sub": "user123"})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := jwt.Parse(tokenString, second.Keyfunc); err != nil {
+		t.Errorf("expected the token of an instance to be valid on the other, got %v", err)
+	}
+	if got := len(second.JWKS().Keys); got != 1 {
+		t.Errorf("expected 1 shared key, got %d", got)
+	}
+}
+
+func TestService_RefreshError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMocksigningKeyRepository(ctrl)
+	repo.EXPECT().GetAll(gomock.Any()).Return(nil, errors.New("db error"))
+
+	s := signing_key.NewSigningKeyService(repo, signing_key.DefaultConfig())
+	if err := s.Refresh(context.Background()); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := s.Sign(jwt.MapClaims{"sub": "user123"}); !errors.Is(err, signing_key.ErrNoSigningKey) {
+		t.Errorf("expected error %v, got %v", signing_key.ErrNoSigningKey, err)
+	}
+}
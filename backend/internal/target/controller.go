@@ -0,0 +1,129 @@
+package target
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/gin-gonic/gin"
+)
+
+type targetService interface {
+	SetTarget(ctx context.Context, userID string, deviceID string, brightness int) (*Target, error)
+	GetTarget(ctx context.Context, userID string, deviceID string) (*Target, error)
+}
+
+type Controller struct {
+	service targetService
+}
+
+func NewTargetController(service targetService) *Controller {
+	return &Controller{service: service}
+}
+
+type deviceURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type setTargetRequest struct {
+	// a pointer is needed because "required" would reject a brightness of 0
+	Brightness *int `json:"brightness" binding:"required,min=0,max=100"`
+}
+
+type targetResponse struct {
+	DeviceID   string    `json:"device_id"`
+	Brightness int       `json:"brightness"`
+	SetBy      string    `json:"set_by,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (tc *Controller) SetTarget(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request setTargetRequest
+	err = c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid set target request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	target, err := tc.service.SetTarget(ctx, userID, uri.ID, *request.Brightness)
+	if err != nil {
+		tc.handleTargetError(c, err, "failed to set brightness target")
+		return
+	}
+
+	slog.Info("brightness target set successfully", "userID", userID, "deviceID", uri.ID, "brightness", target.Brightness)
+	c.JSON(http.StatusOK, toResponse(target))
+}
+
+func (tc *Controller) GetTarget(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	target, err := tc.service.GetTarget(ctx, userID, uri.ID)
+	if err != nil {
+		tc.handleTargetError(c, err, "failed to get brightness target")
+		return
+	}
+
+	c.JSON(http.StatusOK, toResponse(target))
+}
+
+func (tc *Controller) handleTargetError(c *gin.Context, err error, message string) {
+	if errors.Is(err, device.ErrDeviceNotFound) || errors.Is(err, device.ErrNotDeviceOwner) {
+		slog.Warn("device not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+		return
+	}
+	if errors.Is(err, device.ErrPermissionDenied) {
+		slog.Warn("device action forbidden", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrTargetNotSet) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
+
+func toResponse(target *Target) targetResponse {
+	return targetResponse{
+		DeviceID:   target.DeviceID,
+		Brightness: target.Brightness,
+		SetBy:      target.SetBy,
+		UpdatedAt:  target.UpdatedAt,
+	}
+}
@@ -0,0 +1,175 @@
+package target_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target/mocks"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+const testDeviceID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+/**
+ * newTestContext creates a gin.Context for an authenticated user
+ * with the device id already in the path parameters.
+ */
+func newTestContext(method string, deviceID string, body []byte) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, "/devices/"+deviceID+"/target", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: deviceID}}
+	c.Set("userID", "user-1")
+	return c, w
+}
+
+func TestController_SetTarget(t *testing.T) {
+	tests := []struct {
+		name         string
+		deviceID     string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MocktargetService)
+	}{
+		{
+			name:         "success",
+			deviceID:     testDeviceID,
+			body:         `{"brightness":40}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MocktargetService) {
+				m.EXPECT().
+					SetTarget(gomock.Any(), "user-1", testDeviceID, 40).
+					Return(&target.Target{DeviceID: testDeviceID, Brightness: 40, SetBy: "user-1", UpdatedAt: time.Now()}, nil)
+			},
+		},
+		{
+			name:         "zero_is_valid",
+			deviceID:     testDeviceID,
+			body:         `{"brightness":0}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MocktargetService) {
+				m.EXPECT().
+					SetTarget(gomock.Any(), "user-1", testDeviceID, 0).
+					Return(&target.Target{DeviceID: testDeviceID, Brightness: 0, SetBy: "user-1"}, nil)
+			},
+		},
+		{
+			name:         "missing_brightness",
+			deviceID:     testDeviceID,
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktargetService) {},
+		},
+		{
+			name:         "brightness_too_high",
+			deviceID:     testDeviceID,
+			body:         `{"brightness":101}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktargetService) {},
+		},
+		{
+			name:         "negative_brightness",
+			deviceID:     testDeviceID,
+			body:         `{"brightness":-1}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktargetService) {},
+		},
+		{
+			name:         "invalid_device_id",
+			deviceID:     "42",
+			body:         `{"brightness":40}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktargetService) {},
+		},
+		{
+			name:         "not_owner",
+			deviceID:     testDeviceID,
+			body:         `{"brightness":40}`,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MocktargetService) {
+				m.EXPECT().SetTarget(gomock.Any(), "user-1", testDeviceID, 40).Return(nil, device.ErrNotDeviceOwner)
+			},
+		},
+		{
+			name:         "viewer_of_shared_device",
+			deviceID:     testDeviceID,
+			body:         `{"brightness":40}`,
+			expectedCode: http.StatusForbidden,
+			setupMock: func(m *mocks.MocktargetService) {
+				m.EXPECT().SetTarget(gomock.Any(), "user-1", testDeviceID, 40).Return(nil, device.ErrPermissionDenied)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTargetService := mocks.NewMocktargetService(ctrl)
+			tt.setupMock(mockTargetService)
+
+			tc := target.NewTargetController(mockTargetService)
+			c, w := newTestContext(http.MethodPut, tt.deviceID, []byte(tt.body))
+
+			tc.SetTarget(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_GetTarget(t *testing.T) {
+	tests := []struct {
+		name         string
+		expectedCode int
+		setupMock    func(*mocks.MocktargetService)
+	}{
+		{
+			name:         "success",
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MocktargetService) {
+				m.EXPECT().GetTarget(gomock.Any(), "user-1", testDeviceID).Return(&target.Target{DeviceID: testDeviceID, Brightness: 40}, nil)
+			},
+		},
+		{
+			name:         "not_set",
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MocktargetService) {
+				m.EXPECT().GetTarget(gomock.Any(), "user-1", testDeviceID).Return(nil, target.ErrTargetNotSet)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTargetService := mocks.NewMocktargetService(ctrl)
+			tt.setupMock(mockTargetService)
+
+			tc := target.NewTargetController(mockTargetService)
+			c, w := newTestContext(http.MethodGet, testDeviceID, nil)
+
+			tc.GetTarget(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
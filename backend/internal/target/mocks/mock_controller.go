@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: controller.go
+//
+// Generated by this command:
+//
+//	mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	target "github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MocktargetService is a mock of targetService interface.
+type MocktargetService struct {
+	ctrl     *gomock.Controller
+	recorder *MocktargetServiceMockRecorder
+	isgomock struct{}
+}
+
+// MocktargetServiceMockRecorder is the mock recorder for MocktargetService.
+type MocktargetServiceMockRecorder struct {
+	mock *MocktargetService
+}
+
+// NewMocktargetService creates a new mock instance.
+func NewMocktargetService(ctrl *gomock.Controller) *MocktargetService {
+	mock := &MocktargetService{ctrl: ctrl}
+	mock.recorder = &MocktargetServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktargetService) EXPECT() *MocktargetServiceMockRecorder {
+	return m.recorder
+}
+
+// GetTarget mocks base method.
+func (m *MocktargetService) GetTarget(ctx context.Context, userID, deviceID string) (*target.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTarget", ctx, userID, deviceID)
+	ret0, _ := ret[0].(*target.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTarget indicates an expected call of GetTarget.
+func (mr *MocktargetServiceMockRecorder) GetTarget(ctx, userID, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTarget", reflect.TypeOf((*MocktargetService)(nil).GetTarget), ctx, userID, deviceID)
+}
+
+// SetTarget mocks base method.
+func (m *MocktargetService) SetTarget(ctx context.Context, userID, deviceID string, brightness int) (*target.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTarget", ctx, userID, deviceID, brightness)
+	ret0, _ := ret[0].(*target.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetTarget indicates an expected call of SetTarget.
+func (mr *MocktargetServiceMockRecorder) SetTarget(ctx, userID, deviceID, brightness any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTarget", reflect.TypeOf((*MocktargetService)(nil).SetTarget), ctx, userID, deviceID, brightness)
+}
@@ -0,0 +1,147 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	device "github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	target "github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MocktargetRepository is a mock of targetRepository interface.
+type MocktargetRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocktargetRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MocktargetRepositoryMockRecorder is the mock recorder for MocktargetRepository.
+type MocktargetRepositoryMockRecorder struct {
+	mock *MocktargetRepository
+}
+
+// NewMocktargetRepository creates a new mock instance.
+func NewMocktargetRepository(ctrl *gomock.Controller) *MocktargetRepository {
+	mock := &MocktargetRepository{ctrl: ctrl}
+	mock.recorder = &MocktargetRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktargetRepository) EXPECT() *MocktargetRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetOneByDeviceID mocks base method.
+func (m *MocktargetRepository) GetOneByDeviceID(ctx context.Context, deviceID string) (*target.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOneByDeviceID", ctx, deviceID)
+	ret0, _ := ret[0].(*target.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOneByDeviceID indicates an expected call of GetOneByDeviceID.
+func (mr *MocktargetRepositoryMockRecorder) GetOneByDeviceID(ctx, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOneByDeviceID", reflect.TypeOf((*MocktargetRepository)(nil).GetOneByDeviceID), ctx, deviceID)
+}
+
+// UpsertOne mocks base method.
+func (m *MocktargetRepository) UpsertOne(ctx context.Context, arg1 *target.Target) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertOne", ctx, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertOne indicates an expected call of UpsertOne.
+func (mr *MocktargetRepositoryMockRecorder) UpsertOne(ctx, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertOne", reflect.TypeOf((*MocktargetRepository)(nil).UpsertOne), ctx, arg1)
+}
+
+// MockdeviceService is a mock of deviceService interface.
+type MockdeviceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeviceServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockdeviceServiceMockRecorder is the mock recorder for MockdeviceService.
+type MockdeviceServiceMockRecorder struct {
+	mock *MockdeviceService
+}
+
+// NewMockdeviceService creates a new mock instance.
+func NewMockdeviceService(ctrl *gomock.Controller) *MockdeviceService {
+	mock := &MockdeviceService{ctrl: ctrl}
+	mock.recorder = &MockdeviceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeviceService) EXPECT() *MockdeviceServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthorizeDevice mocks base method.
+func (m *MockdeviceService) AuthorizeDevice(ctx context.Context, userID, deviceID string, permission device.Permission) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeDevice", ctx, userID, deviceID, permission)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeDevice indicates an expected call of AuthorizeDevice.
+func (mr *MockdeviceServiceMockRecorder) AuthorizeDevice(ctx, userID, deviceID, permission any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeDevice", reflect.TypeOf((*MockdeviceService)(nil).AuthorizeDevice), ctx, userID, deviceID, permission)
+}
+
+// MockeventPublisher is a mock of eventPublisher interface.
+type MockeventPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *MockeventPublisherMockRecorder
+	isgomock struct{}
+}
+
+// MockeventPublisherMockRecorder is the mock recorder for MockeventPublisher.
+type MockeventPublisherMockRecorder struct {
+	mock *MockeventPublisher
+}
+
+// NewMockeventPublisher creates a new mock instance.
+func NewMockeventPublisher(ctrl *gomock.Controller) *MockeventPublisher {
+	mock := &MockeventPublisher{ctrl: ctrl}
+	mock.recorder = &MockeventPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockeventPublisher) EXPECT() *MockeventPublisherMockRecorder {
+	return m.recorder
+}
+
+// PublishTarget mocks base method.
+func (m *MockeventPublisher) PublishTarget(ctx context.Context, arg1 *target.Target) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PublishTarget", ctx, arg1)
+}
+
+// PublishTarget indicates an expected call of PublishTarget.
+func (mr *MockeventPublisherMockRecorder) PublishTarget(ctx, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishTarget", reflect.TypeOf((*MockeventPublisher)(nil).PublishTarget), ctx, arg1)
+}
@@ -0,0 +1,12 @@
+package target
+
+import (
+	"time"
+)
+
+type Target struct {
+	DeviceID   string
+	Brightness int
+	SetBy      string
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,108 @@
+package target
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TargetEntity struct {
+	DeviceID   uuid.UUID
+	Brightness int16
+	SetBy      uuid.NullUUID
+	UpdatedAt  time.Time
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewTargetRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) UpsertOne(ctx context.Context, target *Target) error {
+	targetEntity, err := toEntity(target)
+	if err != nil {
+		return err
+	}
+	// each device has only one target, the last one set wins
+	query := `
+		INSERT INTO device_target(device_id, brightness, set_by, updated_at)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (device_id) DO UPDATE
+		SET brightness = EXCLUDED.brightness,
+			set_by = EXCLUDED.set_by,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err = r.db.ExecContext(ctx, query, targetEntity.DeviceID, targetEntity.Brightness, targetEntity.SetBy, targetEntity.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	target.UpdatedAt = targetEntity.UpdatedAt
+	return nil
+}
+
+func (r *repository) GetOneByDeviceID(ctx context.Context, deviceID string) (*Target, error) {
+	query := `
+		SELECT device_id, brightness, set_by, updated_at
+		FROM device_target
+		WHERE device_id = $1
+	`
+	row := r.db.QueryRowContext(ctx, query, deviceID)
+
+	var target TargetEntity
+	err := row.Scan(&target.DeviceID, &target.Brightness, &target.SetBy, &target.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return target.toTarget(), nil
+}
+
+func (te *TargetEntity) toTarget() *Target {
+	var setBy string
+	if te.SetBy.Valid {
+		setBy = te.SetBy.UUID.String()
+	}
+	return &Target{
+		DeviceID:   te.DeviceID.String(),
+		Brightness: int(te.Brightness),
+		SetBy:      setBy,
+		UpdatedAt:  te.UpdatedAt,
+	}
+}
+
+func toEntity(target *Target) (*TargetEntity, error) {
+	deviceID, err := uuid.Parse(target.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	// set_by is empty when the target is not set by a user (e.g. a schedule)
+	var setBy uuid.NullUUID
+	if target.SetBy != "" {
+		id, err := uuid.Parse(target.SetBy)
+		if err != nil {
+			return nil, err
+		}
+		setBy = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	updatedAt := target.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now().UTC()
+	}
+	return &TargetEntity{
+		DeviceID:   deviceID,
+		Brightness: int16(target.Brightness),
+		SetBy:      setBy,
+		UpdatedAt:  updatedAt,
+	}, nil
+}
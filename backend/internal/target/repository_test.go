@@ -0,0 +1,79 @@
+package target
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+// createTestDevice inserts a user and one of its devices, since the target references both
+func createTestDevice(ctx context.Context, t *testing.T) (string, string) {
+	t.Helper()
+	userID := uuid.New()
+	deviceID := uuid.New()
+	_, err := testPostgresDB.ExecContext(ctx,
+		"INSERT INTO user_account(id, username, email, password, name, surname) VALUES($1, $2, $3, $4, $5, $6)",
+		userID, userID.String()[:8], userID.String()+"@example.com", "hash", "test", "user",
+	)
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	_, err = testPostgresDB.ExecContext(ctx, "INSERT INTO device(id, user_id, name) VALUES($1, $2, $3)", deviceID, userID, "lamp")
+	if err != nil {
+		t.Fatalf("failed to create test device: %v", err)
+	}
+	return userID.String(), deviceID.String()
+}
+
+func TestRepository_UpsertOne(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewTargetRepository(testPostgresDB)
+	userID, deviceID := createTestDevice(ctx, t)
+
+	missing, err := repo.GetOneByDeviceID(ctx, deviceID)
+	if err != nil || missing != nil {
+		t.Fatalf("expected no target before the first upsert, got %+v, %v", missing, err)
+	}
+
+	for _, brightness := range []int{30, 75} {
+		err := repo.UpsertOne(ctx, &Target{DeviceID: deviceID, Brightness: brightness, SetBy: userID})
+		if err != nil {
+			t.Fatalf("UpsertOne() error = %v", err)
+		}
+	}
+
+	got, err := repo.GetOneByDeviceID(ctx, deviceID)
+	if err != nil {
+		t.Fatalf("GetOneByDeviceID() error = %v", err)
+	}
+	if got == nil || got.Brightness != 75 || got.SetBy != userID {
+		t.Errorf("expected the last target to win, got %+v", got)
+	}
+
+	// the database rejects values outside of the percentage range
+	err = repo.UpsertOne(ctx, &Target{DeviceID: deviceID, Brightness: 101, SetBy: userID})
+	if err == nil {
+		t.Errorf("expected an error for brightness 101")
+	}
+}
@@ -0,0 +1,79 @@
+package target
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"errors"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+)
+
+var (
+	ErrTargetNotSet = errors.New("brightness target not set")
+)
+
+type targetRepository interface {
+	UpsertOne(ctx context.Context, target *Target) error
+	GetOneByDeviceID(ctx context.Context, deviceID string) (*Target, error)
+}
+
+// deviceService is used to check that the user can access the device
+type deviceService interface {
+	AuthorizeDevice(ctx context.Context, userID string, deviceID string, permission device.Permission) (*device.Device, error)
+}
+
+// eventPublisher pushes the new target to the apps watching the device
+type eventPublisher interface {
+	PublishTarget(ctx context.Context, target *Target)
+}
+
+type service struct {
+	targetRepo     targetRepository
+	deviceService  deviceService
+	eventPublisher eventPublisher
+}
+
+func NewTargetService(targetRepo targetRepository, deviceService deviceService, eventPublisher eventPublisher) *service {
+	return &service{
+		targetRepo:     targetRepo,
+		deviceService:  deviceService,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (s *service) SetTarget(ctx context.Context, userID string, deviceID string, brightness int) (*Target, error) {
+	_, err := s.deviceService.AuthorizeDevice(ctx, userID, deviceID, device.PermissionControl)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &Target{
+		DeviceID:   deviceID,
+		Brightness: brightness,
+		SetBy:      userID,
+	}
+	err = s.targetRepo.UpsertOne(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	s.eventPublisher.PublishTarget(ctx, target)
+
+	return target, nil
+}
+
+func (s *service) GetTarget(ctx context.Context, userID string, deviceID string) (*Target, error) {
+	_, err := s.deviceService.AuthorizeDevice(ctx, userID, deviceID, device.PermissionView)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.targetRepo.GetOneByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrTargetNotSet
+	}
+	return target, nil
+}
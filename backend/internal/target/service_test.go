@@ -0,0 +1,125 @@
+package target_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/target/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_SetTarget(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MocktargetRepository, *mocks.MockdeviceService)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(r *mocks.MocktargetRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionControl).Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				r.EXPECT().UpsertOne(gomock.Any(), &target.Target{DeviceID: "device-1", Brightness: 40, SetBy: "user-1"}).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "not_owner",
+			setupMock: func(r *mocks.MocktargetRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionControl).Return(nil, device.ErrNotDeviceOwner)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+		{
+			name: "db_error",
+			setupMock: func(r *mocks.MocktargetRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionControl).Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				r.EXPECT().UpsertOne(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTargetRepo := mocks.NewMocktargetRepository(ctrl)
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockTargetRepo, mockDeviceService)
+			mockEventPublisher := mocks.NewMockeventPublisher(ctrl)
+			if tt.expectedError == nil {
+				mockEventPublisher.EXPECT().PublishTarget(gomock.Any(), &target.Target{DeviceID: "device-1", Brightness: 40, SetBy: "user-1"})
+			}
+
+			s := target.NewTargetService(mockTargetRepo, mockDeviceService, mockEventPublisher)
+			got, err := s.SetTarget(context.Background(), "user-1", "device-1", 40)
+
+			if tt.expectedError != nil {
+				if err == nil || (!errors.Is(err, tt.expectedError) && err.Error() != tt.expectedError.Error()) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got.Brightness != 40 || got.SetBy != "user-1" {
+				t.Errorf("unexpected target %+v", got)
+			}
+		})
+	}
+}
+
+func TestService_GetTarget(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMock     func(*mocks.MocktargetRepository, *mocks.MockdeviceService)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMock: func(r *mocks.MocktargetRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				r.EXPECT().GetOneByDeviceID(gomock.Any(), "device-1").Return(&target.Target{DeviceID: "device-1", Brightness: 40}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "not_set",
+			setupMock: func(r *mocks.MocktargetRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1", UserID: "user-1"}, nil)
+				r.EXPECT().GetOneByDeviceID(gomock.Any(), "device-1").Return(nil, nil)
+			},
+			expectedError: target.ErrTargetNotSet,
+		},
+		{
+			name: "device_not_found",
+			setupMock: func(r *mocks.MocktargetRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(nil, device.ErrDeviceNotFound)
+			},
+			expectedError: device.ErrDeviceNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTargetRepo := mocks.NewMocktargetRepository(ctrl)
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockTargetRepo, mockDeviceService)
+
+			s := target.NewTargetService(mockTargetRepo, mockDeviceService, mocks.NewMockeventPublisher(ctrl))
+			_, err := s.GetTarget(context.Background(), "user-1", "device-1")
+
+			if !errors.Is(err, tt.expectedError) {
+				t.Errorf("expected error %v, got %v", tt.expectedError, err)
+			}
+		})
+	}
+}
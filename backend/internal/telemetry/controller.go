@@ -0,0 +1,212 @@
+package telemetry
+
+//go:generate mockgen -source=controller.go -destination=mocks/mock_controller.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/gin-gonic/gin"
+)
+
+type telemetryService interface {
+	Ingest(ctx context.Context, deviceID string, dutyCycle float64, samples []*Reading) (*IngestResult, error)
+	GetReadings(ctx context.Context, userID string, deviceID string, from time.Time, to time.Time, step time.Duration) ([]*Bucket, time.Duration, error)
+}
+
+type Controller struct {
+	service telemetryService
+}
+
+func NewTelemetryController(service telemetryService) *Controller {
+	return &Controller{service: service}
+}
+
+type sampleRequest struct {
+	Timestamp time.Time `json:"timestamp" binding:"required"`
+	Lux       *float64  `json:"lux" binding:"required,min=0"`
+	Raw       *int      `json:"raw" binding:"required,min=0,max=65535"`
+}
+
+type uploadTelemetryRequest struct {
+	DutyCycle *float64        `json:"duty_cycle" binding:"required,min=0,max=100"`
+	Samples   []sampleRequest `json:"samples" binding:"required,min=1,max=500,dive"`
+}
+
+type uploadTelemetryResponse struct {
+	Accepted  int      `json:"accepted"`
+	Rejected  int      `json:"rejected"`
+	DutyCycle *float64 `json:"duty_cycle,omitempty"`
+}
+
+type deviceURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type readingsQuery struct {
+	From time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To   time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Step is a Go duration (e.g. "30s", "5m"), empty means chosen by the server
+	Step string `form:"step"`
+}
+
+type bucketResponse struct {
+	Time    time.Time `json:"time"`
+	Count   int       `json:"count"`
+	LuxMin  float64   `json:"lux_min"`
+	LuxAvg  float64   `json:"lux_avg"`
+	LuxMax  float64   `json:"lux_max"`
+	DutyMin float64   `json:"duty_cycle_min"`
+	DutyAvg float64   `json:"duty_cycle_avg"`
+	DutyMax float64   `json:"duty_cycle_max"`
+}
+
+type readingsResponse struct {
+	DeviceID    string           `json:"device_id"`
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	StepSeconds float64          `json:"step_seconds"`
+	Buckets     []bucketResponse `json:"buckets"`
+}
+
+func (tc *Controller) UploadTelemetry(c *gin.Context) {
+	var request uploadTelemetryRequest
+	err := c.ShouldBindJSON(&request)
+	if err != nil {
+		slog.Warn("invalid telemetry request payload", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	samples := make([]*Reading, 0, len(request.Samples))
+	for _, sample := range request.Samples {
+		samples = append(samples, &Reading{
+			Timestamp: sample.Timestamp,
+			Lux:       *sample.Lux,
+			Raw:       *sample.Raw,
+		})
+	}
+
+	ctx := c.Request.Context()
+	deviceID := c.GetString("deviceID")
+	result, err := tc.service.Ingest(ctx, deviceID, *request.DutyCycle, samples)
+	if err != nil {
+		tc.handleTelemetryError(c, err, "failed to ingest telemetry")
+		return
+	}
+
+	if result.Rejected > 0 {
+		slog.Warn("telemetry samples rejected", "deviceID", deviceID, "accepted", result.Accepted, "rejected", result.Rejected)
+	}
+	c.JSON(http.StatusOK, uploadTelemetryResponse{
+		Accepted:  result.Accepted,
+		Rejected:  result.Rejected,
+		DutyCycle: result.DutyCycle,
+	})
+}
+
+func (tc *Controller) GetReadings(c *gin.Context) {
+	var uri deviceURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		slog.Warn("invalid device id", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var query readingsQuery
+	err = c.ShouldBindQuery(&query)
+	if err != nil {
+		slog.Warn("invalid readings query", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// by default the last 24 hours are returned
+	to := query.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := query.From
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	var step time.Duration
+	if query.Step != "" {
+		step, err = time.ParseDuration(query.Step)
+		if err != nil || step < time.Second {
+			slog.Warn("invalid readings step", "step", query.Step)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "step must be a duration of at least 1s"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString("userID")
+	buckets, step, err := tc.service.GetReadings(ctx, userID, uri.ID, from, to, step)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRange) || errors.Is(err, ErrTooManyBuckets) {
+			slog.Warn("invalid readings range", "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tc.handleTelemetryError(c, err, "failed to get readings")
+		return
+	}
+
+	response := readingsResponse{
+		DeviceID:    uri.ID,
+		From:        from,
+		To:          to,
+		StepSeconds: step.Seconds(),
+		Buckets:     make([]bucketResponse, 0, len(buckets)),
+	}
+	for _, bucket := range buckets {
+		response.Buckets = append(response.Buckets, bucketResponse{
+			Time:    bucket.Start,
+			Count:   bucket.Count,
+			LuxMin:  bucket.LuxMin,
+			LuxAvg:  bucket.LuxAvg,
+			LuxMax:  bucket.LuxMax,
+			DutyMin: bucket.DutyMin,
+			DutyAvg: bucket.DutyAvg,
+			DutyMax: bucket.DutyMax,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (tc *Controller) handleTelemetryError(c *gin.Context, err error, message string) {
+	// for a board this means that the device has been deleted while its token was
+	// still valid, for a user that the device does not exist or is not its own
+	if errors.Is(err, device.ErrDeviceNotFound) || errors.Is(err, device.ErrNotDeviceOwner) {
+		if c.GetString("deviceID") != "" {
+			slog.Warn("telemetry from unknown device", "error", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unknown device"})
+			return
+		}
+		slog.Warn("device not found", "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+		return
+	}
+	if errors.Is(err, device.ErrPermissionDenied) {
+		slog.Warn("device action forbidden", "error", err)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("request timeout", "error", err)
+		c.JSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
+		return
+	}
+
+	slog.Error(message, "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+}
@@ -0,0 +1,199 @@
+package telemetry_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry/mocks"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/mock/gomock"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+/**
+ * newTestContext creates a gin.Context for an authenticated board,
+ * as if the request already went through the DeviceAuthMiddleware.
+ */
+func newTestContext(method, path string, body []byte) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Set("deviceID", "device-1")
+	return c, w
+}
+
+func TestController_UploadTelemetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		setupMock    func(*mocks.MocktelemetryService)
+	}{
+		{
+			name:         "success",
+			body:         `{"duty_cycle":55,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":120.5,"raw":2048},{"timestamp":"2026-01-01T00:00:01Z","lux":0,"raw":0}]}`,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MocktelemetryService) {
+				m.EXPECT().
+					Ingest(gomock.Any(), "device-1", 55.0, gomock.Len(2)).
+					Return(&telemetry.IngestResult{Accepted: 2}, nil)
+			},
+		},
+		{
+			name:         "missing_duty_cycle",
+			body:         `{"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":120.5,"raw":2048}]}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktelemetryService) {},
+		},
+		{
+			name:         "empty_batch",
+			body:         `{"duty_cycle":55,"samples":[]}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktelemetryService) {},
+		},
+		{
+			name:         "negative_lux",
+			body:         `{"duty_cycle":55,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":-1,"raw":2048}]}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktelemetryService) {},
+		},
+		{
+			name:         "raw_out_of_range",
+			body:         `{"duty_cycle":55,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":1,"raw":70000}]}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktelemetryService) {},
+		},
+		{
+			name:         "missing_timestamp",
+			body:         `{"duty_cycle":55,"samples":[{"lux":1,"raw":2048}]}`,
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktelemetryService) {},
+		},
+		{
+			name:         "deleted_device",
+			body:         `{"duty_cycle":55,"samples":[{"timestamp":"2026-01-01T00:00:00Z","lux":120.5,"raw":2048}]}`,
+			expectedCode: http.StatusUnauthorized,
+			setupMock: func(m *mocks.MocktelemetryService) {
+				m.EXPECT().Ingest(gomock.Any(), "device-1", 55.0, gomock.Any()).Return(nil, device.ErrDeviceNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTelemetryService := mocks.NewMocktelemetryService(ctrl)
+			tt.setupMock(mockTelemetryService)
+
+			tc := telemetry.NewTelemetryController(mockTelemetryService)
+			c, w := newTestContext(http.MethodPost, "/telemetry", []byte(tt.body))
+
+			tc.UploadTelemetry(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestController_GetReadings(t *testing.T) {
+	const deviceID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+
+	tests := []struct {
+		name         string
+		deviceID     string
+		query        string
+		expectedCode int
+		setupMock    func(*mocks.MocktelemetryService)
+	}{
+		{
+			name:         "success",
+			deviceID:     deviceID,
+			query:        "?from=2026-01-01T00:00:00Z&to=2026-01-02T00:00:00Z&step=5m",
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MocktelemetryService) {
+				from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+				to := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+				m.EXPECT().
+					GetReadings(gomock.Any(), "user-1", deviceID, gomock.Cond(from.Equal), gomock.Cond(to.Equal), 5*time.Minute).
+					Return([]*telemetry.Bucket{{Start: from, Count: 300, LuxAvg: 120}}, 5*time.Minute, nil)
+			},
+		},
+		{
+			name:         "defaults",
+			deviceID:     deviceID,
+			expectedCode: http.StatusOK,
+			setupMock: func(m *mocks.MocktelemetryService) {
+				m.EXPECT().
+					GetReadings(gomock.Any(), "user-1", deviceID, gomock.Any(), gomock.Any(), time.Duration(0)).
+					Return([]*telemetry.Bucket{}, 5*time.Minute, nil)
+			},
+		},
+		{
+			name:         "invalid_from",
+			deviceID:     deviceID,
+			query:        "?from=yesterday",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktelemetryService) {},
+		},
+		{
+			name:         "invalid_step",
+			deviceID:     deviceID,
+			query:        "?step=10ms",
+			expectedCode: http.StatusBadRequest,
+			setupMock:    func(m *mocks.MocktelemetryService) {},
+		},
+		{
+			name:         "too_many_buckets",
+			deviceID:     deviceID,
+			query:        "?step=1s",
+			expectedCode: http.StatusBadRequest,
+			setupMock: func(m *mocks.MocktelemetryService) {
+				m.EXPECT().GetReadings(gomock.Any(), "user-1", deviceID, gomock.Any(), gomock.Any(), time.Second).Return(nil, time.Duration(0), telemetry.ErrTooManyBuckets)
+			},
+		},
+		{
+			name:         "not_owner",
+			deviceID:     deviceID,
+			expectedCode: http.StatusNotFound,
+			setupMock: func(m *mocks.MocktelemetryService) {
+				m.EXPECT().GetReadings(gomock.Any(), "user-1", deviceID, gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, time.Duration(0), device.ErrNotDeviceOwner)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTelemetryService := mocks.NewMocktelemetryService(ctrl)
+			tt.setupMock(mockTelemetryService)
+
+			tc := telemetry.NewTelemetryController(mockTelemetryService)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/devices/"+tt.deviceID+"/readings"+tt.query, nil)
+			c.Params = gin.Params{{Key: "id", Value: tt.deviceID}}
+			c.Set("userID", "user-1")
+
+			tc.GetReadings(c)
+
+			if w.Code != tt.expectedCode {
+				t.Fatalf("got %d want %d; body=%s", w.Code, tt.expectedCode, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+//
+// Generated by this command:
+//
+//	mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	control "github.com/AliceOrlandini/Auto-Light-Pi/internal/control"
+	device "github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	telemetry "github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MocktelemetryRepository is a mock of telemetryRepository interface.
+type MocktelemetryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MocktelemetryRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MocktelemetryRepositoryMockRecorder is the mock recorder for MocktelemetryRepository.
+type MocktelemetryRepositoryMockRecorder struct {
+	mock *MocktelemetryRepository
+}
+
+// NewMocktelemetryRepository creates a new mock instance.
+func NewMocktelemetryRepository(ctrl *gomock.Controller) *MocktelemetryRepository {
+	mock := &MocktelemetryRepository{ctrl: ctrl}
+	mock.recorder = &MocktelemetryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktelemetryRepository) EXPECT() *MocktelemetryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateMany mocks base method.
+func (m *MocktelemetryRepository) CreateMany(ctx context.Context, readings []*telemetry.Reading) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMany", ctx, readings)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMany indicates an expected call of CreateMany.
+func (mr *MocktelemetryRepositoryMockRecorder) CreateMany(ctx, readings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMany", reflect.TypeOf((*MocktelemetryRepository)(nil).CreateMany), ctx, readings)
+}
+
+// GetBucketsByDeviceID mocks base method.
+func (m *MocktelemetryRepository) GetBucketsByDeviceID(ctx context.Context, deviceID string, from, to time.Time, step time.Duration) ([]*telemetry.Bucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBucketsByDeviceID", ctx, deviceID, from, to, step)
+	ret0, _ := ret[0].([]*telemetry.Bucket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBucketsByDeviceID indicates an expected call of GetBucketsByDeviceID.
+func (mr *MocktelemetryRepositoryMockRecorder) GetBucketsByDeviceID(ctx, deviceID, from, to, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBucketsByDeviceID", reflect.TypeOf((*MocktelemetryRepository)(nil).GetBucketsByDeviceID), ctx, deviceID, from, to, step)
+}
+
+// GetLatestTimestamp mocks base method.
+func (m *MocktelemetryRepository) GetLatestTimestamp(ctx context.Context, deviceID string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatestTimestamp", ctx, deviceID)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatestTimestamp indicates an expected call of GetLatestTimestamp.
+func (mr *MocktelemetryRepositoryMockRecorder) GetLatestTimestamp(ctx, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestTimestamp", reflect.TypeOf((*MocktelemetryRepository)(nil).GetLatestTimestamp), ctx, deviceID)
+}
+
+// MockdeviceService is a mock of deviceService interface.
+type MockdeviceService struct {
+	ctrl     *gomock.Controller
+	recorder *MockdeviceServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockdeviceServiceMockRecorder is the mock recorder for MockdeviceService.
+type MockdeviceServiceMockRecorder struct {
+	mock *MockdeviceService
+}
+
+// NewMockdeviceService creates a new mock instance.
+func NewMockdeviceService(ctrl *gomock.Controller) *MockdeviceService {
+	mock := &MockdeviceService{ctrl: ctrl}
+	mock.recorder = &MockdeviceServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockdeviceService) EXPECT() *MockdeviceServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthorizeDevice mocks base method.
+func (m *MockdeviceService) AuthorizeDevice(ctx context.Context, userID, deviceID string, permission device.Permission) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeDevice", ctx, userID, deviceID, permission)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthorizeDevice indicates an expected call of AuthorizeDevice.
+func (mr *MockdeviceServiceMockRecorder) AuthorizeDevice(ctx, userID, deviceID, permission any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeDevice", reflect.TypeOf((*MockdeviceService)(nil).AuthorizeDevice), ctx, userID, deviceID, permission)
+}
+
+// GetDeviceByID mocks base method.
+func (m *MockdeviceService) GetDeviceByID(ctx context.Context, deviceID string) (*device.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceByID", ctx, deviceID)
+	ret0, _ := ret[0].(*device.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceByID indicates an expected call of GetDeviceByID.
+func (mr *MockdeviceServiceMockRecorder) GetDeviceByID(ctx, deviceID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceByID", reflect.TypeOf((*MockdeviceService)(nil).GetDeviceByID), ctx, deviceID)
+}
+
+// MockcontrolService is a mock of controlService interface.
+type MockcontrolService struct {
+	ctrl     *gomock.Controller
+	recorder *MockcontrolServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockcontrolServiceMockRecorder is the mock recorder for MockcontrolService.
+type MockcontrolServiceMockRecorder struct {
+	mock *MockcontrolService
+}
+
+// NewMockcontrolService creates a new mock instance.
+func NewMockcontrolService(ctrl *gomock.Controller) *MockcontrolService {
+	mock := &MockcontrolService{ctrl: ctrl}
+	mock.recorder = &MockcontrolServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockcontrolService) EXPECT() *MockcontrolServiceMockRecorder {
+	return m.recorder
+}
+
+// Update mocks base method.
+func (m *MockcontrolService) Update(ctx context.Context, deviceID string, dutyCycle float64, samples []control.Sample) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, deviceID, dutyCycle, samples)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockcontrolServiceMockRecorder) Update(ctx, deviceID, dutyCycle, samples any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockcontrolService)(nil).Update), ctx, deviceID, dutyCycle, samples)
+}
+
+// MockeventPublisher is a mock of eventPublisher interface.
+type MockeventPublisher struct {
+	ctrl     *gomock.Controller
+	recorder *MockeventPublisherMockRecorder
+	isgomock struct{}
+}
+
+// MockeventPublisherMockRecorder is the mock recorder for MockeventPublisher.
+type MockeventPublisherMockRecorder struct {
+	mock *MockeventPublisher
+}
+
+// NewMockeventPublisher creates a new mock instance.
+func NewMockeventPublisher(ctrl *gomock.Controller) *MockeventPublisher {
+	mock := &MockeventPublisher{ctrl: ctrl}
+	mock.recorder = &MockeventPublisherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockeventPublisher) EXPECT() *MockeventPublisherMockRecorder {
+	return m.recorder
+}
+
+// PublishOutput mocks base method.
+func (m *MockeventPublisher) PublishOutput(ctx context.Context, deviceID string, dutyCycle float64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PublishOutput", ctx, deviceID, dutyCycle)
+}
+
+// PublishOutput indicates an expected call of PublishOutput.
+func (mr *MockeventPublisherMockRecorder) PublishOutput(ctx, deviceID, dutyCycle any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishOutput", reflect.TypeOf((*MockeventPublisher)(nil).PublishOutput), ctx, deviceID, dutyCycle)
+}
+
+// PublishReadings mocks base method.
+func (m *MockeventPublisher) PublishReadings(ctx context.Context, deviceID string, readings []*telemetry.Reading) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PublishReadings", ctx, deviceID, readings)
+}
+
+// PublishReadings indicates an expected call of PublishReadings.
+func (mr *MockeventPublisherMockRecorder) PublishReadings(ctx, deviceID, readings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishReadings", reflect.TypeOf((*MockeventPublisher)(nil).PublishReadings), ctx, deviceID, readings)
+}
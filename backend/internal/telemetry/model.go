@@ -0,0 +1,32 @@
+package telemetry
+
+import (
+	"time"
+)
+
+type Reading struct {
+	DeviceID  string
+	Timestamp time.Time
+	Lux       float64
+	Raw       int
+	DutyCycle float64
+}
+
+type IngestResult struct {
+	Accepted int
+	Rejected int
+	// DutyCycle is the next output of the controller, nil when the device has no target
+	DutyCycle *float64
+}
+
+// Bucket aggregates all the readings of a device in the window [Start, Start+step)
+type Bucket struct {
+	Start   time.Time
+	Count   int
+	LuxMin  float64
+	LuxAvg  float64
+	LuxMax  float64
+	DutyMin float64
+	DutyAvg float64
+	DutyMax float64
+}
@@ -0,0 +1,151 @@
+package telemetry
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReadingEntity struct {
+	DeviceID  uuid.UUID
+	Timestamp time.Time
+	Lux       float64
+	Raw       int32
+	DutyCycle float64
+}
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewTelemetryRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateMany(ctx context.Context, readings []*Reading) (int, error) {
+	if len(readings) == 0 {
+		return 0, nil
+	}
+
+	// all the readings of a batch are written with a single multi-row insert,
+	// a reading already stored for the same timestamp is skipped
+	const columns = 5
+	placeholders := make([]string, 0, len(readings))
+	args := make([]any, 0, len(readings)*columns)
+	for i, reading := range readings {
+		readingEntity, err := toEntity(reading)
+		if err != nil {
+			return 0, err
+		}
+		n := i * columns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, readingEntity.DeviceID, readingEntity.Timestamp, readingEntity.Lux, readingEntity.Raw, readingEntity.DutyCycle)
+	}
+
+	query := `
+		INSERT INTO reading(device_id, ts, lux, raw, duty_cycle)
+		VALUES ` + strings.Join(placeholders, ", ") + `
+		ON CONFLICT (device_id, ts) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(inserted), nil
+}
+
+func (r *repository) GetLatestTimestamp(ctx context.Context, deviceID string) (time.Time, error) {
+	query := `
+		SELECT max(ts)
+		FROM reading
+		WHERE device_id = $1
+	`
+	row := r.db.QueryRowContext(ctx, query, deviceID)
+
+	// max returns NULL when the device has no readings yet
+	var latest sql.NullTime
+	err := row.Scan(&latest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return latest.Time, nil
+}
+
+// CountOnlineDevices counts the devices with a reading after since, the readings
+// are looked up device by device so that the primary key is used
+func (r *repository) CountOnlineDevices(ctx context.Context, since time.Time) (int, error) {
+	query := `
+		SELECT count(*)
+		FROM device d
+		WHERE EXISTS (
+			SELECT 1 FROM reading r WHERE r.device_id = d.id AND r.ts > $1
+		)
+	`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, since).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *repository) GetBucketsByDeviceID(ctx context.Context, deviceID string, from time.Time, to time.Time, step time.Duration) ([]*Bucket, error) {
+	// date_bin aligns the buckets to "from", so the first bucket starts
+	// exactly where the requested range starts
+	query := `
+		SELECT date_bin($2::interval, ts, $3) AS bucket,
+			count(*), min(lux), avg(lux), max(lux),
+			min(duty_cycle), avg(duty_cycle), max(duty_cycle)
+		FROM reading
+		WHERE device_id = $1 AND ts >= $3 AND ts < $4
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+	interval := fmt.Sprintf("%d milliseconds", step.Milliseconds())
+	rows, err := r.db.QueryContext(ctx, query, deviceID, interval, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []*Bucket{}
+	for rows.Next() {
+		var bucket Bucket
+		err := rows.Scan(&bucket.Start, &bucket.Count,
+			&bucket.LuxMin, &bucket.LuxAvg, &bucket.LuxMax,
+			&bucket.DutyMin, &bucket.DutyAvg, &bucket.DutyMax,
+		)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, &bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+func toEntity(reading *Reading) (*ReadingEntity, error) {
+	deviceID, err := uuid.Parse(reading.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadingEntity{
+		DeviceID:  deviceID,
+		Timestamp: reading.Timestamp.UTC(),
+		Lux:       reading.Lux,
+		Raw:       int32(reading.Raw),
+		DutyCycle: reading.DutyCycle,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+// createTestDevice inserts a user and one of its devices, since the readings reference the device
+func createTestDevice(ctx context.Context, t *testing.T) string {
+	t.Helper()
+	userID := uuid.New()
+	deviceID := uuid.New()
+	_, err := testPostgresDB.ExecContext(ctx,
+		"INSERT INTO user_account(id, username, email, password, name, surname) VALUES($1, $2, $3, $4, $5, $6)",
+		userID, userID.String()[:8], userID.String()+"@example.com", "hash", "test", "user",
+	)
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	_, err = testPostgresDB.ExecContext(ctx, "INSERT INTO device(id, user_id, name) VALUES($1, $2, $3)", deviceID, userID, "lamp")
+	if err != nil {
+		t.Fatalf("failed to create test device: %v", err)
+	}
+	return deviceID.String()
+}
+
+func TestRepository_CreateMany(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewTelemetryRepository(testPostgresDB)
+	deviceID := createTestDevice(ctx, t)
+
+	latest, err := repo.GetLatestTimestamp(ctx, deviceID)
+	if err != nil || !latest.IsZero() {
+		t.Fatalf("expected zero timestamp without readings, got %v, %v", latest, err)
+	}
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	readings := []*Reading{
+		{DeviceID: deviceID, Timestamp: base, Lux: 100, Raw: 2000, DutyCycle: 40},
+		{DeviceID: deviceID, Timestamp: base.Add(time.Second), Lux: 110, Raw: 2100, DutyCycle: 40},
+	}
+	inserted, err := repo.CreateMany(ctx, readings)
+	if err != nil {
+		t.Fatalf("CreateMany() error = %v", err)
+	}
+	if inserted != 2 {
+		t.Errorf("expected 2 inserted readings, got %d", inserted)
+	}
+
+	// the same timestamps are skipped instead of failing the whole batch
+	inserted, err = repo.CreateMany(ctx, append(readings, &Reading{DeviceID: deviceID, Timestamp: base.Add(2 * time.Second)}))
+	if err != nil {
+		t.Fatalf("CreateMany() error = %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("expected 1 inserted reading, got %d", inserted)
+	}
+
+	latest, err = repo.GetLatestTimestamp(ctx, deviceID)
+	if err != nil {
+		t.Fatalf("GetLatestTimestamp() error = %v", err)
+	}
+	if !latest.Equal(base.Add(2 * time.Second)) {
+		t.Errorf("expected latest %v, got %v", base.Add(2*time.Second), latest)
+	}
+}
+
+func TestRepository_CountOnlineDevices(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewTelemetryRepository(testPostgresDB)
+	since := time.Now().Add(-5 * time.Minute)
+
+	// the other tests share the database, so only the difference is checked
+	before, err := repo.CountOnlineDevices(ctx, since)
+	if err != nil {
+		t.Fatalf("CountOnlineDevices() error = %v", err)
+	}
+
+	online := createTestDevice(ctx, t)
+	offline := createTestDevice(ctx, t)
+	createTestDevice(ctx, t)
+	readings := []*Reading{
+		{DeviceID: online, Timestamp: time.Now().Add(-2 * time.Minute), Lux: 100},
+		{DeviceID: online, Timestamp: time.Now().Add(-time.Minute), Lux: 100},
+		{DeviceID: offline, Timestamp: time.Now().Add(-time.Hour), Lux: 100},
+	}
+	if _, err := repo.CreateMany(ctx, readings); err != nil {
+		t.Fatalf("CreateMany() error = %v", err)
+	}
+
+	after, err := repo.CountOnlineDevices(ctx, since)
+	if err != nil {
+		t.Fatalf("CountOnlineDevices() error = %v", err)
+	}
+	if after-before != 1 {
+		t.Errorf("expected 1 more online device, got %d", after-before)
+	}
+}
+
+func TestRepository_GetBucketsByDeviceID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewTelemetryRepository(testPostgresDB)
+	deviceID := createTestDevice(ctx, t)
+
+	// two minutes of readings at 1 Hz, the lux value is the second of the minute
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	readings := make([]*Reading, 0, 120)
+	for i := 0; i < 120; i++ {
+		readings = append(readings, &Reading{DeviceID: deviceID, Timestamp: base.Add(time.Duration(i) * time.Second), Lux: float64(i % 60), DutyCycle: 50})
+	}
+	if _, err := repo.CreateMany(ctx, readings); err != nil {
+		t.Fatalf("CreateMany() error = %v", err)
+	}
+
+	buckets, err := repo.GetBucketsByDeviceID(ctx, deviceID, base, base.Add(2*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatalf("GetBucketsByDeviceID() error = %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(buckets))
+	}
+	for i, bucket := range buckets {
+		if !bucket.Start.Equal(base.Add(time.Duration(i) * time.Minute)) {
+			t.Errorf("bucket %d: unexpected start %v", i, bucket.Start)
+		}
+		if bucket.Count != 60 || bucket.LuxMin != 0 || bucket.LuxMax != 59 || bucket.LuxAvg != 29.5 || bucket.DutyAvg != 50 {
+			t.Errorf("bucket %d: unexpected aggregates %+v", i, bucket)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package telemetry
+
+//go:generate mockgen -source=service.go -destination=mocks/mock_service.go -package=mocks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/control"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/metrics"
+)
+
+// maxClockSkew is how far in the future a sample can be, the clock of
+// the board is synchronized with NTP but it can still drift a little
+const maxClockSkew = time.Minute
+
+const (
+	// targetBuckets is how many points a chart should have when the client does not choose the step
+	targetBuckets = 300
+	// maxBuckets protects the database from a huge range with a tiny step
+	maxBuckets = 2000
+)
+
+// bucketSteps are the steps chosen automatically, from the smallest to the largest
+var bucketSteps = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+var (
+	ErrInvalidRange   = errors.New("the start of the range must be before its end")
+	ErrTooManyBuckets = errors.New("the step is too small for the requested range")
+)
+
+type telemetryRepository interface {
+	CreateMany(ctx context.Context, readings []*Reading) (int, error)
+	GetLatestTimestamp(ctx context.Context, deviceID string) (time.Time, error)
+	GetBucketsByDeviceID(ctx context.Context, deviceID string, from time.Time, to time.Time, step time.Duration) ([]*Bucket, error)
+}
+
+type deviceService interface {
+	AuthorizeDevice(ctx context.Context, userID string, deviceID string, permission device.Permission) (*device.Device, error)
+	GetDeviceByID(ctx context.Context, deviceID string) (*device.Device, error)
+}
+
+// controlService computes the next duty cycle of the lamp from the new readings
+type controlService interface {
+	Update(ctx context.Context, deviceID string, dutyCycle float64, samples []control.Sample) (float64, error)
+}
+
+// eventPublisher pushes the new data to the apps watching the device
+type eventPublisher interface {
+	PublishReadings(ctx context.Context, deviceID string, readings []*Reading)
+	PublishOutput(ctx context.Context, deviceID string, dutyCycle float64)
+}
+
+type service struct {
+	telemetryRepo  telemetryRepository
+	deviceService  deviceService
+	controlService controlService
+	eventPublisher eventPublisher
+}
+
+func NewTelemetryService(telemetryRepo telemetryRepository, deviceService deviceService, controlService controlService, eventPublisher eventPublisher) *service {
+	return &service{
+		telemetryRepo:  telemetryRepo,
+		deviceService:  deviceService,
+		controlService: controlService,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (s *service) Ingest(ctx context.Context, deviceID string, dutyCycle float64, samples []*Reading) (*IngestResult, error) {
+	// the token of a deleted device is still valid until it expires
+	_, err := s.deviceService.GetDeviceByID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.telemetryRepo.GetLatestTimestamp(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the timestamps must be strictly increasing, also with respect to what
+	// is already stored, a sample out of order is rejected and not reordered
+	result := &IngestResult{}
+	horizon := time.Now().Add(maxClockSkew)
+	accepted := make([]*Reading, 0, len(samples))
+	for _, sample := range samples {
+		if !sample.Timestamp.After(latest) || sample.Timestamp.After(horizon) {
+			result.Rejected++
+			continue
+		}
+		latest = sample.Timestamp
+		accepted = append(accepted, &Reading{
+			DeviceID:  deviceID,
+			Timestamp: sample.Timestamp,
+			Lux:       sample.Lux,
+			Raw:       sample.Raw,
+			DutyCycle: dutyCycle,
+		})
+	}
+
+	inserted, err := s.telemetryRepo.CreateMany(ctx, accepted)
+	if err != nil {
+		return nil, err
+	}
+	// a concurrent batch could have stored the same timestamps in the meantime
+	result.Accepted = inserted
+	result.Rejected += len(accepted) - inserted
+	metrics.TelemetryReadings.WithLabelValues("accepted").Add(float64(result.Accepted))
+	metrics.TelemetryReadings.WithLabelValues("rejected").Add(float64(result.Rejected))
+
+	if len(accepted) == 0 {
+		return result, nil
+	}
+	if inserted > 0 {
+		s.eventPublisher.PublishReadings(ctx, deviceID, accepted)
+	}
+
+	controlSamples := make([]control.Sample, 0, len(accepted))
+	for _, reading := range accepted {
+		controlSamples = append(controlSamples, control.Sample{Timestamp: reading.Timestamp, Lux: reading.Lux})
+	}
+	next, err := s.controlService.Update(ctx, deviceID, dutyCycle, controlSamples)
+	if errors.Is(err, control.ErrNoTarget) {
+		// without a target the lamp is not driven by the server
+		return result, nil
+	}
+	if err != nil {
+		// the readings are already stored, failing now would make the board
+		// retry a batch that is rejected as duplicated, so the lamp keeps
+		// its duty cycle until the next upload instead
+		slog.Error("failed to update the controller", "deviceID", deviceID, "error", err)
+		return result, nil
+	}
+	result.DutyCycle = &next
+	s.eventPublisher.PublishOutput(ctx, deviceID, next)
+
+	return result, nil
+}
+
+// GetReadings returns the history of a device downsampled in buckets of the given step,
+// when step is zero the smallest step that keeps the chart around targetBuckets points is used
+func (s *service) GetReadings(ctx context.Context, userID string, deviceID string, from time.Time, to time.Time, step time.Duration) ([]*Bucket, time.Duration, error) {
+	if !from.Before(to) {
+		return nil, 0, ErrInvalidRange
+	}
+
+	span := to.Sub(from)
+	if step == 0 {
+		step = bucketSteps[len(bucketSteps)-1]
+		for _, candidate := range bucketSteps {
+			if span/candidate <= targetBuckets {
+				step = candidate
+				break
+			}
+		}
+	}
+	if span/step > maxBuckets {
+		return nil, 0, ErrTooManyBuckets
+	}
+
+	_, err := s.deviceService.AuthorizeDevice(ctx, userID, deviceID, device.PermissionView)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buckets, err := s.telemetryRepo.GetBucketsByDeviceID(ctx, deviceID, from, to, step)
+	if err != nil {
+		return nil, 0, err
+	}
+	return buckets, step, nil
+}
@@ -0,0 +1,262 @@
+package telemetry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/control"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/device"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/telemetry/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestService_Ingest(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	sample := func(offset time.Duration) *telemetry.Reading {
+		return &telemetry.Reading{Timestamp: base.Add(offset), Lux: 100, Raw: 2048}
+	}
+
+	tests := []struct {
+		name             string
+		samples          []*telemetry.Reading
+		latest           time.Time
+		inserted         int
+		expectedInserts  int
+		expectedAccepted int
+		expectedRejected int
+	}{
+		{
+			name:             "all_monotonic",
+			samples:          []*telemetry.Reading{sample(0), sample(time.Second), sample(2 * time.Second)},
+			inserted:         3,
+			expectedInserts:  3,
+			expectedAccepted: 3,
+			expectedRejected: 0,
+		},
+		{
+			name:             "out_of_order_and_duplicate",
+			samples:          []*telemetry.Reading{sample(0), sample(2 * time.Second), sample(time.Second), sample(2 * time.Second), sample(3 * time.Second)},
+			inserted:         3,
+			expectedInserts:  3,
+			expectedAccepted: 3,
+			expectedRejected: 2,
+		},
+		{
+			name:             "older_than_stored",
+			samples:          []*telemetry.Reading{sample(0), sample(time.Second), sample(2 * time.Second)},
+			latest:           base.Add(time.Second),
+			inserted:         1,
+			expectedInserts:  1,
+			expectedAccepted: 1,
+			expectedRejected: 2,
+		},
+		{
+			name:             "in_the_future",
+			samples:          []*telemetry.Reading{sample(0), sample(2 * time.Hour)},
+			inserted:         1,
+			expectedInserts:  1,
+			expectedAccepted: 1,
+			expectedRejected: 1,
+		},
+		{
+			name:             "concurrent_duplicates",
+			samples:          []*telemetry.Reading{sample(0), sample(time.Second)},
+			inserted:         1,
+			expectedInserts:  2,
+			expectedAccepted: 1,
+			expectedRejected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTelemetryRepo := mocks.NewMocktelemetryRepository(ctrl)
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			mockDeviceService.EXPECT().GetDeviceByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1"}, nil)
+			mockTelemetryRepo.EXPECT().GetLatestTimestamp(gomock.Any(), "device-1").Return(tt.latest, nil)
+			mockTelemetryRepo.EXPECT().CreateMany(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, readings []*telemetry.Reading) (int, error) {
+					if len(readings) != tt.expectedInserts {
+						t.Errorf("expected %d readings to insert, got %d", tt.expectedInserts, len(readings))
+					}
+					for _, reading := range readings {
+						if reading.DeviceID != "device-1" || reading.DutyCycle != 55 {
+							t.Errorf("unexpected reading %+v", reading)
+						}
+					}
+					return tt.inserted, nil
+				},
+			)
+
+			mockControlService := mocks.NewMockcontrolService(ctrl)
+			mockControlService.EXPECT().Update(gomock.Any(), "device-1", 55.0, gomock.Len(tt.expectedInserts)).Return(60.0, nil)
+			mockEventPublisher := mocks.NewMockeventPublisher(ctrl)
+			mockEventPublisher.EXPECT().PublishReadings(gomock.Any(), "device-1", gomock.Len(tt.expectedInserts))
+			mockEventPublisher.EXPECT().PublishOutput(gomock.Any(), "device-1", 60.0)
+
+			s := telemetry.NewTelemetryService(mockTelemetryRepo, mockDeviceService, mockControlService, mockEventPublisher)
+			result, err := s.Ingest(context.Background(), "device-1", 55, tt.samples)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result.Accepted != tt.expectedAccepted || result.Rejected != tt.expectedRejected {
+				t.Errorf("got accepted=%d rejected=%d, want accepted=%d rejected=%d",
+					result.Accepted, result.Rejected, tt.expectedAccepted, tt.expectedRejected)
+			}
+			if result.DutyCycle == nil || *result.DutyCycle != 60 {
+				t.Errorf("expected next duty cycle 60, got %v", result.DutyCycle)
+			}
+		})
+	}
+}
+
+func TestService_Ingest_UnknownDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTelemetryRepo := mocks.NewMocktelemetryRepository(ctrl)
+	mockDeviceService := mocks.NewMockdeviceService(ctrl)
+	mockDeviceService.EXPECT().GetDeviceByID(gomock.Any(), "device-1").Return(nil, device.ErrDeviceNotFound)
+
+	s := telemetry.NewTelemetryService(mockTelemetryRepo, mockDeviceService, mocks.NewMockcontrolService(ctrl), mocks.NewMockeventPublisher(ctrl))
+	_, err := s.Ingest(context.Background(), "device-1", 55, []*telemetry.Reading{{Timestamp: time.Now()}})
+	if !errors.Is(err, device.ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound, got %v", err)
+	}
+}
+
+func TestService_Ingest_NoDutyCycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		controlErr error
+	}{
+		{name: "no_target", controlErr: control.ErrNoTarget},
+		// the readings are stored anyway, so the upload must not fail
+		{name: "controller_error", controlErr: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTelemetryRepo := mocks.NewMocktelemetryRepository(ctrl)
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			mockControlService := mocks.NewMockcontrolService(ctrl)
+			mockDeviceService.EXPECT().GetDeviceByID(gomock.Any(), "device-1").Return(&device.Device{ID: "device-1"}, nil)
+			mockTelemetryRepo.EXPECT().GetLatestTimestamp(gomock.Any(), "device-1").Return(time.Time{}, nil)
+			mockTelemetryRepo.EXPECT().CreateMany(gomock.Any(), gomock.Len(1)).Return(1, nil)
+			mockControlService.EXPECT().Update(gomock.Any(), "device-1", 55.0, gomock.Len(1)).Return(0.0, tt.controlErr)
+			mockEventPublisher := mocks.NewMockeventPublisher(ctrl)
+			mockEventPublisher.EXPECT().PublishReadings(gomock.Any(), "device-1", gomock.Len(1))
+
+			s := telemetry.NewTelemetryService(mockTelemetryRepo, mockDeviceService, mockControlService, mockEventPublisher)
+			result, err := s.Ingest(context.Background(), "device-1", 55, []*telemetry.Reading{{Timestamp: time.Now().Add(-time.Second)}})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if result.Accepted != 1 {
+				t.Errorf("expected the reading to be accepted, got %+v", result)
+			}
+			if result.DutyCycle != nil {
+				t.Errorf("expected no duty cycle, got %v", *result.DutyCycle)
+			}
+		})
+	}
+}
+
+func TestService_GetReadings(t *testing.T) {
+	to := time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		from          time.Time
+		step          time.Duration
+		setupMock     func(*mocks.MocktelemetryRepository, *mocks.MockdeviceService)
+		expectedStep  time.Duration
+		expectedError error
+	}{
+		{
+			name: "auto_step_for_a_week",
+			from: to.Add(-7 * 24 * time.Hour),
+			setupMock: func(r *mocks.MocktelemetryRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1"}, nil)
+				r.EXPECT().GetBucketsByDeviceID(gomock.Any(), "device-1", gomock.Any(), to, time.Hour).Return([]*telemetry.Bucket{}, nil)
+			},
+			expectedStep: time.Hour,
+		},
+		{
+			name: "auto_step_for_an_hour",
+			from: to.Add(-time.Hour),
+			setupMock: func(r *mocks.MocktelemetryRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1"}, nil)
+				r.EXPECT().GetBucketsByDeviceID(gomock.Any(), "device-1", gomock.Any(), to, 30*time.Second).Return([]*telemetry.Bucket{}, nil)
+			},
+			expectedStep: 30 * time.Second,
+		},
+		{
+			name: "explicit_step",
+			from: to.Add(-time.Hour),
+			step: time.Minute,
+			setupMock: func(r *mocks.MocktelemetryRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(&device.Device{ID: "device-1"}, nil)
+				r.EXPECT().GetBucketsByDeviceID(gomock.Any(), "device-1", gomock.Any(), to, time.Minute).Return([]*telemetry.Bucket{}, nil)
+			},
+			expectedStep: time.Minute,
+		},
+		{
+			name:          "too_many_buckets",
+			from:          to.Add(-7 * 24 * time.Hour),
+			step:          time.Second,
+			setupMock:     func(r *mocks.MocktelemetryRepository, d *mocks.MockdeviceService) {},
+			expectedError: telemetry.ErrTooManyBuckets,
+		},
+		{
+			name:          "inverted_range",
+			from:          to.Add(time.Hour),
+			setupMock:     func(r *mocks.MocktelemetryRepository, d *mocks.MockdeviceService) {},
+			expectedError: telemetry.ErrInvalidRange,
+		},
+		{
+			name: "not_owner",
+			from: to.Add(-time.Hour),
+			setupMock: func(r *mocks.MocktelemetryRepository, d *mocks.MockdeviceService) {
+				d.EXPECT().AuthorizeDevice(gomock.Any(), "user-1", "device-1", device.PermissionView).Return(nil, device.ErrNotDeviceOwner)
+			},
+			expectedError: device.ErrNotDeviceOwner,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTelemetryRepo := mocks.NewMocktelemetryRepository(ctrl)
+			mockDeviceService := mocks.NewMockdeviceService(ctrl)
+			tt.setupMock(mockTelemetryRepo, mockDeviceService)
+
+			s := telemetry.NewTelemetryService(mockTelemetryRepo, mockDeviceService, mocks.NewMockcontrolService(ctrl), mocks.NewMockeventPublisher(ctrl))
+			_, step, err := s.GetReadings(context.Background(), "user-1", "device-1", tt.from, to, tt.step)
+
+			if tt.expectedError != nil {
+				if !errors.Is(err, tt.expectedError) {
+					t.Errorf("expected error %v, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if step != tt.expectedStep {
+				t.Errorf("expected step %v, got %v", tt.expectedStep, step)
+			}
+		})
+	}
+}
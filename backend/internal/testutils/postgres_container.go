@@ -2,9 +2,11 @@ package testutils
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"sync"
 
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/migrations"
 	_ "github.com/lib/pq"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 )
@@ -24,7 +26,6 @@ func SetupPostgres() string {
 		postgres.WithDatabase("testdb"),
 		postgres.WithUsername("user"),
 		postgres.WithPassword("password"),
-		postgres.WithInitScripts("../testdata/schema.sql"),
 		postgres.BasicWaitStrategies(),
 	)
 	if err != nil {
@@ -36,8 +37,28 @@ func SetupPostgres() string {
 			panic(err)
 		}
 
+		// the tests use the same migrations of the server
+		if err := migrate(ctx, url); err != nil {
+			panic(fmt.Errorf("failed to migrate postgres container: %w", err))
+		}
+
 		pgContainer = container
 		pgDBURL = url
 	})
 	return pgDBURL
-}
\ No newline at end of file
+}
+
+func migrate(ctx context.Context, url string) error {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(ctx)
+	return err
+}
@@ -0,0 +1,36 @@
+package token_denylist
+
+//go:generate mockgen -source=repository.go -destination=mocks/mock_repository.go -package=mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type repository struct {
+	db *redis.Client
+}
+
+func NewTokenDenylistRepository(db *redis.Client) *repository {
+	return &repository{db: db}
+}
+
+// CreateOne records jti:{jti} until the token expires, after that the
+// signature check rejects the token anyway and the key is no longer needed
+func (r *repository) CreateOne(ctx context.Context, jti string, expiresAt time.Time) error {
+	if !expiresAt.After(time.Now()) {
+		return nil
+	}
+
+	return r.db.SetArgs(ctx, "jti:"+jti, 1, redis.SetArgs{ExpireAt: expiresAt}).Err()
+}
+
+func (r *repository) Exists(ctx context.Context, jti string) (bool, error) {
+	count, err := r.db.Exists(ctx, "jti:"+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,80 @@
+package token_denylist
+
+import (
+	"context"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/redis/go-redis/v9"
+)
+
+var testRedisDB *redis.Client
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		redisConnectionStr := testutils.SetupRedis()
+		opt, _ := redis.ParseURL(redisConnectionStr)
+		testRedisDB = redis.NewClient(opt)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRepository_CreateOneAndExists(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewTokenDenylistRepository(testRedisDB)
+
+	tests := []struct {
+		name      string
+		jti       string
+		expiresAt time.Time
+		wantFound bool
+	}{
+		{
+			name:      "denied_until_expiration",
+			jti:       "jti-valid",
+			expiresAt: time.Now().Add(time.Hour),
+			wantFound: true,
+		},
+		{
+			name:      "already_expired_is_skipped",
+			jti:       "jti-expired",
+			expiresAt: time.Now().Add(-time.Minute),
+			wantFound: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.CreateOne(ctx, tt.jti, tt.expiresAt); err != nil {
+				t.Fatalf("CreateOne() error = %v", err)
+			}
+
+			found, err := repo.Exists(ctx, tt.jti)
+			if err != nil {
+				t.Fatalf("Exists() error = %v", err)
+			}
+			if found != tt.wantFound {
+				t.Errorf("expected found=%v, got %v", tt.wantFound, found)
+			}
+			if tt.wantFound {
+				ttl := testRedisDB.PTTL(ctx, "jti:"+tt.jti).Val()
+				if ttl <= 0 || ttl > time.Hour {
+					t.Errorf("expected the key to expire with the token, got ttl %v", ttl)
+				}
+			}
+		})
+	}
+
+	found, err := repo.Exists(ctx, "jti-unknown")
+	if err != nil || found {
+		t.Errorf("expected unknown jti not found, got %v %v", found, err)
+	}
+}
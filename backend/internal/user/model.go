@@ -1,5 +1,33 @@
 package user
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// the errors of the checks of the password of a logged in user, they are here
+// since not only the auth package asks for the password before a sensitive change
+var (
+	ErrInvalidPassword  = errors.New("password not valid")
+	ErrPasswordRequired = errors.New("current password required")
+	ErrAccountLocked    = errors.New("account temporarily locked")
+)
+
+// AccountLockedError is returned instead of ErrAccountLocked
+// to tell the client how long to wait
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("%s for %s", ErrAccountLocked, e.RetryAfter)
+}
+
+func (e *AccountLockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
 type User struct {
 	ID       string
 	Username string
@@ -7,4 +35,21 @@ type User struct {
 	Password string
 	Name     string
 	Surname  string
+
+	// EmailVerifiedAt is nil until the user confirms its email
+	EmailVerifiedAt *time.Time
+}
+
+// IsEmailVerified reports whether the user has confirmed its email
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// ProfileUpdate holds the fields of the profile to change,
+// the nil ones are left as they are
+type ProfileUpdate struct {
+	Username *string
+	Email    *string
+	Name     *string
+	Surname  *string
 }
@@ -5,12 +5,23 @@ package user
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"errors"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username already taken")
+	ErrEmailTaken    = errors.New("email already taken")
+)
+
+// uniqueViolation is the postgres error code of a duplicate key
+const uniqueViolation = "23505"
+
 type UserEntity struct {
 	ID       uuid.UUID
 	Username string
@@ -18,6 +29,8 @@ type UserEntity struct {
 	Password []byte
 	Name     string
 	Surname  string
+
+	EmailVerifiedAt sql.NullTime
 }
 
 type repository struct {
@@ -43,14 +56,14 @@ func (r *repository) CreateOne(ctx context.Context, user *User) error {
 
 func (r *repository) GetOneByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, username, email, password, name, surname
+		SELECT id, username, email, password, name, surname, email_verified_at
 		FROM user_account 
 		WHERE email = $1;
 	`
 	row := r.db.QueryRowContext(ctx, query, email)
 
 	var user UserEntity
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Name, &user.Surname)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Name, &user.Surname, &user.EmailVerifiedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -62,14 +75,14 @@ func (r *repository) GetOneByEmail(ctx context.Context, email string) (*User, er
 
 func (r *repository) GetOneByUsername(ctx context.Context, username string) (*User, error) {
 	query := `
-		SELECT id, username, email, password, name, surname
+		SELECT id, username, email, password, name, surname, email_verified_at
 		FROM user_account 
 		WHERE username = $1
 	`
 	row := r.db.QueryRowContext(ctx, query, username)
 
 	var user UserEntity
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Name, &user.Surname)
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Name, &user.Surname, &user.EmailVerifiedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -79,8 +92,153 @@ func (r *repository) GetOneByUsername(ctx context.Context, username string) (*Us
 	return user.toUser(), nil
 }
 
+func (r *repository) GetOneByID(ctx context.Context, id string) (*User, error) {
+	query := `
+		SELECT id, username, email, password, name, surname, email_verified_at
+		FROM user_account
+		WHERE id = $1
+	`
+	row := r.db.QueryRowContext(ctx, query, id)
+
+	var user UserEntity
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Name, &user.Surname, &user.EmailVerifiedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user.toUser(), nil
+}
+
+func (r *repository) UpdatePassword(ctx context.Context, id string, password string) error {
+	query := `
+		UPDATE user_account
+		SET password = $2
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, []byte(password))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// MarkEmailVerified confirms the email of the user, the first confirmation
+// is kept when the same email is verified again
+func (r *repository) MarkEmailVerified(ctx context.Context, id string) error {
+	query := `
+		UPDATE user_account
+		SET email_verified_at = COALESCE(email_verified_at, $2)
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateProfile saves the username, the email, the name and the surname of the
+// user, a new email has to be verified again so its confirmation is dropped
+func (r *repository) UpdateProfile(ctx context.Context, user *User) error {
+	query := `
+		UPDATE user_account
+		SET username = $2,
+			email = $3,
+			name = $4,
+			surname = $5,
+			email_verified_at = CASE WHEN email = $3 THEN email_verified_at ELSE NULL END
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, user.ID, user.Username, user.Email, user.Name, user.Surname)
+	if err != nil {
+		return toUniqueError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteOneByID deletes the user and the households it owns, the devices with their
+// readings and targets, the memberships and the second factor are deleted
+// by the database with ON DELETE CASCADE
+func (r *repository) DeleteOneByID(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// the rollback does nothing once the transaction is committed
+	defer tx.Rollback()
+
+	// a household cannot be left without an owner
+	query := `
+		DELETE FROM household
+		WHERE id IN (
+			SELECT household_id FROM household_member
+			WHERE user_id = $1 AND role = 'owner'
+		)
+	`
+	_, err = tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM user_account
+		WHERE id = $1
+	`
+	result, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return tx.Commit()
+}
+
+// toUniqueError tells which unique column has been duplicated,
+// the other errors are returned as they are
+func toUniqueError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return err
+	}
+	switch pqErr.Constraint {
+	case "user_account_username_key":
+		return ErrUsernameTaken
+	case "user_account_email_key":
+		return ErrEmailTaken
+	}
+	return err
+}
+
 func (ue *UserEntity) toUser() *User {
-	return &User{
+	user := &User{
 		ID:       ue.ID.String(),
 		Username: ue.Username,
 		Email:    ue.Email,
@@ -88,6 +246,11 @@ func (ue *UserEntity) toUser() *User {
 		Name:     ue.Name,
 		Surname:  ue.Surname,
 	}
+	if ue.EmailVerifiedAt.Valid {
+		verifiedAt := ue.EmailVerifiedAt.Time
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	return user
 }
 
 func toEntity(user *User) (*UserEntity, error) {
@@ -1 +1,190 @@
 package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/testutils"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+var testPostgresDB *sql.DB
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Short() {
+		pgConnectionStr := testutils.SetupPostgres()
+		testPostgresDB, _ = sql.Open("postgres", pgConnectionStr)
+	}
+
+	os.Exit(m.Run())
+}
+
+// createTestUser registers a user with a verified email and a unique username
+func createTestUser(ctx context.Context, t *testing.T, repo *repository) *User {
+	t.Helper()
+	id := uuid.NewString()
+	user := &User{
+		ID:       id,
+		Username: id[:8],
+		Email:    id + "@example.com",
+		Password: "hash",
+		Name:     "test",
+		Surname:  "user",
+	}
+	if err := repo.CreateOne(ctx, user); err != nil {
+		t.Fatalf("CreateOne() error = %v", err)
+	}
+	if err := repo.MarkEmailVerified(ctx, id); err != nil {
+		t.Fatalf("MarkEmailVerified() error = %v", err)
+	}
+	return user
+}
+
+func TestRepository_UpdateProfile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewUserRepository(testPostgresDB)
+
+	t.Run("same_email_keeps_verification", func(t *testing.T) {
+		user := createTestUser(ctx, t, repo)
+		user.Name = "mario"
+		user.Surname = "rossi"
+		if err := repo.UpdateProfile(ctx, user); err != nil {
+			t.Fatalf("UpdateProfile() error = %v", err)
+		}
+
+		got, err := repo.GetOneByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetOneByID() error = %v", err)
+		}
+		if got.Name != "mario" || got.Surname != "rossi" {
+			t.Errorf("GetOneByID() = %+v", got)
+		}
+		if !got.IsEmailVerified() {
+			t.Errorf("expected the email to stay verified")
+		}
+	})
+
+	t.Run("new_email_drops_verification", func(t *testing.T) {
+		user := createTestUser(ctx, t, repo)
+		user.Email = uuid.NewString() + "@example.com"
+		if err := repo.UpdateProfile(ctx, user); err != nil {
+			t.Fatalf("UpdateProfile() error = %v", err)
+		}
+
+		got, err := repo.GetOneByEmail(ctx, user.Email)
+		if err != nil {
+			t.Fatalf("GetOneByEmail() error = %v", err)
+		}
+		if got == nil || got.ID != user.ID {
+			t.Fatalf("GetOneByEmail() = %+v", got)
+		}
+		if got.IsEmailVerified() {
+			t.Errorf("expected the new email to be unverified")
+		}
+	})
+
+	t.Run("duplicate_username", func(t *testing.T) {
+		user := createTestUser(ctx, t, repo)
+		other := createTestUser(ctx, t, repo)
+		user.Username = other.Username
+		err := repo.UpdateProfile(ctx, user)
+		if !errors.Is(err, ErrUsernameTaken) {
+			t.Errorf("UpdateProfile() error = %v, want %v", err, ErrUsernameTaken)
+		}
+	})
+
+	t.Run("duplicate_email", func(t *testing.T) {
+		user := createTestUser(ctx, t, repo)
+		other := createTestUser(ctx, t, repo)
+		user.Email = other.Email
+		err := repo.UpdateProfile(ctx, user)
+		if !errors.Is(err, ErrEmailTaken) {
+			t.Errorf("UpdateProfile() error = %v, want %v", err, ErrEmailTaken)
+		}
+	})
+
+	t.Run("missing_user", func(t *testing.T) {
+		id := uuid.NewString()
+		err := repo.UpdateProfile(ctx, &User{ID: id, Username: id[:8], Email: id + "@example.com"})
+		if !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("UpdateProfile() error = %v, want %v", err, ErrUserNotFound)
+		}
+	})
+}
+
+func TestRepository_DeleteOneByID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+	ctx := context.Background()
+	repo := NewUserRepository(testPostgresDB)
+	user := createTestUser(ctx, t, repo)
+
+	// a device with a reading, a target, a second factor and a household, all owned by the user
+	deviceID := uuid.NewString()
+	householdID := uuid.NewString()
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{"INSERT INTO device(id, user_id, name) VALUES($1, $2, $3)", []any{deviceID, user.ID, "living room"}},
+		{"INSERT INTO reading(device_id, ts, lux, raw, duty_cycle) VALUES($1, $2, $3, $4, $5)", []any{deviceID, time.Now(), 120.5, 512, 0.4}},
+		{"INSERT INTO device_target(device_id, brightness, set_by) VALUES($1, $2, $3)", []any{deviceID, 60, user.ID}},
+		{"INSERT INTO user_mfa(user_id, totp_secret) VALUES($1, $2)", []any{user.ID, "secret"}},
+		{"INSERT INTO household(id, name) VALUES($1, $2)", []any{householdID, "home"}},
+		{"INSERT INTO household_member(household_id, user_id, role) VALUES($1, $2, $3)", []any{householdID, user.ID, "owner"}},
+	}
+	for _, statement := range statements {
+		if _, err := testPostgresDB.ExecContext(ctx, statement.query, statement.args...); err != nil {
+			t.Fatalf("failed to insert test data: %v", err)
+		}
+	}
+
+	if err := repo.DeleteOneByID(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteOneByID() error = %v", err)
+	}
+
+	got, err := repo.GetOneByID(ctx, user.ID)
+	if err != nil || got != nil {
+		t.Errorf("GetOneByID() after delete = %+v, %v", got, err)
+	}
+
+	counts := map[string]string{
+		"device":        "SELECT COUNT(*) FROM device WHERE user_id = $1",
+		"reading":       "SELECT COUNT(*) FROM reading WHERE device_id = $1",
+		"device_target": "SELECT COUNT(*) FROM device_target WHERE device_id = $1",
+		"user_mfa":      "SELECT COUNT(*) FROM user_mfa WHERE user_id = $1",
+		"household":     "SELECT COUNT(*) FROM household WHERE id = $1",
+	}
+	for table, query := range counts {
+		arg := user.ID
+		if table == "reading" || table == "device_target" {
+			arg = deviceID
+		}
+		if table == "household" {
+			arg = householdID
+		}
+		var count int
+		if err := testPostgresDB.QueryRowContext(ctx, query, arg).Scan(&count); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("expected the %s rows to be deleted, got %d", table, count)
+		}
+	}
+
+	err = repo.DeleteOneByID(ctx, user.ID)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("DeleteOneByID() on missing user error = %v, want %v", err, ErrUserNotFound)
+	}
+}
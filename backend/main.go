@@ -2,17 +2,52 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/AliceOrlandini/Auto-Light-Pi/internal/bootstrap"
+	"github.com/AliceOrlandini/Auto-Light-Pi/internal/config"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 func main() {
 	ctx := context.Background()
-	port := os.Getenv("BACKEND_PORT")
-	
+
+	// "migrate up|down|status" only updates the schema, the flags follow the command
+	args := os.Args[1:]
+	migrateCommand := ""
+	if len(args) > 0 && args[0] == "migrate" {
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "usage: migrate up|down|status [flags]")
+			os.Exit(2)
+		}
+		migrateCommand, args = args[1], args[2:]
+	}
+
+	// the configuration is checked before anything else, so that a wrong
+	// value stops the server right away with the list of the fields to fix
+	cfg, err := config.Load(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if migrateCommand != "" {
+		if err := bootstrap.RunMigrate(ctx, cfg, migrateCommand, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "migrate "+migrateCommand+": "+err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	// configure log rotation on a file
 	rotator := &lumberjack.Logger{
 		Filename:   "app.log", 	// log file path
@@ -28,10 +63,38 @@ func main() {
 	// makes the log defined the main logger of the application
 	slog.SetDefault(slog.New(handler))
 
-	app, err := bootstrap.InitializeServer(ctx)
+	server, err := bootstrap.InitializeServer(ctx, cfg)
 	if err != nil {
 		panic("failed to initialize server: " + err.Error())
 	}
 
-  app.Run("0.0.0.0:" + port)
+	// docker compose stops the container with SIGTERM, ctrl+c sends SIGINT
+	signalCtx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		// e.g. the port is already in use, what has been started is stopped anyway
+		slog.Error("failed to serve", "error", err)
+		exitCode = 1
+	case <-signalCtx.Done():
+		slog.Info("shutdown requested", "timeout", cfg.Server.ShutdownTimeout)
+	}
+	// a second signal kills the server at once
+	stopSignals()
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
+	err = server.Shutdown(shutdownCtx)
+	cancel()
+	if err != nil {
+		slog.Error("failed to shut down cleanly", "error", err)
+		exitCode = 1
+	}
+	slog.Info("server stopped")
+	os.Exit(exitCode)
 }
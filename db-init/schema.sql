@@ -1,8 +0,0 @@
-CREATE TABLE IF NOT EXISTS USER_ACCOUNT (
-  id UUID PRIMARY KEY,
-  username VARCHAR(50) UNIQUE NOT NULL,
-  email VARCHAR(254) UNIQUE NOT NULL,
-  password TEXT NOT NULL,
-  name VARCHAR(50) NOT NULL,
-  surname VARCHAR(50) NOT NULL
-);